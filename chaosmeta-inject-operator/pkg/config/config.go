@@ -74,6 +74,16 @@ type MistConfig struct {
 
 type AgentExecutorConfig struct {
 	AgentPort int `json:"agentPort"`
+	// TLSConfig use https to call the agent if "caPath" is provided
+	TLSConfig AgentTLSConfig `json:"tlsConfig"`
+	// TokenPath the file of bearer token, such as a mounted secret, the token is sent in "Authorization" header if provided
+	TokenPath string `json:"tokenPath"`
+}
+
+type AgentTLSConfig struct {
+	CaPath   string `json:"caPath"`
+	CertPath string `json:"certPath"`
+	KeyPath  string `json:"keyPath"`
 }
 
 type DaemonsetExecutorConfig struct {
@@ -18,12 +18,17 @@ package agentexecutor
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"github.com/traas-stack/chaosmeta/chaosmeta-inject-operator/api/v1alpha1"
+	"github.com/traas-stack/chaosmeta/chaosmeta-inject-operator/pkg/config"
 	"github.com/traas-stack/chaosmeta/chaosmeta-inject-operator/pkg/executor/remoteexecutor/base"
 	httpclient "github.com/traas-stack/chaosmeta/chaosmeta-inject-operator/pkg/http"
 	"github.com/traas-stack/chaosmeta/chaosmeta-inject-operator/pkg/model"
+	"net/http"
+	"os"
 	"strconv"
 	"strings"
 )
@@ -32,6 +37,57 @@ type AgentRemoteExecutor struct {
 	Client      *httpclient.HTTPClient
 	ServicePort int
 	Version     string
+	// Scheme "http" or "https"
+	Scheme string
+}
+
+func NewAgentRemoteExecutor(version string, agentConfig config.AgentExecutorConfig) (*AgentRemoteExecutor, error) {
+	var (
+		scheme    = "http"
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+		token     string
+	)
+
+	if agentConfig.TLSConfig.CaPath != "" {
+		caBytes, err := os.ReadFile(agentConfig.TLSConfig.CaPath)
+		if err != nil {
+			return nil, fmt.Errorf("read ca file error: %s", err.Error())
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBytes) {
+			return nil, fmt.Errorf("no valid certificate found in ca file[%s]", agentConfig.TLSConfig.CaPath)
+		}
+
+		tlsConfig := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+		if agentConfig.TLSConfig.CertPath != "" && agentConfig.TLSConfig.KeyPath != "" {
+			cert, err := tls.LoadX509KeyPair(agentConfig.TLSConfig.CertPath, agentConfig.TLSConfig.KeyPath)
+			if err != nil {
+				return nil, fmt.Errorf("load client certificate error: %s", err.Error())
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		scheme, transport.TLSClientConfig = "https", tlsConfig
+	}
+
+	if agentConfig.TokenPath != "" {
+		tokenBytes, err := os.ReadFile(agentConfig.TokenPath)
+		if err != nil {
+			return nil, fmt.Errorf("read token file error: %s", err.Error())
+		}
+		token = strings.TrimSpace(string(tokenBytes))
+	}
+
+	return &AgentRemoteExecutor{
+		Client: &httpclient.HTTPClient{
+			Client: &http.Client{Transport: transport},
+			Token:  token,
+		},
+		Version:     version,
+		ServicePort: agentConfig.AgentPort,
+		Scheme:      scheme,
+	}, nil
 }
 
 func (r *AgentRemoteExecutor) CheckExecutorWay(ctx context.Context) error {
@@ -40,7 +96,7 @@ func (r *AgentRemoteExecutor) CheckExecutorWay(ctx context.Context) error {
 }
 
 func (r *AgentRemoteExecutor) CheckAlive(ctx context.Context, injectObject string) error {
-	resBytes, err := r.Client.Get(ctx, fmt.Sprintf("http://%s:%d/v1/version", injectObject, r.ServicePort))
+	resBytes, err := r.Client.Get(ctx, fmt.Sprintf("%s://%s:%d/v1/version", r.Scheme, injectObject, r.ServicePort))
 	if err != nil {
 		return fmt.Errorf("get response error: %s", err.Error())
 	}
@@ -110,7 +166,7 @@ func (r *AgentRemoteExecutor) Inject(ctx context.Context, injectObject string, t
 		return fmt.Errorf("request to string error: %s", err.Error())
 	}
 
-	resBytes, err := r.Client.Post(ctx, fmt.Sprintf("http://%s:%d/v1/experiment/inject", injectObject, r.ServicePort), bytesData)
+	resBytes, err := r.Client.Post(ctx, fmt.Sprintf("%s://%s:%d/v1/experiment/inject", r.Scheme, injectObject, r.ServicePort), bytesData)
 	if err != nil {
 		return fmt.Errorf("get response error: %s", err.Error())
 	}
@@ -136,7 +192,7 @@ func (r *AgentRemoteExecutor) Recover(ctx context.Context, injectObject string,
 		return fmt.Errorf("request to string error: %s", err.Error())
 	}
 
-	resBytes, err := r.Client.Post(ctx, fmt.Sprintf("http://%s:%d/v1/experiment/recover", injectObject, r.ServicePort), bytesData)
+	resBytes, err := r.Client.Post(ctx, fmt.Sprintf("%s://%s:%d/v1/experiment/recover", r.Scheme, injectObject, r.ServicePort), bytesData)
 	if err != nil {
 		return fmt.Errorf("get response error: %s", err.Error())
 	}
@@ -162,7 +218,7 @@ func (r *AgentRemoteExecutor) Query(ctx context.Context, injectObject string, ui
 		return nil, fmt.Errorf("request to string error: %s", err.Error())
 	}
 
-	resBytes, err := r.Client.Post(ctx, fmt.Sprintf("http://%s:%d/v1/experiment/query", injectObject, r.ServicePort), bytesData)
+	resBytes, err := r.Client.Post(ctx, fmt.Sprintf("%s://%s:%d/v1/experiment/query", r.Scheme, injectObject, r.ServicePort), bytesData)
 	if err != nil {
 		return nil, fmt.Errorf("get response error: %s", err.Error())
 	}
@@ -26,11 +26,9 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmeta-inject-operator/pkg/executor/remoteexecutor/middlewareexecutor"
 	"github.com/traas-stack/chaosmeta/chaosmeta-inject-operator/pkg/executor/remoteexecutor/middlewareexecutor/tse"
 	"github.com/traas-stack/chaosmeta/chaosmeta-inject-operator/pkg/executor/remoteexecutor/middlewareexecutor/tse/auth"
-	httpclient "github.com/traas-stack/chaosmeta/chaosmeta-inject-operator/pkg/http"
 	"github.com/traas-stack/chaosmeta/chaosmeta-inject-operator/pkg/model"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
-	"net/http"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -99,12 +97,9 @@ func AutoSelectRemoteExecutor(ctx context.Context, config *config.ExecutorConfig
 		logger.Error(err, "fail to check middleware way")
 	}
 
-	agentExecutor := &agentexecutor.AgentRemoteExecutor{
-		Client: &httpclient.HTTPClient{
-			Client: &http.Client{},
-		},
-		Version:     config.Version,
-		ServicePort: config.AgentConfig.AgentPort,
+	agentExecutor, err := agentexecutor.NewAgentRemoteExecutor(config.Version, config.AgentConfig)
+	if err != nil {
+		return fmt.Errorf("new agent executor error: %s", err.Error())
 	}
 	if err := agentExecutor.CheckExecutorWay(ctx); err == nil {
 		logger.Info("select agent way")
@@ -116,13 +111,11 @@ func AutoSelectRemoteExecutor(ctx context.Context, config *config.ExecutorConfig
 func SetGlobalRemoteExecutor(config *config.ExecutorConfig, restConfig *rest.Config, schema *runtime.Scheme) error {
 	switch RemoteModeType(config.Mode) {
 	case AgentRemoteMode:
-		globalRemoteExecutor = &agentexecutor.AgentRemoteExecutor{
-			Client: &httpclient.HTTPClient{
-				Client: &http.Client{},
-			},
-			Version:     config.Version,
-			ServicePort: config.AgentConfig.AgentPort,
+		agentExecutor, err := agentexecutor.NewAgentRemoteExecutor(config.Version, config.AgentConfig)
+		if err != nil {
+			return fmt.Errorf("new agent executor error: %s", err.Error())
 		}
+		globalRemoteExecutor = agentExecutor
 	case DaemonsetRemoteMode:
 		globalRemoteExecutor = &daemonsetexecutor.DaemonsetRemoteExecutor{
 			//ApiServer:  apiServer,
@@ -27,6 +27,8 @@ import (
 
 type HTTPClient struct {
 	Client *http.Client
+	// Token set as "Authorization: Bearer [Token]" if not empty
+	Token string
 }
 
 func (h *HTTPClient) Post(ctx context.Context, url string, data []byte) ([]byte, error) {
@@ -37,6 +39,7 @@ func (h *HTTPClient) Post(ctx context.Context, url string, data []byte) ([]byte,
 	if err != nil {
 		return nil, fmt.Errorf("new requset error: %s", err.Error())
 	}
+	h.setAuth(req)
 
 	resp, err := h.Client.Do(req)
 	if err != nil {
@@ -59,6 +62,7 @@ func (h *HTTPClient) Get(ctx context.Context, url string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("new requset error: %s", err.Error())
 	}
+	h.setAuth(req)
 
 	resp, err := h.Client.Do(req)
 	if err != nil {
@@ -73,3 +77,9 @@ func (h *HTTPClient) Get(ctx context.Context, url string) ([]byte, error) {
 	logger.Info("response: " + string(res))
 	return res, nil
 }
+
+func (h *HTTPClient) setAuth(req *http.Request) {
+	if h.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.Token)
+	}
+}
@@ -44,9 +44,15 @@ CPU_LOAD="chaosmeta_cpuload"
 DISK_BURN="chaosmeta_diskburn"
 
 MEM_FILL="chaosmeta_memfill"
+MEM_LEAK="chaosmeta_memleak"
 FD_FULL="chaosmeta_fd"
 NPROC="chaosmeta_nproc"
+CONNTRACK="chaosmeta_conntrack"
 NET_OCCUPY="chaosmeta_occupy"
+NET_HTTPPROXY="chaosmeta_httpproxy"
+NET_GRPCPROXY="chaosmeta_grpcproxy"
+DNS_PROXY="chaosmeta_dnsproxy"
+IO_FAULT="chaosmeta_iofault"
 JVM_AGENT="ChaosMetaJVMAgent"
 JVM_ATTACHER="ChaosMetaJVMAttacher"
 JVM_METHOD_RULE="ChaosMetaJVMMethodRule"
@@ -55,7 +61,7 @@ JVM_PACKAGE="chaosmeta-jvm-1.1.1.tar.gz"
 
 DISK_EXEC="chaosmeta_diskfill"
 TOOL_EXECNS="chaosmeta_execns"
-#FILE_EXEC="chaosmeta_file"
+FILE_EXEC="chaosmeta_file"
 #PRO_EXEC="chaosmeta_process"
 #NET_EXEC="chaosmeta_network"
 DISKIO_EXEC="chaosmeta_diskio"
@@ -91,17 +97,23 @@ rm -rf ${VERSION_DIR}/version.go && mv ${VERSION_DIR}/version.bak ${VERSION_DIR}
 # build tool
 mkdir -p ${PACKAGE_DIR}/${OS_NAME}/tools
 
-gcc ${PROJECT_DIR}/tools/${CPU_LOAD}.c -o ${PACKAGE_DIR}/${OS_NAME}/tools/${CPU_LOAD}
+gcc ${PROJECT_DIR}/tools/${CPU_LOAD}.c -o ${PACKAGE_DIR}/${OS_NAME}/tools/${CPU_LOAD} -lm
 CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${CPU_BURN} ${PROJECT_DIR}/tools/${CPU_BURN}.go
 CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${DISK_BURN} ${PROJECT_DIR}/tools/${DISK_BURN}.go
 CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${MEM_FILL} ${PROJECT_DIR}/tools/${MEM_FILL}.go
+CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${MEM_LEAK} ${PROJECT_DIR}/tools/${MEM_LEAK}.go
 CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${NET_OCCUPY} ${PROJECT_DIR}/tools/${NET_OCCUPY}.go
+CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${NET_HTTPPROXY} ${PROJECT_DIR}/tools/${NET_HTTPPROXY}.go
+CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${NET_GRPCPROXY} ${PROJECT_DIR}/tools/${NET_GRPCPROXY}.go
+CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${DNS_PROXY} ${PROJECT_DIR}/tools/${DNS_PROXY}.go
+CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${IO_FAULT} ${PROJECT_DIR}/tools/${IO_FAULT}.go
 CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${FD_FULL} ${PROJECT_DIR}/tools/${FD_FULL}.go
 CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${NPROC} ${PROJECT_DIR}/tools/${NPROC}.go
+CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${CONNTRACK} ${PROJECT_DIR}/tools/${CONNTRACK}.go
 
 gcc ${EXEC_DIR}/execns/${TOOL_EXECNS}.c -o ${PACKAGE_DIR}/${OS_NAME}/tools/${TOOL_EXECNS}
 CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${DISK_EXEC} ${EXEC_DIR}/disk/${DISK_EXEC}.go
-#CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${FILE_EXEC} ${EXEC_DIR}/file/${FILE_EXEC}.go
+CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${FILE_EXEC} ${EXEC_DIR}/file/${FILE_EXEC}.go
 #CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${PRO_EXEC} ${EXEC_DIR}/process/${PRO_EXEC}.go
 #CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${NET_EXEC} ${EXEC_DIR}/network/${NET_EXEC}.go
 CGO_ENABLED=1 GOOS=${OS_NAME} GOARCH=${ARCH_NAME} ${GO_TOOL} build -o ${PACKAGE_DIR}/${OS_NAME}/tools/${DISKIO_EXEC} ${EXEC_DIR}/diskio/${DISKIO_EXEC}.go
@@ -25,11 +25,15 @@ import (
 	_ "github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector/diskio"
 	_ "github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector/dns"
 	_ "github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector/file"
+	_ "github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector/grpc"
+	_ "github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector/http"
+	_ "github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector/io"
 	_ "github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector/jvm"
 	_ "github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector/kernel"
 	_ "github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector/mem"
 	_ "github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector/network"
 	_ "github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector/process"
+	_ "github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector/time"
 )
 
 // NewInjectCommand injectCmd represents the inject command
@@ -45,10 +49,14 @@ func NewInjectCommand() *cobra.Command {
 	injectCmd.PersistentFlags().StringVarP(&args.Timeout, "timeout", "t", "", "experiment's duration, support unit: \"s、m、h\"(default s)")
 	injectCmd.PersistentFlags().StringVar(&args.Creator, "creator", "", "experiment's creator（default the cmd exec user）")
 
-	injectCmd.PersistentFlags().StringVar(&args.ContainerRuntime, "container-runtime", "", "if attack a container of local host, need to provide the container runtime of target container")
+	injectCmd.PersistentFlags().StringVar(&args.ContainerRuntime, "container-runtime", "", "container runtime of target container, support: docker、containerd、pouch、crio、cri(socket is provided by env CONTAINER_RUNTIME_ENDPOINT). if not provided, it will be detected by the runtime sockets on local host")
 	injectCmd.PersistentFlags().StringVar(&args.ContainerId, "container-id", "", "if attack a container of local host, need to provide the container id of target container")
 
 	injectCmd.PersistentFlags().StringVar(&args.Uid, "uid", "", "if not provide, it will automatically generate an uid")
+
+	injectCmd.PersistentFlags().StringVar(&args.Interval, "interval", "", "flapping mode: inject the fault every interval, support unit: \"s、m、h\"(default s)")
+	injectCmd.PersistentFlags().StringVar(&args.OnDuration, "on-duration", "", "flapping mode: duration to keep the fault injected in each interval, must be less than \"interval\", support unit: \"s、m、h\"(default 0, means recover at once, eg: for process kill)")
+	injectCmd.PersistentFlags().IntVar(&args.Repeat, "repeat", 0, "flapping mode: count of cycles to inject（default 0, means until timeout or recovered manually）")
 	//var args = make([]string, 2)
 	//injectCmd.PersistentFlags().StringVarP(&args[0], "timeout", "t", "", "experiment's duration（default 0, means need to stop manually）")
 	//injectCmd.PersistentFlags().StringVar(&args[1], "creator", "", "experiment's creator（default the cmd exec user）")
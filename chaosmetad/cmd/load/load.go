@@ -31,7 +31,7 @@ func NewLoadCommand() *cobra.Command {
 
 	loadCmd.PersistentFlags().StringVar(&loadArgs.Info.Creator, "creator", "", "experiment's creator（default the cmd exec user）")
 
-	loadCmd.PersistentFlags().StringVar(&loadArgs.Info.ContainerRuntime, "container-runtime", "", "if attack a container of local host, need to provide the container runtime of target container")
+	loadCmd.PersistentFlags().StringVar(&loadArgs.Info.ContainerRuntime, "container-runtime", "", "container runtime of target container, support: docker、containerd、pouch、crio、cri(socket is provided by env CONTAINER_RUNTIME_ENDPOINT). if not provided, it will be detected by the runtime sockets on local host")
 	loadCmd.PersistentFlags().StringVar(&loadArgs.Info.ContainerId, "container-id", "", "if attack a container of local host, need to provide the container id of target container")
 
 	loadCmd.PersistentFlags().StringVar(&loadArgs.Info.Uid, "uid", "", "if not provide, it will automatically generate an uid")
@@ -19,6 +19,7 @@ package main
 import (
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/cmd/flapping"
 	"github.com/traas-stack/chaosmeta/chaosmetad/cmd/inject"
 	"github.com/traas-stack/chaosmeta/chaosmetad/cmd/load"
 	"github.com/traas-stack/chaosmeta/chaosmetad/cmd/query"
@@ -48,6 +49,7 @@ func initRootCmd() {
 	rootCmd.AddCommand(server.NewServerCommand())
 	rootCmd.AddCommand(version.NewVersionCommand())
 	rootCmd.AddCommand(load.NewLoadCommand())
+	rootCmd.AddCommand(flapping.NewFlappingCommand())
 }
 
 func main() {
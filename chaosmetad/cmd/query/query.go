@@ -29,6 +29,7 @@ func NewQueryCommand() *cobra.Command {
 	var (
 		optionQuery = &query.OptionExpQuery{}
 		ifAll       bool
+		ifWatch     bool
 		format      string
 	)
 
@@ -36,7 +37,12 @@ func NewQueryCommand() *cobra.Command {
 		Use:   "query",
 		Short: "experiment query command",
 		Run: func(cmd *cobra.Command, args []string) {
-			query.PrintExpByOption(utils.GetCtxWithTraceId(context.Background(), utils.TraceId), optionQuery, ifAll, format)
+			ctx := utils.GetCtxWithTraceId(context.Background(), utils.TraceId)
+			if ifWatch {
+				query.WatchExpByOption(ctx, optionQuery, ifAll, format)
+			} else {
+				query.PrintExpByOption(ctx, optionQuery, ifAll, format)
+			}
 		},
 	}
 
@@ -48,6 +54,7 @@ func NewQueryCommand() *cobra.Command {
 	queryCmd.Flags().UintVarP(&optionQuery.Offset, "offset", "o", 0, "query experiment records with offset, eg: chaosmetad query -o 5")
 	queryCmd.Flags().UintVarP(&optionQuery.Limit, "limit", "l", 10, "query experiment records with limit, eg: chaosmetad query -o 5 -l 5")
 	queryCmd.Flags().BoolVarP(&ifAll, "all", "a", false, "if show all")
+	queryCmd.Flags().BoolVarP(&ifWatch, "watch", "w", false, "print the events of the matched experiments continuously after query, eg: chaosmetad query -t cpu -w")
 	queryCmd.Flags().StringVar(&format, "format", query.TableFormat, fmt.Sprintf("data show format, support: %s(default), %s", query.TableFormat, query.JsonFormat))
 
 	return queryCmd
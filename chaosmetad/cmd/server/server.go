@@ -18,8 +18,12 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/metrics"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/errutil"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
@@ -53,51 +57,115 @@ func watchSignal(ctx context.Context) {
 // NewServerCommand serverCmd represents the server command
 func NewServerCommand() *cobra.Command {
 	var addr, port string
-	//var cert, key string
-	var isPprof bool
+	var cert, key, clientCA, authConfigPath, auditLogPath string
+	var isPprof, isReconcile, isClientCertOptional bool
 	cmd := &cobra.Command{
 		Use:   "server",
 		Short: "start up daemon service",
 		Run: func(cmd *cobra.Command, args []string) {
 			ctx := utils.GetCtxWithTraceId(context.Background(), "system")
+			tlsConfig, err := getTLSConfig(cert, key, clientCA, isClientCertOptional)
+			if err != nil {
+				errutil.SolveErr(ctx, errutil.BadArgsErr, fmt.Sprintf("get tls config error: %s", err.Error()))
+			}
+
+			var authConfig *web.AuthConfig
+			if authConfigPath != "" {
+				if authConfig, err = web.LoadAuthConfig(authConfigPath); err != nil {
+					errutil.SolveErr(ctx, errutil.BadArgsErr, fmt.Sprintf("load auth config[%s] error: %s", authConfigPath, err.Error()))
+				}
+			}
+
+			auditor, err := web.NewAuditor(auditLogPath)
+			if err != nil {
+				errutil.SolveErr(ctx, errutil.BadArgsErr, fmt.Sprintf("new auditor error: %s", err.Error()))
+			}
+
+			metrics.Registry.MustRegister(injector.NewExperimentCollector(ctx))
 			go watchSignal(ctx)
 
-			//if cert != "" && key != "" {
-			//	startHTTPSServer(addr, port, isPprof, cert, key)
-			//} else {
-			startHTTPService(ctx, addr, port, isPprof)
-			//}
+			if isReconcile {
+				injector.ProcessReconcile(ctx)
+			}
+
+			startHTTPService(ctx, addr, port, isPprof, tlsConfig, cert, key, authConfig, auditor)
 		},
 	}
 
 	cmd.Flags().StringVarP(&addr, "addr", "a", "0.0.0.0", "service bind addr")
 	cmd.Flags().StringVarP(&port, "port", "p", "29595", "service bind port")
-	cmd.Flags().BoolVar(&isPprof, "enable-pprof", true, "if open pprof service")
-	//cmd.Flags().StringVarP(&cert, "cert", "c", "", "path to certificate file")
-	//cmd.Flags().StringVarP(&key, "key", "k", "", "path to private key file")
-	// HTTPS
-	//      --CA string            path to a PEM encoded CA's certificate file
-	//      --cert string          path to a PEM encoded certificate file
-	//   	--key string           path to a PEM encoded private key file
+	cmd.Flags().BoolVar(&isPprof, "enable-pprof", false, "if open pprof service")
+	cmd.Flags().BoolVar(&isReconcile, "enable-reconcile", true, "if recover the experiments left over by the last crash of daemon or host when start up")
+	cmd.Flags().StringVar(&cert, "cert", "", "path to a PEM encoded certificate file, enable https if provided with \"--key\"")
+	cmd.Flags().StringVar(&key, "key", "", "path to a PEM encoded private key file")
+	cmd.Flags().StringVar(&clientCA, "client-ca", "", "path to a PEM encoded CA's certificate file, enable the verification of client certificate if provided")
+	cmd.Flags().BoolVar(&isClientCertOptional, "client-cert-optional", false, "only verify the client certificate if it is given, so that the client can authenticate by token too")
+	cmd.Flags().StringVar(&authConfigPath, "auth-config", "", "path to the auth config file in json format, enable the authentication if provided")
+	cmd.Flags().StringVar(&auditLogPath, "audit-log-path", fmt.Sprintf("%s/%s_audit.log", utils.GetRunPath(), utils.RootName), "path to the audit log file of inject and recover")
 	return cmd
 }
 
-func startHTTPService(ctx context.Context, addr string, port string, isPprof bool) {
-	logger := log.GetLogger(ctx)
-	logger.Infof("HTTP Service Listen on %s:%s, pprof: %t", addr, port, isPprof)
-	router := web.NewRouter(ctx, isPprof)
+func getTLSConfig(cert, key, clientCA string, isClientCertOptional bool) (*tls.Config, error) {
+	if cert == "" && key == "" {
+		if clientCA != "" {
+			return nil, fmt.Errorf("\"--client-ca\" must be provided with \"--cert\" and \"--key\"")
+		}
+
+		return nil, nil
+	}
+
+	if cert == "" || key == "" {
+		return nil, fmt.Errorf("\"--cert\" and \"--key\" must be provided together")
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if clientCA == "" {
+		return config, nil
+	}
+
+	caBytes, err := os.ReadFile(clientCA)
+	if err != nil {
+		return nil, fmt.Errorf("read client ca file error: %s", err.Error())
+	}
 
-	if err := http.ListenAndServe(fmt.Sprintf("%s:%s", addr, port), router); err != nil {
-		logger.Fatalf("start http service fail: %s", err.Error())
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBytes) {
+		return nil, fmt.Errorf("no valid certificate found in client ca file[%s]", clientCA)
 	}
+
+	config.ClientCAs = pool
+	if isClientCertOptional {
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	} else {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
 }
 
-//func startHTTPSServer(addr string, port string, isPprof bool, cert, key string) {
-//	logger := tools.GetLogger()
-//	logger.Infof("HTTPS Service Listen on %s:%s, pprof: %t, cert: %s, key: %s", addr, port, isPprof, cert, key)
-//	router := web.NewRouter(isPprof)
-//
-//	if err := http.ListenAndServeTLS(fmt.Sprintf("%s:%s", addr, port), cert, key, router); err != nil {
-//		logger.Fatalf("start https service fail: %s", err.Error())
-//	}
-//}
+func startHTTPService(ctx context.Context, addr string, port string, isPprof bool, tlsConfig *tls.Config, cert, key string, authConfig *web.AuthConfig, auditor *web.Auditor) {
+	logger := log.GetLogger(ctx)
+	if authConfig == nil && (tlsConfig == nil || tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert) {
+		logger.Warnf("authentication is disabled, anyone can call the api, use \"--auth-config\" or \"--client-ca\" to enable it")
+	}
+
+	server := &http.Server{
+		Addr:      fmt.Sprintf("%s:%s", addr, port),
+		Handler:   web.NewRouter(ctx, isPprof, authConfig, auditor),
+		TLSConfig: tlsConfig,
+	}
+
+	if tlsConfig == nil {
+		logger.Warnf("tls is disabled, the requests are transported in plain text, use \"--cert\" and \"--key\" to enable it")
+		logger.Infof("HTTP Service Listen on %s:%s, pprof: %t", addr, port, isPprof)
+		if err := server.ListenAndServe(); err != nil {
+			logger.Fatalf("start http service fail: %s", err.Error())
+		}
+		return
+	}
+
+	logger.Infof("HTTPS Service Listen on %s:%s, pprof: %t, cert: %s, key: %s, client auth: %s", addr, port, isPprof, cert, key, tlsConfig.ClientAuth.String())
+	if err := server.ListenAndServeTLS(cert, key); err != nil {
+		logger.Fatalf("start https service fail: %s", err.Error())
+	}
+}
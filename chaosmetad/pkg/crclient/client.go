@@ -21,17 +21,37 @@ import (
 	"fmt"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/crclient/base"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/crclient/containerd"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/crclient/cri"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/crclient/docker"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/crclient/pouch"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"os"
+	"strings"
+	"time"
 )
 
 const (
 	CrDocker     = "docker"
 	CrContainerd = "containerd"
 	CrPouch      = "pouch"
+	CrCrio       = "crio"
+	CrCRI        = "cri"
+
+	// DetectTimeout the max time to find the runtime of a container among all available runtimes
+	DetectTimeout = 10 * time.Second
 )
 
+// socketMap the sockets used to detect the container runtime, in order of priority
+var socketMap = []struct {
+	cr     string
+	socket string
+}{
+	{CrDocker, "/var/run/docker.sock"},
+	{CrContainerd, "/run/containerd/containerd.sock"},
+	{CrPouch, "/var/run/pouchd.sock"},
+	{CrCrio, cri.CrioSocket},
+}
+
 type Client interface {
 	GetPidById(ctx context.Context, containerID string) (int, error)
 	ListId(ctx context.Context) ([]string, error)
@@ -55,7 +75,59 @@ func GetClient(ctx context.Context, cr string) (Client, error) {
 		return containerd.GetClient(ctx)
 	case CrPouch:
 		return pouch.GetClient(ctx)
+	case CrCrio:
+		return cri.GetClient(ctx, cri.CrioSocket)
+	case CrCRI:
+		endpoint, err := cri.GetEndpoint()
+		if err != nil {
+			return nil, err
+		}
+
+		return cri.GetClient(ctx, endpoint)
 	default:
 		return nil, fmt.Errorf("not support container runtime: %s", cr)
 	}
 }
+
+// DetectRuntime find the runtime that the container belongs to among the runtimes whose socket exists,
+// the generic cri runtime is preferred if its endpoint is provided by env
+func DetectRuntime(ctx context.Context, containerID string) (string, error) {
+	crList := getAvailableRuntimeList()
+	if len(crList) == 0 {
+		return "", fmt.Errorf("no container runtime is found, please provide \"container-runtime\"")
+	}
+
+	for _, cr := range crList {
+		client, err := GetClient(ctx, cr)
+		if err != nil {
+			log.GetLogger(ctx).Debugf("get %s client error: %s", cr, err.Error())
+			continue
+		}
+
+		if _, err := client.GetPidById(ctx, containerID); err != nil {
+			log.GetLogger(ctx).Debugf("find container[%s] in runtime[%s] error: %s", containerID, cr, err.Error())
+			continue
+		}
+
+		log.GetLogger(ctx).Debugf("container[%s] is found in runtime: %s", containerID, cr)
+		return cr, nil
+	}
+
+	return "", fmt.Errorf("container[%s] is not found in any of the detected runtimes: %s", containerID, strings.Join(crList, ", "))
+}
+
+// getAvailableRuntimeList the runtimes whose endpoint is provided by env or whose socket exists, in order of priority
+func getAvailableRuntimeList() []string {
+	var crList []string
+	if os.Getenv(cri.EndpointKey) != "" {
+		crList = append(crList, CrCRI)
+	}
+
+	for _, unit := range socketMap {
+		if _, err := os.Stat(unit.socket); err == nil {
+			crList = append(crList, unit.cr)
+		}
+	}
+
+	return crList
+}
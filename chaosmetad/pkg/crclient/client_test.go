@@ -0,0 +1,119 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package crclient
+
+import (
+	"context"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/crclient/cri"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetAvailableRuntimeList(t *testing.T) {
+	dir := t.TempDir()
+	oldSocketMap := socketMap
+	defer func() { socketMap = oldSocketMap }()
+	socketMap = []struct {
+		cr     string
+		socket string
+	}{
+		{CrDocker, filepath.Join(dir, "docker.sock")},
+		{CrContainerd, filepath.Join(dir, "containerd.sock")},
+		{CrPouch, filepath.Join(dir, "pouchd.sock")},
+		{CrCrio, filepath.Join(dir, "crio.sock")},
+	}
+
+	tests := []struct {
+		name     string
+		endpoint string
+		sockets  []string
+		want     []string
+	}{
+		{
+			name: "no runtime",
+		},
+		{
+			name:    "keep priority",
+			sockets: []string{"crio.sock", "containerd.sock"},
+			want:    []string{CrContainerd, CrCrio},
+		},
+		{
+			name:     "cri endpoint first",
+			endpoint: "unix:///run/custom.sock",
+			sockets:  []string{"docker.sock", "pouchd.sock"},
+			want:     []string{CrCRI, CrDocker, CrPouch},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(cri.EndpointKey, tt.endpoint)
+			for _, unit := range socketMap {
+				_ = os.Remove(unit.socket)
+			}
+			for _, unit := range tt.sockets {
+				if err := os.WriteFile(filepath.Join(dir, unit), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := getAvailableRuntimeList(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getAvailableRuntimeList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectRuntimeWithoutRuntime(t *testing.T) {
+	oldSocketMap := socketMap
+	defer func() { socketMap = oldSocketMap }()
+	socketMap = nil
+	t.Setenv(cri.EndpointKey, "")
+
+	if got, err := DetectRuntime(context.Background(), "abc"); err == nil {
+		t.Errorf("DetectRuntime() = %v, want error", got)
+	}
+}
+
+func TestDetectRuntimeNotFound(t *testing.T) {
+	dir := t.TempDir()
+	oldSocketMap := socketMap
+	defer func() { socketMap = oldSocketMap }()
+	socketMap = []struct {
+		cr     string
+		socket string
+	}{
+		{CrDocker, filepath.Join(dir, "docker.sock")},
+	}
+	t.Setenv(cri.EndpointKey, "")
+	if err := os.WriteFile(filepath.Join(dir, "docker.sock"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got, err := DetectRuntime(ctx, "abc")
+	if err == nil {
+		t.Fatalf("DetectRuntime() = %v, want error", got)
+	}
+	if !strings.Contains(err.Error(), CrDocker) {
+		t.Errorf("DetectRuntime() error = %v, want the detected runtimes in it", err)
+	}
+}
@@ -0,0 +1,262 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cri
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	runtimeapi "github.com/alibaba/pouch/cri/apis/v1alpha2"
+	"github.com/shirou/gopsutil/process"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/crclient/base"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/containercgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EndpointKey the same env as crictl, used to specify the socket of generic cri runtime
+	EndpointKey   = "CONTAINER_RUNTIME_ENDPOINT"
+	CrioSocket    = "/var/run/crio/crio.sock"
+	unixPrefix    = "unix://"
+	connTimeout   = 5 * time.Second
+	serviceV1     = "/runtime.v1.RuntimeService"
+	serviceAlpha2 = "/runtime.v1alpha2.RuntimeService"
+)
+
+// DefaultSocketList the sockets to search when the endpoint of generic cri runtime is not provided
+var DefaultSocketList = []string{CrioSocket, "/run/containerd/containerd.sock", "/var/run/cri-dockerd.sock"}
+
+// Client talks to the RuntimeService of kubernetes CRI. the messages of v1 and v1alpha2 are the same in wire format,
+// so the v1alpha2 messages are used for both, and the service path is chosen by the version supported by the runtime
+type Client struct {
+	conn    *grpc.ClientConn
+	service string
+}
+
+var (
+	clientMap = make(map[string]*Client)
+	mutex     sync.Mutex
+)
+
+// GetEndpoint get the socket of generic cri runtime from env, otherwise the first existing socket in DefaultSocketList
+func GetEndpoint() (string, error) {
+	if endpoint := os.Getenv(EndpointKey); endpoint != "" {
+		return strings.TrimPrefix(endpoint, unixPrefix), nil
+	}
+
+	for _, socket := range DefaultSocketList {
+		if _, err := os.Stat(socket); err == nil {
+			return socket, nil
+		}
+	}
+
+	return "", fmt.Errorf("no cri socket found in %v, please provide it by env %s", DefaultSocketList, EndpointKey)
+}
+
+func GetClient(ctx context.Context, socket string) (*Client, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if client, ok := clientMap[socket]; ok {
+		return client, nil
+	}
+
+	log.GetLogger(ctx).Debugf("new cri client, socket: %s", socket)
+	if _, err := os.Stat(socket); err != nil {
+		return nil, fmt.Errorf("check socket[%s] error: %s", socket, err.Error())
+	}
+
+	conn, err := grpc.Dial(unixPrefix+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("dial socket[%s] error: %s", socket, err.Error())
+	}
+
+	client := &Client{conn: conn}
+	if client.service, err = getService(ctx, conn); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("get cri version error: %s", err.Error())
+	}
+
+	clientMap[socket] = client
+	return client, nil
+}
+
+// getService prefer v1, and fall back to v1alpha2 for old runtimes
+func getService(ctx context.Context, conn *grpc.ClientConn) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, connTimeout)
+	defer cancel()
+
+	var re runtimeapi.VersionResponse
+	for _, service := range []string{serviceV1, serviceAlpha2} {
+		err := conn.Invoke(ctx, service+"/Version", &runtimeapi.VersionRequest{}, &re)
+		if err == nil {
+			log.GetLogger(ctx).Debugf("cri runtime: %s %s, api: %s", re.RuntimeName, re.RuntimeVersion, re.RuntimeApiVersion)
+			return service, nil
+		}
+
+		if status.Code(err) != codes.Unimplemented {
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("runtime does not support cri v1 and v1alpha2")
+}
+
+func (d *Client) invoke(ctx context.Context, method string, in, out interface{}) error {
+	return d.conn.Invoke(ctx, fmt.Sprintf("%s/%s", d.service, method), in, out)
+}
+
+func (d *Client) GetPidById(ctx context.Context, containerID string) (int, error) {
+	var re runtimeapi.ContainerStatusResponse
+	if err := d.invoke(ctx, "ContainerStatus", &runtimeapi.ContainerStatusRequest{ContainerId: containerID, Verbose: true}, &re); err != nil {
+		return -1, fmt.Errorf("get status of container[%s] error: %s", containerID, err.Error())
+	}
+
+	if re.Status == nil || re.Status.State != runtimeapi.ContainerState_CONTAINER_RUNNING {
+		return -1, fmt.Errorf("container[%s] is not running", containerID)
+	}
+
+	// containerd and cri-o both provide the pid in the verbose info
+	var info struct {
+		Pid int `json:"pid"`
+	}
+	if err := json.Unmarshal([]byte(re.Info["info"]), &info); err != nil {
+		return -1, fmt.Errorf("parse verbose info of container[%s] error: %s", containerID, err.Error())
+	}
+
+	if info.Pid <= 0 {
+		return -1, fmt.Errorf("no such container[%s]", containerID)
+	}
+
+	return info.Pid, nil
+}
+
+func (d *Client) ListId(ctx context.Context) ([]string, error) {
+	var re runtimeapi.ListContainersResponse
+	req := &runtimeapi.ListContainersRequest{Filter: &runtimeapi.ContainerFilter{
+		State: &runtimeapi.ContainerStateValue{State: runtimeapi.ContainerState_CONTAINER_RUNNING}}}
+	if err := d.invoke(ctx, "ListContainers", req, &re); err != nil {
+		return nil, fmt.Errorf("get container list error: %s", err.Error())
+	}
+
+	var idList = make([]string, len(re.Containers))
+	for i, c := range re.Containers {
+		idList[i] = c.Id
+	}
+
+	return idList, nil
+}
+
+// KillContainerById stop container without grace period
+func (d *Client) KillContainerById(ctx context.Context, containerID string) error {
+	return d.invoke(ctx, "StopContainer", &runtimeapi.StopContainerRequest{ContainerId: containerID, Timeout: 0}, &runtimeapi.StopContainerResponse{})
+}
+
+// PauseContainerById cri does not support pause, so freeze the cgroup of container instead
+func (d *Client) PauseContainerById(ctx context.Context, containerID string) error {
+	pid, err := d.GetPidById(ctx, containerID)
+	if err != nil {
+		return err
+	}
+
+	return containercgroup.FreezeCgroup(pid, true)
+}
+
+func (d *Client) UnPauseContainerById(ctx context.Context, containerID string) error {
+	pid, err := d.GetPidById(ctx, containerID)
+	if err != nil {
+		return err
+	}
+
+	return containercgroup.FreezeCgroup(pid, false)
+}
+
+// RmFContainerById remove container
+func (d *Client) RmFContainerById(ctx context.Context, containerID string) error {
+	if err := d.KillContainerById(ctx, containerID); err != nil {
+		return fmt.Errorf("kill container error: %s", err.Error())
+	}
+
+	return d.invoke(ctx, "RemoveContainer", &runtimeapi.RemoveContainerRequest{ContainerId: containerID}, &runtimeapi.RemoveContainerResponse{})
+}
+
+// RestartContainerById cri can not start a stopped container, so the container is stopped gracefully, and then restarted by kubelet with a new id
+func (d *Client) RestartContainerById(ctx context.Context, containerID string, timeout int64) error {
+	return d.invoke(ctx, "StopContainer", &runtimeapi.StopContainerRequest{ContainerId: containerID, Timeout: timeout}, &runtimeapi.StopContainerResponse{})
+}
+
+// CpFile write to the root of container's mount namespace
+func (d *Client) CpFile(ctx context.Context, containerID, src, dst string) error {
+	pid, err := d.GetPidById(ctx, containerID)
+	if err != nil {
+		return err
+	}
+
+	dst = fmt.Sprintf("/proc/%d/root%s", pid, dst)
+	log.GetLogger(ctx).Debugf("target merged file: %s", dst)
+	return base.CopyFile(src, dst)
+}
+
+func (d *Client) Exec(ctx context.Context, containerID, cmd string) (string, error) {
+	var re runtimeapi.ExecSyncResponse
+	if err := d.invoke(ctx, "ExecSync", &runtimeapi.ExecSyncRequest{ContainerId: containerID, Cmd: []string{"/bin/bash", "-c", cmd}}, &re); err != nil {
+		return "", fmt.Errorf("container exec error: %s", err.Error())
+	}
+
+	output := string(re.Stdout) + string(re.Stderr)
+	if re.ExitCode != 0 {
+		return output, fmt.Errorf("exit code: %d, msg: %s", re.ExitCode, output)
+	}
+
+	return output, nil
+}
+
+func (d *Client) GetAllPidList(ctx context.Context, containerID string) ([]base.SimpleProcess, error) {
+	pid, err := d.GetPidById(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	pidList, err := containercgroup.GetCgroupPidList(pid)
+	if err != nil {
+		return nil, fmt.Errorf("get container's process error: %s", err.Error())
+	}
+
+	var reProList = make([]base.SimpleProcess, len(pidList))
+	for i, unitPid := range pidList {
+		reProList[i].Pid = unitPid
+		p, err := process.NewProcess(int32(unitPid))
+		if err != nil {
+			return nil, fmt.Errorf("process[%d] is not exist, error: %s", unitPid, err.Error())
+		}
+
+		reProList[i].Cmd, err = p.Cmdline()
+		if err != nil {
+			return nil, fmt.Errorf("get cmd of process[%d] error: %s", unitPid, err.Error())
+		}
+	}
+
+	return reProList, nil
+}
@@ -18,6 +18,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
@@ -25,12 +26,19 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/disk"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/errutil"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/profile"
 	"os"
 	"strconv"
+	"sync"
+	"syscall"
+	"time"
 )
 
 const (
 	FillFileName = "chaosmeta_fill"
+	ModeInode    = "inode"
+
+	RemoveWorkers = 4
 )
 
 // [func] [fault] [level] [args]
@@ -58,6 +66,7 @@ func main() {
 	}
 }
 
+// [percent] [bytes] [dir] [profile], or [percent] [bytes] [dir] [profile] [mode] [count]
 func execValidator(ctx context.Context, args []string) error {
 	percentStr, bytes, dir := args[0], args[1], args[2]
 	percent, err := strconv.Atoi(percentStr)
@@ -65,9 +74,24 @@ func execValidator(ctx context.Context, args []string) error {
 		return fmt.Errorf("percent is not a num")
 	}
 
+	if len(args) > 5 && args[4] == ModeInode {
+		count, err := strconv.ParseInt(args[5], 10, 64)
+		if err != nil {
+			return fmt.Errorf("count is not a num")
+		}
+
+		return validatorInodeFill(ctx, percent, count, dir)
+	}
+
+	if len(args) > 3 && args[3] != "" {
+		return validatorProfileDiskFill(ctx, dir, args[3])
+	}
+
 	return validatorDiskFill(ctx, percent, bytes, dir)
 }
 
+// [percent] [bytes] [dir] [uid], or [percent] [bytes] [dir] [uid] [profile] [timeout] if run in background with profile,
+// or [percent] [bytes] [dir] [uid] inode [count] in inode mode
 func execInject(ctx context.Context, args []string) error {
 	percentStr, bytes, dir, uid := args[0], args[1], args[2], args[3]
 	percent, err := strconv.Atoi(percentStr)
@@ -75,6 +99,24 @@ func execInject(ctx context.Context, args []string) error {
 		return fmt.Errorf("pecent is not a num")
 	}
 
+	if len(args) > 5 && args[4] == ModeInode {
+		count, err := strconv.ParseInt(args[5], 10, 64)
+		if err != nil {
+			return fmt.Errorf("count is not a num")
+		}
+
+		return injectInodeFill(ctx, percent, count, dir, uid)
+	}
+
+	if len(args) > 5 {
+		timeout, err := strconv.Atoi(args[5])
+		if err != nil {
+			return fmt.Errorf("timeout is not a num")
+		}
+
+		return injectProfileDiskFill(ctx, dir, uid, args[4], timeout)
+	}
+
 	return injectDiskFill(ctx, percent, bytes, dir, uid)
 }
 
@@ -112,6 +154,47 @@ func validatorDiskFill(ctx context.Context, percent int, bytes, dir string) erro
 	return nil
 }
 
+func validatorInodeFill(ctx context.Context, percent int, count int64, dir string) error {
+	if percent == 0 && count == 0 {
+		return fmt.Errorf("must provide \"percent\" or \"count\"")
+	}
+
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("\"percent\"[%d] must be in (0,100]", percent)
+	}
+
+	if count < 0 {
+		return fmt.Errorf("\"count\"[%d] can not less than 0", count)
+	}
+
+	if err := filesys.CheckDirLocal(dir); err != nil {
+		return fmt.Errorf("\"dir\"[%s] check error: %s", dir, err.Error())
+	}
+
+	if _, err := disk.GetFillInodes(dir, percent, count); err != nil {
+		return fmt.Errorf("calculate fill inodes error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func validatorProfileDiskFill(ctx context.Context, dir, spec string) error {
+	if err := filesys.CheckDirLocal(dir); err != nil {
+		return fmt.Errorf("\"dir\"[%s] check error: %s", dir, err.Error())
+	}
+
+	p, err := profile.Parse(spec, profile.UnitKB)
+	if err != nil {
+		return fmt.Errorf("\"profile\"[%s] is invalid: %s", spec, err.Error())
+	}
+
+	if _, err := disk.GetFillKBytes(dir, 0, fmt.Sprintf("%dKB", int64(p.Max()))); err != nil {
+		return fmt.Errorf("calculate max fill bytes of profile error: %s", err.Error())
+	}
+
+	return nil
+}
+
 func getFillFileName(uid string) string {
 	return fmt.Sprintf("%s%s.dat", FillFileName, uid)
 }
@@ -131,6 +214,117 @@ func injectDiskFill(ctx context.Context, percent int, bytes, dir, uid string) er
 	return nil
 }
 
+// injectInodeFill create empty files until the count of inodes used by the fill dir reaches the target. it is regarded
+// as success if the inodes are used up by others in the meantime
+func injectInodeFill(ctx context.Context, percent int, count int64, dir, uid string) error {
+	logger := log.GetLogger(ctx)
+	fillInodes, err := disk.GetFillInodes(dir, percent, count)
+	if err != nil {
+		return fmt.Errorf("calculate fill inodes error: %s", err.Error())
+	}
+
+	fillDir := disk.GetInodeFillDir(dir, uid)
+	if err := os.Mkdir(fillDir, 0755); err != nil {
+		return fmt.Errorf("create fill dir[%s] error: %s", fillDir, err.Error())
+	}
+
+	var (
+		created int64 = 1
+		subDir  string
+	)
+	for index := 0; created < fillInodes; index++ {
+		if index%disk.InodeFilesPerDir == 0 {
+			subDir = fmt.Sprintf("%s/%d", fillDir, index/disk.InodeFilesPerDir)
+			err = os.Mkdir(subDir, 0755)
+		} else {
+			err = syscall.Mknod(fmt.Sprintf("%s/%d", subDir, index), syscall.S_IFREG|0644, 0)
+		}
+
+		if err != nil {
+			if errors.Is(err, syscall.ENOSPC) {
+				logger.Warnf("no inode left, %d of %d inodes are filled", created, fillInodes)
+				return nil
+			}
+
+			if err := removeInodeFillDir(fillDir); err != nil {
+				logger.Warnf("run failed and delete fill dir error: %s", err.Error())
+			}
+			return fmt.Errorf("create file in dir[%s] error: %s", subDir, err.Error())
+		}
+		created++
+	}
+
+	logger.Debugf("%d inodes are filled in dir[%s]", created, fillDir)
+	return nil
+}
+
+// injectProfileDiskFill adjust the size of fill file to the level of profile every second until timeout
+func injectProfileDiskFill(ctx context.Context, dir, uid, spec string, timeout int) error {
+	logger := log.GetLogger(ctx)
+	p, err := profile.Parse(spec, profile.UnitKB)
+	if err != nil {
+		return fmt.Errorf("\"profile\"[%s] is invalid: %s", spec, err.Error())
+	}
+
+	fillFile := fmt.Sprintf("%s/%s", dir, getFillFileName(uid))
+	f, err := os.OpenFile(fillFile, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open fill file[%s] error: %s", fillFile, err.Error())
+	}
+	defer f.Close()
+
+	start := time.Now()
+	if err := resizeFillFile(f, int64(p.Value(0))*1024); err != nil {
+		if err := os.Remove(fillFile); err != nil {
+			logger.Warnf("run failed and delete fill file error: %s", err.Error())
+		}
+		return fmt.Errorf("fill file[%s] error: %s", fillFile, err.Error())
+	}
+	fmt.Println("[success]inject success")
+
+	for timeout <= 0 || time.Since(start) < time.Duration(timeout)*time.Second {
+		time.Sleep(profile.AdjustInterval)
+		if err := resizeFillFile(f, int64(p.Value(time.Since(start)))*1024); err != nil {
+			logger.Warnf("resize fill file[%s] error: %s", fillFile, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// resizeFillFile the space is allocated by fallocate, or by writing zero if the file system does not support fallocate
+func resizeFillFile(f *os.File, size int64) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat error: %s", err.Error())
+	}
+
+	nowSize := info.Size()
+	if size <= nowSize {
+		return f.Truncate(size)
+	}
+
+	if err := syscall.Fallocate(int(f.Fd()), 0, 0, size); err == nil {
+		return nil
+	}
+
+	buf := make([]byte, 1024*1024)
+	for nowSize < size {
+		n := int64(len(buf))
+		if size-nowSize < n {
+			n = size - nowSize
+		}
+
+		if _, err := f.WriteAt(buf[:n], nowSize); err != nil {
+			return fmt.Errorf("write error: %s", err.Error())
+		}
+		nowSize += n
+	}
+
+	return nil
+}
+
+// recoverDiskFill the fill file and the fill dir of inode mode are both removed, so the mode is not needed
 func recoverDiskFill(ctx context.Context, dir, uid string) error {
 	fillFile := fmt.Sprintf("%s/%s", dir, getFillFileName(uid))
 	isExist, err := filesys.ExistPathLocal(fillFile)
@@ -139,8 +333,60 @@ func recoverDiskFill(ctx context.Context, dir, uid string) error {
 	}
 
 	if isExist {
-		return os.Remove(fillFile)
+		if err := os.Remove(fillFile); err != nil {
+			return err
+		}
+	}
+
+	fillDir := disk.GetInodeFillDir(dir, uid)
+	isExist, err = filesys.ExistPathLocal(fillDir)
+	if err != nil {
+		return fmt.Errorf("check dir[%s] exist error: %s", fillDir, err.Error())
+	}
+
+	if isExist {
+		return removeInodeFillDir(fillDir)
 	}
 
 	return nil
 }
+
+// removeInodeFillDir the sub dirs are removed concurrently
+func removeInodeFillDir(fillDir string) error {
+	subDirList, err := os.ReadDir(fillDir)
+	if err != nil {
+		return fmt.Errorf("read dir[%s] error: %s", fillDir, err.Error())
+	}
+
+	var (
+		wg      sync.WaitGroup
+		lock    sync.Mutex
+		lastErr error
+		ch      = make(chan string)
+	)
+	for i := 0; i < RemoveWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for subDir := range ch {
+				if err := os.RemoveAll(subDir); err != nil {
+					lock.Lock()
+					lastErr = err
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, unit := range subDirList {
+		ch <- fmt.Sprintf("%s/%s", fillDir, unit.Name())
+	}
+	close(ch)
+	wg.Wait()
+
+	if lastErr != nil {
+		return fmt.Errorf("remove dir in [%s] error: %s", fillDir, lastErr.Error())
+	}
+
+	return os.RemoveAll(fillDir)
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/errutil"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	FaultFileCorrupt   = "corrupt"
+	CorruptModeRandom  = "random"
+	CorruptModeBitFlip = "bitflip"
+
+	CorruptBlockSize = 1024 * 1024
+)
+
+// [func] [fault] [level] [args]
+func main() {
+	var (
+		err                       error
+		fName, fault, level, args = os.Args[1], os.Args[2], os.Args[3], os.Args[4:]
+		ctx                       = context.Background()
+	)
+	log.Level = level
+
+	switch fName {
+	case utils.MethodInject:
+		err = execInject(ctx, fault, args)
+	default:
+		errutil.ExitExpectedErr(fmt.Sprintf("not support method: %s", fName))
+	}
+
+	if err != nil {
+		errutil.ExitExpectedErr(err.Error())
+	}
+}
+
+func execInject(ctx context.Context, fault string, args []string) error {
+	switch fault {
+	case FaultFileCorrupt:
+		return execCorrupt(ctx, args)
+	default:
+		return fmt.Errorf("not support fault: %s", fault)
+	}
+}
+
+// [path] [mode] [offset] [length]
+func execCorrupt(ctx context.Context, args []string) error {
+	if len(args) < 4 {
+		return fmt.Errorf("args is not enough: %v", args)
+	}
+
+	path, mode := args[0], args[1]
+	offset, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return fmt.Errorf("offset is not a num: %s", err.Error())
+	}
+
+	length, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		return fmt.Errorf("length is not a num: %s", err.Error())
+	}
+
+	return corruptFile(ctx, path, mode, offset, length)
+}
+
+// corruptFile overwrite the bytes in [offset, offset+length) with random bytes, or flip a random bit of each byte,
+// the size of file is not changed
+func corruptFile(ctx context.Context, path, mode string, offset, length int64) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open file[%s] error: %s", path, err.Error())
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat file[%s] error: %s", path, err.Error())
+	}
+
+	if offset < 0 || length <= 0 || offset+length > info.Size() {
+		return fmt.Errorf("range[%d,%d) is out of file size[%d]", offset, offset+length, info.Size())
+	}
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	buf := make([]byte, CorruptBlockSize)
+	for done := int64(0); done < length; {
+		n := int64(len(buf))
+		if length-done < n {
+			n = length - done
+		}
+
+		block := buf[:n]
+		switch mode {
+		case CorruptModeRandom:
+			r.Read(block)
+		case CorruptModeBitFlip:
+			if _, err := f.ReadAt(block, offset+done); err != nil {
+				return fmt.Errorf("read file[%s] error: %s", path, err.Error())
+			}
+
+			for i := range block {
+				block[i] ^= 1 << uint(r.Intn(8))
+			}
+		default:
+			return fmt.Errorf("not support mode: %s", mode)
+		}
+
+		if _, err := f.WriteAt(block, offset+done); err != nil {
+			return fmt.Errorf("write file[%s] error: %s", path, err.Error())
+		}
+		done += n
+	}
+
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("sync file[%s] error: %s", path, err.Error())
+	}
+
+	log.GetLogger(ctx).Debugf("corrupt %d bytes of file[%s] from offset %d by %s", length, path, offset, mode)
+	return nil
+}
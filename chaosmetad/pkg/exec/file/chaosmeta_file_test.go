@@ -0,0 +1,90 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCorruptFile(t *testing.T) {
+	const size = 2*CorruptBlockSize + 100
+	origin := make([]byte, size)
+	for i := range origin {
+		origin[i] = byte(i)
+	}
+
+	tests := []struct {
+		name    string
+		mode    string
+		offset  int64
+		length  int64
+		wantErr bool
+	}{
+		{name: "random", mode: CorruptModeRandom, offset: 10, length: 100},
+		{name: "bitflip", mode: CorruptModeBitFlip, offset: 10, length: 100},
+		{name: "bitflip across blocks", mode: CorruptModeBitFlip, offset: CorruptBlockSize - 10, length: CorruptBlockSize + 20},
+		{name: "bitflip to the end", mode: CorruptModeBitFlip, offset: size - 5, length: 5},
+		{name: "out of file", mode: CorruptModeBitFlip, offset: size - 5, length: 6, wantErr: true},
+		{name: "negative offset", mode: CorruptModeBitFlip, offset: -1, length: 6, wantErr: true},
+		{name: "zero length", mode: CorruptModeRandom, offset: 0, length: 0, wantErr: true},
+		{name: "unknown mode", mode: "zero", offset: 0, length: 10, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file with space")
+			if err := os.WriteFile(path, origin, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := corruptFile(context.Background(), path, tt.mode, tt.offset, tt.length)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("corruptFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != size {
+				t.Fatalf("size of file = %d, want %d", len(got), size)
+			}
+			if tt.wantErr {
+				if !bytes.Equal(got, origin) {
+					t.Errorf("file is changed after error")
+				}
+				return
+			}
+
+			start, end := tt.offset, tt.offset+tt.length
+			if !bytes.Equal(got[:start], origin[:start]) || !bytes.Equal(got[end:], origin[end:]) {
+				t.Errorf("bytes out of [%d,%d) are changed", start, end)
+			}
+			if tt.mode == CorruptModeBitFlip {
+				for i := start; i < end; i++ {
+					if n := bits.OnesCount8(got[i] ^ origin[i]); n != 1 {
+						t.Fatalf("byte %d has %d bits flipped, want 1", i, n)
+					}
+				}
+			}
+		})
+	}
+}
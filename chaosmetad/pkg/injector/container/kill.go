@@ -22,6 +22,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/crclient"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
 )
 
 func init() {
@@ -52,8 +53,9 @@ func (i *KillInjector) SetOption(cmd *cobra.Command) {
 }
 
 func (i *KillInjector) Validator(ctx context.Context) error {
-	if i.Info.ContainerRuntime == "" || i.Info.ContainerId == "" {
-		return fmt.Errorf("please provide container runtime and id")
+	// the container runtime is detected by BaseInjector.Validator if not provided
+	if i.Info.ContainerId == "" {
+		return fmt.Errorf("please provide container id")
 	}
 
 	return i.BaseInjector.Validator(ctx)
@@ -65,6 +67,10 @@ func (i *KillInjector) Inject(ctx context.Context) error {
 		return fmt.Errorf("get %s client error: %s", i.Info.ContainerRuntime, err.Error())
 	}
 
+	if dryrun.Record(ctx, dryrun.KindContainer, dryrun.GetContainerTarget(i.Info.ContainerId), "kill container") {
+		return nil
+	}
+
 	return client.KillContainerById(ctx, i.Info.ContainerId)
 }
 
@@ -22,6 +22,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/crclient"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
 )
 
 func init() {
@@ -58,12 +59,17 @@ func (i *RestartInjector) SetDefault() {
 }
 
 func (i *RestartInjector) SetOption(cmd *cobra.Command) {
-	cmd.Flags().Int64VarP(&i.Args.WaitTime, "wait-time", "w", 0, "tolerable time-consuming(seconds) to restart the container")
+	cmd.Long = fmt.Sprintf("restart the container. runtime %s and %s can not start a stopped container, so the container is "+
+		"only stopped, and it is restarted by kubelet with a new id. the container not managed by kubelet or with restartPolicy "+
+		"\"Never\" stays stopped", crclient.CrCrio, crclient.CrCRI)
+	cmd.Flags().Int64VarP(&i.Args.WaitTime, "wait-time", "w", 0, fmt.Sprintf("tolerable time-consuming(seconds) to restart the container, "+
+		"for runtime %s and %s it is the time to wait for the container to stop", crclient.CrCrio, crclient.CrCRI))
 }
 
 func (i *RestartInjector) Validator(ctx context.Context) error {
-	if i.Info.ContainerRuntime == "" || i.Info.ContainerId == "" {
-		return fmt.Errorf("please provide container runtime and id")
+	// the container runtime is detected by BaseInjector.Validator if not provided
+	if i.Info.ContainerId == "" {
+		return fmt.Errorf("please provide container id")
 	}
 
 	return i.BaseInjector.Validator(ctx)
@@ -75,6 +81,10 @@ func (i *RestartInjector) Inject(ctx context.Context) error {
 		return fmt.Errorf("get %s client error: %s", i.Info.ContainerRuntime, err.Error())
 	}
 
+	if dryrun.Record(ctx, dryrun.KindContainer, dryrun.GetContainerTarget(i.Info.ContainerId), fmt.Sprintf("restart container with wait time[%d]", i.Args.WaitTime)) {
+		return nil
+	}
+
 	return client.RestartContainerById(ctx, i.Info.ContainerId, i.Args.WaitTime)
 }
 
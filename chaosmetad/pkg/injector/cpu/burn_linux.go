@@ -22,11 +22,13 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/metrics"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cgroup"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/profile"
 )
 
 func init() {
@@ -43,9 +45,11 @@ type BurnArgs struct {
 	Percent int    `json:"percent"`
 	Count   int    `json:"count,omitempty"`
 	List    string `json:"list,omitempty"`
+	Profile string `json:"profile,omitempty"`
 }
 
 type BurnRuntime struct {
+	profile.State
 }
 
 func (i *BurnInjector) GetArgs() interface{} {
@@ -70,6 +74,7 @@ func (i *BurnInjector) SetOption(cmd *cobra.Command) {
 	cmd.Flags().IntVarP(&i.Args.Percent, "percent", "p", 0, "cpu burn usage percent to add, an integer in (0,100] without \"%\", eg: \"30\" means \"30%\"")
 	cmd.Flags().StringVarP(&i.Args.List, "list", "l", "", "cpu burn core number list, start from 0, eg: \"0-2,6\" means \"0,1,2,6\" core")
 	cmd.Flags().IntVarP(&i.Args.Count, "count", "c", 0, "cpu burn core count（default 0, means all core）. if provide args \"list\", \"count\" will be ignored.")
+	cmd.Flags().StringVar(&i.Args.Profile, "profile", "", "cpu burn usage percent varying with time, format: \"[type]:[start]-[end]:[duration][:steps]\", type support: linear、step、sine、sawtooth, eg: \"linear:10-90:10m\". if provided, \"percent\" will be ignored")
 }
 
 // Validator list > count
@@ -78,7 +83,11 @@ func (i *BurnInjector) Validator(ctx context.Context) error {
 		return err
 	}
 
-	if i.Args.Percent <= 0 || i.Args.Percent > 100 {
+	if i.Args.Profile != "" {
+		if _, err := profile.Parse(i.Args.Profile, profile.UnitPercent); err != nil {
+			return fmt.Errorf("\"profile\"[%s] is invalid: %s", i.Args.Profile, err.Error())
+		}
+	} else if i.Args.Percent <= 0 || i.Args.Percent > 100 {
 		return fmt.Errorf("\"percent\"[%d] must be in (0,100]", i.Args.Percent)
 	}
 
@@ -148,8 +157,12 @@ func (i *BurnInjector) Inject(ctx context.Context) error {
 		return fmt.Errorf("get root pid error: %s", err.Error())
 	}
 
+	if i.Args.Profile != "" {
+		i.Runtime.State = profile.NewState(i.Args.Profile, profile.UnitPercent)
+	}
+
 	for c := 0; c < len(coreList); c++ {
-		cmd := fmt.Sprintf("taskset -c %d %s %s %d %d %d %d", coreList[c], utils.GetToolPath(CpuBurnKey), i.Info.Uid, coreList[c], i.Args.Percent, targetPid, timeout)
+		cmd := fmt.Sprintf("taskset -c %d %s %s %d %d %d %d %s", coreList[c], utils.GetToolPath(CpuBurnKey), i.Info.Uid, coreList[c], i.Args.Percent, targetPid, timeout, i.Args.Profile)
 		if err := e.StartCmdAndWait(ctx, cmd); err != nil {
 			if err := i.Recover(ctx); err != nil {
 				logger.Warnf("undo error: %s", err.Error())
@@ -161,6 +174,30 @@ func (i *BurnInjector) Inject(ctx context.Context) error {
 	return nil
 }
 
+// GetResource the cores burned: the count of cores multiplied by the usage percent, the percent of profile now is used if
+// profile is provided
+func (i *BurnInjector) GetResource(ctx context.Context) (map[string]float64, error) {
+	percent := float64(i.Args.Percent)
+	if i.Args.Profile != "" {
+		value, err := i.Runtime.GetValue()
+		if err != nil {
+			return nil, fmt.Errorf("get percent of profile error: %s", err.Error())
+		}
+		percent = value
+	}
+
+	count := i.Args.Count
+	if i.Args.List != "" {
+		coreList, err := utils.GetNumArrByList(i.Args.List)
+		if err != nil {
+			return nil, fmt.Errorf("\"list\"[%s] is not valid: %s", i.Args.List, err.Error())
+		}
+		count = len(coreList)
+	}
+
+	return map[string]float64{metrics.ResourceCpuCores: float64(count) * percent / 100}, nil
+}
+
 func (i *BurnInjector) Recover(ctx context.Context) error {
 	if i.BaseInjector.Recover(ctx) == nil {
 		return nil
@@ -24,4 +24,11 @@ const (
 
 	FaultCpuLoad = "load"
 	CpuLoadKey   = "chaosmeta_cpuload"
+
+	FaultCpuLimit    = "limit"
+	DefaultCpuPeriod = "100ms"
+	MinCpuPeriodUs   = 1000
+	MaxCpuPeriodUs   = 1000000
+	MinCpuQuotaUs    = 1000
+	TmpCgroup        = "/"
 )
@@ -0,0 +1,427 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cpu
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cgroup"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/containercgroup"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
+)
+
+func init() {
+	injector.Register(TargetCpu, FaultCpuLimit, func() injector.IInjector { return &LimitInjector{} })
+}
+
+type LimitInjector struct {
+	injector.BaseInjector
+	Args    LimitArgs
+	Runtime LimitRuntime
+}
+
+type LimitArgs struct {
+	Pid     int    `json:"pid,omitempty"`
+	Key     string `json:"key,omitempty"`
+	Percent int    `json:"percent"`
+	Period  string `json:"period,omitempty"`
+	Cpus    string `json:"cpus,omitempty"`
+}
+
+type LimitRuntime struct {
+	OldCgroupMap map[int]string
+	OldCpusetMap map[int]string
+	// OldCpuMaxMap and OldCpusMap the old cpu.max and cpuset.cpus of each cgroup in cgroup v2
+	OldCpuMaxMap map[string]string
+	OldCpusMap   map[string]string
+}
+
+func (i *LimitInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *LimitInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *LimitInjector) SetDefault() {
+	i.BaseInjector.SetDefault()
+
+	if i.Args.Period == "" {
+		i.Args.Period = DefaultCpuPeriod
+	}
+}
+
+func (i *LimitInjector) SetOption(cmd *cobra.Command) {
+	// i.BaseInjector.SetOption(cmd)
+
+	cmd.Flags().IntVarP(&i.Args.Pid, "pid", "p", 0, "target process's pid")
+	cmd.Flags().StringVarP(&i.Args.Key, "key", "k", "", "the key used to grep to get target process, the effect is equivalent to \"ps -ef | grep [key]\". if \"pid\" provided, \"key\" will be ignored")
+	cmd.Flags().IntVarP(&i.Args.Percent, "percent", "P", 0, "the max cpu usage of target processes, an integer larger than 0 without \"%\", \"100\" means one core, eg: \"50\" means half of a core, \"200\" means two cores")
+	cmd.Flags().StringVar(&i.Args.Period, "period", "", fmt.Sprintf("the period of cpu quota, support unit: us、ms、s（default %s）, must be in [1ms,1s]", DefaultCpuPeriod))
+	cmd.Flags().StringVarP(&i.Args.Cpus, "cpus", "c", "", "pin target processes to the cpu core list, start from 0, eg: \"0-2,6\" means \"0,1,2,6\" core")
+}
+
+func (i *LimitInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	if i.Args.Percent <= 0 {
+		return fmt.Errorf("\"percent\"[%d] must larger than 0", i.Args.Percent)
+	}
+
+	period, err := utils.GetTimeUs(i.Args.Period)
+	if err != nil {
+		return fmt.Errorf("\"period\"[%s] is invalid: %s", i.Args.Period, err.Error())
+	}
+
+	if period < MinCpuPeriodUs || period > MaxCpuPeriodUs {
+		return fmt.Errorf("\"period\"[%s] must be in [1ms,1s]", i.Args.Period)
+	}
+
+	if getCpuQuota(period, i.Args.Percent) < MinCpuQuotaUs {
+		return fmt.Errorf("the cpu quota of \"percent\"[%d] in \"period\"[%s] can not less than 1ms", i.Args.Percent, i.Args.Period)
+	}
+
+	if i.Args.Cpus != "" {
+		if err := checkCpuList(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Cpus); err != nil {
+			return fmt.Errorf("\"cpus\"[%s] is invalid: %s", i.Args.Cpus, err.Error())
+		}
+	}
+
+	pidList, err := getHostPidList(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Pid, i.Args.Key)
+	if err != nil {
+		return fmt.Errorf("get target process error: %s", err.Error())
+	}
+
+	if err := cgroup.CheckPidListCpuCgroup(ctx, pidList); err != nil {
+		return fmt.Errorf("check cgroup of %v error: %s", pidList, err.Error())
+	}
+
+	if containercgroup.IsCgroupV2() {
+		if err := i.checkUnifiedCgroup(ctx, pidList); err != nil {
+			return fmt.Errorf("check cgroup of %v error: %s", pidList, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Inject in cgroup v1, the target processes are moved into a new cgroup with cpu quota, and a new cpuset cgroup if
+// "cpus" provided. all threads of target processes are moved, but the child processes created after injection
+// are not tracked. in cgroup v2, moving processes would take them out of the other limits of their own cgroup, so
+// cpu.max and cpuset.cpus of the cgroup of target processes are modified instead, which affects all the processes
+// in that cgroup
+func (i *LimitInjector) Inject(ctx context.Context) error {
+	logger := log.GetLogger(ctx)
+	pidList, err := getHostPidList(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Pid, i.Args.Key)
+	if err != nil {
+		return fmt.Errorf("get target process error: %s", err.Error())
+	}
+
+	period, _ := utils.GetTimeUs(i.Args.Period)
+	if containercgroup.IsCgroupV2() {
+		if err := i.injectUnified(ctx, pidList, getCpuQuota(period, i.Args.Percent), period); err != nil {
+			return i.getErrWithUndo(ctx, err.Error())
+		}
+
+		return nil
+	}
+
+	i.Runtime.OldCgroupMap, err = cgroup.GetPidListCurCgroup(ctx, pidList, cgroup.CPU)
+	if err != nil {
+		return fmt.Errorf("get old path error: %s", err.Error())
+	}
+	logger.Debugf("old cgroup path: %v", i.Runtime.OldCgroupMap)
+
+	if i.isCpusetSeparate() {
+		i.Runtime.OldCpusetMap, err = cgroup.GetPidListCurCgroup(ctx, pidList, cgroup.CPUSET)
+		if err != nil {
+			return fmt.Errorf("get old cpuset path error: %s", err.Error())
+		}
+		logger.Debugf("old cpuset cgroup path: %v", i.Runtime.OldCpusetMap)
+	}
+
+	cpuPath, err := i.getCgroupPath(ctx, cgroup.CPU)
+	if err != nil {
+		return err
+	}
+
+	if err := cgroup.NewCgroup(ctx, cpuPath, cgroup.GetCpuQuotaConfig(getCpuQuota(period, i.Args.Percent), period, cpuPath)); err != nil {
+		return i.getErrWithUndo(ctx, fmt.Sprintf("create cgroup[%s] error: %s", cpuPath, err.Error()))
+	}
+
+	if i.isCpusetSeparate() {
+		cpusetPath, err := i.getCgroupPath(ctx, cgroup.CPUSET)
+		if err != nil {
+			return i.getErrWithUndo(ctx, err.Error())
+		}
+
+		if err := cgroup.NewCgroup(ctx, cpusetPath, cgroup.GetCpusetConfig(i.Args.Cpus, cpusetPath)); err != nil {
+			return i.getErrWithUndo(ctx, fmt.Sprintf("create cgroup[%s] error: %s", cpusetPath, err.Error()))
+		}
+
+		if err := cgroup.MoveProcListToCgroup(ctx, pidList, cpusetPath); err != nil {
+			return i.getErrWithUndo(ctx, fmt.Sprintf("move pid list to cgroup[%s] error: %s", cpusetPath, err.Error()))
+		}
+	}
+
+	if err := cgroup.MoveProcListToCgroup(ctx, pidList, cpuPath); err != nil {
+		return i.getErrWithUndo(ctx, fmt.Sprintf("move pid list to cgroup[%s] error: %s", cpuPath, err.Error()))
+	}
+
+	return nil
+}
+
+func (i *LimitInjector) getErrWithUndo(ctx context.Context, errMsg string) error {
+	if err := i.Recover(ctx); err != nil {
+		log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
+	}
+
+	return fmt.Errorf(errMsg)
+}
+
+func (i *LimitInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	if containercgroup.IsCgroupV2() {
+		return i.recoverUnified(ctx)
+	}
+
+	if err := i.recoverCgroup(ctx, cgroup.CPU, i.Runtime.OldCgroupMap); err != nil {
+		return err
+	}
+
+	if i.isCpusetSeparate() {
+		return i.recoverCgroup(ctx, cgroup.CPUSET, i.Runtime.OldCpusetMap)
+	}
+
+	return nil
+}
+
+// recoverCgroup move the processes in experiment cgroup back to their old cgroup, and then remove the experiment cgroup
+func (i *LimitInjector) recoverCgroup(ctx context.Context, subSys string, oldCgroupMap map[int]string) error {
+	logger := log.GetLogger(ctx)
+	cgroupPath, err := i.getCgroupPath(ctx, subSys)
+	if err != nil {
+		return err
+	}
+
+	isCgroupExist, err := cgroup.ExistCgroup(ctx, cgroupPath)
+	if err != nil {
+		return fmt.Errorf("check cgroup[%s] exist error: %s", cgroupPath, err.Error())
+	}
+
+	if !isCgroupExist {
+		return nil
+	}
+
+	pidList, err := cgroup.GetProcListByCgroup(ctx, cgroupPath)
+	if err != nil {
+		return fmt.Errorf("fail to get pid from cgroup[%s]: %s", cgroupPath, err.Error())
+	}
+
+	var tmpPath = TmpCgroup
+	if i.Info.ContainerRuntime != "" {
+		tmpPath, err = cgroup.GetContainerCgroupPath(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, subSys)
+		if err != nil {
+			return fmt.Errorf("get cgroup[%s] path of container[%s] error: %s", subSys, i.Info.ContainerId, err.Error())
+		}
+	}
+
+	for _, pid := range pidList {
+		oldPath, ok := oldCgroupMap[pid]
+		if !ok {
+			logger.Warnf("fail to get pid[%d]'s old cgroup path, move to \"%s\" instead", pid, tmpPath)
+			oldPath = tmpPath
+		}
+
+		if err := cgroup.MoveProcToCgroup(ctx, pid, cgroup.GetCgroupPath(subSys, oldPath)); err != nil {
+			return fmt.Errorf("recover pid[%d] error: %s", pid, err.Error())
+		}
+	}
+
+	if err := cgroup.RemoveCgroup(ctx, cgroupPath); err != nil {
+		return fmt.Errorf("remove cgroup[%s] error: %s", cgroupPath, err.Error())
+	}
+
+	return nil
+}
+
+// checkUnifiedCgroup the cpu controller, and the cpuset controller if "cpus" provided, should be enabled by the parent
+// of the cgroup of target processes. the parent is not modified because it would affect all the siblings
+func (i *LimitInjector) checkUnifiedCgroup(ctx context.Context, pidList []int) error {
+	cgroupList, err := cgroup.GetPidListUnifiedCgroup(ctx, pidList)
+	if err != nil {
+		return err
+	}
+
+	for _, unitPath := range cgroupList {
+		if err := cgroup.CheckUnifiedController(unitPath, cgroup.CpuController); err != nil {
+			return err
+		}
+
+		if i.Args.Cpus != "" {
+			if err := cgroup.CheckUnifiedController(unitPath, cgroup.CpusetController); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// injectUnified the old value is saved before writing each file, so that a failed injection can be undone
+func (i *LimitInjector) injectUnified(ctx context.Context, pidList []int, quota, period int64) error {
+	cgroupList, err := cgroup.GetPidListUnifiedCgroup(ctx, pidList)
+	if err != nil {
+		return err
+	}
+
+	i.Runtime.OldCpuMaxMap, i.Runtime.OldCpusMap = make(map[string]string), make(map[string]string)
+	for _, unitPath := range cgroupList {
+		oldCpuMax, err := cgroup.ReadUnifiedFile(unitPath, cgroup.CpuMaxFile)
+		if err != nil {
+			return err
+		}
+
+		i.Runtime.OldCpuMaxMap[unitPath] = oldCpuMax
+		if err := cgroup.WriteUnifiedFile(ctx, unitPath, cgroup.CpuMaxFile, []string{cgroup.GetCpuMaxValue(quota, period)}); err != nil {
+			return err
+		}
+
+		if i.Args.Cpus == "" {
+			continue
+		}
+
+		oldCpus, err := cgroup.ReadUnifiedFile(unitPath, cgroup.CpusetCoreFile)
+		if err != nil {
+			return err
+		}
+
+		i.Runtime.OldCpusMap[unitPath] = oldCpus
+		if err := cgroup.WriteUnifiedFile(ctx, unitPath, cgroup.CpusetCoreFile, []string{i.Args.Cpus}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recoverUnified an empty cpuset.cpus means using the cpus of parent, so it is written back as it is. the cgroup
+// removed after injection is skipped
+func (i *LimitInjector) recoverUnified(ctx context.Context) error {
+	logger := log.GetLogger(ctx)
+	for unitPath, oldCpuMax := range i.Runtime.OldCpuMaxMap {
+		isExist, err := cgroup.ExistCgroup(ctx, cgroup.GetCgroupPath("", unitPath))
+		if err != nil {
+			return fmt.Errorf("check cgroup[%s] exist error: %s", unitPath, err.Error())
+		}
+
+		if !isExist {
+			logger.Warnf("cgroup[%s] is not exist, skip recover", unitPath)
+			continue
+		}
+
+		if err := cgroup.WriteUnifiedFile(ctx, unitPath, cgroup.CpuMaxFile, []string{oldCpuMax}); err != nil {
+			return err
+		}
+
+		if oldCpus, ok := i.Runtime.OldCpusMap[unitPath]; ok {
+			if err := cgroup.WriteUnifiedFile(ctx, unitPath, cgroup.CpusetCoreFile, []string{oldCpus}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// getCgroupPath the experiment cgroup is created under the cgroup of container
+func (i *LimitInjector) getCgroupPath(ctx context.Context, subSys string) (string, error) {
+	var prefix string
+	if i.Info.ContainerRuntime != "" {
+		var err error
+		prefix, err = cgroup.GetContainerCgroupPath(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, subSys)
+		if err != nil {
+			return "", fmt.Errorf("get cgroup[%s] path of container[%s] error: %s", subSys, i.Info.ContainerId, err.Error())
+		}
+
+		if prefix == "/" {
+			prefix = ""
+		}
+	}
+
+	if subSys == cgroup.CPUSET {
+		return cgroup.GetCpusetCPath(i.Info.Uid, prefix), nil
+	}
+
+	return cgroup.GetCpuCPath(i.Info.Uid, prefix), nil
+}
+
+// isCpusetSeparate in cgroup v1, cpuset is another hierarchy and needs another cgroup
+func (i *LimitInjector) isCpusetSeparate() bool {
+	return i.Args.Cpus != "" && !containercgroup.IsCgroupV2()
+}
+
+func getCpuQuota(period int64, percent int) int64 {
+	return period * int64(percent) / 100
+}
+
+func getHostPidList(ctx context.Context, cr, cId string, pid int, key string) ([]int, error) {
+	pidList, err := process.GetPidListByPidOrKeyInContainer(ctx, cr, cId, pid, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return process.GetHostPidList(ctx, cr, cId, pidList)
+}
+
+func checkCpuList(ctx context.Context, cr, cId, cpus string) error {
+	targetList, err := utils.GetNumArrByList(cpus)
+	if err != nil {
+		return err
+	}
+
+	cpuList, err := getAllCpuList(ctx, cr, cId)
+	if err != nil {
+		return fmt.Errorf("get all available cpu list error: %s", err.Error())
+	}
+
+	for _, core := range targetList {
+		var exist bool
+		for _, availCore := range cpuList {
+			if availCore == core {
+				exist = true
+				break
+			}
+		}
+
+		if !exist {
+			return fmt.Errorf("core[%d] is not available", core)
+		}
+	}
+
+	return nil
+}
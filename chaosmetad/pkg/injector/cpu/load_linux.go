@@ -26,6 +26,7 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/profile"
 )
 
 // Register
@@ -40,10 +41,12 @@ type LoadInjector struct {
 }
 
 type LoadArgs struct {
-	Count int `json:"count,omitempty"`
+	Count   int    `json:"count,omitempty"`
+	Profile string `json:"profile,omitempty"`
 }
 
 type LoadRuntime struct {
+	profile.State
 }
 
 func (i *LoadInjector) GetArgs() interface{} {
@@ -58,6 +61,7 @@ func (i *LoadInjector) SetOption(cmd *cobra.Command) {
 	// i.BaseInjector.SetOption(cmd)
 
 	cmd.Flags().IntVarP(&i.Args.Count, "count", "c", 0, "cpu load value（default 0, mean: cpu core num * 4）")
+	cmd.Flags().StringVar(&i.Args.Profile, "profile", "", "cpu load value varying with time, format: \"[type]:[start]-[end]:[duration][:steps]\", type support: linear、step、sine、sawtooth, eg: \"step:4-16:10m:4\". if provided, \"count\" will be ignored")
 }
 
 func (i *LoadInjector) getCmdExecutor() *cmdexec.CmdExecutor {
@@ -86,11 +90,24 @@ func (i *LoadInjector) Validator(ctx context.Context) error {
 		return fmt.Errorf("\"count\"[%d] can not less than 0", i.Args.Count)
 	}
 
+	if i.Args.Profile != "" {
+		if _, err := profile.Parse(i.Args.Profile, profile.UnitCount); err != nil {
+			return fmt.Errorf("\"profile\"[%s] is invalid: %s", i.Args.Profile, err.Error())
+		}
+	}
+
 	return nil
 }
 
 func (i *LoadInjector) Inject(ctx context.Context) error {
 	cmd := fmt.Sprintf("%s %s %d", utils.GetToolPath(CpuLoadKey), i.Info.Uid, i.Args.Count)
+	if i.Args.Profile != "" {
+		// the tool written in c gets the parsed profile
+		p, _ := profile.Parse(i.Args.Profile, profile.UnitCount)
+		cmd = fmt.Sprintf("%s %s %d %d %d %d", cmd, p.Type, int(p.Start), int(p.End), int(p.Duration.Seconds()), p.Steps)
+		i.Runtime.State = profile.NewState(i.Args.Profile, profile.UnitCount)
+	}
+
 	if err := i.getCmdExecutor().StartCmd(ctx, cmd); err != nil {
 		if err := i.Recover(ctx); err != nil {
 			log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
@@ -24,4 +24,13 @@ const (
 	DefaultDir    = "/tmp"
 
 	DiskFillExec = "chaosmeta_diskfill"
+	// FillFileName the prefix of fill file, same as the one used by tool chaosmeta_diskfill
+	FillFileName = "chaosmeta_fill"
+	ModeBytes    = "bytes"
+	ModeInode    = "inode"
+
+	FaultDiskReadonly = "readonly"
+
+	FaultDiskUnmount = "unmount"
+	TmpfsSource      = "chaosmeta_tmpfs"
 )
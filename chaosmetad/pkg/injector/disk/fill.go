@@ -20,11 +20,18 @@ import (
 	"context"
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/crclient"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/metrics"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/disk"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/profile"
+	"os"
 )
 
 func init() {
@@ -41,9 +48,13 @@ type FillArgs struct {
 	Percent int    `json:"percent,omitempty"`
 	Bytes   string `json:"bytes,omitempty"`
 	Dir     string `json:"dir,omitempty"`
+	Profile string `json:"profile,omitempty"`
+	Mode    string `json:"mode,omitempty"`
+	Count   int64  `json:"count,omitempty"`
 }
 
 type FillRuntime struct {
+	profile.State
 }
 
 func (i *FillInjector) GetArgs() interface{} {
@@ -60,14 +71,21 @@ func (i *FillInjector) SetDefault() {
 	if i.Args.Dir == "" {
 		i.Args.Dir = DefaultDir
 	}
+
+	if i.Args.Mode == "" {
+		i.Args.Mode = ModeBytes
+	}
 }
 
 func (i *FillInjector) SetOption(cmd *cobra.Command) {
 	// i.BaseInjector.SetOption(cmd)
 
-	cmd.Flags().IntVarP(&i.Args.Percent, "percent", "p", 0, "disk fill target percent, an integer in (0,100] without \"%\", eg: \"30\" means \"30%\"")
+	cmd.Flags().IntVarP(&i.Args.Percent, "percent", "p", 0, "disk fill target percent, an integer in (0,100] without \"%\", eg: \"30\" means \"30%\". in inode mode, it is the target percent of inode usage")
 	cmd.Flags().StringVarP(&i.Args.Bytes, "bytes", "b", "", "disk fill bytes to add, support unit: KB/MB/GB/TB（default KB）")
 	cmd.Flags().StringVarP(&i.Args.Dir, "dir", "d", "", fmt.Sprintf("disk fill target dir（default %s）", DefaultDir))
+	cmd.Flags().StringVarP(&i.Args.Mode, "mode", "m", "", fmt.Sprintf("disk fill mode, support: %s、%s（default %s）. in %s mode, empty files are created to use up the inodes", ModeBytes, ModeInode, ModeBytes, ModeInode))
+	cmd.Flags().Int64VarP(&i.Args.Count, "count", "c", 0, fmt.Sprintf("the count of inodes to fill in %s mode. if provide args \"percent\", \"count\" will be ignored", ModeInode))
+	cmd.Flags().StringVar(&i.Args.Profile, "profile", "", "disk fill bytes varying with time, format: \"[type]:[start]-[end]:[duration][:steps]\", type support: linear、step、sine、sawtooth, bytes support unit: KB/MB/GB/TB（default KB）, eg: \"linear:1GB-10GB:1h\". if provided, \"percent\" and \"bytes\" will be ignored")
 }
 
 func (i *FillInjector) getCmdExecutor(method, args string) *cmdexec.CmdExecutor {
@@ -91,11 +109,97 @@ func (i *FillInjector) Validator(ctx context.Context) error {
 		return fmt.Errorf("\"dir\" must provide absolute path")
 	}
 
-	return i.getCmdExecutor(utils.MethodValidator, fmt.Sprintf("%d '%s' %s", i.Args.Percent, i.Args.Bytes, i.Args.Dir)).ExecTool(ctx)
+	if i.Args.Mode != ModeBytes && i.Args.Mode != ModeInode {
+		return fmt.Errorf("\"mode\"[%s] is not support, only support: %s、%s", i.Args.Mode, ModeBytes, ModeInode)
+	}
+
+	if i.Args.Mode == ModeInode && i.Args.Profile != "" {
+		return fmt.Errorf("\"profile\" is not support in %s mode", ModeInode)
+	}
+
+	return i.getCmdExecutor(utils.MethodValidator, fmt.Sprintf("%d '%s' %s '%s' %s %d", i.Args.Percent, i.Args.Bytes, i.Args.Dir, i.Args.Profile, i.Args.Mode, i.Args.Count)).ExecTool(ctx)
 }
 
 func (i *FillInjector) Inject(ctx context.Context) error {
-	return i.getCmdExecutor(utils.MethodInject, fmt.Sprintf("%d '%s' %s %s", i.Args.Percent, i.Args.Bytes, i.Args.Dir, i.Info.Uid)).ExecTool(ctx)
+	if i.Args.Mode == ModeInode {
+		return i.getCmdExecutor(utils.MethodInject, fmt.Sprintf("%d '%s' %s %s %s %d", i.Args.Percent, i.Args.Bytes, i.Args.Dir, i.Info.Uid, ModeInode, i.Args.Count)).ExecTool(ctx)
+	}
+
+	if i.Args.Profile == "" {
+		return i.getCmdExecutor(utils.MethodInject, fmt.Sprintf("%d '%s' %s %s", i.Args.Percent, i.Args.Bytes, i.Args.Dir, i.Info.Uid)).ExecTool(ctx)
+	}
+
+	// the size of fill file is adjusted by the tool in background until timeout or recover
+	var timeout int64
+	if i.Info.Timeout != "" {
+		timeout, _ = utils.GetTimeSecond(i.Info.Timeout)
+	}
+
+	toolPath := utils.GetToolPath(DiskFillExec)
+	if i.Info.ContainerRuntime != "" {
+		localPath := toolPath
+		toolPath = utils.GetContainerPath(DiskFillExec)
+		if err := cmdexec.CpContainerFile(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, localPath, toolPath); err != nil {
+			return fmt.Errorf("container cp from [%s] to [%s] error: %s", localPath, toolPath, err.Error())
+		}
+	}
+
+	i.Runtime.State = profile.NewState(i.Args.Profile, profile.UnitKB)
+	cmd := fmt.Sprintf("%s %s %s %s %d '%s' %s %d", toolPath, utils.MethodInject, FaultDiskFill, log.Level,
+		i.Args.Percent, i.Args.Bytes, i.getProfileProcessKey(), timeout)
+	if err := i.getCmdExecutor("", "").StartCmdAndWait(ctx, cmd); err != nil {
+		if err := i.Recover(ctx); err != nil {
+			log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// getProfileProcessKey the args "dir uid profile" of the tool running in background
+func (i *FillInjector) getProfileProcessKey() string {
+	return fmt.Sprintf("%s %s %s", i.Args.Dir, i.Info.Uid, i.Args.Profile)
+}
+
+// GetResource the size of the fill file, or the inodes used by the fill dir in inode mode. the file in container is
+// found by the root of the container's process
+func (i *FillInjector) GetResource(ctx context.Context) (map[string]float64, error) {
+	fillFile := fmt.Sprintf("%s/%s%s.dat", i.Args.Dir, FillFileName, i.Info.Uid)
+	if i.Args.Mode == ModeInode {
+		fillFile = disk.GetInodeFillDir(i.Args.Dir, i.Info.Uid)
+	}
+
+	if i.Info.ContainerRuntime != "" {
+		client, err := crclient.GetClient(ctx, i.Info.ContainerRuntime)
+		if err != nil {
+			return nil, fmt.Errorf("create container runtime client[%s] error: %s", i.Info.ContainerRuntime, err.Error())
+		}
+
+		pid, err := client.GetPidById(ctx, i.Info.ContainerId)
+		if err != nil {
+			return nil, fmt.Errorf("get pid of container[%s] error: %s", i.Info.ContainerId, err.Error())
+		}
+
+		fillFile = fmt.Sprintf("/proc/%d/root%s", pid, fillFile)
+	}
+
+	if i.Args.Mode == ModeInode {
+		count, err := disk.CountFillInodes(fillFile)
+		if err != nil {
+			return nil, fmt.Errorf("count inodes of fill dir[%s] error: %s", fillFile, err.Error())
+		}
+
+		return map[string]float64{metrics.ResourceDiskInodes: float64(count)}, nil
+	}
+
+	info, err := os.Stat(fillFile)
+	if err != nil {
+		return nil, fmt.Errorf("stat fill file[%s] error: %s", fillFile, err.Error())
+	}
+
+	return map[string]float64{metrics.ResourceDiskBytes: float64(info.Size())}, nil
 }
 
 func (i *FillInjector) Recover(ctx context.Context) error {
@@ -103,5 +207,11 @@ func (i *FillInjector) Recover(ctx context.Context) error {
 		return nil
 	}
 
+	if i.Args.Profile != "" {
+		if err := process.CheckExistAndKillByKey(ctx, i.getProfileProcessKey()); err != nil {
+			return fmt.Errorf("stop the process adjusting fill file error: %s", err.Error())
+		}
+	}
+
 	return i.getCmdExecutor(utils.MethodRecover, fmt.Sprintf("%s %s", i.Args.Dir, i.Info.Uid)).ExecTool(ctx)
 }
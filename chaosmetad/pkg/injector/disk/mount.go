@@ -0,0 +1,123 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package disk
+
+import (
+	"context"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
+	"strings"
+)
+
+// checkMountDir the dir must be a real path, because the mount point in mountinfo is the resolved one. the root dir
+// and the dir of chaosmetad in host can not be the target, otherwise the experiment may not be recovered
+func checkMountDir(ctx context.Context, cr, cId, dir string) error {
+	if dir == "" {
+		return fmt.Errorf("\"dir\" is empty")
+	}
+
+	if !filesys.IfPathAbs(ctx, dir) {
+		return fmt.Errorf("\"dir\" must provide absolute path")
+	}
+
+	exist, err := filesys.CheckDir(ctx, cr, cId, dir)
+	if err != nil {
+		return fmt.Errorf("check exist dir[%s] error: %s", dir, err.Error())
+	}
+
+	if !exist {
+		return fmt.Errorf("dir[%s] is not exist", dir)
+	}
+
+	realPath, err := filesys.GetRealPath(ctx, cr, cId, dir)
+	if err != nil {
+		return fmt.Errorf("get real path of dir[%s] error: %s", dir, err.Error())
+	}
+
+	if realPath != dir {
+		return fmt.Errorf("dir[%s] is not a real path, use \"%s\" instead", dir, realPath)
+	}
+
+	if dir == "/" {
+		return fmt.Errorf("root dir is not allowed")
+	}
+
+	if cr == "" {
+		runPath := utils.GetRunPath()
+		if runPath == dir || strings.HasPrefix(runPath, dir+"/") {
+			return fmt.Errorf("dir[%s] contains the dir of %s[%s]", dir, utils.RootName, runPath)
+		}
+	}
+
+	return nil
+}
+
+// injectMount return the id of the new mount on dir, so that only the mount created by experiment is removed when
+// recover. the id is returned even if mount error, because the mount may be created partly
+func injectMount(ctx context.Context, cr, cId, dir string, mount func() error) (int, error) {
+	oldId, err := filesys.GetTopMountId(ctx, cr, cId, dir)
+	if err != nil {
+		return 0, fmt.Errorf("get mount of dir[%s] error: %s", dir, err.Error())
+	}
+
+	mountErr := mount()
+	newId, err := filesys.GetTopMountId(ctx, cr, cId, dir)
+	if err != nil {
+		return 0, fmt.Errorf("get mount of dir[%s] error: %s", dir, err.Error())
+	}
+
+	if newId == oldId {
+		newId = 0
+	}
+
+	return newId, mountErr
+}
+
+// recoverMount the mount of experiment is removed only if it is on top, the mounts created after injection are not
+// touched
+func recoverMount(ctx context.Context, cr, cId, dir string, mountId int) error {
+	if dryrun.IsRecoverPhase(ctx) {
+		return filesys.Umount(ctx, cr, cId, dir)
+	}
+
+	if mountId == 0 {
+		return nil
+	}
+
+	idList, err := filesys.GetMountIdList(ctx, cr, cId, dir)
+	if err != nil {
+		return fmt.Errorf("get mount of dir[%s] error: %s", dir, err.Error())
+	}
+
+	for index, id := range idList {
+		if id != mountId {
+			continue
+		}
+
+		if index != len(idList)-1 {
+			return fmt.Errorf("mount[%d] of dir[%s] is covered by other mounts: %v", mountId, dir, idList[index+1:])
+		}
+
+		return filesys.Umount(ctx, cr, cId, dir)
+	}
+
+	log.GetLogger(ctx).Warnf("mount[%d] of dir[%s] is not exist", mountId, dir)
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package disk
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
+)
+
+func init() {
+	injector.Register(TargetDisk, FaultDiskReadonly, func() injector.IInjector { return &ReadonlyInjector{} })
+}
+
+type ReadonlyInjector struct {
+	injector.BaseInjector
+	Args    ReadonlyArgs
+	Runtime ReadonlyRuntime
+}
+
+type ReadonlyArgs struct {
+	Dir string `json:"dir"`
+}
+
+type ReadonlyRuntime struct {
+	MountId int `json:"mount_id,omitempty"`
+}
+
+func (i *ReadonlyInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *ReadonlyInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *ReadonlyInjector) SetOption(cmd *cobra.Command) {
+	// i.BaseInjector.SetOption(cmd)
+
+	cmd.Flags().StringVarP(&i.Args.Dir, "dir", "d", "", "target dir, the files in it become read-only, and the write will fail with \"Read-only file system\"")
+}
+
+func (i *ReadonlyInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	return checkMountDir(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Dir)
+}
+
+// Inject the dir is bind mounted on itself and remounted read-only in the mount namespace of target
+func (i *ReadonlyInjector) Inject(ctx context.Context) error {
+	var err error
+	i.Runtime.MountId, err = injectMount(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Dir, func() error {
+		return filesys.BindMountReadOnly(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Dir)
+	})
+
+	if err != nil {
+		if err := i.Recover(ctx); err != nil {
+			log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
+		}
+
+		return fmt.Errorf("mount dir[%s] read-only error: %s", i.Args.Dir, err.Error())
+	}
+
+	return nil
+}
+
+func (i *ReadonlyInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	return recoverMount(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Dir, i.Runtime.MountId)
+}
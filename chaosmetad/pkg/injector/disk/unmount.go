@@ -0,0 +1,97 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package disk
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
+)
+
+func init() {
+	injector.Register(TargetDisk, FaultDiskUnmount, func() injector.IInjector { return &UnmountInjector{} })
+}
+
+type UnmountInjector struct {
+	injector.BaseInjector
+	Args    UnmountArgs
+	Runtime UnmountRuntime
+}
+
+type UnmountArgs struct {
+	Dir string `json:"dir"`
+}
+
+type UnmountRuntime struct {
+	MountId int `json:"mount_id,omitempty"`
+}
+
+func (i *UnmountInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *UnmountInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *UnmountInjector) SetOption(cmd *cobra.Command) {
+	// i.BaseInjector.SetOption(cmd)
+
+	cmd.Flags().StringVarP(&i.Args.Dir, "dir", "d", "", "target dir, the files in it disappear as if the disk is unmounted, and the new files are written to memory")
+}
+
+func (i *UnmountInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	return checkMountDir(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Dir)
+}
+
+// Inject an empty tmpfs with the same permission of dir is mounted on dir in the mount namespace of target,
+// the files opened before injection are still accessible
+func (i *UnmountInjector) Inject(ctx context.Context) error {
+	perm, err := filesys.GetPerm(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Dir)
+	if err != nil {
+		return fmt.Errorf("get perm of dir[%s] error: %s", i.Args.Dir, err.Error())
+	}
+
+	i.Runtime.MountId, err = injectMount(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Dir, func() error {
+		return filesys.MountTmpfs(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, TmpfsSource, i.Args.Dir, perm)
+	})
+
+	if err != nil {
+		if err := i.Recover(ctx); err != nil {
+			log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
+		}
+
+		return fmt.Errorf("mount tmpfs on dir[%s] error: %s", i.Args.Dir, err.Error())
+	}
+
+	return nil
+}
+
+func (i *UnmountInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	return recoverMount(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Dir, i.Runtime.MountId)
+}
@@ -25,7 +25,6 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cgroup"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/containercgroup"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/disk"
-	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
 	"strings"
 )
@@ -51,6 +50,8 @@ type HangArgs struct {
 
 type HangRuntime struct {
 	OldCgroupMap map[int]string
+	// OldIOMaxMap the lines to restore io.max of each cgroup in cgroup v2
+	OldIOMaxMap map[string][]string
 }
 
 func (i *HangInjector) GetArgs() interface{} {
@@ -92,6 +93,12 @@ func (i *HangInjector) Validator(ctx context.Context) error {
 		return fmt.Errorf("check cgroup of %v error: %s", pidList, err.Error())
 	}
 
+	if containercgroup.IsCgroupV2() {
+		if err := checkIOMaxCgroup(ctx, pidList); err != nil {
+			return fmt.Errorf("check io.max of %v error: %s", pidList, err.Error())
+		}
+	}
+
 	i.Args.DevList = strings.TrimSpace(i.Args.DevList)
 	if _, err := disk.GetDevList(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.DevList); err != nil {
 		return fmt.Errorf("\"dev-list\"[%s] is invalid: %s", i.Args.DevList, err.Error())
@@ -112,12 +119,6 @@ func (i *HangInjector) Inject(ctx context.Context) error {
 		return err
 	}
 
-	i.Runtime.OldCgroupMap, err = cgroup.GetPidListCurCgroup(ctx, pidList, cgroup.BLKIO)
-	if err != nil {
-		return fmt.Errorf("get old path error: %s", err.Error())
-	}
-	logger.Debugf("old cgroup path: %v", i.Runtime.OldCgroupMap)
-
 	devList, _ := disk.GetDevList(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.DevList)
 
 	rByte, wByte := HangBytes, HangBytes
@@ -127,6 +128,25 @@ func (i *HangInjector) Inject(ctx context.Context) error {
 		rByte = ""
 	}
 
+	if containercgroup.IsCgroupV2() {
+		i.Runtime.OldIOMaxMap = make(map[string][]string)
+		if err := injectIOMax(ctx, pidList, devList, cgroup.GetIOMaxLimitList(devList, rByte, wByte, 0, 0), i.Runtime.OldIOMaxMap); err != nil {
+			if err := i.Recover(ctx); err != nil {
+				logger.Warnf("undo error: %s", err.Error())
+			}
+
+			return fmt.Errorf("write io.max error: %s", err.Error())
+		}
+
+		return nil
+	}
+
+	i.Runtime.OldCgroupMap, err = cgroup.GetPidListCurCgroup(ctx, pidList, cgroup.BLKIO)
+	if err != nil {
+		return fmt.Errorf("get old path error: %s", err.Error())
+	}
+	logger.Debugf("old cgroup path: %v", i.Runtime.OldCgroupMap)
+
 	var containerCgroup string
 	if i.Info.ContainerRuntime != "" {
 		containerCgroup, err = cgroup.GetContainerCgroup(ctx, i.Info.ContainerRuntime, i.Info.ContainerId)
@@ -160,6 +180,10 @@ func (i *HangInjector) Recover(ctx context.Context) error {
 		return nil
 	}
 
+	if containercgroup.IsCgroupV2() {
+		return recoverIOMax(ctx, i.Runtime.OldIOMaxMap)
+	}
+
 	var (
 		logger          = log.GetLogger(ctx)
 		containerCgroup string
@@ -176,7 +200,7 @@ func (i *HangInjector) Recover(ctx context.Context) error {
 	}
 
 	cgroupPath := cgroup.GetBlkioCPath(i.Info.Uid, containerCgroup)
-	isCgroupExist, err := filesys.ExistPathLocal(cgroupPath)
+	isCgroupExist, err := cgroup.ExistCgroup(ctx, cgroupPath)
 	if err != nil {
 		return fmt.Errorf("check cgroup[%s] exist error: %s", cgroupPath, err.Error())
 	}
@@ -197,7 +221,7 @@ func (i *HangInjector) Recover(ctx context.Context) error {
 			oldPath = tmpPath
 		}
 
-		if err := cgroup.MoveTaskToCgroup(ctx, pid, fmt.Sprintf("%s/%s%s", containercgroup.RootCgroupPath, cgroup.BLKIO, oldPath)); err != nil {
+		if err := cgroup.MoveTaskToCgroup(ctx, pid, cgroup.GetCgroupPath(cgroup.BLKIO, oldPath)); err != nil {
 			return fmt.Errorf("recover pid[%d] error: %s", pid, err.Error())
 		}
 	}
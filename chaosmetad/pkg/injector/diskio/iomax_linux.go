@@ -0,0 +1,87 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package diskio
+
+import (
+	"context"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cgroup"
+)
+
+// In cgroup v2, moving target processes into a new cgroup would take them out of the memory/pids/cpu limits of their
+// own cgroup, so the io limit is written to io.max of the cgroup of target processes instead, which affects all the
+// processes in that cgroup. The old lines of target devices are saved in runtime and written back when recovering.
+
+func checkIOMaxCgroup(ctx context.Context, pidList []int) error {
+	cgroupList, err := cgroup.GetPidListUnifiedCgroup(ctx, pidList)
+	if err != nil {
+		return err
+	}
+
+	for _, unitPath := range cgroupList {
+		if err := cgroup.CheckUnifiedController(unitPath, cgroup.IOController); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// injectIOMax oldIOMaxMap is filled before writing each cgroup, so that a failed injection can be undone by recoverIOMax
+func injectIOMax(ctx context.Context, pidList []int, devList, limitList []string, oldIOMaxMap map[string][]string) error {
+	cgroupList, err := cgroup.GetPidListUnifiedCgroup(ctx, pidList)
+	if err != nil {
+		return err
+	}
+
+	for _, unitPath := range cgroupList {
+		oldIOMax, err := cgroup.ReadUnifiedFile(unitPath, cgroup.IOMaxFile)
+		if err != nil {
+			return err
+		}
+
+		oldIOMaxMap[unitPath] = cgroup.GetIOMaxRecoverList(devList, oldIOMax)
+		if err := cgroup.WriteUnifiedFile(ctx, unitPath, cgroup.IOMaxFile, limitList); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recoverIOMax the cgroup removed after injection is skipped
+func recoverIOMax(ctx context.Context, oldIOMaxMap map[string][]string) error {
+	logger := log.GetLogger(ctx)
+	for unitPath, lineList := range oldIOMaxMap {
+		isExist, err := cgroup.ExistCgroup(ctx, cgroup.GetCgroupPath("", unitPath))
+		if err != nil {
+			return fmt.Errorf("check cgroup[%s] exist error: %s", unitPath, err.Error())
+		}
+
+		if !isExist {
+			logger.Warnf("cgroup[%s] is not exist, skip recover", unitPath)
+			continue
+		}
+
+		if err := cgroup.WriteUnifiedFile(ctx, unitPath, cgroup.IOMaxFile, lineList); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -26,7 +26,6 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cgroup"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/containercgroup"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/disk"
-	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
 	"strings"
 )
@@ -55,6 +54,8 @@ type LimitArgs struct {
 
 type LimitRuntime struct {
 	OldCgroupMap map[int]string
+	// OldIOMaxMap the lines to restore io.max of each cgroup in cgroup v2
+	OldIOMaxMap map[string][]string
 }
 
 func (i *LimitInjector) GetArgs() interface{} {
@@ -91,6 +92,12 @@ func (i *LimitInjector) Validator(ctx context.Context) error {
 		return fmt.Errorf("check cgroup of %v error: %s", pidList, err.Error())
 	}
 
+	if containercgroup.IsCgroupV2() {
+		if err := checkIOMaxCgroup(ctx, pidList); err != nil {
+			return fmt.Errorf("check io.max of %v error: %s", pidList, err.Error())
+		}
+	}
+
 	i.Args.DevList = strings.TrimSpace(i.Args.DevList)
 	if _, err := disk.GetDevList(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.DevList); err != nil {
 		return fmt.Errorf("\"dev-list\"[%s] is invalid: %s", i.Args.DevList, err.Error())
@@ -122,14 +129,26 @@ func (i *LimitInjector) Inject(ctx context.Context) error {
 		return err
 	}
 
+	devList, _ := disk.GetDevList(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.DevList)
+	if containercgroup.IsCgroupV2() {
+		i.Runtime.OldIOMaxMap = make(map[string][]string)
+		if err := injectIOMax(ctx, pidList, devList, cgroup.GetIOMaxLimitList(devList, i.Args.ReadBytes, i.Args.WriteBytes, i.Args.ReadIO, i.Args.WriteIO), i.Runtime.OldIOMaxMap); err != nil {
+			if err := i.Recover(ctx); err != nil {
+				logger.Warnf("undo error: %s", err.Error())
+			}
+
+			return fmt.Errorf("write io.max error: %s", err.Error())
+		}
+
+		return nil
+	}
+
 	i.Runtime.OldCgroupMap, err = cgroup.GetPidListCurCgroup(ctx, pidList, cgroup.BLKIO)
 	if err != nil {
 		return fmt.Errorf("get old path error: %s", err.Error())
 	}
 	logger.Debugf("old cgroup path: %v", i.Runtime.OldCgroupMap)
 
-	devList, _ := disk.GetDevList(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.DevList)
-
 	var containerCgroup string
 	if i.Info.ContainerRuntime != "" {
 		containerCgroup, err = cgroup.GetContainerCgroup(ctx, i.Info.ContainerRuntime, i.Info.ContainerId)
@@ -162,6 +181,10 @@ func (i *LimitInjector) Recover(ctx context.Context) error {
 	if i.BaseInjector.Recover(ctx) == nil {
 		return nil
 	}
+
+	if containercgroup.IsCgroupV2() {
+		return recoverIOMax(ctx, i.Runtime.OldIOMaxMap)
+	}
 	var (
 		logger          = log.GetLogger(ctx)
 		containerCgroup string
@@ -178,7 +201,7 @@ func (i *LimitInjector) Recover(ctx context.Context) error {
 	}
 
 	cgroupPath := cgroup.GetBlkioCPath(i.Info.Uid, containerCgroup)
-	isCgroupExist, err := filesys.ExistPathLocal(cgroupPath)
+	isCgroupExist, err := cgroup.ExistCgroup(ctx, cgroupPath)
 	if err != nil {
 		return fmt.Errorf("check cgroup[%s] exist error: %s", cgroupPath, err.Error())
 	}
@@ -199,7 +222,7 @@ func (i *LimitInjector) Recover(ctx context.Context) error {
 			oldPath = tmpPath
 		}
 
-		if err := cgroup.MoveTaskToCgroup(ctx, pid, fmt.Sprintf("%s/%s%s", containercgroup.RootCgroupPath, cgroup.BLKIO, oldPath)); err != nil {
+		if err := cgroup.MoveTaskToCgroup(ctx, pid, cgroup.GetCgroupPath(cgroup.BLKIO, oldPath)); err != nil {
 			return fmt.Errorf("recover pid[%d] error: %s", pid, err.Error())
 		}
 	}
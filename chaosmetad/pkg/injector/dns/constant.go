@@ -16,6 +16,21 @@
 
 package dns
 
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/net"
+	gonet "net"
+	"path"
+	"strconv"
+	"strings"
+)
+
 const (
 	TargetDNS = "dns"
 
@@ -29,4 +44,232 @@ const (
 	ConfServer    = "/etc/resolv.conf"
 	ConfRecordBak = "/etc/hosts.chaosmeta"
 	ConfServerBak = "/etc/resolv.conf.chaosmeta"
+
+	FaultDNSNXDomain = "nxdomain"
+	FaultDNSServFail = "servfail"
+	FaultDNSTimeout  = "timeout"
+	FaultDNSDelay    = "delay"
+	FaultDNSSpoof    = "spoof"
+
+	ProxyKey         = "chaosmeta_dnsproxy"
+	DefaultProxyPort = 15053
+	DefaultPercent   = 100
+	// ProxyMark the mark of the queries sent to upstream by the proxy, should be consistent with tools/chaosmeta_dnsproxy.go
+	ProxyMark = 0x63686d
 )
+
+// ProxyArgs the common args of dns proxy faults: which queries to affect and where to forward the others
+type ProxyArgs struct {
+	Domain    string `json:"domain,omitempty"`
+	Percent   int    `json:"percent"`
+	ProxyPort int    `json:"proxy_port"`
+	Upstream  string `json:"upstream,omitempty"`
+}
+
+type ProxyRuntime struct {
+	Pid int `json:"pid,omitempty"`
+}
+
+// ProxyRule the rule executed by the proxy tool, should be consistent with the rule in tools/chaosmeta_dnsproxy.go
+type ProxyRule struct {
+	Fault     string   `json:"fault"`
+	Domain    []string `json:"domain,omitempty"`
+	Percent   int      `json:"percent"`
+	Upstream  string   `json:"upstream"`
+	LatencyUs int64    `json:"latency_us,omitempty"`
+	Answer    []string `json:"answer,omitempty"`
+}
+
+func setProxyDefault(args *ProxyArgs) {
+	if args.ProxyPort == 0 {
+		args.ProxyPort = DefaultProxyPort
+	}
+
+	if args.Percent == 0 {
+		args.Percent = DefaultPercent
+	}
+}
+
+func setProxyOption(cmd *cobra.Command, args *ProxyArgs) {
+	cmd.Flags().StringVarP(&args.Domain, "domain", "d", "", "filter condition: domain patterns of the query, support wildcard \"*\", eg: \"*.example.com,api.test.com\"（default all domains）")
+	cmd.Flags().IntVar(&args.Percent, "percent", 0, fmt.Sprintf("percent of the matched queries to inject, an integer in (0,100]（default %d）", DefaultPercent))
+	cmd.Flags().IntVar(&args.ProxyPort, "proxy-port", 0, fmt.Sprintf("listen port of the dns proxy（default %d）", DefaultProxyPort))
+	cmd.Flags().StringVar(&args.Upstream, "upstream", "", fmt.Sprintf("upstream ipv4 dns server which the queries are forwarded to, format: \"[ip]\" or \"[ip]:[port]\"（default the first nameserver of %s）", ConfServer))
+}
+
+func checkProxyArgs(ctx context.Context, cr, cId string, args *ProxyArgs) error {
+	if args.Percent <= 0 || args.Percent > 100 {
+		return fmt.Errorf("\"percent\" should in (0, 100]")
+	}
+
+	for _, domain := range getDomainList(args.Domain) {
+		if _, err := path.Match(domain, ""); err != nil {
+			return fmt.Errorf("\"domain\"[%s] is not a valid pattern: %s", domain, err.Error())
+		}
+	}
+
+	if args.ProxyPort <= 0 || args.ProxyPort > 65535 {
+		return fmt.Errorf("\"proxy-port\" should in (0, 65536)")
+	}
+
+	if args.Upstream != "" {
+		upstream, err := getUpstreamAddr(args.Upstream)
+		if err != nil {
+			return fmt.Errorf("\"upstream\"[%s] is invalid: %s", args.Upstream, err.Error())
+		}
+
+		if !isIPv4Addr(upstream) {
+			return fmt.Errorf("\"upstream\"[%s] is not ipv4, only ipv4 is supported", args.Upstream)
+		}
+	}
+
+	// the queries are only redirected by iptables, the queries sent to an ipv6 nameserver would bypass the proxy
+	nameserverList, err := getNameserverList(ctx, cr, cId)
+	if err != nil {
+		return err
+	}
+
+	// the nameserver in resolv.conf has no port, so ":" only appears in ipv6 address
+	for _, unit := range nameserverList {
+		if strings.Contains(unit, ":") {
+			return fmt.Errorf("nameserver[%s] in %s is not ipv4, only ipv4 is supported", unit, ConfServer)
+		}
+	}
+
+	if !cmdexec.SupportCmd(net.FirewallIptables) {
+		return fmt.Errorf("not support command \"%s\"", net.FirewallIptables)
+	}
+
+	for _, protocol := range []string{net.ProtocolTCP, net.ProtocolUDP} {
+		pid, err := net.GetPidByPort(ctx, cr, cId, args.ProxyPort, protocol)
+		if err != nil {
+			return fmt.Errorf("check %s \"proxy-port\"[%d] error: %s", protocol, args.ProxyPort, err.Error())
+		}
+
+		if pid != utils.NoPid {
+			return fmt.Errorf("%s \"proxy-port\"[%d] is occupied by process[%d]", protocol, args.ProxyPort, pid)
+		}
+	}
+
+	isRedirect, err := net.ExistDNSRedirectRule(ctx, cr, cId)
+	if err != nil {
+		return err
+	}
+
+	if isRedirect {
+		return fmt.Errorf("dns queries have been intercepted by other experiment")
+	}
+
+	return nil
+}
+
+// getDomainList convert domain list like "*.a.com,b.com" to lower case list without the last "."
+func getDomainList(domainStr string) []string {
+	var re []string
+	for _, unit := range strings.Split(domainStr, ",") {
+		if unit = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(unit), ".")); unit != "" {
+			re = append(re, unit)
+		}
+	}
+
+	return re
+}
+
+// getUpstreamAddr convert "[ip]" or "[ip]:[port]" to "[ip]:[port]", the port is 53 by default
+func getUpstreamAddr(upstream string) (string, error) {
+	if ip := gonet.ParseIP(upstream); ip != nil {
+		return gonet.JoinHostPort(ip.String(), strconv.Itoa(net.DNSPort)), nil
+	}
+
+	host, port, err := gonet.SplitHostPort(upstream)
+	if err != nil {
+		return "", err
+	}
+
+	if gonet.ParseIP(host) == nil {
+		return "", fmt.Errorf("%s is not a valid ip", host)
+	}
+
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return "", fmt.Errorf("port[%s] should in (0, 65536)", port)
+	}
+
+	return upstream, nil
+}
+
+// getNameserverList the nameservers in the resolv.conf of target
+func getNameserverList(ctx context.Context, cr, cId string) ([]string, error) {
+	re, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, fmt.Sprintf("cat %s", ConfServer), []string{namespace.MNT})
+	if err != nil {
+		return nil, fmt.Errorf("read %s error: %s", ConfServer, err.Error())
+	}
+
+	var nameserverList []string
+	for _, line := range strings.Split(re, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			nameserverList = append(nameserverList, fields[1])
+		}
+	}
+
+	return nameserverList, nil
+}
+
+// getDefaultUpstream the first nameserver in the resolv.conf of target
+func getDefaultUpstream(ctx context.Context, cr, cId string) (string, error) {
+	nameserverList, err := getNameserverList(ctx, cr, cId)
+	if err != nil {
+		return "", err
+	}
+
+	if len(nameserverList) == 0 {
+		return "", fmt.Errorf("no nameserver found in %s", ConfServer)
+	}
+
+	return getUpstreamAddr(nameserverList[0])
+}
+
+// isIPv4Addr addr is in "[ip]:[port]" format
+func isIPv4Addr(addr string) bool {
+	host, _, err := gonet.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+
+	ip := gonet.ParseIP(host)
+	return ip != nil && ip.To4() != nil
+}
+
+func getProxyRule(ctx context.Context, info *injector.BaseInfo, fault string, args *ProxyArgs) (*ProxyRule, error) {
+	var (
+		upstream string
+		err      error
+	)
+
+	if args.Upstream != "" {
+		upstream, err = getUpstreamAddr(args.Upstream)
+	} else {
+		upstream, err = getDefaultUpstream(ctx, info.ContainerRuntime, info.ContainerId)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get upstream error: %s", err.Error())
+	}
+
+	return &ProxyRule{
+		Fault:    fault,
+		Domain:   getDomainList(args.Domain),
+		Percent:  args.Percent,
+		Upstream: upstream,
+	}, nil
+}
+
+func startProxy(ctx context.Context, info *injector.BaseInfo, args *ProxyArgs, rule *ProxyRule, r *ProxyRuntime) error {
+	var err error
+	r.Pid, err = net.StartDNSProxy(ctx, info.ContainerRuntime, info.ContainerId, info.Uid, ProxyKey, args.ProxyPort, ProxyMark, rule)
+	return err
+}
+
+func stopProxy(ctx context.Context, info *injector.BaseInfo) error {
+	return net.StopDNSProxy(ctx, info.ContainerRuntime, info.ContainerId, info.Uid, ProxyKey)
+}
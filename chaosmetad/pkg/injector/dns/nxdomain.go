@@ -0,0 +1,90 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dns
+
+import (
+	"context"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+)
+
+func init() {
+	injector.Register(TargetDNS, FaultDNSNXDomain, func() injector.IInjector { return &NXDomainInjector{} })
+}
+
+// NXDomainInjector the matched queries are answered with NXDOMAIN without forwarding
+type NXDomainInjector struct {
+	injector.BaseInjector
+	Args    NXDomainArgs
+	Runtime ProxyRuntime
+}
+
+type NXDomainArgs struct {
+	ProxyArgs
+}
+
+func (i *NXDomainInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *NXDomainInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *NXDomainInjector) SetDefault() {
+	i.BaseInjector.SetDefault()
+	setProxyDefault(&i.Args.ProxyArgs)
+}
+
+func (i *NXDomainInjector) SetOption(cmd *cobra.Command) {
+	// i.BaseInjector.SetOption(cmd)
+	setProxyOption(cmd, &i.Args.ProxyArgs)
+}
+
+func (i *NXDomainInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	return checkProxyArgs(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, &i.Args.ProxyArgs)
+}
+
+func (i *NXDomainInjector) Inject(ctx context.Context) error {
+	rule, err := getProxyRule(ctx, &i.Info, FaultDNSNXDomain, &i.Args.ProxyArgs)
+	if err != nil {
+		return err
+	}
+
+	if err := startProxy(ctx, &i.Info, &i.Args.ProxyArgs, rule, &i.Runtime); err != nil {
+		if err := i.Recover(ctx); err != nil {
+			log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (i *NXDomainInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	return stopProxy(ctx, &i.Info)
+}
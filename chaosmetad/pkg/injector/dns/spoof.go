@@ -0,0 +1,126 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dns
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"net"
+	"strings"
+)
+
+func init() {
+	injector.Register(TargetDNS, FaultDNSSpoof, func() injector.IInjector { return &SpoofInjector{} })
+}
+
+// SpoofInjector the matched A/AAAA queries are answered with the provided ips, the queries of other types are forwarded
+type SpoofInjector struct {
+	injector.BaseInjector
+	Args    SpoofArgs
+	Runtime ProxyRuntime
+}
+
+type SpoofArgs struct {
+	ProxyArgs
+	Answer string `json:"answer"`
+}
+
+func (i *SpoofInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *SpoofInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *SpoofInjector) SetDefault() {
+	i.BaseInjector.SetDefault()
+	setProxyDefault(&i.Args.ProxyArgs)
+}
+
+func (i *SpoofInjector) SetOption(cmd *cobra.Command) {
+	// i.BaseInjector.SetOption(cmd)
+	setProxyOption(cmd, &i.Args.ProxyArgs)
+	cmd.Flags().StringVarP(&i.Args.Answer, "answer", "a", "", "ips returned to the matched queries, ipv4 for A query and ipv6 for AAAA query. eg: \"1.1.1.1,2.2.2.2,::1\"")
+}
+
+// getAnswerList convert ip list like "1.1.1.1,::1" to list
+func getAnswerList(answerStr string) ([]string, error) {
+	var re []string
+	for _, unit := range strings.Split(answerStr, ",") {
+		if unit = strings.TrimSpace(unit); unit == "" {
+			continue
+		}
+
+		ip := net.ParseIP(unit)
+		if ip == nil {
+			return nil, fmt.Errorf("%s is not a valid ip", unit)
+		}
+
+		re = append(re, ip.String())
+	}
+
+	if len(re) == 0 {
+		return nil, fmt.Errorf("is empty")
+	}
+
+	return re, nil
+}
+
+func (i *SpoofInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	if _, err := getAnswerList(i.Args.Answer); err != nil {
+		return fmt.Errorf("\"answer\"[%s] is invalid: %s", i.Args.Answer, err.Error())
+	}
+
+	return checkProxyArgs(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, &i.Args.ProxyArgs)
+}
+
+func (i *SpoofInjector) Inject(ctx context.Context) error {
+	rule, err := getProxyRule(ctx, &i.Info, FaultDNSSpoof, &i.Args.ProxyArgs)
+	if err != nil {
+		return err
+	}
+
+	if rule.Answer, err = getAnswerList(i.Args.Answer); err != nil {
+		return fmt.Errorf("\"answer\"[%s] is invalid: %s", i.Args.Answer, err.Error())
+	}
+
+	if err := startProxy(ctx, &i.Info, &i.Args.ProxyArgs, rule, &i.Runtime); err != nil {
+		if err := i.Recover(ctx); err != nil {
+			log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (i *SpoofInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	return stopProxy(ctx, &i.Info)
+}
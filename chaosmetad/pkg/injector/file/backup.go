@@ -0,0 +1,128 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file
+
+import (
+	"context"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
+)
+
+// backupFile copy the file with its mode, owner and timestamps to the backup dir before modifying, and return the
+// checksum of the original content
+func backupFile(ctx context.Context, cr, cId, uid, path string) (string, error) {
+	checksum, err := filesys.GetChecksum(ctx, cr, cId, path)
+	if err != nil {
+		return "", fmt.Errorf("get checksum of file[%s] error: %s", path, err.Error())
+	}
+
+	backupDir := getBackupDir(uid)
+	if err := filesys.MkdirForce(ctx, cr, cId, backupDir); err != nil {
+		return "", fmt.Errorf("create backup dir[%s] error: %s", backupDir, err.Error())
+	}
+
+	backup := getBackupFile(uid, path)
+	if err := filesys.CopyFile(ctx, cr, cId, path, backup); err != nil {
+		return "", fmt.Errorf("cp from[%s] to[%s] error: %s", path, backup, err.Error())
+	}
+
+	if err := checkChecksum(ctx, cr, cId, backup, checksum); err != nil {
+		return "", fmt.Errorf("check backup file error: %s", err.Error())
+	}
+
+	return checksum, nil
+}
+
+// restoreFile overwrite the file in place by the backup, so the inode and the hard links are kept. the backup is
+// kept if its checksum is not the same as the original content
+func restoreFile(ctx context.Context, cr, cId, uid, path, checksum string) error {
+	backup := getBackupFile(uid, path)
+	isExist, err := filesys.CheckFile(ctx, cr, cId, backup)
+	if err != nil {
+		return fmt.Errorf("check exist file[%s] error: %s", backup, err.Error())
+	}
+
+	if !isExist {
+		log.GetLogger(ctx).Warnf("backup file[%s] is not exist, skip restoring file[%s]", backup, path)
+		return filesys.RemoveRF(ctx, cr, cId, getBackupDir(uid))
+	}
+
+	if checksum != "" {
+		if err := checkChecksum(ctx, cr, cId, backup, checksum); err != nil {
+			return fmt.Errorf("check backup file error: %s", err.Error())
+		}
+	}
+
+	if err := filesys.CopyFile(ctx, cr, cId, backup, path); err != nil {
+		return fmt.Errorf("cp from[%s] to[%s] error: %s", backup, path, err.Error())
+	}
+
+	if checksum != "" {
+		if err := checkChecksum(ctx, cr, cId, path, checksum); err != nil {
+			return fmt.Errorf("check restored file error: %s", err.Error())
+		}
+	}
+
+	return filesys.RemoveRF(ctx, cr, cId, getBackupDir(uid))
+}
+
+// checkChecksum the file copied in dry-run mode only exists in the plan, so it is not checked
+func checkChecksum(ctx context.Context, cr, cId, file, checksum string) error {
+	if dryrun.IsDryRun(ctx) {
+		return nil
+	}
+
+	nowChecksum, err := filesys.GetChecksum(ctx, cr, cId, file)
+	if err != nil {
+		return fmt.Errorf("get checksum of file[%s] error: %s", file, err.Error())
+	}
+
+	if nowChecksum != checksum {
+		return fmt.Errorf("checksum of file[%s] is %s, expected: %s", file, nowChecksum, checksum)
+	}
+
+	return nil
+}
+
+// getTargetFileSize check the target file of corrupt and truncate, and return its size
+func getTargetFileSize(ctx context.Context, cr, cId, path string) (int64, error) {
+	if path == "" {
+		return -1, fmt.Errorf("\"path\" is empty")
+	}
+
+	if !filesys.IfPathAbs(ctx, path) {
+		return -1, fmt.Errorf("\"path\" must provide absolute path")
+	}
+
+	exist, err := filesys.CheckFile(ctx, cr, cId, path)
+	if err != nil {
+		return -1, fmt.Errorf("check exist file[%s] error: %s", path, err.Error())
+	}
+
+	if !exist {
+		return -1, fmt.Errorf("file[%s] is not exist", path)
+	}
+
+	size, err := filesys.GetFileSize(ctx, cr, cId, path)
+	if err != nil {
+		return -1, fmt.Errorf("get size of file[%s] error: %s", path, err.Error())
+	}
+
+	return size, nil
+}
@@ -20,6 +20,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"path/filepath"
 )
 
 const (
@@ -34,7 +35,14 @@ const (
 	FaultFileDelete = "del"
 
 	FaultFileChmod = "chmod"
-	//FileExec       = "chaosmeta_file"
+
+	FaultFileCorrupt   = "corrupt"
+	CorruptModeRandom  = "random"
+	CorruptModeBitFlip = "bitflip"
+
+	FaultFileTruncate = "truncate"
+
+	FileExec = "chaosmeta_file"
 
 	BackUpDir = "/tmp/chaosmeta_backup_file"
 )
@@ -47,6 +55,10 @@ func getBackupDir(uid string) string {
 	return fmt.Sprintf("%s%s", BackUpDir, uid)
 }
 
+func getBackupFile(uid, path string) string {
+	return fmt.Sprintf("%s/%s", getBackupDir(uid), filepath.Base(path))
+}
+
 func decodeBase64(base64Str string) (string, error) {
 	base64Byte := []byte(base64Str)
 	var rawByte = make([]byte, base64.StdEncoding.DecodedLen(len(base64Byte)))
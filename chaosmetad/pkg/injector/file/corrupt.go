@@ -0,0 +1,192 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
+)
+
+func init() {
+	injector.Register(TargetFile, FaultFileCorrupt, func() injector.IInjector { return &CorruptInjector{} })
+}
+
+type CorruptInjector struct {
+	injector.BaseInjector
+	Args    CorruptArgs
+	Runtime CorruptRuntime
+}
+
+type CorruptArgs struct {
+	Path    string `json:"path"`
+	Mode    string `json:"mode,omitempty"`
+	Offset  string `json:"offset,omitempty"`
+	Length  string `json:"length,omitempty"`
+	Percent int    `json:"percent,omitempty"`
+}
+
+type CorruptRuntime struct {
+	Checksum string `json:"checksum,omitempty"`
+}
+
+func (i *CorruptInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *CorruptInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *CorruptInjector) SetDefault() {
+	i.BaseInjector.SetDefault()
+
+	if i.Args.Mode == "" {
+		i.Args.Mode = CorruptModeRandom
+	}
+
+	if i.Args.Offset == "" {
+		i.Args.Offset = "0"
+	}
+}
+
+func (i *CorruptInjector) SetOption(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&i.Args.Path, "path", "p", "", "file path, include dir and file name")
+	cmd.Flags().StringVarP(&i.Args.Mode, "mode", "m", "", fmt.Sprintf("corrupt mode, support: %s（overwrite with random bytes）、%s（flip a random bit of each byte）（default %s）", CorruptModeRandom, CorruptModeBitFlip, CorruptModeRandom))
+	cmd.Flags().StringVarP(&i.Args.Offset, "offset", "o", "", "the start offset of the corrupted bytes, support unit: B/KB/MB/GB/TB（default B）（default 0）")
+	cmd.Flags().StringVarP(&i.Args.Length, "length", "l", "", "the length of the corrupted bytes from \"offset\", support unit: B/KB/MB/GB/TB（default B）. the bytes beyond the end of file are ignored")
+	cmd.Flags().IntVarP(&i.Args.Percent, "percent", "P", 0, "the length of the corrupted bytes is the percent of file size, an integer in (0,100] without \"%\". if \"length\" provided, \"percent\" will be ignored")
+}
+
+func (i *CorruptInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	if i.Args.Mode != CorruptModeRandom && i.Args.Mode != CorruptModeBitFlip {
+		return fmt.Errorf("\"mode\"[%s] is not support, only support: %s、%s", i.Args.Mode, CorruptModeRandom, CorruptModeBitFlip)
+	}
+
+	if i.Args.Length == "" && i.Args.Percent == 0 {
+		return fmt.Errorf("must provide \"length\" or \"percent\"")
+	}
+
+	if i.Args.Length == "" && (i.Args.Percent < 0 || i.Args.Percent > 100) {
+		return fmt.Errorf("\"percent\"[%d] must be in (0,100]", i.Args.Percent)
+	}
+
+	fileSize, err := getTargetFileSize(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Path)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := i.getRange(fileSize); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// getRange get the offset and length of the corrupted bytes, which are limited in the file
+func (i *CorruptInjector) getRange(fileSize int64) (int64, int64, error) {
+	offset, err := utils.GetBytes(i.Args.Offset)
+	if err != nil {
+		return -1, -1, fmt.Errorf("\"offset\"[%s] is invalid: %s", i.Args.Offset, err.Error())
+	}
+
+	if offset < 0 || offset >= fileSize {
+		return -1, -1, fmt.Errorf("\"offset\"[%s] must be in [0,%d), the size of file[%s]", i.Args.Offset, fileSize, i.Args.Path)
+	}
+
+	var length int64
+	if i.Args.Length != "" {
+		length, err = utils.GetBytes(i.Args.Length)
+		if err != nil {
+			return -1, -1, fmt.Errorf("\"length\"[%s] is invalid: %s", i.Args.Length, err.Error())
+		}
+	} else {
+		length = fileSize * int64(i.Args.Percent) / 100
+	}
+
+	if length <= 0 {
+		return -1, -1, fmt.Errorf("the length of the corrupted bytes must larger than 0")
+	}
+
+	if offset+length > fileSize {
+		length = fileSize - offset
+	}
+
+	return offset, length, nil
+}
+
+func (i *CorruptInjector) getCmdExecutor(method, args string) *cmdexec.CmdExecutor {
+	return &cmdexec.CmdExecutor{
+		ContainerId:      i.Info.ContainerId,
+		ContainerRuntime: i.Info.ContainerRuntime,
+		ContainerNs:      []string{namespace.MNT},
+		ToolKey:          FileExec,
+		Method:           method,
+		Fault:            FaultFileCorrupt,
+		Args:             args,
+	}
+}
+
+func (i *CorruptInjector) Inject(ctx context.Context) error {
+	logger := log.GetLogger(ctx)
+	fileSize, err := filesys.GetFileSize(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Path)
+	if err != nil {
+		return fmt.Errorf("get size of file[%s] error: %s", i.Args.Path, err.Error())
+	}
+
+	offset, length, err := i.getRange(fileSize)
+	if err != nil {
+		return err
+	}
+
+	i.Runtime.Checksum, err = backupFile(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Path)
+	if err != nil {
+		if err := i.Recover(ctx); err != nil {
+			logger.Warnf("undo error: %s", err.Error())
+		}
+
+		return fmt.Errorf("backup file[%s] error: %s", i.Args.Path, err.Error())
+	}
+
+	if err := i.getCmdExecutor(utils.MethodInject, fmt.Sprintf("%s %s %d %d", filesys.QuotePath(i.Args.Path), i.Args.Mode, offset, length)).ExecTool(ctx); err != nil {
+		if err := i.Recover(ctx); err != nil {
+			logger.Warnf("undo error: %s", err.Error())
+		}
+
+		return fmt.Errorf("corrupt file[%s] error: %s", i.Args.Path, err.Error())
+	}
+
+	return nil
+}
+
+func (i *CorruptInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	return restoreFile(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Path, i.Runtime.Checksum)
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file
+
+import (
+	"testing"
+)
+
+func TestCorruptInjector_getRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       CorruptArgs
+		fileSize   int64
+		wantOffset int64
+		wantLength int64
+		wantErr    bool
+	}{
+		{name: "length", args: CorruptArgs{Offset: "10", Length: "20"}, fileSize: 100, wantOffset: 10, wantLength: 20},
+		{name: "length with unit", args: CorruptArgs{Offset: "1KB", Length: "1KB"}, fileSize: 4096, wantOffset: 1024, wantLength: 1024},
+		{name: "length limited in file", args: CorruptArgs{Offset: "90", Length: "20"}, fileSize: 100, wantOffset: 90, wantLength: 10},
+		{name: "percent", args: CorruptArgs{Offset: "0", Percent: 30}, fileSize: 100, wantOffset: 0, wantLength: 30},
+		{name: "percent limited in file", args: CorruptArgs{Offset: "80", Percent: 50}, fileSize: 100, wantOffset: 80, wantLength: 20},
+		{name: "last byte", args: CorruptArgs{Offset: "99", Length: "1"}, fileSize: 100, wantOffset: 99, wantLength: 1},
+		{name: "offset at the end", args: CorruptArgs{Offset: "100", Length: "1"}, fileSize: 100, wantErr: true},
+		{name: "negative offset", args: CorruptArgs{Offset: "-1", Length: "1"}, fileSize: 100, wantErr: true},
+		{name: "invalid offset", args: CorruptArgs{Offset: "1x", Length: "1"}, fileSize: 100, wantErr: true},
+		{name: "invalid length", args: CorruptArgs{Offset: "0", Length: "1x"}, fileSize: 100, wantErr: true},
+		{name: "zero length", args: CorruptArgs{Offset: "0", Length: "0"}, fileSize: 100, wantErr: true},
+		{name: "percent of small file", args: CorruptArgs{Offset: "0", Percent: 1}, fileSize: 50, wantErr: true},
+		{name: "empty file", args: CorruptArgs{Offset: "0", Length: "1"}, fileSize: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &CorruptInjector{Args: tt.args}
+			offset, length, err := i.getRange(tt.fileSize)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getRange() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if offset != tt.wantOffset || length != tt.wantLength {
+				t.Errorf("getRange() = (%v, %v), want (%v, %v)", offset, length, tt.wantOffset, tt.wantLength)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
+)
+
+func init() {
+	injector.Register(TargetFile, FaultFileTruncate, func() injector.IInjector { return &TruncateInjector{} })
+}
+
+type TruncateInjector struct {
+	injector.BaseInjector
+	Args    TruncateArgs
+	Runtime TruncateRuntime
+}
+
+type TruncateArgs struct {
+	Path    string `json:"path"`
+	Size    string `json:"size,omitempty"`
+	Percent int    `json:"percent,omitempty"`
+}
+
+type TruncateRuntime struct {
+	Checksum string `json:"checksum,omitempty"`
+}
+
+func (i *TruncateInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *TruncateInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *TruncateInjector) SetOption(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&i.Args.Path, "path", "p", "", "file path, include dir and file name")
+	cmd.Flags().StringVarP(&i.Args.Size, "size", "s", "", "the size of file after truncated, must less than the size of file, support unit: B/KB/MB/GB/TB（default B）, eg: \"0\" means empty the file")
+	cmd.Flags().IntVarP(&i.Args.Percent, "percent", "P", 0, "the percent of file size kept after truncated, an integer in (0,100) without \"%\". if \"size\" provided, \"percent\" will be ignored")
+}
+
+func (i *TruncateInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	if i.Args.Size == "" && i.Args.Percent == 0 {
+		return fmt.Errorf("must provide \"size\" or \"percent\"")
+	}
+
+	if i.Args.Size == "" && (i.Args.Percent < 0 || i.Args.Percent >= 100) {
+		return fmt.Errorf("\"percent\"[%d] must be in (0,100)", i.Args.Percent)
+	}
+
+	fileSize, err := getTargetFileSize(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := i.getTargetSize(fileSize); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// getTargetSize "size" is preferred
+func (i *TruncateInjector) getTargetSize(fileSize int64) (int64, error) {
+	if i.Args.Size == "" {
+		return fileSize * int64(i.Args.Percent) / 100, nil
+	}
+
+	size, err := utils.GetBytes(i.Args.Size)
+	if err != nil {
+		return -1, fmt.Errorf("\"size\"[%s] is invalid: %s", i.Args.Size, err.Error())
+	}
+
+	if size < 0 || size >= fileSize {
+		return -1, fmt.Errorf("\"size\"[%s] must be in [0,%d), the size of file[%s]", i.Args.Size, fileSize, i.Args.Path)
+	}
+
+	return size, nil
+}
+
+func (i *TruncateInjector) Inject(ctx context.Context) error {
+	logger := log.GetLogger(ctx)
+	fileSize, err := filesys.GetFileSize(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Path)
+	if err != nil {
+		return fmt.Errorf("get size of file[%s] error: %s", i.Args.Path, err.Error())
+	}
+
+	size, err := i.getTargetSize(fileSize)
+	if err != nil {
+		return err
+	}
+
+	i.Runtime.Checksum, err = backupFile(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Path)
+	if err != nil {
+		if err := i.Recover(ctx); err != nil {
+			logger.Warnf("undo error: %s", err.Error())
+		}
+
+		return fmt.Errorf("backup file[%s] error: %s", i.Args.Path, err.Error())
+	}
+
+	if err := filesys.TruncateFile(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Path, size); err != nil {
+		if err := i.Recover(ctx); err != nil {
+			logger.Warnf("undo error: %s", err.Error())
+		}
+
+		return fmt.Errorf("truncate file[%s] to %d bytes error: %s", i.Args.Path, size, err.Error())
+	}
+
+	return nil
+}
+
+func (i *TruncateInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	return restoreFile(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Path, i.Runtime.Checksum)
+}
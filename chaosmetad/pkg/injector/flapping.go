@@ -0,0 +1,274 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package injector
+
+import (
+	"context"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/storage"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/errutil"
+	"runtime/debug"
+	"time"
+)
+
+// FlappingCheckInterval the interval to check if the experiment has been recovered by others while waiting
+const FlappingCheckInterval = time.Second
+
+// ProcessFlapping cycle the inject and recover of a flapping experiment in background, the cycle N starts at
+// "start + (N-1) * interval", the fault is injected in [cycle start, cycle start + on-duration) and recovered in the rest.
+// the schedule is calculated from the db, so the process can be restarted at any time, eg: by reconcile.
+// exit when the experiment is recovered by others, or finished by "repeat" or "timeout"
+func ProcessFlapping(ctx context.Context, uid string) (code int, msg string) {
+	logger := log.GetLogger(ctx)
+	defer func() {
+		if err := recover(); err != any(nil) {
+			logger.Debug(string(debug.Stack()))
+			code, msg = errutil.UnknownErr, fmt.Sprintf("ProcessFlapping Exception: %v", err)
+		}
+	}()
+
+	db, err := storage.GetExperimentStore()
+	if err != nil {
+		return errutil.DBErr, fmt.Sprintf("connect db error: %s", err.Error())
+	}
+
+	exp, err := db.GetByUid(uid)
+	if err != nil {
+		return errutil.DBErr, fmt.Sprintf("query experiment by uid[%s] error: %s", uid, err.Error())
+	}
+
+	if exp.Interval == "" {
+		return errutil.BadArgsErr, fmt.Sprintf("experiment[%s] is not in flapping mode", uid)
+	}
+
+	start, err := getFlappingStart(exp)
+	if err != nil {
+		return errutil.InternalErr, fmt.Sprintf("get start time of flapping error: %s", err.Error())
+	}
+
+	interval, _ := utils.GetTimeSecond(exp.Interval)
+	var onDuration, timeout int64
+	if exp.OnDuration != "" {
+		onDuration, _ = utils.GetTimeSecond(exp.OnDuration)
+	}
+
+	if exp.Timeout != "" {
+		timeout, _ = utils.GetTimeSecond(exp.Timeout)
+	}
+
+	end := start.Add(time.Duration(timeout) * time.Second)
+	for exp.Status == utils.StatusSuccess {
+		cycleStart := start.Add(time.Duration(int64(exp.Cycle-1)*interval) * time.Second)
+		if exp.CyclePhase == utils.CyclePhaseOn {
+			offTime := cycleStart.Add(time.Duration(onDuration) * time.Second)
+			if exp.Repeat > 0 && exp.Cycle >= exp.Repeat {
+				return finishFlapping(ctx, uid, offTime, end, timeout, fmt.Sprintf("all the %d cycles are finished", exp.Repeat))
+			}
+
+			if timeout > 0 && !offTime.Before(end) {
+				return finishFlapping(ctx, uid, end, end, timeout, fmt.Sprintf("timeout[%s] has elapsed", exp.Timeout))
+			}
+
+			if !waitFlapping(ctx, uid, offTime) {
+				return errutil.NoErr, "experiment is recovered"
+			}
+
+			if err := processCycle(ctx, exp, utils.CyclePhaseOff, exp.Cycle); err != nil {
+				return errutil.RecoverErr, fmt.Sprintf("recover of cycle %d error: %s", exp.Cycle, err.Error())
+			}
+		} else {
+			nextCycle := getNextCycle(start, time.Now(), interval, onDuration, exp.Cycle)
+			if exp.Repeat > 0 && nextCycle > exp.Repeat {
+				return finishFlapping(ctx, uid, time.Now(), end, timeout, fmt.Sprintf("all the %d cycles are finished", exp.Repeat))
+			}
+
+			nextStart := start.Add(time.Duration(int64(nextCycle-1)*interval) * time.Second)
+			if timeout > 0 && !nextStart.Before(end) {
+				return finishFlapping(ctx, uid, end, end, timeout, fmt.Sprintf("timeout[%s] has elapsed", exp.Timeout))
+			}
+
+			if !waitFlapping(ctx, uid, nextStart) {
+				return errutil.NoErr, "experiment is recovered"
+			}
+
+			if err := processCycle(ctx, exp, utils.CyclePhaseOn, nextCycle); err != nil {
+				return errutil.InjectErr, fmt.Sprintf("inject of cycle %d error: %s", nextCycle, err.Error())
+			}
+		}
+
+		if exp, err = db.GetByUid(uid); err != nil {
+			return errutil.DBErr, fmt.Sprintf("query experiment by uid[%s] error: %s", uid, err.Error())
+		}
+	}
+
+	return errutil.NoErr, "experiment is recovered"
+}
+
+// getFlappingStart the first cycle starts at the time of the first inject success
+func getFlappingStart(exp *storage.Experiment) (time.Time, error) {
+	startTime := exp.CreateTime
+	journal, err := storage.GetJournalStore()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("connect journal error: %s", err.Error())
+	}
+
+	lastInject, err := journal.GetLastByAction(exp.Uid, storage.JournalInject)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("query last inject journal error: %s", err.Error())
+	}
+
+	if lastInject != nil && lastInject.Phase == storage.JournalSuccess {
+		startTime = lastInject.CreateTime
+	}
+
+	return time.ParseInLocation(utils.TimeFormat, startTime, time.Local)
+}
+
+// getNextCycle the cycle after the current one normally. the cycles whose "on" phase has passed are skipped, eg: the
+// flapping process is restarted by reconcile after a long time
+func getNextCycle(start, now time.Time, interval, onDuration int64, cycle int) int {
+	next := cycle + 1
+	elapsed := int64(now.Sub(start).Seconds())
+	if elapsed < 0 {
+		return next
+	}
+
+	nowCycle := int(elapsed/interval) + 1
+	if elapsed%interval >= onDuration {
+		nowCycle++
+	}
+
+	if nowCycle > next {
+		return nowCycle
+	}
+
+	return next
+}
+
+// waitFlapping wait until the time, return false if the experiment is no longer injected
+func waitFlapping(ctx context.Context, uid string, until time.Time) bool {
+	for {
+		db, err := storage.GetExperimentStore()
+		if err != nil {
+			log.GetLogger(ctx).Warnf("connect db error: %s", err.Error())
+		} else if exp, err := db.GetByUid(uid); err != nil {
+			log.GetLogger(ctx).Warnf("query experiment by uid[%s] error: %s", uid, err.Error())
+		} else if exp.Status != utils.StatusSuccess {
+			return false
+		}
+
+		remain := time.Until(until)
+		if remain <= 0 {
+			return true
+		}
+
+		if remain > FlappingCheckInterval {
+			remain = FlappingCheckInterval
+		}
+
+		time.Sleep(remain)
+	}
+}
+
+// finishFlapping wait until the end of the last "on" phase and recover the experiment. if timeout is provided, finish
+// at the time of timeout at the latest
+func finishFlapping(ctx context.Context, uid string, until, end time.Time, timeout int64, reason string) (int, string) {
+	if timeout > 0 && until.After(end) {
+		until = end
+	}
+
+	if !waitFlapping(ctx, uid, until) {
+		return errutil.NoErr, "experiment is recovered"
+	}
+
+	log.GetLogger(ctx).Infof("experiment[%s]: %s, recover it", uid, reason)
+	return ProcessRecover(ctx, uid)
+}
+
+// processCycle inject or recover the fault for the phase of cycle, and record it to db
+func processCycle(ctx context.Context, exp *storage.Experiment, phase string, cycle int) error {
+	i, err := NewInjector(exp.Target, exp.Fault)
+	if err != nil {
+		return fmt.Errorf("find injector by target[%s] and fault[%s] error: %s", exp.Target, exp.Fault, err.Error())
+	}
+
+	if err := i.LoadInjector(exp, i.GetArgs(), i.GetRuntime()); err != nil {
+		return fmt.Errorf("load experiment to injector error: %s", err.Error())
+	}
+
+	db, err := storage.GetExperimentStore()
+	if err != nil {
+		return fmt.Errorf("connect db error: %s", err.Error())
+	}
+
+	var (
+		action = storage.JournalCycleRecover
+		msg    = fmt.Sprintf("cycle %d", cycle)
+	)
+
+	if phase == utils.CyclePhaseOn {
+		action = storage.JournalCycleInject
+	}
+
+	appendJournal(ctx, exp.Uid, action, storage.JournalStart, msg)
+	if phase == utils.CyclePhaseOn {
+		err = i.Inject(ctx)
+	} else {
+		err = i.Recover(ctx)
+	}
+
+	if err != nil {
+		errMsg := fmt.Sprintf("%s error: %s", msg, err.Error())
+		appendJournal(ctx, exp.Uid, action, storage.JournalError, errMsg)
+		// the fault of failed inject has been undone, so the experiment is finished with error. the failed recover
+		// keeps the status to be recovered again
+		if phase == utils.CyclePhaseOn {
+			if err := db.UpdateStatusAndErr(exp.Uid, utils.StatusError, errMsg); err != nil {
+				log.GetLogger(ctx).Warnf("update status[%s] for experiment[%s] error: %s", utils.StatusError, exp.Uid, err.Error())
+			}
+		} else {
+			db.AppendEvent(exp.Uid, storage.EventError, errMsg)
+		}
+
+		return err
+	}
+
+	newExp, err := i.OptionToExp(i.GetArgs(), i.GetRuntime())
+	if err != nil {
+		return fmt.Errorf("get experiment info error: %s", err.Error())
+	}
+
+	if err := db.UpdateCycle(exp.Uid, cycle, phase, newExp.Runtime); err != nil {
+		return fmt.Errorf("update cycle[%d %s] error: %s", cycle, phase, err.Error())
+	}
+
+	appendJournal(ctx, exp.Uid, action, storage.JournalSuccess, msg)
+
+	// the experiment may be recovered by others during the inject, and the new injected fault will be left
+	if phase == utils.CyclePhaseOn {
+		if nowExp, err := db.GetByUid(exp.Uid); err == nil && nowExp.Status != utils.StatusSuccess {
+			log.GetLogger(ctx).Warnf("experiment[%s] is recovered during the inject of %s, undo it", exp.Uid, msg)
+			if err := i.Recover(ctx); err != nil {
+				log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package injector
+
+import (
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/storage"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"testing"
+	"time"
+)
+
+func TestGetNextCycle(t *testing.T) {
+	start := time.Date(2023, 5, 1, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name  string
+		now   time.Time
+		cycle int
+		want  int
+	}{
+		{name: "before start", now: start.Add(-10 * time.Second), cycle: 1, want: 2},
+		{name: "off phase of current cycle", now: start.Add(30 * time.Second), cycle: 1, want: 2},
+		{name: "end of off phase", now: start.Add(59 * time.Second), cycle: 1, want: 2},
+		{name: "on phase of next cycle", now: start.Add(70 * time.Second), cycle: 1, want: 2},
+		{name: "off phase of next cycle", now: start.Add(80 * time.Second), cycle: 1, want: 3},
+		{name: "restarted long after", now: start.Add(600 * time.Second), cycle: 1, want: 11},
+		{name: "restarted long after in off phase", now: start.Add(620 * time.Second), cycle: 1, want: 12},
+		{name: "cycle ahead of now", now: start.Add(30 * time.Second), cycle: 5, want: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getNextCycle(start, tt.now, 60, 20, tt.cycle); got != tt.want {
+				t.Errorf("getNextCycle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsFaultActive(t *testing.T) {
+	tests := []struct {
+		name string
+		exp  *storage.Experiment
+		want bool
+	}{
+		{name: "not flapping", exp: &storage.Experiment{}, want: true},
+		{name: "not flapping with phase", exp: &storage.Experiment{CyclePhase: utils.CyclePhaseOff}, want: true},
+		{name: "on phase", exp: &storage.Experiment{Interval: "1m", Cycle: 3, CyclePhase: utils.CyclePhaseOn}, want: true},
+		{name: "off phase", exp: &storage.Experiment{Interval: "1m", Cycle: 3, CyclePhase: utils.CyclePhaseOff}, want: false},
+		{name: "no phase recorded", exp: &storage.Experiment{Interval: "1m"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isFaultActive(tt.exp); got != tt.want {
+				t.Errorf("isFaultActive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+)
+
+func init() {
+	injector.Register(TargetGRPC, FaultAbort, func() injector.IInjector { return &AbortInjector{} })
+}
+
+type AbortInjector struct {
+	injector.BaseInjector
+	Args    AbortArgs
+	Runtime ProxyRuntime
+}
+
+type AbortArgs struct {
+	ProxyArgs
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func (i *AbortInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *AbortInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *AbortInjector) SetDefault() {
+	i.BaseInjector.SetDefault()
+	setProxyDefault(&i.Args.ProxyArgs)
+
+	if i.Args.Code == "" {
+		i.Args.Code = DefaultAbortCode
+	}
+}
+
+func (i *AbortInjector) SetOption(cmd *cobra.Command) {
+	// i.BaseInjector.SetOption(cmd)
+	setProxyOption(cmd, &i.Args.ProxyArgs)
+	cmd.Flags().StringVarP(&i.Args.Code, "code", "c", "", fmt.Sprintf("grpc status code returned to the matched calls without forwarding them, support name or number. eg: Unavailable, DEADLINE_EXCEEDED, 14（default %s）", DefaultAbortCode))
+	cmd.Flags().StringVarP(&i.Args.Message, "message", "m", "", "grpc status message returned to the matched calls")
+}
+
+func (i *AbortInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	if _, err := getCode(i.Args.Code); err != nil {
+		return fmt.Errorf("\"code\"[%s] is invalid: %s", i.Args.Code, err.Error())
+	}
+
+	return checkProxyArgs(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, &i.Args.ProxyArgs)
+}
+
+func (i *AbortInjector) Inject(ctx context.Context) error {
+	rule := getProxyRule(FaultAbort, &i.Args.ProxyArgs)
+	code, err := getCode(i.Args.Code)
+	if err != nil {
+		return fmt.Errorf("\"code\"[%s] is invalid: %s", i.Args.Code, err.Error())
+	}
+	rule.Code, rule.Message = int(code), i.Args.Message
+
+	if err := startProxy(ctx, &i.Info, &i.Args.ProxyArgs, rule, &i.Runtime); err != nil {
+		if err := i.Recover(ctx); err != nil {
+			log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (i *AbortInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	return stopProxy(ctx, &i.Info)
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/net"
+	"google.golang.org/grpc/codes"
+	"strconv"
+	"strings"
+)
+
+const (
+	TargetGRPC = "grpc"
+
+	FaultDelay = "delay"
+
+	FaultAbort       = "abort"
+	DefaultAbortCode = "Unavailable"
+
+	FaultTruncate = "truncate"
+
+	ProxyKey         = "chaosmeta_grpcproxy"
+	DefaultProxyPort = 15081
+	DefaultPercent   = 100
+)
+
+// ProxyArgs the common args of grpc faults: which port to intercept and which methods to affect
+type ProxyArgs struct {
+	Port      int    `json:"port"`
+	ProxyPort int    `json:"proxy_port"`
+	Service   string `json:"service,omitempty"`
+	Method    string `json:"method,omitempty"`
+	Percent   int    `json:"percent"`
+}
+
+type ProxyRuntime struct {
+	Pid int `json:"pid,omitempty"`
+}
+
+// ProxyRule the rule executed by the proxy tool, should be consistent with the rule in tools/chaosmeta_grpcproxy.go
+type ProxyRule struct {
+	Fault     string `json:"fault"`
+	Service   string `json:"service,omitempty"`
+	Method    string `json:"method,omitempty"`
+	Percent   int    `json:"percent"`
+	LatencyUs int64  `json:"latency_us,omitempty"`
+	Code      int    `json:"code,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Length    int    `json:"length,omitempty"`
+}
+
+func setProxyDefault(args *ProxyArgs) {
+	if args.ProxyPort == 0 {
+		args.ProxyPort = DefaultProxyPort
+	}
+
+	if args.Percent == 0 {
+		args.Percent = DefaultPercent
+	}
+
+	args.Service, args.Method = strings.TrimPrefix(args.Service, "/"), strings.TrimPrefix(args.Method, "/")
+}
+
+func setProxyOption(cmd *cobra.Command, args *ProxyArgs) {
+	cmd.Flags().IntVarP(&args.Port, "port", "p", 0, "target port of grpc service, the requests sent to this port from other hosts will be intercepted")
+	cmd.Flags().IntVar(&args.ProxyPort, "proxy-port", 0, fmt.Sprintf("listen port of the fault proxy（default %d）", DefaultProxyPort))
+	cmd.Flags().StringVar(&args.Service, "service", "", "filter condition: full name of grpc service. eg: helloworld.Greeter")
+	cmd.Flags().StringVar(&args.Method, "method", "", "filter condition: name of grpc method. eg: SayHello")
+	cmd.Flags().IntVar(&args.Percent, "percent", 0, fmt.Sprintf("percent of the matched requests to inject, an integer in (0,100]（default %d）", DefaultPercent))
+}
+
+func checkProxyArgs(ctx context.Context, cr, cId string, args *ProxyArgs) error {
+	if args.Percent <= 0 || args.Percent > 100 {
+		return fmt.Errorf("\"percent\" should in (0, 100]")
+	}
+
+	if strings.Contains(args.Service, "/") {
+		return fmt.Errorf("\"service\"[%s] should not contain \"/\"", args.Service)
+	}
+
+	if strings.Contains(args.Method, "/") {
+		return fmt.Errorf("\"method\"[%s] should not contain \"/\"", args.Method)
+	}
+
+	return net.CheckProxyPort(ctx, cr, cId, args.Port, args.ProxyPort)
+}
+
+// getCode parse grpc status code by name like "Unavailable", "UNAVAILABLE" or number like "14"
+func getCode(codeStr string) (codes.Code, error) {
+	if num, err := strconv.Atoi(codeStr); err == nil {
+		if num <= int(codes.OK) || num > int(codes.Unauthenticated) {
+			return codes.OK, fmt.Errorf("should in (%d, %d]", codes.OK, codes.Unauthenticated)
+		}
+
+		return codes.Code(num), nil
+	}
+
+	name := strings.ReplaceAll(codeStr, "_", "")
+	for c := codes.Canceled; c <= codes.Unauthenticated; c++ {
+		if strings.EqualFold(c.String(), name) {
+			return c, nil
+		}
+	}
+
+	return codes.OK, fmt.Errorf("not a valid grpc code")
+}
+
+func getProxyRule(fault string, args *ProxyArgs) *ProxyRule {
+	return &ProxyRule{
+		Fault:   fault,
+		Service: args.Service,
+		Method:  args.Method,
+		Percent: args.Percent,
+	}
+}
+
+func startProxy(ctx context.Context, info *injector.BaseInfo, args *ProxyArgs, rule *ProxyRule, r *ProxyRuntime) error {
+	var err error
+	r.Pid, err = net.StartProxy(ctx, info.ContainerRuntime, info.ContainerId, info.Uid, ProxyKey, args.Port, args.ProxyPort, rule)
+	return err
+}
+
+func stopProxy(ctx context.Context, info *injector.BaseInfo) error {
+	return net.StopProxy(ctx, info.ContainerRuntime, info.ContainerId, info.Uid, ProxyKey)
+}
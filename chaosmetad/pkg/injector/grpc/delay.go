@@ -0,0 +1,102 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+)
+
+func init() {
+	injector.Register(TargetGRPC, FaultDelay, func() injector.IInjector { return &DelayInjector{} })
+}
+
+type DelayInjector struct {
+	injector.BaseInjector
+	Args    DelayArgs
+	Runtime ProxyRuntime
+}
+
+type DelayArgs struct {
+	ProxyArgs
+	Latency string `json:"latency"`
+}
+
+func (i *DelayInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *DelayInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *DelayInjector) SetDefault() {
+	i.BaseInjector.SetDefault()
+	setProxyDefault(&i.Args.ProxyArgs)
+}
+
+func (i *DelayInjector) SetOption(cmd *cobra.Command) {
+	// i.BaseInjector.SetOption(cmd)
+	setProxyOption(cmd, &i.Args.ProxyArgs)
+	cmd.Flags().StringVarP(&i.Args.Latency, "latency", "l", "", "delay time of the matched grpc calls before forwarding them, support unit: \"s、ms、us\"(default us)")
+}
+
+func (i *DelayInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	if i.Args.Latency == "" {
+		return fmt.Errorf("\"latency\" is empty")
+	}
+
+	if err := utils.CheckTimeValue(i.Args.Latency); err != nil {
+		return fmt.Errorf("\"latency\"[%s] is invalid: %s", i.Args.Latency, err.Error())
+	}
+
+	return checkProxyArgs(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, &i.Args.ProxyArgs)
+}
+
+func (i *DelayInjector) Inject(ctx context.Context) error {
+	var err error
+	rule := getProxyRule(FaultDelay, &i.Args.ProxyArgs)
+	if rule.LatencyUs, err = utils.GetTimeUs(i.Args.Latency); err != nil {
+		return fmt.Errorf("\"latency\"[%s] is invalid: %s", i.Args.Latency, err.Error())
+	}
+
+	if err := startProxy(ctx, &i.Info, &i.Args.ProxyArgs, rule, &i.Runtime); err != nil {
+		if err := i.Recover(ctx); err != nil {
+			log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (i *DelayInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	return stopProxy(ctx, &i.Info)
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+)
+
+func init() {
+	injector.Register(TargetGRPC, FaultTruncate, func() injector.IInjector { return &TruncateInjector{} })
+}
+
+type TruncateInjector struct {
+	injector.BaseInjector
+	Args    TruncateArgs
+	Runtime ProxyRuntime
+}
+
+type TruncateArgs struct {
+	ProxyArgs
+	Length int `json:"length"`
+}
+
+func (i *TruncateInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *TruncateInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *TruncateInjector) SetDefault() {
+	i.BaseInjector.SetDefault()
+	setProxyDefault(&i.Args.ProxyArgs)
+}
+
+func (i *TruncateInjector) SetOption(cmd *cobra.Command) {
+	// i.BaseInjector.SetOption(cmd)
+	setProxyOption(cmd, &i.Args.ProxyArgs)
+	cmd.Flags().IntVarP(&i.Args.Length, "length", "l", 0, "bytes of response body kept for the matched calls, the rest is dropped, so the client will get an incomplete message（default 0）")
+}
+
+func (i *TruncateInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	if i.Args.Length < 0 {
+		return fmt.Errorf("\"length\" should not be less than 0")
+	}
+
+	return checkProxyArgs(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, &i.Args.ProxyArgs)
+}
+
+func (i *TruncateInjector) Inject(ctx context.Context) error {
+	rule := getProxyRule(FaultTruncate, &i.Args.ProxyArgs)
+	rule.Length = i.Args.Length
+
+	if err := startProxy(ctx, &i.Info, &i.Args.ProxyArgs, rule, &i.Runtime); err != nil {
+		if err := i.Recover(ctx); err != nil {
+			log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (i *TruncateInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	return stopProxy(ctx, &i.Info)
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+)
+
+func init() {
+	injector.Register(TargetHTTP, FaultAbort, func() injector.IInjector { return &AbortInjector{} })
+}
+
+type AbortInjector struct {
+	injector.BaseInjector
+	Args    AbortArgs
+	Runtime ProxyRuntime
+}
+
+type AbortArgs struct {
+	ProxyArgs
+	Code int `json:"code"`
+}
+
+func (i *AbortInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *AbortInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *AbortInjector) SetDefault() {
+	i.BaseInjector.SetDefault()
+	setProxyDefault(&i.Args.ProxyArgs)
+
+	if i.Args.Code == 0 {
+		i.Args.Code = DefaultAbortCode
+	}
+}
+
+func (i *AbortInjector) SetOption(cmd *cobra.Command) {
+	// i.BaseInjector.SetOption(cmd)
+	setProxyOption(cmd, &i.Args.ProxyArgs)
+	cmd.Flags().IntVarP(&i.Args.Code, "code", "c", 0, fmt.Sprintf("http status code returned to the matched requests without forwarding them, in [%d, %d]（default %d）", MinCode, MaxCode, DefaultAbortCode))
+}
+
+func (i *AbortInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	if i.Args.Code < MinCode || i.Args.Code > MaxCode {
+		return fmt.Errorf("\"code\" should in [%d, %d]", MinCode, MaxCode)
+	}
+
+	return checkProxyArgs(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, &i.Args.ProxyArgs)
+}
+
+func (i *AbortInjector) Inject(ctx context.Context) error {
+	rule, err := getProxyRule(FaultAbort, &i.Args.ProxyArgs)
+	if err != nil {
+		return err
+	}
+	rule.Code = i.Args.Code
+
+	if err := startProxy(ctx, &i.Info, &i.Args.ProxyArgs, rule, &i.Runtime); err != nil {
+		if err := i.Recover(ctx); err != nil {
+			log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (i *AbortInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	return stopProxy(ctx, &i.Info)
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/net"
+	"regexp"
+	"strings"
+)
+
+const (
+	TargetHTTP = "http"
+
+	FaultAbort       = "abort"
+	DefaultAbortCode = 503
+	// MinCode the informational 1xx code can not be used, it is followed by an implicit 200 response
+	MinCode = 200
+	MaxCode = 599
+
+	FaultDelay = "delay"
+
+	FaultHeader = "header"
+
+	FaultBodyReplace = "body-replace"
+
+	ProxyKey         = "chaosmeta_httpproxy"
+	DefaultProxyPort = 15080
+	DefaultPercent   = 100
+)
+
+// ProxyArgs the common args of http faults: which port to intercept and which requests to affect
+type ProxyArgs struct {
+	Port      int    `json:"port"`
+	ProxyPort int    `json:"proxy_port"`
+	Method    string `json:"method,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Header    string `json:"header,omitempty"`
+	Percent   int    `json:"percent"`
+}
+
+type ProxyRuntime struct {
+	Pid int `json:"pid,omitempty"`
+}
+
+// ProxyRule the rule executed by the proxy tool, should be consistent with the rule in tools/chaosmeta_httpproxy.go
+type ProxyRule struct {
+	Fault     string            `json:"fault"`
+	Method    string            `json:"method,omitempty"`
+	Path      string            `json:"path,omitempty"`
+	Header    map[string]string `json:"header,omitempty"`
+	Percent   int               `json:"percent"`
+	Code      int               `json:"code,omitempty"`
+	LatencyUs int64             `json:"latency_us,omitempty"`
+	SetHeader map[string]string `json:"set_header,omitempty"`
+	DelHeader []string          `json:"del_header,omitempty"`
+	Body      string            `json:"body,omitempty"`
+}
+
+func setProxyDefault(args *ProxyArgs) {
+	if args.ProxyPort == 0 {
+		args.ProxyPort = DefaultProxyPort
+	}
+
+	if args.Percent == 0 {
+		args.Percent = DefaultPercent
+	}
+
+	args.Method = strings.ToUpper(args.Method)
+}
+
+func setProxyOption(cmd *cobra.Command, args *ProxyArgs) {
+	cmd.Flags().IntVarP(&args.Port, "port", "p", 0, "target port of http service, the requests sent to this port from other hosts will be intercepted")
+	cmd.Flags().IntVar(&args.ProxyPort, "proxy-port", 0, fmt.Sprintf("listen port of the fault proxy（default %d）", DefaultProxyPort))
+	cmd.Flags().StringVar(&args.Method, "method", "", "filter condition: http method. eg: GET")
+	cmd.Flags().StringVar(&args.Path, "path", "", "filter condition: regular expression of request path. eg: ^/api/orders")
+	cmd.Flags().StringVar(&args.Header, "header", "", "filter condition: request headers, all of them should be matched. eg: \"x-user:test,x-env:gray\"")
+	cmd.Flags().IntVar(&args.Percent, "percent", 0, fmt.Sprintf("percent of the matched requests to inject, an integer in (0,100]（default %d）", DefaultPercent))
+}
+
+func checkProxyArgs(ctx context.Context, cr, cId string, args *ProxyArgs) error {
+	if args.Percent <= 0 || args.Percent > 100 {
+		return fmt.Errorf("\"percent\" should in (0, 100]")
+	}
+
+	if args.Path != "" {
+		if _, err := regexp.Compile(args.Path); err != nil {
+			return fmt.Errorf("\"path\"[%s] is not a valid regular expression: %s", args.Path, err.Error())
+		}
+	}
+
+	if _, err := getHeaderMap(args.Header); err != nil {
+		return fmt.Errorf("\"header\"[%s] is invalid: %s", args.Header, err.Error())
+	}
+
+	return net.CheckProxyPort(ctx, cr, cId, args.Port, args.ProxyPort)
+}
+
+// getHeaderMap convert header list like "k1:v1,k2:v2" to map
+func getHeaderMap(headerStr string) (map[string]string, error) {
+	headerStr = strings.TrimSpace(headerStr)
+	if headerStr == "" {
+		return nil, nil
+	}
+
+	re := make(map[string]string)
+	for _, unit := range strings.Split(headerStr, ",") {
+		kv := strings.SplitN(unit, ":", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("%s is not in format \"key:value\"", unit)
+		}
+
+		re[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+
+	return re, nil
+}
+
+// getHeaderKeyList convert header key list like "k1,k2" to list
+func getHeaderKeyList(keyStr string) []string {
+	var re []string
+	for _, unit := range strings.Split(keyStr, ",") {
+		if unit = strings.TrimSpace(unit); unit != "" {
+			re = append(re, unit)
+		}
+	}
+
+	return re
+}
+
+func getProxyRule(fault string, args *ProxyArgs) (*ProxyRule, error) {
+	header, err := getHeaderMap(args.Header)
+	if err != nil {
+		return nil, fmt.Errorf("\"header\"[%s] is invalid: %s", args.Header, err.Error())
+	}
+
+	return &ProxyRule{
+		Fault:   fault,
+		Method:  args.Method,
+		Path:    args.Path,
+		Header:  header,
+		Percent: args.Percent,
+	}, nil
+}
+
+func startProxy(ctx context.Context, info *injector.BaseInfo, args *ProxyArgs, rule *ProxyRule, r *ProxyRuntime) error {
+	var err error
+	r.Pid, err = net.StartProxy(ctx, info.ContainerRuntime, info.ContainerId, info.Uid, ProxyKey, args.Port, args.ProxyPort, rule)
+	return err
+}
+
+func stopProxy(ctx context.Context, info *injector.BaseInfo) error {
+	return net.StopProxy(ctx, info.ContainerRuntime, info.ContainerId, info.Uid, ProxyKey)
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+)
+
+func init() {
+	injector.Register(TargetHTTP, FaultHeader, func() injector.IInjector { return &HeaderInjector{} })
+}
+
+type HeaderInjector struct {
+	injector.BaseInjector
+	Args    HeaderArgs
+	Runtime ProxyRuntime
+}
+
+type HeaderArgs struct {
+	ProxyArgs
+	SetHeader string `json:"set_header,omitempty"`
+	DelHeader string `json:"del_header,omitempty"`
+}
+
+func (i *HeaderInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *HeaderInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *HeaderInjector) SetDefault() {
+	i.BaseInjector.SetDefault()
+	setProxyDefault(&i.Args.ProxyArgs)
+}
+
+func (i *HeaderInjector) SetOption(cmd *cobra.Command) {
+	// i.BaseInjector.SetOption(cmd)
+	setProxyOption(cmd, &i.Args.ProxyArgs)
+	cmd.Flags().StringVar(&i.Args.SetHeader, "set-header", "", "headers to add or overwrite in the response of the matched requests. eg: \"x-fault:chaosmeta,cache-control:no-cache\"")
+	cmd.Flags().StringVar(&i.Args.DelHeader, "del-header", "", "headers to remove from the response of the matched requests. eg: \"etag,x-trace-id\"")
+}
+
+func (i *HeaderInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	if i.Args.SetHeader == "" && i.Args.DelHeader == "" {
+		return fmt.Errorf("\"set-header\" and \"del-header\" can not be both empty")
+	}
+
+	if _, err := getHeaderMap(i.Args.SetHeader); err != nil {
+		return fmt.Errorf("\"set-header\"[%s] is invalid: %s", i.Args.SetHeader, err.Error())
+	}
+
+	return checkProxyArgs(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, &i.Args.ProxyArgs)
+}
+
+func (i *HeaderInjector) Inject(ctx context.Context) error {
+	rule, err := getProxyRule(FaultHeader, &i.Args.ProxyArgs)
+	if err != nil {
+		return err
+	}
+	if rule.SetHeader, err = getHeaderMap(i.Args.SetHeader); err != nil {
+		return fmt.Errorf("\"set-header\"[%s] is invalid: %s", i.Args.SetHeader, err.Error())
+	}
+	rule.DelHeader = getHeaderKeyList(i.Args.DelHeader)
+
+	if err := startProxy(ctx, &i.Info, &i.Args.ProxyArgs, rule, &i.Runtime); err != nil {
+		if err := i.Recover(ctx); err != nil {
+			log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (i *HeaderInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	return stopProxy(ctx, &i.Info)
+}
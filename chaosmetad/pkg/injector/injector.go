@@ -23,13 +23,17 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/crclient"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/metrics"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/storage"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/errutil"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/user"
+	"os"
 	"runtime/debug"
 	"strings"
+	"time"
 )
 
 type IInjector interface {
@@ -40,6 +44,7 @@ type IInjector interface {
 
 	GetArgs() interface{}
 	GetRuntime() interface{}
+	GetInfo() *BaseInfo
 
 	SetOption(cmd *cobra.Command)
 	SetDefault()
@@ -69,6 +74,12 @@ type BaseInfo struct {
 	ContainerId      string `json:"container_id"`
 	ContainerRuntime string `json:"container_runtime"`
 	//ContainerNs      []string `json:"container_ns"`
+	// flapping information
+	Interval   string `json:"interval"`
+	OnDuration string `json:"on_duration"`
+	Repeat     int    `json:"repeat"`
+	Cycle      int    `json:"cycle"`
+	CyclePhase string `json:"cycle_phase"`
 }
 
 func (i *BaseInjector) GetArgs() interface{} {
@@ -81,6 +92,10 @@ func (i *BaseInjector) GetRuntime() interface{} {
 	return empty
 }
 
+func (i *BaseInjector) GetInfo() *BaseInfo {
+	return &i.Info
+}
+
 func (i *BaseInjector) SetCommonArgs(info *BaseInfo) {
 	if info == nil {
 		return
@@ -113,6 +128,18 @@ func (i *BaseInjector) SetCommonArgs(info *BaseInfo) {
 	if info.ContainerId != "" {
 		i.Info.ContainerId = info.ContainerId
 	}
+
+	if info.Interval != "" {
+		i.Info.Interval = info.Interval
+	}
+
+	if info.OnDuration != "" {
+		i.Info.OnDuration = info.OnDuration
+	}
+
+	if info.Repeat != 0 {
+		i.Info.Repeat = info.Repeat
+	}
 }
 
 func (i *BaseInjector) SetOption(cmd *cobra.Command) {
@@ -145,13 +172,21 @@ func (i *BaseInjector) SetDefault() {
 	if i.Info.Status == "" {
 		i.Info.Status = utils.StatusCreated
 	}
+}
 
+func (i *BaseInjector) Validator(ctx context.Context) error {
+	// detecting the runtime connects to the runtime sockets, so it is done here with the ctx of caller instead of in SetDefault
 	if i.Info.ContainerId != "" && i.Info.ContainerRuntime == "" {
-		i.Info.ContainerRuntime = crclient.CrDocker
+		detectCtx, cancel := context.WithTimeout(ctx, crclient.DetectTimeout)
+		cr, err := crclient.DetectRuntime(detectCtx, i.Info.ContainerId)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("detect container runtime error: %s", err.Error())
+		}
+
+		i.Info.ContainerRuntime = cr
 	}
-}
 
-func (i *BaseInjector) Validator(ctx context.Context) error {
 	if i.Info.ContainerRuntime != "" {
 		if i.Info.ContainerId == "" {
 			return fmt.Errorf("\"container-id\" is empty")
@@ -162,21 +197,59 @@ func (i *BaseInjector) Validator(ctx context.Context) error {
 			return fmt.Errorf("create container runtime client[%s] error: %s", i.Info.ContainerRuntime, err.Error())
 		}
 
-		if _, err := client.GetPidById(ctx, i.Info.ContainerId); err != nil {
+		pid, err := client.GetPidById(ctx, i.Info.ContainerId)
+		if err != nil {
 			return fmt.Errorf("check container error: %s", err.Error())
 		}
+
+		dryrun.Resolve(ctx, dryrun.ResolvedContainerPid, pid)
 	}
 
 	if err := utils.IsValidUid(i.Info.Uid); err != nil {
 		return fmt.Errorf("\"uid\" format error: %s", err.Error())
 	}
 
-	if i.Info.Timeout == "" {
+	if i.Info.Timeout != "" {
+		if _, err := utils.GetTimeSecond(i.Info.Timeout); err != nil {
+			return fmt.Errorf("\"timeout\" is not valid: %s", err.Error())
+		}
+	}
+
+	return i.checkFlapping()
+}
+
+// checkFlapping "on-duration" and "repeat" only work with "interval", the fault keeps injected in the whole cycle is meaningless
+func (i *BaseInjector) checkFlapping() error {
+	if i.Info.Interval == "" {
+		if i.Info.OnDuration != "" || i.Info.Repeat != 0 {
+			return fmt.Errorf("\"on-duration\" and \"repeat\" must be provided with \"interval\"")
+		}
+
 		return nil
 	}
 
-	if _, err := utils.GetTimeSecond(i.Info.Timeout); err != nil {
-		return fmt.Errorf("\"timeout\" is not valid: %s", err.Error())
+	interval, err := utils.GetTimeSecond(i.Info.Interval)
+	if err != nil {
+		return fmt.Errorf("\"interval\" is not valid: %s", err.Error())
+	}
+
+	if interval <= 0 {
+		return fmt.Errorf("\"interval\" must larger than 0")
+	}
+
+	if i.Info.OnDuration != "" {
+		onDuration, err := utils.GetTimeSecond(i.Info.OnDuration)
+		if err != nil {
+			return fmt.Errorf("\"on-duration\" is not valid: %s", err.Error())
+		}
+
+		if onDuration < 0 || onDuration >= interval {
+			return fmt.Errorf("\"on-duration\" must be in [0, interval)")
+		}
+	}
+
+	if i.Info.Repeat < 0 {
+		return fmt.Errorf("\"repeat\" must not less than 0")
 	}
 
 	return nil
@@ -204,6 +277,11 @@ func (i *BaseInjector) LoadInjector(exp *storage.Experiment, argsVar, rVar inter
 	i.Info.Timeout = exp.Timeout
 	i.Info.ContainerRuntime = exp.ContainerRuntime
 	i.Info.ContainerId = exp.ContainerId
+	i.Info.Interval = exp.Interval
+	i.Info.OnDuration = exp.OnDuration
+	i.Info.Repeat = exp.Repeat
+	i.Info.Cycle = exp.Cycle
+	i.Info.CyclePhase = exp.CyclePhase
 
 	return nil
 }
@@ -230,6 +308,11 @@ func (i *BaseInjector) OptionToExp(args, r interface{}) (*storage.Experiment, er
 		Runtime:          string(runtimeByte),
 		ContainerRuntime: i.Info.ContainerRuntime,
 		ContainerId:      i.Info.ContainerId,
+		Interval:         i.Info.Interval,
+		OnDuration:       i.Info.OnDuration,
+		Repeat:           i.Info.Repeat,
+		Cycle:            i.Info.Cycle,
+		CyclePhase:       i.Info.CyclePhase,
 	}
 
 	return exp, nil
@@ -238,7 +321,10 @@ func (i *BaseInjector) OptionToExp(args, r interface{}) (*storage.Experiment, er
 /*=======================================Main Process===================================================*/
 
 func ProcessInject(ctx context.Context, i IInjector) (code int, msg string) {
-	logger := log.GetLogger(ctx)
+	logger, start := log.GetLogger(ctx), time.Now()
+	defer func() {
+		metrics.ObserveInject(i.GetInfo().Target, i.GetInfo().Fault, code, time.Since(start))
+	}()
 	defer func() {
 		if err := recover(); err != any(nil) {
 			logger.Debug(string(debug.Stack()))
@@ -269,8 +355,10 @@ func ProcessInject(ctx context.Context, i IInjector) (code int, msg string) {
 	logger.Infof("uid: %s", exp.Uid)
 	logger.Infof("args: %s", exp.Args)
 
+	appendJournal(ctx, exp.Uid, storage.JournalInject, storage.JournalStart, getInjectPidMsg(os.Getpid()))
 	if err := i.Inject(ctx); err != nil {
 		errMsg := fmt.Sprintf("inject error: %s", err.Error())
+		appendJournal(ctx, exp.Uid, storage.JournalInject, storage.JournalError, errMsg)
 		if err := db.UpdateStatusAndErr(exp.Uid, utils.StatusError, errMsg); err != nil {
 			logger.Warnf("update status[%s] for experiment[%s] error: %s", utils.StatusError, exp.Uid, errMsg)
 		}
@@ -278,6 +366,10 @@ func ProcessInject(ctx context.Context, i IInjector) (code int, msg string) {
 		return errutil.InjectErr, errMsg
 	}
 
+	if info := i.GetInfo(); info.Interval != "" {
+		info.Cycle, info.CyclePhase = 1, utils.CyclePhaseOn
+	}
+
 	exp, _ = i.OptionToExp(i.GetArgs(), i.GetRuntime())
 	exp.Status = utils.StatusSuccess
 	if err := db.Update(exp); err != nil {
@@ -288,9 +380,15 @@ func ProcessInject(ctx context.Context, i IInjector) (code int, msg string) {
 		return errutil.DBErr, fmt.Sprintf("update status[%s] for experiment[%s] error: %s", exp.Status, exp.Uid, err.Error())
 	}
 
+	appendJournal(ctx, exp.Uid, storage.JournalInject, storage.JournalSuccess, "")
 	logger.Info("inject success")
 
-	if exp.Timeout != "" {
+	// the timeout of flapping mode is handled by the flapping process
+	if exp.Interval != "" {
+		if err := cmdexec.StartFlapping(ctx, exp.Uid); err != nil {
+			logger.Warnf("inject success but flapping cmd exec error: %s, please execute [chaosmetad recover -u %s] manually to recover", err.Error(), exp.Uid)
+		}
+	} else if exp.Timeout != "" {
 		timeSecond, _ := utils.GetTimeSecond(exp.Timeout)
 		if err := i.DelayRecover(ctx, timeSecond); err != nil {
 			logger.Warnf("inject success but auto delay recover cmd exec error: %s, please execute [chaosmetad recover -u %s] manually to recover", err.Error(), exp.Uid)
@@ -300,9 +398,68 @@ func ProcessInject(ctx context.Context, i IInjector) (code int, msg string) {
 	return errutil.NoErr, "success"
 }
 
-func ProcessRecover(ctx context.Context, uid string) (code int, msg string) {
+// ProcessDryRun validate args and simulate inject and recover. the operations which modify the system are recorded into
+// the plan instead of being executed, the read-only operations are still executed to resolve targets. nothing is saved to db
+func ProcessDryRun(ctx context.Context, i IInjector) (plan *dryrun.Plan, code int, msg string) {
 	logger := log.GetLogger(ctx)
+	defer func() {
+		if err := recover(); err != any(nil) {
+			logger.Debug(string(debug.Stack()))
+			code, msg = errutil.UnknownErr, fmt.Sprintf("ProcessDryRun Exception: %v", err)
+		}
+	}()
+
+	i.SetDefault()
+
+	plan = dryrun.NewPlan()
+	ctx = dryrun.GetCtxWithPlan(ctx, plan)
+	if err := i.Validator(ctx); err != nil {
+		return nil, errutil.BadArgsErr, fmt.Sprintf("args error: %s", err.Error())
+	}
+
+	exp, err := i.OptionToExp(i.GetArgs(), i.GetRuntime())
+	if err != nil {
+		return nil, errutil.BadArgsErr, fmt.Sprintf("create experiment error: %s", err.Error())
+	}
+
+	plan.Uid, plan.Target, plan.Fault, plan.Args = exp.Uid, exp.Target, exp.Fault, json.RawMessage(exp.Args)
+	plan.ContainerRuntime, plan.ContainerId = exp.ContainerRuntime, exp.ContainerId
+
+	if err := i.Inject(ctx); err != nil {
+		plan.Error = fmt.Sprintf("inject error: %s", err.Error())
+		return plan, errutil.InjectErr, plan.Error
+	}
+
+	if exp.Interval != "" {
+		if err := cmdexec.StartFlapping(ctx, exp.Uid); err != nil {
+			logger.Warnf("flapping error: %s", err.Error())
+		}
+	} else if exp.Timeout != "" {
+		timeSecond, _ := utils.GetTimeSecond(exp.Timeout)
+		if err := i.DelayRecover(ctx, timeSecond); err != nil {
+			logger.Warnf("auto delay recover error: %s", err.Error())
+		}
+	}
+
+	dryrun.SetPhase(ctx, dryrun.PhaseRecover)
+	if err := i.Recover(ctx); err != nil {
+		plan.Error = fmt.Sprintf("recover error: %s", err.Error())
+		return plan, errutil.RecoverErr, plan.Error
+	}
+
+	return plan, errutil.NoErr, "success"
+}
+
+func ProcessRecover(ctx context.Context, uid string) (code int, msg string) {
+	var (
+		logger        = log.GetLogger(ctx)
+		start         = time.Now()
+		target, fault string
+	)
 
+	defer func() {
+		metrics.ObserveRecover(target, fault, code, time.Since(start))
+	}()
 	defer func() {
 		if err := recover(); err != any(nil) {
 			logger.Debug(string(debug.Stack()))
@@ -321,6 +478,7 @@ func ProcessRecover(ctx context.Context, uid string) (code int, msg string) {
 	if err != nil {
 		return errutil.DBErr, fmt.Sprintf("query experiment by uid[%s] error: %s", uid, err.Error())
 	}
+	target, fault = exp.Target, exp.Fault
 
 	i, err := NewInjector(exp.Target, exp.Fault)
 	if err != nil {
@@ -331,8 +489,16 @@ func ProcessRecover(ctx context.Context, uid string) (code int, msg string) {
 		return errutil.InternalErr, fmt.Sprintf("load experiment to injector error: %s", err.Error())
 	}
 
-	if err := i.Recover(ctx); err != nil {
-		return errutil.RecoverErr, fmt.Sprintf("recover error: %s", err.Error())
+	appendJournal(ctx, uid, storage.JournalRecover, storage.JournalStart, "")
+	db.AppendEvent(uid, storage.EventRecovering, "")
+	if !isFaultActive(exp) {
+		logger.Infof("experiment is in the \"%s\" phase of cycle %d, no need to recover the fault", exp.CyclePhase, exp.Cycle)
+	} else if err := i.Recover(ctx); err != nil {
+		errMsg := fmt.Sprintf("recover error: %s", err.Error())
+		appendJournal(ctx, uid, storage.JournalRecover, storage.JournalError, errMsg)
+		// the status is not changed, so that the experiment can be recovered again
+		db.AppendEvent(uid, storage.EventError, errMsg)
+		return errutil.RecoverErr, errMsg
 	}
 
 	logger.Info("recover success")
@@ -340,10 +506,30 @@ func ProcessRecover(ctx context.Context, uid string) (code int, msg string) {
 	if err := db.UpdateStatus(uid, utils.StatusDestroyed); err != nil {
 		logger.Warnf("update status[%s] for experiment[%s] error: %s", utils.StatusDestroyed, uid, err.Error())
 	}
+	appendJournal(ctx, uid, storage.JournalRecover, storage.JournalSuccess, "")
 
 	return errutil.NoErr, "success"
 }
 
+// isFaultActive the fault of a flapping experiment has been recovered in the "off" phase of the cycle, the other
+// experiments keep the fault until recover
+func isFaultActive(exp *storage.Experiment) bool {
+	return exp.Interval == "" || exp.CyclePhase != utils.CyclePhaseOff
+}
+
+// appendJournal journal is an auxiliary record, so failure of writing journal will not interrupt the main process
+func appendJournal(ctx context.Context, uid, action, phase, msg string) {
+	journal, err := storage.GetJournalStore()
+	if err != nil {
+		log.GetLogger(ctx).Warnf("connect journal error: %s", err.Error())
+		return
+	}
+
+	if err := journal.Append(uid, action, phase, msg); err != nil {
+		log.GetLogger(ctx).Warnf("append journal[%s %s] for experiment[%s] error: %s", action, phase, uid, err.Error())
+	}
+}
+
 /*=======================================Command Constructor===================================================*/
 
 func NewCmdByTarget(target string, args *BaseInfo) *cobra.Command {
@@ -362,6 +548,7 @@ func NewCmdByTarget(target string, args *BaseInfo) *cobra.Command {
 
 func NewCmdByTargetAndFault(target, fault string, infoArgs *BaseInfo) *cobra.Command {
 	i, _ := NewInjector(target, fault)
+	var dryRun bool
 	var cmd = &cobra.Command{
 		Use:   fault,
 		Short: fmt.Sprintf("create %s experiment for %s", fault, target),
@@ -372,16 +559,38 @@ func NewCmdByTargetAndFault(target, fault string, infoArgs *BaseInfo) *cobra.Com
 			}
 
 			i.SetCommonArgs(infoArgs)
+			if dryRun {
+				plan, code, msg := ProcessDryRun(ctx, i)
+				if plan != nil {
+					printPlan(ctx, plan)
+				}
+				errutil.SolveErr(ctx, code, msg)
+			}
+
 			code, msg := ProcessInject(ctx, i)
 			errutil.SolveErr(ctx, code, msg)
 		},
 	}
 
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print the targets and the ordered operations of inject and recover, without modifying the system")
 	i.SetOption(cmd)
 
 	return cmd
 }
 
+func printPlan(ctx context.Context, plan *dryrun.Plan) {
+	reBytes, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		errutil.SolveErr(ctx, errutil.InternalErr, fmt.Sprintf("plan change to string error: %s", err.Error()))
+	}
+
+	if log.Path != "" {
+		log.GetLogger(ctx).Info(string(reBytes))
+	} else {
+		fmt.Println(string(reBytes))
+	}
+}
+
 /*=======================================ConstructorScheme Function===================================================*/
 
 var constructorScheme = map[string]func() IInjector{}
@@ -413,7 +622,7 @@ func GetTargets() []string {
 	set := make(map[string]bool)
 	targets := make([]string, 0)
 	for k := range constructorScheme {
-		kArr := strings.Split(k, utils.BuilderSplit)
+		kArr := strings.SplitN(k, utils.BuilderSplit, 2)
 		if !set[kArr[0]] {
 			set[kArr[0]] = true
 			targets = append(targets, kArr[0])
@@ -426,7 +635,7 @@ func GetTargets() []string {
 func GetFaultsByTarget(target string) []string {
 	faults := make([]string, 0)
 	for k := range constructorScheme {
-		kArr := strings.Split(k, utils.BuilderSplit)
+		kArr := strings.SplitN(k, utils.BuilderSplit, 2)
 		if kArr[0] == target {
 			faults = append(faults, kArr[1])
 		}
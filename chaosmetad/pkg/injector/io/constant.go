@@ -0,0 +1,49 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package io
+
+const (
+	TargetIO = "io"
+
+	FaultFault = "fault"
+
+	TypeErrno = "errno"
+	TypeDelay = "delay"
+	TypeShort = "short"
+
+	MethodOpen     = "open"
+	MethodRead     = "read"
+	MethodWrite    = "write"
+	MethodReadv    = "readv"
+	MethodWritev   = "writev"
+	MethodFsync    = "fsync"
+	MethodTruncate = "truncate"
+	MethodUnlink   = "unlink"
+	MethodMkdir    = "mkdir"
+	MethodRename   = "rename"
+
+	DefaultMethods = "read,readv,write,writev"
+	// DefaultShortMethods the vectored syscalls have no count argument to shrink, so they are not supported by "short"
+	DefaultShortMethods = "read,write"
+	DefaultErrno        = "EIO"
+	DefaultPercent      = 100
+
+	IOFaultKey = "chaosmeta_iofault"
+
+	// StopTimeout the seconds to wait for the tracer to detach from target processes
+	StopTimeout = 5
+)
@@ -0,0 +1,307 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package io
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
+	"golang.org/x/sys/unix"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func init() {
+	injector.Register(TargetIO, FaultFault, func() injector.IInjector { return &FaultInjector{} })
+}
+
+type FaultInjector struct {
+	injector.BaseInjector
+	Args    FaultArgs
+	Runtime FaultRuntime
+}
+
+type FaultArgs struct {
+	Pid     int    `json:"pid,omitempty"`
+	Key     string `json:"key,omitempty"`
+	Path    string `json:"path"`
+	Methods string `json:"methods"`
+	Percent int    `json:"percent"`
+	Type    string `json:"type"`
+	Errno   string `json:"errno,omitempty"`
+	Latency string `json:"latency,omitempty"`
+}
+
+type FaultRuntime struct {
+	Pid     int   `json:"pid,omitempty"`
+	PidList []int `json:"pid_list,omitempty"`
+}
+
+// FaultRule the rule executed by the tracer tool, should be consistent with the rule in tools/chaosmeta_iofault.go
+type FaultRule struct {
+	PidList   []int    `json:"pid_list"`
+	Path      string   `json:"path"`
+	Methods   []string `json:"methods"`
+	Percent   int      `json:"percent"`
+	Fault     string   `json:"fault"`
+	Errno     int      `json:"errno,omitempty"`
+	LatencyUs int64    `json:"latency_us,omitempty"`
+}
+
+func (i *FaultInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *FaultInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *FaultInjector) SetDefault() {
+	i.BaseInjector.SetDefault()
+
+	if i.Args.Percent == 0 {
+		i.Args.Percent = DefaultPercent
+	}
+
+	if i.Args.Type == "" {
+		i.Args.Type = TypeErrno
+	}
+
+	if i.Args.Methods == "" {
+		i.Args.Methods = DefaultMethods
+		if i.Args.Type == TypeShort {
+			i.Args.Methods = DefaultShortMethods
+		}
+	}
+
+	if i.Args.Type == TypeErrno && i.Args.Errno == "" {
+		i.Args.Errno = DefaultErrno
+	}
+}
+
+func (i *FaultInjector) SetOption(cmd *cobra.Command) {
+	// i.BaseInjector.SetOption(cmd)
+
+	cmd.Flags().IntVarP(&i.Args.Pid, "pid", "p", 0, "target process's pid")
+	cmd.Flags().StringVarP(&i.Args.Key, "key", "k", "", "the key used to grep to get target process, the effect is equivalent to \"ps -ef | grep [key]\". if \"pid\" provided, \"key\" will be ignored")
+	cmd.Flags().StringVarP(&i.Args.Path, "path", "f", "", "absolute path glob of the files to affect, the path is as seen by target process(the path in container for a process in container), \"*\" and \"?\" do not match \"/\", \"**\" matches any path. eg: /home/admin/logs/**")
+	cmd.Flags().StringVarP(&i.Args.Methods, "methods", "m", "", fmt.Sprintf("syscall types to affect, separated by \",\", support: %s. \"%s\" and \"%s\" are the vectored syscalls like readv, preadv（default %s, %s for type \"%s\"）",
+		strings.Join(getMethodList(), "、"), MethodReadv, MethodWritev, DefaultMethods, DefaultShortMethods, TypeShort))
+	cmd.Flags().IntVarP(&i.Args.Percent, "percent", "P", 0, fmt.Sprintf("percent of matched syscalls to affect, range: (0, 100]（default %d）", DefaultPercent))
+	cmd.Flags().StringVarP(&i.Args.Type, "type", "T", "", fmt.Sprintf("fault type, support: %s(return an errno)、%s(delay the syscall)、%s(read/write only half of the requested bytes, only support method %s、%s)（default %s）",
+		TypeErrno, TypeDelay, TypeShort, MethodRead, MethodWrite, TypeErrno))
+	cmd.Flags().StringVarP(&i.Args.Errno, "errno", "e", "", fmt.Sprintf("errno to return for type \"%s\", support name or number. eg: EIO, ENOSPC, 28（default %s）", TypeErrno, DefaultErrno))
+	cmd.Flags().StringVarP(&i.Args.Latency, "latency", "l", "", fmt.Sprintf("latency of type \"%s\", support unit: us、ms、s（default us）", TypeDelay))
+}
+
+func getMethodList() []string {
+	return []string{MethodOpen, MethodRead, MethodReadv, MethodWrite, MethodWritev, MethodFsync, MethodTruncate, MethodUnlink, MethodMkdir, MethodRename}
+}
+
+func getMethods(methodsStr string) ([]string, error) {
+	var methods []string
+	for _, unit := range strings.Split(methodsStr, ",") {
+		unit = strings.ToLower(strings.TrimSpace(unit))
+		if unit == "" {
+			continue
+		}
+
+		var isValid bool
+		for _, method := range getMethodList() {
+			if unit == method {
+				isValid = true
+				break
+			}
+		}
+
+		if !isValid {
+			return nil, fmt.Errorf("method %s is not support", unit)
+		}
+
+		methods = append(methods, unit)
+	}
+
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("method is empty")
+	}
+
+	return methods, nil
+}
+
+// getErrno convert errno name like "ENOSPC" or number like "28" to errno value
+func getErrno(errnoStr string) (int, error) {
+	errnoStr = strings.ToUpper(strings.TrimSpace(errnoStr))
+	if errno, err := strconv.Atoi(errnoStr); err == nil {
+		if errno <= 0 || unix.ErrnoName(syscall.Errno(errno)) == "" {
+			return 0, fmt.Errorf("errno %d is not support", errno)
+		}
+
+		return errno, nil
+	}
+
+	for errno := 1; errno < 256; errno++ {
+		if unix.ErrnoName(syscall.Errno(errno)) == errnoStr {
+			return errno, nil
+		}
+	}
+
+	return 0, fmt.Errorf("errno %s is not support", errnoStr)
+}
+
+func (i *FaultInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	if runtime.GOARCH != "amd64" {
+		return fmt.Errorf("only support arch amd64, current: %s", runtime.GOARCH)
+	}
+
+	if !strings.HasPrefix(i.Args.Path, "/") {
+		return fmt.Errorf("\"path\"[%s] must be an absolute path", i.Args.Path)
+	}
+
+	methods, err := getMethods(i.Args.Methods)
+	if err != nil {
+		return fmt.Errorf("\"methods\"[%s] is invalid: %s", i.Args.Methods, err.Error())
+	}
+
+	if i.Args.Percent <= 0 || i.Args.Percent > 100 {
+		return fmt.Errorf("\"percent\"[%d] should in (0, 100]", i.Args.Percent)
+	}
+
+	switch i.Args.Type {
+	case TypeErrno:
+		if _, err := getErrno(i.Args.Errno); err != nil {
+			return fmt.Errorf("\"errno\"[%s] is invalid: %s", i.Args.Errno, err.Error())
+		}
+	case TypeDelay:
+		if i.Args.Latency == "" {
+			return fmt.Errorf("\"latency\" is empty")
+		}
+
+		if _, err := utils.GetTimeUs(i.Args.Latency); err != nil {
+			return fmt.Errorf("\"latency\"[%s] is invalid: %s", i.Args.Latency, err.Error())
+		}
+	case TypeShort:
+		// only read, pread64, write and pwrite64 have a count argument to shrink
+		for _, method := range methods {
+			if method != MethodRead && method != MethodWrite {
+				return fmt.Errorf("type \"%s\" only support method %s、%s, method %s is not supported", TypeShort, MethodRead, MethodWrite, method)
+			}
+		}
+	default:
+		return fmt.Errorf("\"type\" is not support: %s, only support: %s、%s、%s", i.Args.Type, TypeErrno, TypeDelay, TypeShort)
+	}
+
+	if _, err := process.GetPidListByPidOrKeyInContainer(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Pid, i.Args.Key); err != nil {
+		return fmt.Errorf("get target process error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (i *FaultInjector) getRule(pidList []int) *FaultRule {
+	methods, _ := getMethods(i.Args.Methods)
+	rule := &FaultRule{PidList: pidList, Path: i.Args.Path, Methods: methods, Percent: i.Args.Percent, Fault: i.Args.Type}
+	switch i.Args.Type {
+	case TypeErrno:
+		rule.Errno, _ = getErrno(i.Args.Errno)
+	case TypeDelay:
+		rule.LatencyUs, _ = utils.GetTimeUs(i.Args.Latency)
+	}
+
+	return rule
+}
+
+func getToolKey(uid string) string {
+	return fmt.Sprintf("%s %s", IOFaultKey, uid)
+}
+
+// Inject trace the target processes in host's pid ns by the tool, the tool is executed as "[tool] [uid] [rule(base64 of json)]"
+func (i *FaultInjector) Inject(ctx context.Context) error {
+	logger := log.GetLogger(ctx)
+	pidList, err := process.GetPidListByPidOrKeyInContainer(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Pid, i.Args.Key)
+	if err != nil {
+		return fmt.Errorf("get target process error: %s", err.Error())
+	}
+
+	hostPidList, err := process.GetHostPidList(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, pidList)
+	if err != nil {
+		return fmt.Errorf("get pid in host of target process error: %s", err.Error())
+	}
+
+	ruleBytes, err := json.Marshal(i.getRule(hostPidList))
+	if err != nil {
+		return fmt.Errorf("marshal rule error: %s", err.Error())
+	}
+
+	i.Runtime.PidList = hostPidList
+	cmd := fmt.Sprintf("%s %s %s", utils.GetToolPath(IOFaultKey), i.Info.Uid, base64.StdEncoding.EncodeToString(ruleBytes))
+	if _, err := cmdexec.StartBashCmdAndWaitPid(ctx, cmd, 0); err != nil {
+		if err := i.Recover(ctx); err != nil {
+			logger.Warnf("undo error: %s", err.Error())
+		}
+
+		return err
+	}
+
+	if i.Runtime.Pid, err = process.GetPidByKeyWithoutExec(ctx, getToolKey(i.Info.Uid)); err != nil {
+		logger.Warnf("get pid of tracer error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// Recover let the tracer detach from target processes by SIGTERM, the tracer is killed if it does not exit in time,
+// in this case the tracees are detached by kernel, but the syscalls being delayed will return immediately
+func (i *FaultInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	key := getToolKey(i.Info.Uid)
+	if err := process.CheckExistAndSignalByKey(ctx, key, process.SIGTERM); err != nil {
+		return err
+	}
+
+	for t := 0; t < StopTimeout*10; t++ {
+		isExist, err := process.ExistProcessByKey(ctx, key)
+		if err != nil {
+			return fmt.Errorf("check tracer exist error: %s", err.Error())
+		}
+
+		if !isExist {
+			return nil
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	log.GetLogger(ctx).Warnf("tracer does not exit in %ds, kill it", StopTimeout)
+	return process.CheckExistAndKillByKey(ctx, key)
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
+	"strconv"
+)
+
+// TODO: It needs to be explained in the document: 1. "conf" mode only support host, because "nf_conntrack_max" is only
+// writable in the network namespace of host; 2. "fill" mode only works when conntrack is enabled in the target network
+// namespace, eg: there are iptables nat/state rules
+func init() {
+	injector.Register(TargetKernel, FaultKernelConntrack, func() injector.IInjector { return &ConntrackInjector{} })
+}
+
+type ConntrackInjector struct {
+	injector.BaseInjector
+	Args    ConntrackArgs
+	Runtime ConntrackRuntime
+}
+
+type ConntrackArgs struct {
+	Mode  string `json:"mode"`
+	Max   int    `json:"max,omitempty"`
+	Count int    `json:"count,omitempty"`
+}
+
+type ConntrackRuntime struct {
+	ConntrackMax int `json:"conntrack_max,omitempty"`
+}
+
+func (i *ConntrackInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *ConntrackInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *ConntrackInjector) SetDefault() {
+	i.BaseInjector.SetDefault()
+
+	if i.Args.Mode == "" {
+		i.Args.Mode = ModeConntrackConf
+	}
+}
+
+func (i *ConntrackInjector) SetOption(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&i.Args.Mode, "mode", "m", "", fmt.Sprintf("mode to make full of conntrack table. \"%s\"(default): change config of \"%s\", \"%s\": add conntrack entries to max", ModeConntrackConf, ConntrackMaxSysctl, ModeConntrackFill))
+	cmd.Flags().IntVar(&i.Args.Max, "max", 0, fmt.Sprintf("target value of \"%s\", args of \"%s\" mode（default 0, means the count of conntrack entries now）", ConntrackMaxSysctl, ModeConntrackConf))
+	cmd.Flags().IntVarP(&i.Args.Count, "count", "c", 0, fmt.Sprintf("count of conntrack entries to add, args of \"%s\" mode（default 0, means add to max）", ModeConntrackFill))
+}
+
+func (i *ConntrackInjector) getConntrackValue(ctx context.Context, key string) (int, error) {
+	re, err := readSysctl(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, getSysctlPath(key, key))
+	if err != nil {
+		return 0, fmt.Errorf("read sysctl[%s] error: %s", key, err.Error())
+	}
+
+	value, err := strconv.Atoi(re)
+	if err != nil {
+		return 0, fmt.Errorf("sysctl[%s] value[%s] is not an integer: %s", key, re, err.Error())
+	}
+
+	return value, nil
+}
+
+func (i *ConntrackInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	if i.Args.Mode != ModeConntrackConf && i.Args.Mode != ModeConntrackFill {
+		return fmt.Errorf("\"mode\" not support: %s, only support: %s, %s", i.Args.Mode, ModeConntrackConf, ModeConntrackFill)
+	}
+
+	if i.Args.Mode == ModeConntrackConf && (i.Info.ContainerId != "" || i.Info.ContainerRuntime != "") {
+		return fmt.Errorf("mode \"%s\" not support in container", ModeConntrackConf)
+	}
+
+	if i.Args.Max < 0 {
+		return fmt.Errorf("\"max\" must larger than 0")
+	}
+
+	if i.Args.Count < 0 {
+		return fmt.Errorf("\"count\" must larger than 0")
+	}
+
+	nowCount, err := i.getConntrackValue(ctx, ConntrackCountSysctl)
+	if err != nil {
+		return fmt.Errorf("conntrack is not enabled: %s", err.Error())
+	}
+
+	maxCount, err := i.getConntrackValue(ctx, ConntrackMaxSysctl)
+	if err != nil {
+		return err
+	}
+
+	if nowCount >= maxCount {
+		return fmt.Errorf("now conntrack count[%d] is larger than max[%d], no need to inject", nowCount, maxCount)
+	}
+
+	return nil
+}
+
+func changeConntrackMax(ctx context.Context, conntrackMax int) error {
+	return writeSysctl(ctx, "", "", getSysctlPath(ConntrackMaxSysctl, ConntrackMaxSysctl), strconv.Itoa(conntrackMax))
+}
+
+func (i *ConntrackInjector) Inject(ctx context.Context) error {
+	nowCount, _ := i.getConntrackValue(ctx, ConntrackCountSysctl)
+	maxCount, _ := i.getConntrackValue(ctx, ConntrackMaxSysctl)
+
+	if i.Args.Mode == ModeConntrackFill {
+		if i.Args.Count == 0 || i.Args.Count > maxCount {
+			i.Args.Count = maxCount - nowCount
+		}
+
+		var timeout int64
+		if i.Info.Timeout != "" {
+			timeout, _ = utils.GetTimeSecond(i.Info.Timeout)
+		}
+
+		cmd := fmt.Sprintf("%s %s %d %d", utils.GetToolPath(ConntrackKey), i.Info.Uid, i.Args.Count, timeout)
+		if err := cmdexec.WaitCommonWithNS(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, cmd, []string{namespace.NET, namespace.PID}); err != nil {
+			return i.getErrWithUndo(ctx, fmt.Sprintf("start cmd error: %s", err.Error()))
+		}
+	} else {
+		// 0 means no limit for "nf_conntrack_max"
+		targetMax := i.Args.Max
+		if targetMax == 0 {
+			targetMax = nowCount
+		}
+
+		if targetMax < 1 {
+			targetMax = 1
+		}
+
+		i.Runtime.ConntrackMax = maxCount
+		if err := changeConntrackMax(ctx, targetMax); err != nil {
+			return i.getErrWithUndo(ctx, fmt.Sprintf("change %s to %d error: %s", ConntrackMaxSysctl, targetMax, err.Error()))
+		}
+	}
+
+	return nil
+}
+
+func (i *ConntrackInjector) getErrWithUndo(ctx context.Context, msg string) error {
+	if err := i.Recover(ctx); err != nil {
+		log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
+	}
+
+	return fmt.Errorf(msg)
+}
+
+func (i *ConntrackInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	if i.Args.Mode == ModeConntrackFill {
+		return process.CheckExistAndKillByKey(ctx, fmt.Sprintf("%s %s", ConntrackKey, i.Info.Uid))
+	}
+
+	if i.Runtime.ConntrackMax == 0 {
+		return nil
+	}
+
+	maxCount, err := i.getConntrackValue(ctx, ConntrackMaxSysctl)
+	if err != nil {
+		return err
+	}
+
+	if i.Runtime.ConntrackMax != maxCount || dryrun.IsRecoverPhase(ctx) {
+		return changeConntrackMax(ctx, i.Runtime.ConntrackMax)
+	}
+
+	return nil
+}
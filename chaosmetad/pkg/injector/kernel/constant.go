@@ -30,4 +30,27 @@ const (
 
 	FaultKernelNproc = "nproc"
 	NprocKey         = "chaosmeta_nproc"
+
+	FaultKernelSysctl = "sysctl"
+	SysctlDir         = "/proc/sys"
+
+	FaultKernelConntrack = "conntrack"
+	ConntrackKey         = "chaosmeta_conntrack"
+	ConntrackMaxSysctl   = "net.netfilter.nf_conntrack_max"
+	ConntrackCountSysctl = "net.netfilter.nf_conntrack_count"
+	ModeConntrackConf    = "conf"
+	ModeConntrackFill    = "fill"
+
+	FaultKernelPortExhaust = "portexhaust"
+	// OccupyKey same tool as fault "network occupy"
+	OccupyKey       = "chaosmeta_occupy"
+	PortRangeSysctl = "net.ipv4.ip_local_port_range"
 )
+
+// SysctlWhitelist the sysctls allowed to be changed by fault "sysctl", the one ending with "." means all the sysctls under
+// it. the ones which may execute commands or crash the os, eg: kernel.core_pattern, kernel.panic, are not included
+var SysctlWhitelist = []string{
+	"net.", "fs.file-max", "fs.nr_open", "fs.inotify.", "kernel.pid_max", "kernel.threads-max", "kernel.msgmax",
+	"kernel.msgmnb", "kernel.shmmax", "kernel.shmall", "vm.max_map_count", "vm.swappiness", "vm.overcommit_memory",
+	"vm.overcommit_ratio", "vm.min_free_kbytes", "vm.dirty_ratio", "vm.dirty_background_ratio",
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
 	"os"
@@ -190,7 +191,7 @@ func (i *FdfullInjector) Recover(ctx context.Context) error {
 			return fmt.Errorf("check process exist by key[%s] error: %s", processKey, err.Error())
 		}
 
-		if isProExist {
+		if isProExist || dryrun.IsRecoverPhase(ctx) {
 			if err := process.KillProcessByKey(ctx, processKey, process.SIGKILL); err != nil {
 				logger.Warnf("kill process by key[%s] error: %s", processKey, err.Error())
 			}
@@ -201,7 +202,11 @@ func (i *FdfullInjector) Recover(ctx context.Context) error {
 			return fmt.Errorf("check dir[%s] exist error: %s", fdFullDir, err.Error())
 		}
 
-		if isDirExist {
+		if isDirExist || dryrun.IsRecoverPhase(ctx) {
+			if dryrun.Record(ctx, dryrun.KindFile, dryrun.TargetHost, fmt.Sprintf("rm -rf %s", fdFullDir)) {
+				return nil
+			}
+
 			return os.RemoveAll(fdFullDir)
 		}
 
@@ -212,7 +217,7 @@ func (i *FdfullInjector) Recover(ctx context.Context) error {
 			return fmt.Errorf("get kernel max fd count error: %s", err.Error())
 		}
 
-		if i.Runtime.FileMax != maxFd {
+		if i.Runtime.FileMax != maxFd || dryrun.IsRecoverPhase(ctx) {
 			return changeFileMax(ctx, i.Runtime.FileMax)
 
 		}
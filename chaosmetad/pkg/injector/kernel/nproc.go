@@ -23,6 +23,7 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/user"
 	"strconv"
@@ -113,6 +114,11 @@ func (i *NprocInjector) Recover(ctx context.Context) error {
 	}
 
 	grepKey := fmt.Sprintf("%s %s", NprocKey, i.Args.User)
+	// the pid is unknown because the process is not really created in dry-run mode
+	if dryrun.Record(ctx, dryrun.KindProcess, dryrun.TargetHost, fmt.Sprintf("send signal[%d] to the process found by key[%s]", process.SIGKILL, grepKey)) {
+		return nil
+	}
+
 	pid, err := process.GetPidByKeyWithoutRunUser(ctx, grepKey)
 	if err != nil {
 		return fmt.Errorf("get pid from key[%s] error: %s", grepKey, err.Error())
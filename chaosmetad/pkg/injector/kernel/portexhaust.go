@@ -0,0 +1,155 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/net"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
+	"strconv"
+	"strings"
+)
+
+// the ports listened by process can not be used as the local port of new connection, so occupying all the ports of
+// "ip_local_port_range" makes the new connection fail with "Cannot assign requested address"
+func init() {
+	injector.Register(TargetKernel, FaultKernelPortExhaust, func() injector.IInjector { return &PortExhaustInjector{} })
+}
+
+type PortExhaustInjector struct {
+	injector.BaseInjector
+	Args    PortExhaustArgs
+	Runtime PortExhaustRuntime
+}
+
+type PortExhaustArgs struct {
+	PortRange string `json:"port_range,omitempty"`
+	Protocol  string `json:"protocol,omitempty"`
+}
+
+type PortExhaustRuntime struct {
+}
+
+func (i *PortExhaustInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *PortExhaustInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *PortExhaustInjector) SetDefault() {
+	i.BaseInjector.SetDefault()
+
+	if i.Args.Protocol == "" {
+		i.Args.Protocol = net.ProtocolTCP
+	}
+}
+
+func (i *PortExhaustInjector) SetOption(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&i.Args.PortRange, "port-range", "r", "", fmt.Sprintf("local port range to occupy, format: \"[start]-[end]\"（default the range of \"%s\"）", PortRangeSysctl))
+	cmd.Flags().StringVarP(&i.Args.Protocol, "protocol", "P", "",
+		fmt.Sprintf("target protocol, support: %s、%s、%s、%s（default %s）",
+			net.ProtocolTCP, net.ProtocolUDP, net.ProtocolTCP6, net.ProtocolUDP6, net.ProtocolTCP))
+}
+
+func parsePortRange(portRange string) (int, int, error) {
+	portList := strings.Split(portRange, "-")
+	if len(portList) != 2 {
+		return 0, 0, fmt.Errorf("must be \"[start]-[end]\"")
+	}
+
+	start, err := strconv.Atoi(strings.TrimSpace(portList[0]))
+	if err != nil || start <= 0 || start > 65535 {
+		return 0, 0, fmt.Errorf("start port must be in [1,65535]")
+	}
+
+	end, err := strconv.Atoi(strings.TrimSpace(portList[1]))
+	if err != nil || end <= 0 || end > 65535 {
+		return 0, 0, fmt.Errorf("end port must be in [1,65535]")
+	}
+
+	if start > end {
+		return 0, 0, fmt.Errorf("start port must not larger than end port")
+	}
+
+	return start, end, nil
+}
+
+// getLocalPortRange the local port range of the network namespace of target, eg: "32768-60999"
+func (i *PortExhaustInjector) getLocalPortRange(ctx context.Context) (string, error) {
+	re, err := readSysctl(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, getSysctlPath(PortRangeSysctl, PortRangeSysctl))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(strings.Fields(re), "-"), nil
+}
+
+func (i *PortExhaustInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	if i.Args.Protocol != net.ProtocolTCP && i.Args.Protocol != net.ProtocolUDP && i.Args.Protocol != net.ProtocolTCP6 && i.Args.Protocol != net.ProtocolUDP6 {
+		return fmt.Errorf("\"protocol\" is not support %s", i.Args.Protocol)
+	}
+
+	if i.Args.PortRange == "" {
+		portRange, err := i.getLocalPortRange(ctx)
+		if err != nil {
+			return fmt.Errorf("get local port range error: %s", err.Error())
+		}
+
+		i.Args.PortRange = portRange
+	}
+
+	if _, _, err := parsePortRange(i.Args.PortRange); err != nil {
+		return fmt.Errorf("\"port-range\"[%s] is invalid: %s", i.Args.PortRange, err.Error())
+	}
+
+	return nil
+}
+
+func (i *PortExhaustInjector) Inject(ctx context.Context) error {
+	var timeout int64
+	if i.Info.Timeout != "" {
+		timeout, _ = utils.GetTimeSecond(i.Info.Timeout)
+	}
+
+	start, end, _ := parsePortRange(i.Args.PortRange)
+	cmd := fmt.Sprintf("%s %s %d-%d %s %d", utils.GetToolPath(OccupyKey), i.Info.Uid, start, end, i.Args.Protocol, timeout)
+	if err := cmdexec.WaitCommonWithNS(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, cmd, []string{namespace.NET, namespace.PID}); err != nil {
+		return fmt.Errorf("start cmd error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (i *PortExhaustInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	return process.CheckExistAndKillByKey(ctx, fmt.Sprintf("%s %s", OccupyKey, i.Info.Uid))
+}
@@ -0,0 +1,241 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	injector.Register(TargetKernel, FaultKernelSysctl, func() injector.IInjector { return &SysctlInjector{} })
+}
+
+type SysctlInjector struct {
+	injector.BaseInjector
+	Args    SysctlArgs
+	Runtime SysctlRuntime
+}
+
+type SysctlArgs struct {
+	Sysctls string `json:"sysctls"`
+}
+
+type SysctlRuntime struct {
+	// Origin the original value of each sysctl, used to restore exactly
+	Origin map[string]string `json:"origin,omitempty"`
+}
+
+func (i *SysctlInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *SysctlInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *SysctlInjector) SetOption(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&i.Args.Sysctls, "sysctls", "s", "", fmt.Sprintf("sysctls to set, format: \"[key]=[value],[key]=[value]\", the key support \".\" or \"/\" as separator, eg: \"net.core.somaxconn=128,net.ipv4.tcp_max_tw_buckets=100\". only support: %s", strings.Join(SysctlWhitelist, "、")))
+}
+
+var sysctlKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_.\-/]+$`)
+
+// parseSysctls the key of result is in "." format
+func parseSysctls(sysctls string) (map[string]string, []string, error) {
+	var (
+		kvMap   = make(map[string]string)
+		keyList []string
+	)
+
+	for _, unit := range strings.Split(sysctls, ",") {
+		kv := strings.SplitN(unit, "=", 2)
+		if len(kv) != 2 {
+			return nil, nil, fmt.Errorf("\"%s\" must be \"[key]=[value]\"", unit)
+		}
+
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if !sysctlKeyRegexp.MatchString(key) || strings.Contains(key, "..") {
+			return nil, nil, fmt.Errorf("key[%s] is invalid", key)
+		}
+
+		if value == "" || strings.ContainsAny(value, "'\n") {
+			return nil, nil, fmt.Errorf("value[%s] of key[%s] is invalid", value, key)
+		}
+
+		key = strings.ReplaceAll(strings.Trim(key, "/"), "/", ".")
+		if _, ok := kvMap[key]; ok {
+			return nil, nil, fmt.Errorf("key[%s] is duplicated", key)
+		}
+
+		kvMap[key] = value
+		keyList = append(keyList, key)
+	}
+
+	return kvMap, keyList, nil
+}
+
+func isSysctlAllowed(key string) bool {
+	for _, unit := range SysctlWhitelist {
+		if key == unit || (strings.HasSuffix(unit, ".") && strings.HasPrefix(key, unit)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getSysctlPath the key in "." format is converted into path. the key with "/", eg: the interface name with ".",
+// is not converted, so it is kept in the original format
+func getSysctlPath(key, originKey string) string {
+	if strings.Contains(originKey, "/") {
+		return fmt.Sprintf("%s/%s", SysctlDir, strings.Trim(originKey, "/"))
+	}
+
+	return fmt.Sprintf("%s/%s", SysctlDir, strings.ReplaceAll(key, ".", "/"))
+}
+
+// readSysctl the sysctls of "net" are isolated by network namespace, so they are read in the network namespace of container
+func readSysctl(ctx context.Context, cr, cId, path string) (string, error) {
+	re, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, fmt.Sprintf("cat %s", path), []string{namespace.NET})
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(re, "\n"), nil
+}
+
+func writeSysctl(ctx context.Context, cr, cId, path, value string) error {
+	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, fmt.Sprintf("echo '%s' > %s", value, path), []string{namespace.NET})
+	return err
+}
+
+// getOriginKeyMap the key in "." format -> the key provided by user
+func getOriginKeyMap(sysctls string) map[string]string {
+	var keyMap = make(map[string]string)
+	for _, unit := range strings.Split(sysctls, ",") {
+		originKey := strings.TrimSpace(strings.SplitN(unit, "=", 2)[0])
+		keyMap[strings.ReplaceAll(strings.Trim(originKey, "/"), "/", ".")] = originKey
+	}
+
+	return keyMap
+}
+
+func (i *SysctlInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	if i.Args.Sysctls == "" {
+		return fmt.Errorf("\"sysctls\" is empty")
+	}
+
+	kvMap, _, err := parseSysctls(i.Args.Sysctls)
+	if err != nil {
+		return fmt.Errorf("\"sysctls\"[%s] is invalid: %s", i.Args.Sysctls, err.Error())
+	}
+
+	originKeyMap := getOriginKeyMap(i.Args.Sysctls)
+	for key := range kvMap {
+		if !isSysctlAllowed(key) {
+			return fmt.Errorf("sysctl[%s] is not allowed, only support: %s", key, strings.Join(SysctlWhitelist, "、"))
+		}
+
+		// only the sysctls of network namespace are isolated for container, others will affect the host
+		if i.Info.ContainerRuntime != "" && !strings.HasPrefix(key, "net.") {
+			return fmt.Errorf("sysctl[%s] is not isolated by container, only support \"net.\" in container", key)
+		}
+
+		path := getSysctlPath(key, originKeyMap[key])
+		if _, err := readSysctl(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, path); err != nil {
+			return fmt.Errorf("read sysctl[%s] error: %s", key, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (i *SysctlInjector) Inject(ctx context.Context) error {
+	kvMap, keyList, _ := parseSysctls(i.Args.Sysctls)
+	originKeyMap := getOriginKeyMap(i.Args.Sysctls)
+
+	i.Runtime.Origin = make(map[string]string)
+	for _, key := range keyList {
+		path := getSysctlPath(key, originKeyMap[key])
+		origin, err := readSysctl(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, path)
+		if err != nil {
+			return i.getErrWithUndo(ctx, fmt.Sprintf("read sysctl[%s] error: %s", key, err.Error()))
+		}
+
+		i.Runtime.Origin[key] = origin
+		if err := writeSysctl(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, path, kvMap[key]); err != nil {
+			return i.getErrWithUndo(ctx, fmt.Sprintf("set sysctl[%s] to \"%s\" error: %s", key, kvMap[key], err.Error()))
+		}
+	}
+
+	return nil
+}
+
+func (i *SysctlInjector) getErrWithUndo(ctx context.Context, msg string) error {
+	if err := i.Recover(ctx); err != nil {
+		log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
+	}
+
+	return fmt.Errorf(msg)
+}
+
+// Recover restore the sysctls which are different from the original value
+func (i *SysctlInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	var (
+		errMsg       string
+		originKeyMap = getOriginKeyMap(i.Args.Sysctls)
+	)
+
+	for key, origin := range i.Runtime.Origin {
+		path := getSysctlPath(key, originKeyMap[key])
+		now, err := readSysctl(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, path)
+		if err != nil {
+			errMsg = fmt.Sprintf("%s read sysctl[%s] error: %s;", errMsg, key, err.Error())
+			continue
+		}
+
+		if now == origin && !dryrun.IsRecoverPhase(ctx) {
+			continue
+		}
+
+		if err := writeSysctl(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, path, origin); err != nil {
+			errMsg = fmt.Sprintf("%s restore sysctl[%s] to \"%s\" error: %s;", errMsg, key, origin, err.Error())
+		}
+	}
+
+	if errMsg != "" {
+		return fmt.Errorf(errMsg)
+	}
+
+	return nil
+}
@@ -21,6 +21,7 @@ const (
 
 	FaultMemFill = "fill"
 	FaultMemOOM  = "oom"
+	FaultMemLeak = "leak"
 	PercentOOM   = 101
 
 	ModeRam   = "ram"
@@ -31,6 +32,7 @@ const (
 	TmpFsFile = "chaosmeta_mem_tmpfs"
 
 	MemFillKey = "chaosmeta_memfill"
+	MemLeakKey = "chaosmeta_memleak"
 
 	MemExec = "chaosmeta_mem"
 )
@@ -22,12 +22,15 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/metrics"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/memory"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/profile"
 )
 
 func init() {
@@ -44,10 +47,12 @@ type FillArgs struct {
 	Percent int    `json:"percent,omitempty"`
 	Bytes   string `json:"bytes,omitempty"`
 	Mode    string `json:"mode"`
+	Profile string `json:"profile,omitempty"`
 }
 
 type FillRuntime struct {
 	//Pid int `json:"pid,omitempty"`
+	profile.State
 }
 
 func (i *FillInjector) GetArgs() interface{} {
@@ -62,7 +67,7 @@ func (i *FillInjector) SetDefault() {
 	i.BaseInjector.SetDefault()
 
 	if i.Args.Mode == "" {
-		if i.Info.ContainerId != "" {
+		if i.Info.ContainerId != "" || i.Args.Profile != "" {
 			i.Args.Mode = ModeRam
 		} else {
 			i.Args.Mode = ModeCache
@@ -76,19 +81,37 @@ func (i *FillInjector) SetOption(cmd *cobra.Command) {
 	cmd.Flags().IntVarP(&i.Args.Percent, "percent", "p", 0, "mem fill target percent, an integer in (0,100] without \"%\", eg: \"30\" means \"30%\"")
 	cmd.Flags().StringVarP(&i.Args.Bytes, "bytes", "b", "", "mem fill bytes to add, support unit: KB/MB/GB/TB（default KB）")
 	cmd.Flags().StringVarP(&i.Args.Mode, "mode", "m", "", fmt.Sprintf("mem fill mode, support: %s、%s（default %s）", ModeRam, ModeCache, ModeCache))
+	cmd.Flags().StringVar(&i.Args.Profile, "profile", "", fmt.Sprintf("mem fill bytes varying with time, only support mode %s, format: \"[type]:[start]-[end]:[duration][:steps]\", type support: linear、step、sine、sawtooth, bytes support unit: KB/MB/GB/TB（default KB）, eg: \"linear:0-2GB:1h\". if provided, \"percent\" and \"bytes\" will be ignored", ModeRam))
 }
 
-// Validator percent > bytes
+// Validator profile > percent > bytes
 func (i *FillInjector) Validator(ctx context.Context) error {
 	if err := i.BaseInjector.Validator(ctx); err != nil {
 		return err
 	}
 
-	if i.Args.Percent == 0 && i.Args.Bytes == "" {
-		return fmt.Errorf("must provide \"percent\" or \"bytes\"")
-	}
+	if i.Args.Profile != "" {
+		p, err := profile.Parse(i.Args.Profile, profile.UnitKB)
+		if err != nil {
+			return fmt.Errorf("\"profile\"[%s] is invalid: %s", i.Args.Profile, err.Error())
+		}
 
-	if i.Args.Percent != 0 {
+		if i.Args.Mode != ModeRam {
+			return fmt.Errorf("\"profile\" only support mode \"%s\"", ModeRam)
+		}
+
+		// the level of profile is the bytes to add, so the highest level must fit in the available memory now
+		availKBytes, err := memory.GetAvailableKBytes(ctx, i.Info.ContainerRuntime, i.Info.ContainerId)
+		if err != nil {
+			return fmt.Errorf("get available mem error: %s", err.Error())
+		}
+
+		if int64(p.Max()) > availKBytes {
+			return fmt.Errorf("the max level of \"profile\"[%dKB] is larger than the available mem[%dKB]", int64(p.Max()), availKBytes)
+		}
+	} else if i.Args.Percent == 0 && i.Args.Bytes == "" {
+		return fmt.Errorf("must provide \"percent\" or \"bytes\"")
+	} else if i.Args.Percent != 0 {
 		if i.Args.Percent < 0 || i.Args.Percent > 100 {
 			return fmt.Errorf("\"percent\" must be in (0,100]")
 		}
@@ -133,7 +156,10 @@ func (i *FillInjector) Inject(ctx context.Context) error {
 
 		toolPath := utils.GetToolPath(MemFillKey)
 		args := fmt.Sprintf("'%s' %d %d '%s' %d", i.Info.Uid, -999, i.Args.Percent, i.Args.Bytes, timeout)
-		if i.Args.Percent > 0 {
+		if i.Args.Profile != "" {
+			args = fmt.Sprintf("'%s' %d %d '%s' %d '%s'", i.Info.Uid, -999, 0, "", timeout, i.Args.Profile)
+			i.Runtime.State = profile.NewState(i.Args.Profile, profile.UnitKB)
+		} else if i.Args.Percent > 0 {
 			fillKBytes, err := memory.CalculateFillKBytes(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Percent, "")
 			if err != nil {
 				return fmt.Errorf("calculateFillKBytes error: %s", err.Error())
@@ -159,6 +185,26 @@ func (i *FillInjector) Inject(ctx context.Context) error {
 	return nil
 }
 
+// GetResource the bytes used by tmpfs in "cache" mode, the resident memory of the fill process in "ram" mode
+func (i *FillInjector) GetResource(ctx context.Context) (map[string]float64, error) {
+	var (
+		bytes uint64
+		err   error
+	)
+
+	if i.Args.Mode == ModeCache {
+		bytes, err = memory.GetTmpfsUsedBytes(getFillDir(i.Info.Uid))
+	} else {
+		bytes, err = process.GetRssByKey(ctx, fmt.Sprintf("%s %s", MemFillKey, i.Info.Uid))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]float64{metrics.ResourceMemBytes: float64(bytes)}, nil
+}
+
 func (i *FillInjector) Recover(ctx context.Context) error {
 	if i.BaseInjector.Recover(ctx) == nil {
 		return nil
@@ -172,7 +218,7 @@ func (i *FillInjector) Recover(ctx context.Context) error {
 			return fmt.Errorf("check tmpfs[%s] exist error: %s", fillDir, err.Error())
 		}
 
-		if isDirExist {
+		if isDirExist || dryrun.IsRecoverPhase(ctx) {
 			return memory.UndoTmpfs(ctx, fillDir)
 		}
 
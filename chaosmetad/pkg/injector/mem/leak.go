@@ -0,0 +1,177 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mem
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/ptrace"
+	"strings"
+)
+
+func init() {
+	injector.Register(TargetMem, FaultMemLeak, func() injector.IInjector { return &LeakInjector{} })
+}
+
+type LeakInjector struct {
+	injector.BaseInjector
+	Args    LeakArgs
+	Runtime LeakRuntime
+}
+
+type LeakArgs struct {
+	Pid  int    `json:"pid,omitempty"`
+	Key  string `json:"key,omitempty"`
+	Cap  string `json:"cap"`
+	Rate string `json:"rate,omitempty"`
+}
+
+type LeakRuntime struct {
+	RegionList []*ptrace.LeakRegion `json:"region_list,omitempty"`
+}
+
+func (i *LeakInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *LeakInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *LeakInjector) SetOption(cmd *cobra.Command) {
+	// i.BaseInjector.SetOption(cmd)
+
+	cmd.Flags().IntVarP(&i.Args.Pid, "pid", "p", 0, "target process's pid")
+	cmd.Flags().StringVarP(&i.Args.Key, "key", "k", "", "the key used to grep to get target process, the effect is equivalent to \"ps -ef | grep [key]\". if \"pid\" provided, \"key\" will be ignored")
+	cmd.Flags().StringVarP(&i.Args.Cap, "cap", "c", "", "the max bytes leaked in each target process, support unit: KB/MB/GB/TB（default KB）")
+	cmd.Flags().StringVarP(&i.Args.Rate, "rate", "r", "", "the bytes leaked per second in each target process, support unit: KB/MB/GB/TB（default KB）. if not provided, all the bytes of \"cap\" are leaked at once")
+}
+
+func (i *LeakInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	capKBytes, err := utils.GetKBytes(i.Args.Cap)
+	if err != nil {
+		return fmt.Errorf("\"cap\"[%s] is invalid: %s", i.Args.Cap, err.Error())
+	}
+
+	if capKBytes <= 0 {
+		return fmt.Errorf("\"cap\"[%s] must larger than 0", i.Args.Cap)
+	}
+
+	if i.Args.Rate != "" {
+		rateKBytes, err := utils.GetKBytes(i.Args.Rate)
+		if err != nil {
+			return fmt.Errorf("\"rate\"[%s] is invalid: %s", i.Args.Rate, err.Error())
+		}
+
+		if rateKBytes <= 0 {
+			return fmt.Errorf("\"rate\"[%s] must larger than 0", i.Args.Rate)
+		}
+	}
+
+	if _, err := process.GetPidListByPidOrKeyInContainer(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Pid, i.Args.Key); err != nil {
+		return fmt.Errorf("get target process error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// Inject map a region of "cap" bytes in each target process, then the tool populates the pages of regions at "rate"
+// in background, so the memory is charged to the target process and its cgroup
+func (i *LeakInjector) Inject(ctx context.Context) error {
+	logger := log.GetLogger(ctx)
+	capKBytes, _ := utils.GetKBytes(i.Args.Cap)
+	rateKBytes := capKBytes
+	if i.Args.Rate != "" {
+		rateKBytes, _ = utils.GetKBytes(i.Args.Rate)
+	}
+
+	pidList, err := process.GetPidListByPidOrKeyInContainer(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Pid, i.Args.Key)
+	if err != nil {
+		return fmt.Errorf("get target process error: %s", err.Error())
+	}
+
+	hostPidList, err := process.GetHostPidList(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, pidList)
+	if err != nil {
+		return fmt.Errorf("get pid in host of target process error: %s", err.Error())
+	}
+
+	var regionStrList []string
+	for _, pid := range hostPidList {
+		region := &ptrace.LeakRegion{Pid: pid, Size: uint64(capKBytes) * 1024}
+		if !dryrun.Record(ctx, dryrun.KindPtrace, dryrun.TargetHost, fmt.Sprintf("mmap %dKB in process[%d]", capKBytes, pid)) {
+			if region, err = ptrace.MapLeakRegion(pid, region.Size); err != nil {
+				if err := i.Recover(ctx); err != nil {
+					logger.Warnf("undo error: %s", err.Error())
+				}
+
+				return fmt.Errorf("map memory in process[%d] error: %s", pid, err.Error())
+			}
+		}
+
+		i.Runtime.RegionList = append(i.Runtime.RegionList, region)
+		regionStrList = append(regionStrList, fmt.Sprintf("%d:0x%x:%d:%d", region.Pid, region.Addr, region.Size, region.StartTime))
+	}
+
+	cmd := fmt.Sprintf("%s %s %d %s", utils.GetToolPath(MemLeakKey), i.Info.Uid, rateKBytes*1024, strings.Join(regionStrList, ","))
+	if _, err := cmdexec.StartBashCmdAndWaitPid(ctx, cmd, 0); err != nil {
+		if err := i.Recover(ctx); err != nil {
+			logger.Warnf("undo error: %s", err.Error())
+		}
+
+		return fmt.Errorf("start leak process error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (i *LeakInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	if err := process.CheckExistAndKillByKey(ctx, fmt.Sprintf("%s %s", MemLeakKey, i.Info.Uid)); err != nil {
+		return fmt.Errorf("stop leak process error: %s", err.Error())
+	}
+
+	var errMsg string
+	for _, region := range i.Runtime.RegionList {
+		if dryrun.Record(ctx, dryrun.KindPtrace, dryrun.TargetHost, fmt.Sprintf("munmap %dKB in process[%d]", region.Size/1024, region.Pid)) {
+			continue
+		}
+
+		if err := ptrace.FreeLeakRegion(region); err != nil {
+			errMsg = fmt.Sprintf("%s free memory in process[%d] error: %s;", errMsg, region.Pid, err.Error())
+		}
+	}
+
+	if errMsg != "" {
+		return fmt.Errorf(errMsg)
+	}
+
+	return nil
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/memory"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
@@ -150,7 +151,7 @@ func (i *OOMInjector) Recover(ctx context.Context) error {
 			return fmt.Errorf("check tmpfs[%s] exist error: %s", fillDir, err.Error())
 		}
 
-		if isDirExist {
+		if isDirExist || dryrun.IsRecoverPhase(ctx) {
 			return memory.UndoTmpfs(ctx, fillDir)
 		}
 
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package injector
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/metrics"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/storage"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+)
+
+// IResourceReporter optional for injector, report the amount of resource occupied by the experiment currently.
+// the key of result is the resource name defined in package metrics, eg: metrics.ResourceCpuCores
+type IResourceReporter interface {
+	GetResource(ctx context.Context) (map[string]float64, error)
+}
+
+type experimentCollector struct {
+	ctx context.Context
+}
+
+// NewExperimentCollector collect the number of experiments by status and the resource occupied by the running
+// experiments from db when scraped
+func NewExperimentCollector(ctx context.Context) prometheus.Collector {
+	return &experimentCollector{ctx: ctx}
+}
+
+func (c *experimentCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- metrics.ExperimentDesc
+	ch <- metrics.ResourceDesc
+}
+
+func (c *experimentCollector) Collect(ch chan<- prometheus.Metric) {
+	logger := log.GetLogger(c.ctx)
+	db, err := storage.GetExperimentStore()
+	if err != nil {
+		logger.Warnf("connect db error: %s", err.Error())
+		return
+	}
+
+	countMap, err := db.CountByStatus()
+	if err != nil {
+		logger.Warnf("count experiments by status error: %s", err.Error())
+		return
+	}
+
+	for _, status := range []string{utils.StatusCreated, utils.StatusSuccess, utils.StatusError, utils.StatusDestroyed} {
+		ch <- prometheus.MustNewConstMetric(metrics.ExperimentDesc, prometheus.GaugeValue, float64(countMap[status]), status)
+	}
+
+	exps, err := db.GetByStatus(utils.StatusSuccess)
+	if err != nil {
+		logger.Warnf("query experiments by status[%s] error: %s", utils.StatusSuccess, err.Error())
+		return
+	}
+
+	type resourceKey struct {
+		target, fault, resource string
+	}
+	var resourceMap = make(map[resourceKey]float64)
+	for _, exp := range exps {
+		i, err := NewInjector(exp.Target, exp.Fault)
+		if err != nil {
+			continue
+		}
+
+		reporter, ok := i.(IResourceReporter)
+		if !ok {
+			continue
+		}
+
+		if err := i.LoadInjector(exp, i.GetArgs(), i.GetRuntime()); err != nil {
+			logger.Warnf("load experiment[%s] to injector error: %s", exp.Uid, err.Error())
+			continue
+		}
+
+		resource, err := reporter.GetResource(c.ctx)
+		if err != nil {
+			logger.Debugf("get resource of experiment[%s] error: %s", exp.Uid, err.Error())
+			continue
+		}
+
+		for name, value := range resource {
+			resourceMap[resourceKey{exp.Target, exp.Fault, name}] += value
+		}
+	}
+
+	for key, value := range resourceMap {
+		ch <- prometheus.MustNewConstMetric(metrics.ResourceDesc, prometheus.GaugeValue, value, key.target, key.fault, key.resource)
+	}
+}
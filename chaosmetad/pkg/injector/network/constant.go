@@ -20,12 +20,20 @@ import (
 	"context"
 	"fmt"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/metrics"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cgroup"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/containercgroup"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/net"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
 )
 
 const (
 	TargetNetwork = "network"
 	DirectionOut  = "out"
+	DirectionIn   = "in"
+	DirectionBoth = "both"
 
 	FaultOccupy = "occupy"
 	OccupyKey   = "chaosmeta_occupy"
@@ -44,24 +52,389 @@ const (
 	DefaultGap     = 3
 	DefaultLatency = "1s"
 
+	FaultNetem = "netem"
+
+	FaultPartition = "partition"
+	ProtocolAll    = "all"
+	ActionDrop     = "drop"
+	ActionReject   = "reject"
+
+	// NetemClassId the netem band of prio qdisc, LimitClassId the limited class of htb qdisc
+	NetemClassId = "1:4"
+	LimitClassId = "1:2"
+
 	//NetworkExec = "chaosmeta_network"
 )
 
+var distributionList = []string{"uniform", "normal", "pareto", "paretonormal"}
+
+func isSupportDistribution(distribution string) bool {
+	for _, unit := range distributionList {
+		if unit == distribution {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isEgress(direction string) bool {
+	return direction == DirectionOut || direction == DirectionBoth
+}
+
+func isIngress(direction string) bool {
+	return direction == DirectionIn || direction == DirectionBoth
+}
+
+func checkDirection(direction string) error {
+	if direction != DirectionOut && direction != DirectionIn && direction != DirectionBoth {
+		return fmt.Errorf("\"direction\" is not support: %s, only support: %s, %s, %s", direction, DirectionOut, DirectionIn, DirectionBoth)
+	}
+
+	return nil
+}
+
+// checkTcRule only one tc network failure can be executed at the same time in each direction
+func checkTcRule(ctx context.Context, cr, cId, netInterface, direction string, force bool) error {
+	if force {
+		return nil
+	}
+
+	if isEgress(direction) {
+		exist, err := net.ExistTCRootQdisc(ctx, cr, cId, netInterface)
+		if err != nil {
+			return fmt.Errorf("check tc rule error: %s", err.Error())
+		}
+
+		if exist {
+			return fmt.Errorf("has other tc root rule, if want to force to execute, please provide [-f] or [--force] args")
+		}
+	}
+
+	if isIngress(direction) {
+		exist, err := net.ExistTCIngressQdisc(ctx, cr, cId, netInterface)
+		if err != nil {
+			return fmt.Errorf("check tc ingress rule error: %s", err.Error())
+		}
+
+		if exist {
+			return fmt.Errorf("has other tc ingress rule, if want to force to execute, please provide [-f] or [--force] args")
+		}
+	}
+
+	return nil
+}
+
+// execInject run injectFunc on netInterface for egress flow, and on the ifb device which the ingress flow is redirected to for ingress flow
+func execInject(ctx context.Context, cr, cId, netInterface, direction string, force bool, injectFunc func(ctx context.Context, device string) error) error {
+	dryrun.Resolve(ctx, dryrun.ResolvedInterface, netInterface)
+	if force {
+		if err := execReset(ctx, cr, cId, netInterface, direction); err != nil {
+			return fmt.Errorf("reset tc rule for %s error: %s", netInterface, err.Error())
+		}
+	}
+
+	if isEgress(direction) {
+		if err := injectFunc(ctx, netInterface); err != nil {
+			return err
+		}
+	}
+
+	if isIngress(direction) {
+		ifb, err := net.AddIngressRedirect(ctx, cr, cId, netInterface)
+		if err != nil {
+			return undoWithErr(ctx, cr, cId, netInterface, direction, fmt.Sprintf("redirect ingress flow of %s error: %s", netInterface, err.Error()))
+		}
+
+		if err := injectFunc(ctx, ifb); err != nil {
+			return undoWithErr(ctx, cr, cId, netInterface, direction, fmt.Sprintf("inject ingress flow of %s error: %s", netInterface, err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// injectNetem add a netem qdisc for all flow of device if no filter condition provided, otherwise add a prio qdisc and
+// filters to let the netem qdisc take effect on the flow matched("normal" mode) or the flow not matched("exclude" mode).
+// the flow of target processes is matched by cgroup filter if byCgroup is true
+func injectNetem(ctx context.Context, cr, cId, device, mode, fault, args, srcIp, dstIp, srcPort, dstPort string, byCgroup bool) error {
+	if !byCgroup && srcIp == "" && dstIp == "" && srcPort == "" && dstPort == "" {
+		return net.AddNetemQdisc(ctx, cr, cId, device, "", fault, args)
+	}
+
+	if err := net.AddPrioQdisc(ctx, cr, cId, device, "", "1:"); err != nil {
+		return fmt.Errorf("add root prio qdisc for %s error: %s", device, err.Error())
+	}
+
+	if mode == net.ModeNormal {
+		parent := NetemClassId
+		if err := net.AddNetemQdisc(ctx, cr, cId, device, parent, fault, args); err != nil {
+			return undoTcWithErr(ctx, cr, cId, device, fmt.Sprintf("add parent %s netem qdisc for %s error: %s", parent, device, err.Error()))
+		}
+	} else {
+		for subIndex := 1; subIndex < 4; subIndex++ {
+			parent := fmt.Sprintf("1:%d", subIndex)
+			if err := net.AddNetemQdisc(ctx, cr, cId, device, parent, fault, args); err != nil {
+				return undoTcWithErr(ctx, cr, cId, device, fmt.Sprintf("add parent %s netem qdisc for %s error: %s", parent, device, err.Error()))
+			}
+		}
+	}
+
+	if byCgroup {
+		if err := net.AddCgroupFilter(ctx, cr, cId, device); err != nil {
+			return undoTcWithErr(ctx, cr, cId, device, fmt.Sprintf("add cgroup filter for %s error: %s", device, err.Error()))
+		}
+
+		return nil
+	}
+
+	if err := net.AddFilter(ctx, cr, cId, device, NetemClassId, srcIp, dstIp, srcPort, dstPort); err != nil {
+		return undoTcWithErr(ctx, cr, cId, device, fmt.Sprintf("add filter for %s error: %s", device, err.Error()))
+	}
+
+	return nil
+}
+
+func isProcessMode(pid int, key string) bool {
+	return pid > 0 || key != ""
+}
+
+// checkProcess the flow of processes is matched by net_cls cgroup, which only works for the out flow and can not be combined with ip and port conditions.
+// cgroup v2 has no net_cls controller, the cgroup of a socket is fixed when the socket is created, so the existing
+// connections of target processes can not be matched after they are moved, cgroup v2 is not supported for this reason
+func checkProcess(ctx context.Context, cr, cId string, pid int, key, direction, srcIp, dstIp, srcPort, dstPort string) error {
+	if !isProcessMode(pid, key) {
+		return nil
+	}
+
+	if containercgroup.IsCgroupV2() {
+		return fmt.Errorf("\"pid\" and \"key\" are not supported on cgroup v2 host, which has no %s controller", cgroup.NETCLS)
+	}
+
+	if direction != DirectionOut {
+		return fmt.Errorf("\"pid\" and \"key\" only support direction: %s", DirectionOut)
+	}
+
+	if srcIp != "" || dstIp != "" || srcPort != "" || dstPort != "" {
+		return fmt.Errorf("\"pid\" and \"key\" can not be used with ip and port conditions")
+	}
+
+	netClsPath := fmt.Sprintf("%s/%s", containercgroup.RootCgroupPath, cgroup.NETCLS)
+	isExist, err := filesys.ExistPathLocal(netClsPath)
+	if err != nil {
+		return fmt.Errorf("check cgroup[%s] exist error: %s", netClsPath, err.Error())
+	}
+
+	if !isExist {
+		return fmt.Errorf("not support cgroup subsystem: %s", cgroup.NETCLS)
+	}
+
+	pidList, err := getHostPidList(ctx, cr, cId, pid, key)
+	if err != nil {
+		return err
+	}
+
+	if err := cgroup.CheckPidListNetClsCgroup(ctx, pidList); err != nil {
+		return fmt.Errorf("check cgroup of %v error: %s", pidList, err.Error())
+	}
+
+	return nil
+}
+
+func getHostPidList(ctx context.Context, cr, cId string, pid int, key string) ([]int, error) {
+	pidList, err := process.GetPidListByPidOrKeyInContainer(ctx, cr, cId, pid, key)
+	if err != nil {
+		return nil, fmt.Errorf("\"pid\" or \"key\" is invalid: %s", err.Error())
+	}
+
+	hostPidList, err := process.GetHostPidList(ctx, cr, cId, pidList)
+	if err != nil {
+		return nil, fmt.Errorf("get pid list in host error: %s", err.Error())
+	}
+
+	return hostPidList, nil
+}
+
+func getContainerNetClsCgroup(ctx context.Context, cr, cId string) (string, error) {
+	if cr == "" {
+		return "", nil
+	}
+
+	containerCgroup, err := cgroup.GetContainerCgroupPath(ctx, cr, cId, cgroup.NETCLS)
+	if err != nil {
+		return "", fmt.Errorf("get cgroup path of container[%s] error: %s", cId, err.Error())
+	}
+
+	return containerCgroup, nil
+}
+
+// injectProcessCgroup move the target processes to a new net_cls cgroup with classId, return the old cgroup of each process
+func injectProcessCgroup(ctx context.Context, cr, cId, uid string, pid int, key, classId string) (map[int]string, error) {
+	if !isProcessMode(pid, key) {
+		return nil, nil
+	}
+
+	pidList, err := getHostPidList(ctx, cr, cId, pid, key)
+	if err != nil {
+		return nil, err
+	}
+
+	oldCgroupMap, err := cgroup.GetPidListCurCgroup(ctx, pidList, cgroup.NETCLS)
+	if err != nil {
+		return nil, fmt.Errorf("get old path error: %s", err.Error())
+	}
+	log.GetLogger(ctx).Debugf("old cgroup path: %v", oldCgroupMap)
+
+	containerCgroup, err := getContainerNetClsCgroup(ctx, cr, cId)
+	if err != nil {
+		return nil, err
+	}
+
+	classIdValue, err := net.ParseHandle(classId)
+	if err != nil {
+		return nil, fmt.Errorf("classid[%s] is invalid: %s", classId, err.Error())
+	}
+
+	netClsPath := cgroup.GetNetClsCPath(uid, containerCgroup)
+	if err := cgroup.NewCgroup(ctx, netClsPath, cgroup.GetNetClsConfig(classIdValue, netClsPath)); err != nil {
+		return oldCgroupMap, fmt.Errorf("create cgroup[%s] error: %s", netClsPath, err.Error())
+	}
+
+	if err := cgroup.MovePidListToCgroup(ctx, pidList, netClsPath); err != nil {
+		return oldCgroupMap, fmt.Errorf("move pid list to cgroup[%s] error: %s", netClsPath, err.Error())
+	}
+
+	return oldCgroupMap, nil
+}
+
+// recoverProcessCgroup move the processes back to their old cgroup, and remove the net_cls cgroup of experiment
+func recoverProcessCgroup(ctx context.Context, cr, cId, uid string, pid int, key string, oldCgroupMap map[int]string) error {
+	if !isProcessMode(pid, key) {
+		return nil
+	}
+
+	logger := log.GetLogger(ctx)
+	containerCgroup, err := getContainerNetClsCgroup(ctx, cr, cId)
+	if err != nil {
+		return err
+	}
+
+	cgroupPath := cgroup.GetNetClsCPath(uid, containerCgroup)
+	isCgroupExist, err := cgroup.ExistCgroup(ctx, cgroupPath)
+	if err != nil {
+		return fmt.Errorf("check cgroup[%s] exist error: %s", cgroupPath, err.Error())
+	}
+
+	if !isCgroupExist {
+		return nil
+	}
+
+	pidList, err := cgroup.GetPidStrListByCgroup(ctx, cgroupPath)
+	if err != nil {
+		return fmt.Errorf("fail to get pid from cgroup[%s]: %s", cgroupPath, err.Error())
+	}
+
+	for _, pid := range pidList {
+		oldPath, ok := oldCgroupMap[pid]
+		if !ok {
+			logger.Warnf("fail to get pid[%d]'s old cgroup path, move to \"%s\" instead", pid, containerCgroup)
+			oldPath = containerCgroup
+		}
+
+		if err := cgroup.MoveTaskToCgroup(ctx, pid, cgroup.GetCgroupPath(cgroup.NETCLS, oldPath)); err != nil {
+			return fmt.Errorf("recover pid[%d] error: %s", pid, err.Error())
+		}
+	}
+
+	if err := cgroup.RemoveCgroup(ctx, cgroupPath); err != nil {
+		return fmt.Errorf("remove cgroup[%s] error: %s", cgroupPath, err.Error())
+	}
+
+	return nil
+}
+
+func undoWithErr(ctx context.Context, cr, cId, netInterface, direction, msg string) error {
+	if err := execRecover(ctx, cr, cId, netInterface, direction); err != nil {
+		log.GetLogger(ctx).Warnf("undo tc rule error: %s", err.Error())
+	}
+
+	return fmt.Errorf(msg)
+}
+
 func undoTcWithErr(ctx context.Context, cr, cId string, netInterface, msg string) error {
-	if err := execRecover(ctx, cr, cId, netInterface); err != nil {
+	if err := clearRootTcRule(ctx, cr, cId, netInterface); err != nil {
 		log.GetLogger(ctx).Warnf("undo tc rule error: %s", err.Error())
 	}
 
 	return fmt.Errorf(msg)
 }
 
-func execRecover(ctx context.Context, cr, cId, netInterface string) error {
+func execRecover(ctx context.Context, cr, cId, netInterface, direction string) error {
+	if isEgress(direction) {
+		if err := clearRootTcRule(ctx, cr, cId, netInterface); err != nil {
+			return err
+		}
+	}
+
+	if isIngress(direction) {
+		if err := net.ClearIngressRedirect(ctx, cr, cId, netInterface); err != nil {
+			return fmt.Errorf("clear ingress rule error: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// execReset remove the existing tc rules of netInterface whether they are added by chaosmeta or not
+func execReset(ctx context.Context, cr, cId, netInterface, direction string) error {
+	if isEgress(direction) {
+		if err := net.ResetTcRule(ctx, cr, cId, netInterface); err != nil {
+			return err
+		}
+	}
+
+	if isIngress(direction) {
+		if err := net.ResetIngressRedirect(ctx, cr, cId, netInterface); err != nil {
+			return fmt.Errorf("reset ingress rule error: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// getTcResource the tc rules present on netInterface, and on the ifb device which the ingress flow is redirected to
+func getTcResource(ctx context.Context, cr, cId, netInterface, direction string) (map[string]float64, error) {
+	count, err := net.CountTcRule(ctx, cr, cId, netInterface)
+	if err != nil {
+		return nil, fmt.Errorf("count tc rule of %s error: %s", netInterface, err.Error())
+	}
+
+	if isIngress(direction) {
+		ifb := net.GetIfbName(netInterface)
+		exist, err := net.ExistLink(ctx, cr, cId, ifb)
+		if err != nil {
+			return nil, fmt.Errorf("check link[%s] exist error: %s", ifb, err.Error())
+		}
+
+		if exist {
+			ifbCount, err := net.CountTcRule(ctx, cr, cId, ifb)
+			if err != nil {
+				return nil, fmt.Errorf("count tc rule of %s error: %s", ifb, err.Error())
+			}
+			count += ifbCount
+		}
+	}
+
+	return map[string]float64{metrics.ResourceTcRules: float64(count)}, nil
+}
+
+func clearRootTcRule(ctx context.Context, cr, cId, netInterface string) error {
 	isTcExist, err := net.ExistTCRootQdisc(ctx, cr, cId, netInterface)
 	if err != nil {
 		return fmt.Errorf("check tc rule exist error: %s", err.Error())
 	}
 
-	if isTcExist {
+	if isTcExist || dryrun.IsRecoverPhase(ctx) {
 		return net.ClearTcRule(ctx, cr, cId, netInterface)
 	}
 
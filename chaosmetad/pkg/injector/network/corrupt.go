@@ -21,7 +21,7 @@ import (
 	"fmt"
 	"github.com/spf13/cobra"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
-	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/net"
 )
 
@@ -44,10 +44,14 @@ type CorruptArgs struct {
 	DstIp     string `json:"dst_ip,omitempty"`
 	SrcPort   string `json:"src_port,omitempty"`
 	DstPort   string `json:"dst_port,omitempty"`
+	Pid       int    `json:"pid,omitempty"`
+	Key       string `json:"key,omitempty"`
 	Force     bool   `json:"force,omitempty"`
 }
 
-type CorruptRuntime struct{}
+type CorruptRuntime struct {
+	OldCgroupMap map[int]string
+}
 
 func (i *CorruptInjector) GetArgs() interface{} {
 	return &i.Args
@@ -74,7 +78,7 @@ func (i *CorruptInjector) SetOption(cmd *cobra.Command) {
 
 	cmd.Flags().IntVarP(&i.Args.Percent, "percent", "p", 0, "packets corrupt percent, an integer in (0,100] without \"%\", eg: \"30\" means \"30%\"")
 
-	cmd.Flags().StringVarP(&i.Args.Direction, "direction", "d", "", fmt.Sprintf("flow direction to inject, support: %s（default %s）", DirectionOut, DirectionOut))
+	cmd.Flags().StringVarP(&i.Args.Direction, "direction", "d", "", fmt.Sprintf("flow direction to inject, support: %s, %s, %s（default %s）", DirectionOut, DirectionIn, DirectionBoth, DirectionOut))
 	cmd.Flags().StringVarP(&i.Args.Mode, "mode", "m", "", fmt.Sprintf("inject mode, support: %s（default）、%s(means white list mode)", net.ModeNormal, net.ModeExclude))
 	cmd.Flags().BoolVarP(&i.Args.Force, "force", "f", false, "force will overwrite the network rule if old rule exist")
 
@@ -83,6 +87,8 @@ func (i *CorruptInjector) SetOption(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&i.Args.DstIp, "dst-ip", "", "filter condition: destination ip. eg: 10.10.0.0/16,192.168.2.5,192.168.1.0/24")
 	cmd.Flags().StringVar(&i.Args.SrcPort, "src-port", "", "filter condition: source port. eg: 8080,9090,12000/8")
 	cmd.Flags().StringVar(&i.Args.DstPort, "dst-port", "", "filter condition: destination port. eg: 8080,9090,12000/8")
+	cmd.Flags().IntVar(&i.Args.Pid, "pid", 0, "filter condition: target process's pid, only the out flow of target process will be affected, not supported on cgroup v2 host")
+	cmd.Flags().StringVar(&i.Args.Key, "key", "", "filter condition: the key used to grep to get target process, the effect is equivalent to \"ps -ef | grep [key]\". if \"pid\" provided, \"key\" will be ignored")
 }
 
 // Validator Only one tc network failure can be executed at the same time
@@ -94,16 +100,12 @@ func (i *CorruptInjector) Validator(ctx context.Context) error {
 		return fmt.Errorf("\"percent\" must larger than 0")
 	}
 
-	if !cmdexec.SupportCmd("tc") {
-		return fmt.Errorf("not support command \"tc\"")
-	}
-
 	if i.Args.Interface == "" {
 		return fmt.Errorf("\"interface\" is empty")
 	}
 
-	if i.Args.Direction != DirectionOut {
-		return fmt.Errorf("\"direction\" only support: %s", DirectionOut)
+	if err := checkDirection(i.Args.Direction); err != nil {
+		return err
 	}
 
 	if i.Args.Mode != net.ModeNormal && i.Args.Mode != net.ModeExclude {
@@ -134,61 +136,51 @@ func (i *CorruptInjector) Validator(ctx context.Context) error {
 		}
 	}
 
-	exist, err := net.ExistTCRootQdisc(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface)
-	if err != nil {
-		return fmt.Errorf("check tc rule error: %s", err.Error())
-	}
-
-	if exist && !i.Args.Force {
-		return fmt.Errorf("has other tc root rule, if want to force to execute, please provide [-f] or [--force] args")
+	if err := checkProcess(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Pid, i.Args.Key, i.Args.Direction,
+		i.Args.SrcIp, i.Args.DstIp, i.Args.SrcPort, i.Args.DstPort); err != nil {
+		return err
 	}
 
-	return nil
+	return checkTcRule(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, i.Args.Direction, i.Args.Force)
 }
 
 func (i *CorruptInjector) Inject(ctx context.Context) error {
-	if i.Args.Force {
-		exist, _ := net.ExistTCRootQdisc(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface)
-		if exist {
-			if err := net.ClearTcRule(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface); err != nil {
-				return fmt.Errorf("reset tc rule for %s error: %s", i.Args.Interface, err.Error())
-			}
+	var err error
+	i.Runtime.OldCgroupMap, err = injectProcessCgroup(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Pid, i.Args.Key, NetemClassId)
+	if err != nil {
+		if err := recoverProcessCgroup(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Pid, i.Args.Key, i.Runtime.OldCgroupMap); err != nil {
+			log.GetLogger(ctx).Warnf("undo process cgroup error: %s", err.Error())
 		}
-	}
 
-	if i.Args.SrcIp == "" && i.Args.DstIp == "" && i.Args.SrcPort == "" && i.Args.DstPort == "" {
-		return net.AddNetemQdisc(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, "", FaultCorrupt, fmt.Sprintf("%d", i.Args.Percent))
+		return fmt.Errorf("move target process to cgroup error: %s", err.Error())
 	}
 
-	if err := net.AddPrioQdisc(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, "", "1:"); err != nil {
-		return fmt.Errorf("add root prio qdisc for %s error: %s", i.Args.Interface, err.Error())
-	}
-
-	if i.Args.Mode == net.ModeNormal {
-		parent := "1:4"
-		if err := net.AddNetemQdisc(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, parent, FaultCorrupt, fmt.Sprintf("%d", i.Args.Percent)); err != nil {
-			return undoTcWithErr(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, fmt.Sprintf("add parent %s netem qdisc for %s error: %s", parent, i.Args.Interface, err.Error()))
-		}
-	} else {
-		for subIndex := 1; subIndex < 4; subIndex++ {
-			parent := fmt.Sprintf("1:%d", subIndex)
-			if err := net.AddNetemQdisc(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, parent, FaultCorrupt, fmt.Sprintf("%d", i.Args.Percent)); err != nil {
-				return undoTcWithErr(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, fmt.Sprintf("add parent %s netem qdisc for %s error: %s", parent, i.Args.Interface, err.Error()))
-			}
+	if err := execInject(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, i.Args.Direction, i.Args.Force, func(ctx context.Context, device string) error {
+		return injectNetem(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, device, i.Args.Mode, FaultCorrupt, fmt.Sprintf("%d", i.Args.Percent),
+			i.Args.SrcIp, i.Args.DstIp, i.Args.SrcPort, i.Args.DstPort, isProcessMode(i.Args.Pid, i.Args.Key))
+	}); err != nil {
+		if err := recoverProcessCgroup(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Pid, i.Args.Key, i.Runtime.OldCgroupMap); err != nil {
+			log.GetLogger(ctx).Warnf("undo process cgroup error: %s", err.Error())
 		}
-	}
 
-	if err := net.AddFilter(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, "1:4", i.Args.SrcIp, i.Args.DstIp, i.Args.SrcPort, i.Args.DstPort); err != nil {
-		return undoTcWithErr(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, fmt.Sprintf("add filter for %s error: %s", i.Args.Interface, err.Error()))
+		return err
 	}
 
 	return nil
 }
 
+func (i *CorruptInjector) GetResource(ctx context.Context) (map[string]float64, error) {
+	return getTcResource(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, i.Args.Direction)
+}
+
 func (i *CorruptInjector) Recover(ctx context.Context) error {
 	if i.BaseInjector.Recover(ctx) == nil {
 		return nil
 	}
 
-	return execRecover(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface)
+	if err := execRecover(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, i.Args.Direction); err != nil {
+		return err
+	}
+
+	return recoverProcessCgroup(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Pid, i.Args.Key, i.Runtime.OldCgroupMap)
 }
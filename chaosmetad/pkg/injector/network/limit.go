@@ -21,8 +21,8 @@ import (
 	"fmt"
 	"github.com/spf13/cobra"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
-	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/net"
 )
 
@@ -45,10 +45,14 @@ type LimitArgs struct {
 	DstIp     string `json:"dst_ip,omitempty"`
 	SrcPort   string `json:"src_port,omitempty"`
 	DstPort   string `json:"dst_port,omitempty"`
+	Pid       int    `json:"pid,omitempty"`
+	Key       string `json:"key,omitempty"`
 	Force     bool   `json:"force,omitempty"`
 }
 
-type LimitRuntime struct{}
+type LimitRuntime struct {
+	OldCgroupMap map[int]string
+}
 
 func (i *LimitInjector) GetArgs() interface{} {
 	return &i.Args
@@ -74,7 +78,7 @@ func (i *LimitInjector) SetOption(cmd *cobra.Command) {
 	// i.BaseInjector.SetOption(cmd)
 	cmd.Flags().StringVarP(&i.Args.Rate, "rate", "r", "", "limit rate, means how fast per second, support unit: \"bit、kbit、mbit、gbit、tbit\"(default bit)")
 
-	cmd.Flags().StringVarP(&i.Args.Direction, "direction", "d", "", fmt.Sprintf("flow direction to inject, support: %s（default %s）", DirectionOut, DirectionOut))
+	cmd.Flags().StringVarP(&i.Args.Direction, "direction", "d", "", fmt.Sprintf("flow direction to inject, support: %s, %s, %s（default %s）", DirectionOut, DirectionIn, DirectionBoth, DirectionOut))
 	cmd.Flags().StringVarP(&i.Args.Mode, "mode", "m", "", fmt.Sprintf("inject mode, support: %s（default）、%s(means white list mode)", net.ModeNormal, net.ModeExclude))
 	cmd.Flags().BoolVarP(&i.Args.Force, "force", "f", false, "force will overwrite the network rule if old rule exist")
 
@@ -83,6 +87,8 @@ func (i *LimitInjector) SetOption(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&i.Args.DstIp, "dst-ip", "", "filter condition: destination ip. eg: 10.10.0.0/16,192.168.2.5,192.168.1.0/24")
 	cmd.Flags().StringVar(&i.Args.SrcPort, "src-port", "", "filter condition: source port. eg: 8080,9090,12000/8")
 	cmd.Flags().StringVar(&i.Args.DstPort, "dst-port", "", "filter condition: destination port. eg: 8080,9090,12000/8")
+	cmd.Flags().IntVar(&i.Args.Pid, "pid", 0, "filter condition: target process's pid, only the out flow of target process will be affected, not supported on cgroup v2 host")
+	cmd.Flags().StringVar(&i.Args.Key, "key", "", "filter condition: the key used to grep to get target process, the effect is equivalent to \"ps -ef | grep [key]\". if \"pid\" provided, \"key\" will be ignored")
 
 }
 
@@ -99,16 +105,12 @@ func (i *LimitInjector) Validator(ctx context.Context) error {
 		return fmt.Errorf("\"rate\" is invalid: %s", err.Error())
 	}
 
-	if !cmdexec.SupportCmd("tc") {
-		return fmt.Errorf("not support command \"tc\"")
-	}
-
 	if i.Args.Interface == "" {
 		return fmt.Errorf("\"interface\" is empty")
 	}
 
-	if i.Args.Direction != DirectionOut {
-		return fmt.Errorf("\"direction\" only support: %s", DirectionOut)
+	if err := checkDirection(i.Args.Direction); err != nil {
+		return err
 	}
 
 	if i.Args.Mode != net.ModeNormal && i.Args.Mode != net.ModeExclude {
@@ -139,49 +141,70 @@ func (i *LimitInjector) Validator(ctx context.Context) error {
 		}
 	}
 
-	exist, err := net.ExistTCRootQdisc(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface)
-	if err != nil {
-		return fmt.Errorf("check tc rule error: %s", err.Error())
-	}
-
-	if exist && !i.Args.Force {
-		return fmt.Errorf("has other tc root rule, if want to force to execute, please provide [-f] or [--force] args")
+	if err := checkProcess(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Pid, i.Args.Key, i.Args.Direction,
+		i.Args.SrcIp, i.Args.DstIp, i.Args.SrcPort, i.Args.DstPort); err != nil {
+		return err
 	}
 
-	return nil
+	return checkTcRule(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, i.Args.Direction, i.Args.Force)
 }
 
 func (i *LimitInjector) Inject(ctx context.Context) error {
-	if i.Args.Force {
-		exist, _ := net.ExistTCRootQdisc(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface)
-		if exist {
-			if err := net.ClearTcRule(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface); err != nil {
-				return fmt.Errorf("reset tc rule for %s error: %s", i.Args.Interface, err.Error())
-			}
+	var err error
+	i.Runtime.OldCgroupMap, err = injectProcessCgroup(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Pid, i.Args.Key, LimitClassId)
+	if err != nil {
+		if err := recoverProcessCgroup(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Pid, i.Args.Key, i.Runtime.OldCgroupMap); err != nil {
+			log.GetLogger(ctx).Warnf("undo process cgroup error: %s", err.Error())
 		}
+
+		return fmt.Errorf("move target process to cgroup error: %s", err.Error())
 	}
 
-	if err := net.AddHTBQdisc(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface); err != nil {
-		return fmt.Errorf("add htb qdisc for %s error: %s", i.Args.Interface, err.Error())
+	if err := execInject(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, i.Args.Direction, i.Args.Force, i.injectDevice); err != nil {
+		if err := recoverProcessCgroup(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Pid, i.Args.Key, i.Runtime.OldCgroupMap); err != nil {
+			log.GetLogger(ctx).Warnf("undo process cgroup error: %s", err.Error())
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (i *LimitInjector) injectDevice(ctx context.Context, device string) error {
+	if err := net.AddHTBQdisc(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, device); err != nil {
+		return fmt.Errorf("add htb qdisc for %s error: %s", device, err.Error())
 	}
 
-	if err := net.AddLimitClass(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, i.Args.Rate, i.Args.Mode); err != nil {
-		return undoTcWithErr(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, fmt.Sprintf("add limit class for %s error: %s", i.Args.Interface, err.Error()))
+	if err := net.AddLimitClass(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, device, i.Args.Rate, i.Args.Mode); err != nil {
+		return undoTcWithErr(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, device, fmt.Sprintf("add limit class for %s error: %s", device, err.Error()))
 	}
 
-	if i.Args.SrcIp != "" || i.Args.DstIp != "" || i.Args.SrcPort != "" || i.Args.DstPort != "" {
-		if err := net.AddFilter(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, "1:2", i.Args.SrcIp, i.Args.DstIp, i.Args.SrcPort, i.Args.DstPort); err != nil {
-			return undoTcWithErr(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, fmt.Sprintf("add filter for %s error: %s", i.Args.Interface, err.Error()))
+	if isProcessMode(i.Args.Pid, i.Args.Key) {
+		if err := net.AddCgroupFilter(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, device); err != nil {
+			return undoTcWithErr(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, device, fmt.Sprintf("add cgroup filter for %s error: %s", device, err.Error()))
+		}
+	} else if i.Args.SrcIp != "" || i.Args.DstIp != "" || i.Args.SrcPort != "" || i.Args.DstPort != "" {
+		if err := net.AddFilter(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, device, LimitClassId, i.Args.SrcIp, i.Args.DstIp, i.Args.SrcPort, i.Args.DstPort); err != nil {
+			return undoTcWithErr(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, device, fmt.Sprintf("add filter for %s error: %s", device, err.Error()))
 		}
 	}
 
 	return nil
 }
 
+func (i *LimitInjector) GetResource(ctx context.Context) (map[string]float64, error) {
+	return getTcResource(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, i.Args.Direction)
+}
+
 func (i *LimitInjector) Recover(ctx context.Context) error {
 	if i.BaseInjector.Recover(ctx) == nil {
 		return nil
 	}
 
-	return execRecover(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface)
+	if err := execRecover(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, i.Args.Direction); err != nil {
+		return err
+	}
+
+	return recoverProcessCgroup(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Pid, i.Args.Key, i.Runtime.OldCgroupMap)
 }
@@ -0,0 +1,314 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/net"
+	"strings"
+)
+
+// tc qdisc add dev ens33 root handle 1: netem delay 200ms 20ms 25% distribution normal loss 5% 25% rate 1mbit
+
+func init() {
+	injector.Register(TargetNetwork, FaultNetem, func() injector.IInjector { return &NetemInjector{} })
+}
+
+type NetemInjector struct {
+	injector.BaseInjector
+	Args    NetemArgs
+	Runtime NetemRuntime
+}
+
+type NetemArgs struct {
+	Interface    string `json:"interface"`
+	Latency      string `json:"latency,omitempty"`
+	Jitter       string `json:"jitter,omitempty"`
+	Correlation  int    `json:"correlation,omitempty"`
+	Distribution string `json:"distribution,omitempty"`
+	Loss         int    `json:"loss,omitempty"`
+	Corrupt      int    `json:"corrupt,omitempty"`
+	Duplicate    int    `json:"duplicate,omitempty"`
+	Reorder      int    `json:"reorder,omitempty"`
+	Gap          int    `json:"gap,omitempty"`
+	Rate         string `json:"rate,omitempty"`
+	Direction    string `json:"direction"`
+	Mode         string `json:"mode"`
+	SrcIp        string `json:"src_ip,omitempty"`
+	DstIp        string `json:"dst_ip,omitempty"`
+	SrcPort      string `json:"src_port,omitempty"`
+	DstPort      string `json:"dst_port,omitempty"`
+	Pid          int    `json:"pid,omitempty"`
+	Key          string `json:"key,omitempty"`
+	Force        bool   `json:"force,omitempty"`
+}
+
+type NetemRuntime struct {
+	OldCgroupMap map[int]string
+}
+
+func (i *NetemInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *NetemInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *NetemInjector) SetDefault() {
+	i.BaseInjector.SetDefault()
+
+	if i.Args.Direction == "" {
+		i.Args.Direction = DirectionOut
+	}
+
+	if i.Args.Mode == "" {
+		i.Args.Mode = net.ModeNormal
+	}
+}
+
+func (i *NetemInjector) SetOption(cmd *cobra.Command) {
+	// i.BaseInjector.SetOption(cmd)
+
+	cmd.Flags().StringVarP(&i.Args.Latency, "latency", "l", "", "delay time value, support unit: \"s、ms、us\"(default us)")
+	cmd.Flags().StringVarP(&i.Args.Jitter, "jitter", "j", "", "jitter time value of delay, support unit: \"s、ms、us\"(default us)")
+	cmd.Flags().IntVar(&i.Args.Correlation, "correlation", 0, "correlation percent with the previous packet of delay、loss、corrupt、duplicate、reorder, an integer in [0,100]")
+	cmd.Flags().StringVar(&i.Args.Distribution, "distribution", "", fmt.Sprintf("distribution of delay jitter, support: %s", strings.Join(distributionList, ", ")))
+	cmd.Flags().IntVar(&i.Args.Loss, "loss", 0, "packets loss percent, an integer in [0,100], 0 means not inject it")
+	cmd.Flags().IntVar(&i.Args.Corrupt, "corrupt", 0, "packets corrupt percent, an integer in [0,100], 0 means not inject it")
+	cmd.Flags().IntVar(&i.Args.Duplicate, "duplicate", 0, "packets duplicate percent, an integer in [0,100], 0 means not inject it")
+	cmd.Flags().IntVar(&i.Args.Reorder, "reorder", 0, "percent of packets sent immediately while the others are delayed, an integer in [0,100], 0 means not inject it, need \"latency\"")
+	cmd.Flags().IntVarP(&i.Args.Gap, "gap", "g", 0, "reorder gap, eg: gap 5 means 1、5、10、15 packet sent immediately, need \"reorder\"")
+	cmd.Flags().StringVarP(&i.Args.Rate, "rate", "r", "", "rate limit, unit: bit、kbit、mbit、gbit、tbit(default bit)")
+
+	cmd.Flags().StringVarP(&i.Args.Direction, "direction", "d", "", fmt.Sprintf("flow direction to inject, support: %s, %s, %s（default %s）", DirectionOut, DirectionIn, DirectionBoth, DirectionOut))
+	cmd.Flags().StringVarP(&i.Args.Mode, "mode", "m", "", fmt.Sprintf("inject mode, support: %s（default）、%s(means white list mode)", net.ModeNormal, net.ModeExclude))
+	cmd.Flags().BoolVarP(&i.Args.Force, "force", "f", false, "force will overwrite the network rule if old rule exist")
+
+	cmd.Flags().StringVarP(&i.Args.Interface, "interface", "i", "", "filter condition: network interface. eg: lo")
+	cmd.Flags().StringVar(&i.Args.SrcIp, "src-ip", "", "filter condition: source ip. eg: 10.10.0.0/16,192.168.2.5,192.168.1.0/24")
+	cmd.Flags().StringVar(&i.Args.DstIp, "dst-ip", "", "filter condition: destination ip. eg: 10.10.0.0/16,192.168.2.5,192.168.1.0/24")
+	cmd.Flags().StringVar(&i.Args.SrcPort, "src-port", "", "filter condition: source port. eg: 8080,9090,12000/8")
+	cmd.Flags().StringVar(&i.Args.DstPort, "dst-port", "", "filter condition: destination port. eg: 8080,9090,12000/8")
+	cmd.Flags().IntVar(&i.Args.Pid, "pid", 0, "filter condition: target process's pid, only the out flow of target process will be affected, not supported on cgroup v2 host")
+	cmd.Flags().StringVar(&i.Args.Key, "key", "", "filter condition: the key used to grep to get target process, the effect is equivalent to \"ps -ef | grep [key]\". if \"pid\" provided, \"key\" will be ignored")
+}
+
+// Validator Only one tc network failure can be executed at the same time
+func (i *NetemInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	if i.Args.Latency == "" && i.Args.Loss == 0 && i.Args.Corrupt == 0 && i.Args.Duplicate == 0 && i.Args.Reorder == 0 && i.Args.Rate == "" {
+		return fmt.Errorf("at least one of \"latency\"、\"loss\"、\"corrupt\"、\"duplicate\"、\"reorder\"、\"rate\" must provide")
+	}
+
+	if i.Args.Latency != "" {
+		if err := utils.CheckTimeValue(i.Args.Latency); err != nil {
+			return fmt.Errorf("\"latency\" is invalid: %s", err.Error())
+		}
+	}
+
+	if i.Args.Jitter != "" {
+		if i.Args.Latency == "" {
+			return fmt.Errorf("\"jitter\" need \"latency\"")
+		}
+
+		if err := utils.CheckTimeValue(i.Args.Jitter); err != nil {
+			return fmt.Errorf("\"jitter\" is invalid: %s", err.Error())
+		}
+	}
+
+	if i.Args.Distribution != "" {
+		if i.Args.Jitter == "" {
+			return fmt.Errorf("\"distribution\" need \"jitter\"")
+		}
+
+		if !isSupportDistribution(i.Args.Distribution) {
+			return fmt.Errorf("\"distribution\" is not support: %s, only support: %s", i.Args.Distribution, strings.Join(distributionList, ", "))
+		}
+	}
+
+	if i.Args.Correlation < 0 || i.Args.Correlation > 100 {
+		return fmt.Errorf("\"correlation\" must in [0,100]")
+	}
+
+	for name, percent := range map[string]int{"loss": i.Args.Loss, "corrupt": i.Args.Corrupt, "duplicate": i.Args.Duplicate, "reorder": i.Args.Reorder} {
+		if percent < 0 || percent > 100 {
+			return fmt.Errorf("\"%s\" must in [0,100], 0 means not inject it", name)
+		}
+	}
+
+	if i.Args.Reorder > 0 && i.Args.Latency == "" {
+		return fmt.Errorf("\"reorder\" need \"latency\"")
+	}
+
+	if i.Args.Gap < 0 {
+		return fmt.Errorf("\"gap\" can not less than 0")
+	}
+
+	if i.Args.Gap > 0 && i.Args.Reorder == 0 {
+		return fmt.Errorf("\"gap\" need \"reorder\"")
+	}
+
+	if i.Args.Rate != "" {
+		if err := utils.CheckSpeedValue(i.Args.Rate); err != nil {
+			return fmt.Errorf("\"rate\" is invalid: %s", err.Error())
+		}
+	}
+
+	// netlink does not support "distribution" and "rate" of netem
+	if (i.Args.Distribution != "" || i.Args.Rate != "") && !cmdexec.SupportCmd("tc") {
+		return fmt.Errorf("not support command \"tc\", which is necessary for \"distribution\" and \"rate\"")
+	}
+
+	if i.Args.Interface == "" {
+		return fmt.Errorf("\"interface\" is empty")
+	}
+
+	if err := checkDirection(i.Args.Direction); err != nil {
+		return err
+	}
+
+	if i.Args.Mode != net.ModeNormal && i.Args.Mode != net.ModeExclude {
+		return fmt.Errorf("\"mode\" is not support: %s, only support: %s, %s", i.Args.Mode, net.ModeNormal, net.ModeExclude)
+	}
+
+	if i.Args.SrcIp != "" {
+		if _, err := net.GetValidIPList(i.Args.SrcIp, true); err != nil {
+			return fmt.Errorf("\"src-ip\"[%s] is invalid: %s", i.Args.SrcIp, err.Error())
+		}
+	}
+
+	if i.Args.DstIp != "" {
+		if _, err := net.GetValidIPList(i.Args.DstIp, true); err != nil {
+			return fmt.Errorf("\"dst-ip\"[%s] is invalid: %s", i.Args.DstIp, err.Error())
+		}
+	}
+
+	if i.Args.SrcPort != "" {
+		if _, err := net.GetValidPortList(i.Args.SrcPort); err != nil {
+			return fmt.Errorf("\"src-port\"[%s] is invalid: %s", i.Args.SrcPort, err.Error())
+		}
+	}
+
+	if i.Args.DstPort != "" {
+		if _, err := net.GetValidPortList(i.Args.DstPort); err != nil {
+			return fmt.Errorf("\"dst-port\"[%s] is invalid: %s", i.Args.DstPort, err.Error())
+		}
+	}
+
+	if err := checkProcess(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Pid, i.Args.Key, i.Args.Direction,
+		i.Args.SrcIp, i.Args.DstIp, i.Args.SrcPort, i.Args.DstPort); err != nil {
+		return err
+	}
+
+	return checkTcRule(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, i.Args.Direction, i.Args.Force)
+}
+
+// getNetemArgs compose all the provided fault into the args of one netem qdisc
+func (i *NetemInjector) getNetemArgs() string {
+	var argList []string
+	correlation := ""
+	if i.Args.Correlation > 0 {
+		correlation = fmt.Sprintf(" %d%%", i.Args.Correlation)
+	}
+
+	if i.Args.Latency != "" {
+		delayArgs := fmt.Sprintf("%s %s", FaultDelay, i.Args.Latency)
+		if i.Args.Jitter != "" {
+			delayArgs = fmt.Sprintf("%s %s%s", delayArgs, i.Args.Jitter, correlation)
+			if i.Args.Distribution != "" {
+				delayArgs = fmt.Sprintf("%s distribution %s", delayArgs, i.Args.Distribution)
+			}
+		}
+
+		argList = append(argList, delayArgs)
+	}
+
+	for _, unit := range []struct {
+		fault   string
+		percent int
+	}{{FaultLoss, i.Args.Loss}, {FaultCorrupt, i.Args.Corrupt}, {FaultDuplicate, i.Args.Duplicate}} {
+		if unit.percent > 0 {
+			argList = append(argList, fmt.Sprintf("%s %d%%%s", unit.fault, unit.percent, correlation))
+		}
+	}
+
+	if i.Args.Reorder > 0 {
+		reorderArgs := fmt.Sprintf("%s %d%%%s", FaultReorder, i.Args.Reorder, correlation)
+		if i.Args.Gap > 0 {
+			reorderArgs = fmt.Sprintf("%s gap %d", reorderArgs, i.Args.Gap)
+		}
+
+		argList = append(argList, reorderArgs)
+	}
+
+	if i.Args.Rate != "" {
+		argList = append(argList, fmt.Sprintf("rate %s", i.Args.Rate))
+	}
+
+	return strings.Join(argList, " ")
+}
+
+func (i *NetemInjector) Inject(ctx context.Context) error {
+	var err error
+	i.Runtime.OldCgroupMap, err = injectProcessCgroup(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Pid, i.Args.Key, NetemClassId)
+	if err != nil {
+		if err := recoverProcessCgroup(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Pid, i.Args.Key, i.Runtime.OldCgroupMap); err != nil {
+			log.GetLogger(ctx).Warnf("undo process cgroup error: %s", err.Error())
+		}
+
+		return fmt.Errorf("move target process to cgroup error: %s", err.Error())
+	}
+
+	if err := execInject(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, i.Args.Direction, i.Args.Force, func(ctx context.Context, device string) error {
+		return injectNetem(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, device, i.Args.Mode, "", i.getNetemArgs(),
+			i.Args.SrcIp, i.Args.DstIp, i.Args.SrcPort, i.Args.DstPort, isProcessMode(i.Args.Pid, i.Args.Key))
+	}); err != nil {
+		if err := recoverProcessCgroup(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Pid, i.Args.Key, i.Runtime.OldCgroupMap); err != nil {
+			log.GetLogger(ctx).Warnf("undo process cgroup error: %s", err.Error())
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (i *NetemInjector) GetResource(ctx context.Context) (map[string]float64, error) {
+	return getTcResource(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, i.Args.Direction)
+}
+
+func (i *NetemInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	if err := execRecover(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Interface, i.Args.Direction); err != nil {
+		return err
+	}
+
+	return recoverProcessCgroup(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Info.Uid, i.Args.Pid, i.Args.Key, i.Runtime.OldCgroupMap)
+}
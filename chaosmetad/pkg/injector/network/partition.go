@@ -0,0 +1,223 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/net"
+)
+
+// iptables -w -I OUTPUT -p tcp -d 10.10.0.0/16 --dport 8080:8080 -m comment --comment chaosmeta_[uid] -j REJECT --reject-with tcp-reset
+
+func init() {
+	injector.Register(TargetNetwork, FaultPartition, func() injector.IInjector { return &PartitionInjector{} })
+}
+
+type PartitionInjector struct {
+	injector.BaseInjector
+	Args    PartitionArgs
+	Runtime PartitionRuntime
+}
+
+type PartitionArgs struct {
+	Interface string `json:"interface,omitempty"`
+	Direction string `json:"direction"`
+	Protocol  string `json:"protocol"`
+	Action    string `json:"action"`
+	Tool      string `json:"tool"`
+	SrcIp     string `json:"src_ip,omitempty"`
+	DstIp     string `json:"dst_ip,omitempty"`
+	SrcPort   string `json:"src_port,omitempty"`
+	DstPort   string `json:"dst_port,omitempty"`
+}
+
+type PartitionRuntime struct{}
+
+func (i *PartitionInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *PartitionInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *PartitionInjector) SetDefault() {
+	i.BaseInjector.SetDefault()
+
+	if i.Args.Direction == "" {
+		i.Args.Direction = DirectionOut
+	}
+
+	if i.Args.Protocol == "" {
+		i.Args.Protocol = ProtocolAll
+	}
+
+	if i.Args.Action == "" {
+		i.Args.Action = ActionDrop
+	}
+
+	if i.Args.Tool == "" {
+		i.Args.Tool = net.FirewallIptables
+		if !cmdexec.SupportCmd(net.FirewallIptables) && cmdexec.SupportCmd(net.FirewallNft) {
+			i.Args.Tool = net.FirewallNft
+		}
+	}
+}
+
+func (i *PartitionInjector) SetOption(cmd *cobra.Command) {
+	// i.BaseInjector.SetOption(cmd)
+
+	cmd.Flags().StringVarP(&i.Args.Direction, "direction", "d", "", fmt.Sprintf("flow direction to inject, support: %s, %s, %s（default %s）. "+
+		"ip and port conditions are described by the out flow, the in flow is matched in reverse", DirectionOut, DirectionIn, DirectionBoth, DirectionOut))
+	cmd.Flags().StringVarP(&i.Args.Protocol, "protocol", "P", "", fmt.Sprintf("filter condition: protocol, support: %s, %s, %s, %s（default %s）",
+		net.ProtocolTCP, net.ProtocolUDP, net.ProtocolICMP, ProtocolAll, ProtocolAll))
+	cmd.Flags().StringVarP(&i.Args.Action, "action", "a", "", fmt.Sprintf("how to block the flow, support: %s, %s(tcp flow will be reset, others will get icmp port unreachable)（default %s）",
+		ActionDrop, ActionReject, ActionDrop))
+	cmd.Flags().StringVar(&i.Args.Tool, "tool", "", fmt.Sprintf("firewall tool to use, support: %s, %s（default %s if exist, otherwise %s）",
+		net.FirewallIptables, net.FirewallNft, net.FirewallIptables, net.FirewallNft))
+
+	cmd.Flags().StringVarP(&i.Args.Interface, "interface", "i", "", "filter condition: network interface. eg: eth0")
+	cmd.Flags().StringVar(&i.Args.SrcIp, "src-ip", "", "filter condition: source ip. eg: 10.10.0.0/16,192.168.2.5,192.168.1.0/24")
+	cmd.Flags().StringVar(&i.Args.DstIp, "dst-ip", "", "filter condition: destination ip. eg: 10.10.0.0/16,192.168.2.5,192.168.1.0/24")
+	cmd.Flags().StringVar(&i.Args.SrcPort, "src-port", "", "filter condition: source port. eg: 8080,9090,12000/8")
+	cmd.Flags().StringVar(&i.Args.DstPort, "dst-port", "", "filter condition: destination port. eg: 8080,9090,12000/8")
+}
+
+func (i *PartitionInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	if i.Args.Direction != DirectionOut && i.Args.Direction != DirectionIn && i.Args.Direction != DirectionBoth {
+		return fmt.Errorf("\"direction\" is not support: %s, only support: %s, %s, %s", i.Args.Direction, DirectionOut, DirectionIn, DirectionBoth)
+	}
+
+	if i.Args.Protocol != net.ProtocolTCP && i.Args.Protocol != net.ProtocolUDP && i.Args.Protocol != net.ProtocolICMP && i.Args.Protocol != ProtocolAll {
+		return fmt.Errorf("\"protocol\" is not support: %s", i.Args.Protocol)
+	}
+
+	if i.Args.Action != ActionDrop && i.Args.Action != ActionReject {
+		return fmt.Errorf("\"action\" is not support: %s, only support: %s, %s", i.Args.Action, ActionDrop, ActionReject)
+	}
+
+	if i.Args.Tool != net.FirewallIptables && i.Args.Tool != net.FirewallNft {
+		return fmt.Errorf("\"tool\" is not support: %s, only support: %s, %s", i.Args.Tool, net.FirewallIptables, net.FirewallNft)
+	}
+
+	if !cmdexec.SupportCmd(i.Args.Tool) {
+		return fmt.Errorf("not support command \"%s\"", i.Args.Tool)
+	}
+
+	if i.Args.SrcPort != "" || i.Args.DstPort != "" {
+		if i.Args.Protocol == net.ProtocolICMP {
+			return fmt.Errorf("\"src-port\" and \"dst-port\" are not support for protocol: %s", net.ProtocolICMP)
+		}
+	}
+
+	rules, err := i.getRules()
+	if err != nil {
+		return err
+	}
+
+	if len(rules) > net.MaxRuleCount {
+		return fmt.Errorf("too many rules: %d, should not larger than %d, please reduce the number of ports", len(rules), net.MaxRuleCount)
+	}
+
+	return nil
+}
+
+// getRules generate the rules of out flow from the args, and the rules of in flow by reversing the ip and port conditions
+func (i *PartitionInjector) getRules() ([]*net.FirewallRule, error) {
+	var srcIpList, dstIpList, srcPortList, dstPortList []string
+	var err error
+	if i.Args.SrcIp != "" {
+		if srcIpList, err = net.GetValidIPList(i.Args.SrcIp, true); err != nil {
+			return nil, fmt.Errorf("\"src-ip\"[%s] is invalid: %s", i.Args.SrcIp, err.Error())
+		}
+	}
+
+	if i.Args.DstIp != "" {
+		if dstIpList, err = net.GetValidIPList(i.Args.DstIp, true); err != nil {
+			return nil, fmt.Errorf("\"dst-ip\"[%s] is invalid: %s", i.Args.DstIp, err.Error())
+		}
+	}
+
+	srcPortList, dstPortList = []string{""}, []string{""}
+	if i.Args.SrcPort != "" {
+		if srcPortList, err = net.GetValidPortRangeList(i.Args.SrcPort); err != nil {
+			return nil, fmt.Errorf("\"src-port\"[%s] is invalid: %s", i.Args.SrcPort, err.Error())
+		}
+	}
+
+	if i.Args.DstPort != "" {
+		if dstPortList, err = net.GetValidPortRangeList(i.Args.DstPort); err != nil {
+			return nil, fmt.Errorf("\"dst-port\"[%s] is invalid: %s", i.Args.DstPort, err.Error())
+		}
+	}
+
+	protocolList := []string{i.Args.Protocol}
+	if i.Args.Protocol == ProtocolAll {
+		if i.Args.SrcPort != "" || i.Args.DstPort != "" {
+			protocolList = []string{net.ProtocolTCP, net.ProtocolUDP}
+		} else if i.Args.Action == ActionReject {
+			// tcp flow should be reset, so match it before the others
+			protocolList = []string{net.ProtocolTCP, ""}
+		} else {
+			protocolList = []string{""}
+		}
+	}
+
+	var rules []*net.FirewallRule
+	for _, protocol := range protocolList {
+		for _, srcPort := range srcPortList {
+			for _, dstPort := range dstPortList {
+				if i.Args.Direction == DirectionOut || i.Args.Direction == DirectionBoth {
+					rules = append(rules, &net.FirewallRule{Chain: net.ChainOutput, Interface: i.Args.Interface, Protocol: protocol,
+						SrcIp: srcIpList, DstIp: dstIpList, SrcPort: srcPort, DstPort: dstPort, Reject: i.Args.Action == ActionReject})
+				}
+
+				if i.Args.Direction == DirectionIn || i.Args.Direction == DirectionBoth {
+					rules = append(rules, &net.FirewallRule{Chain: net.ChainInput, Interface: i.Args.Interface, Protocol: protocol,
+						SrcIp: dstIpList, DstIp: srcIpList, SrcPort: dstPort, DstPort: srcPort, Reject: i.Args.Action == ActionReject})
+				}
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+func (i *PartitionInjector) Inject(ctx context.Context) error {
+	rules, err := i.getRules()
+	if err != nil {
+		return fmt.Errorf("get rules error: %s", err.Error())
+	}
+
+	return net.AddFirewallRules(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Tool, net.GetFirewallTag(i.Info.Uid), rules)
+}
+
+func (i *PartitionInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	return net.ClearFirewallRules(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Tool, net.GetFirewallTag(i.Info.Uid))
+}
@@ -0,0 +1,292 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package injector
+
+import (
+	"context"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/storage"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/errutil"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the action to take for an experiment left over after daemon start
+const (
+	actionNone     = "none"
+	actionRecover  = "recover"
+	actionFlapping = "flapping"
+	actionSleep    = "sleep"
+)
+
+const (
+	// InjectStaleTimeout the unfinished inject is regarded as orphaned after this time even if its process still exists,
+	// the pid may have been reused
+	InjectStaleTimeout = "10m"
+	// injectPidPrefix the pid of the injecting process is recorded in the message of the "start" inject journal
+	injectPidPrefix = "pid: "
+)
+
+/*ProcessReconcile
+  @Description: check the injected experiments when daemon start, handle the experiments left over by a crash of chaosmetad or a reboot of host:
+  1. the inject is not finished(status is still "created") and orphaned: recover it, the fault may have been partially
+  injected. it is orphaned if the injecting process is gone or it has not finished for InjectStaleTimeout
+  2. the last recover is not finished(interrupted or failed): resume it
+  3. the timeout has elapsed: recover it
+  4. the timeout has not elapsed but the sleep recover process is gone: start a new one with the remaining time
+  5. the flapping process is gone: start a new one, it will continue the cycles or finish the experiment by itself
+*/
+func ProcessReconcile(ctx context.Context) {
+	logger := log.GetLogger(ctx)
+
+	db, err := storage.GetExperimentStore()
+	if err != nil {
+		logger.Errorf("reconcile error, connect db error: %s", err.Error())
+		return
+	}
+
+	for _, status := range []string{utils.StatusCreated, utils.StatusSuccess} {
+		exps, err := db.GetByStatus(status)
+		if err != nil {
+			logger.Errorf("reconcile error, query experiments of status[%s] error: %s", status, err.Error())
+			continue
+		}
+
+		logger.Infof("reconcile %d experiments of status[%s]", len(exps), status)
+		for _, exp := range exps {
+			if err := reconcileExperiment(ctx, exp); err != nil {
+				logger.Warnf("reconcile experiment[%s] error: %s", exp.Uid, err.Error())
+			}
+		}
+	}
+}
+
+func reconcileExperiment(ctx context.Context, exp *storage.Experiment) error {
+	journal, err := storage.GetJournalStore()
+	if err != nil {
+		return fmt.Errorf("connect journal error: %s", err.Error())
+	}
+
+	lastInject, err := journal.GetLastByAction(exp.Uid, storage.JournalInject)
+	if err != nil {
+		return fmt.Errorf("query last inject journal error: %s", err.Error())
+	}
+
+	lastRecover, err := journal.GetLastByAction(exp.Uid, storage.JournalRecover)
+	if err != nil {
+		return fmt.Errorf("query last recover journal error: %s", err.Error())
+	}
+
+	action, remain, reason, err := getReconcileAction(exp, lastInject, lastRecover, time.Now(), isInjectPidAlive)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case actionRecover:
+		return reconcileRecover(ctx, exp.Uid, reason)
+	case actionFlapping:
+		return reconcileFlapping(ctx, exp.Uid)
+	case actionSleep:
+		return reconcileSleep(ctx, exp.Uid, remain)
+	default:
+		if reason != "" {
+			log.GetLogger(ctx).Infof("experiment[%s]: %s, skip it", exp.Uid, reason)
+		}
+		return nil
+	}
+}
+
+// getReconcileAction decide how to handle an experiment by its status and journal, "remain" is only valid for
+// the "sleep" action
+func getReconcileAction(exp *storage.Experiment, lastInject, lastRecover *storage.Journal, now time.Time, isPidAlive func(pid int) bool) (action string, remain int64, reason string, err error) {
+	if exp.Status == utils.StatusCreated {
+		return getUnfinishedInjectAction(exp, lastInject, now, isPidAlive)
+	}
+
+	if exp.Status != utils.StatusSuccess {
+		return actionNone, 0, "", nil
+	}
+
+	if lastRecover != nil && lastRecover.Phase != storage.JournalSuccess {
+		return actionRecover, 0, fmt.Sprintf("resume unfinished recover, last phase: %s", lastRecover.Phase), nil
+	}
+
+	if exp.Interval != "" {
+		return actionFlapping, 0, "", nil
+	}
+
+	if exp.Timeout == "" {
+		return actionNone, 0, "", nil
+	}
+
+	startTime := exp.CreateTime
+	if lastInject != nil && lastInject.Phase == storage.JournalSuccess {
+		startTime = lastInject.CreateTime
+	}
+
+	remain, err = getRemainSecond(startTime, exp.Timeout, now)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("calculate remaining time error: %s", err.Error())
+	}
+
+	if remain <= 0 {
+		return actionRecover, 0, fmt.Sprintf("timeout[%s] has elapsed since %s", exp.Timeout, startTime), nil
+	}
+
+	return actionSleep, remain, "", nil
+}
+
+// getUnfinishedInjectAction the inject may be still running in another process, eg: a cli process, so it is only
+// recovered when it is orphaned
+func getUnfinishedInjectAction(exp *storage.Experiment, lastInject *storage.Journal, now time.Time, isPidAlive func(pid int) bool) (action string, remain int64, reason string, err error) {
+	var (
+		phase     = "not started"
+		startTime = exp.CreateTime
+		pid       = utils.NoPid
+	)
+
+	if lastInject != nil {
+		phase, startTime, pid = lastInject.Phase, lastInject.CreateTime, parseInjectPid(lastInject.Message)
+	}
+
+	remain, err = getRemainSecond(startTime, InjectStaleTimeout, now)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("calculate elapsed time of inject error: %s", err.Error())
+	}
+
+	if remain <= 0 {
+		return actionRecover, 0, fmt.Sprintf("resume inject unfinished for %s since %s, last phase: %s", InjectStaleTimeout, startTime, phase), nil
+	}
+
+	if pid == utils.NoPid {
+		return actionNone, 0, fmt.Sprintf("inject started at %s may be still running, last phase: %s", startTime, phase), nil
+	}
+
+	if isPidAlive(pid) {
+		return actionNone, 0, fmt.Sprintf("inject is still running in process[%d], last phase: %s", pid, phase), nil
+	}
+
+	return actionRecover, 0, fmt.Sprintf("resume inject whose process[%d] is gone, last phase: %s", pid, phase), nil
+}
+
+func getInjectPidMsg(pid int) string {
+	return fmt.Sprintf("%s%d", injectPidPrefix, pid)
+}
+
+// parseInjectPid return utils.NoPid if the pid is not recorded, eg: the journal written by an old version
+func parseInjectPid(msg string) int {
+	if !strings.HasPrefix(msg, injectPidPrefix) {
+		return utils.NoPid
+	}
+
+	pid, err := strconv.Atoi(strings.TrimPrefix(msg, injectPidPrefix))
+	if err != nil || pid <= 0 {
+		return utils.NoPid
+	}
+
+	return pid
+}
+
+// isInjectPidAlive reconcile runs before the daemon serves, so the inject recorded with the pid of daemon itself is
+// left by a previous daemon whose pid is reused
+func isInjectPidAlive(pid int) bool {
+	if pid == os.Getpid() {
+		return false
+	}
+
+	exist, err := process.ExistPid(context.Background(), pid)
+	return err != nil || exist
+}
+
+func reconcileSleep(ctx context.Context, uid string, remain int64) error {
+	isSleepExist, err := cmdexec.ExistSleepRecover(ctx, uid)
+	if err != nil {
+		return fmt.Errorf("check sleep recover process error: %s", err.Error())
+	}
+
+	if isSleepExist {
+		return nil
+	}
+
+	msg := fmt.Sprintf("sleep recover process is gone, restart it with remaining time: %ds", remain)
+	log.GetLogger(ctx).Infof("experiment[%s]: %s", uid, msg)
+	if err := cmdexec.StartSleepRecover(ctx, remain, uid); err != nil {
+		errMsg := fmt.Sprintf("restart sleep recover process error: %s", err.Error())
+		appendJournal(ctx, uid, storage.JournalReconcile, storage.JournalError, errMsg)
+		return fmt.Errorf(errMsg)
+	}
+
+	appendJournal(ctx, uid, storage.JournalReconcile, storage.JournalSuccess, msg)
+	return nil
+}
+
+func reconcileFlapping(ctx context.Context, uid string) error {
+	isFlappingExist, err := cmdexec.ExistFlapping(ctx, uid)
+	if err != nil {
+		return fmt.Errorf("check flapping process error: %s", err.Error())
+	}
+
+	if isFlappingExist {
+		return nil
+	}
+
+	msg := "flapping process is gone, restart it"
+	log.GetLogger(ctx).Infof("experiment[%s]: %s", uid, msg)
+	if err := cmdexec.StartFlapping(ctx, uid); err != nil {
+		errMsg := fmt.Sprintf("restart flapping process error: %s", err.Error())
+		appendJournal(ctx, uid, storage.JournalReconcile, storage.JournalError, errMsg)
+		return fmt.Errorf(errMsg)
+	}
+
+	appendJournal(ctx, uid, storage.JournalReconcile, storage.JournalSuccess, msg)
+	return nil
+}
+
+func reconcileRecover(ctx context.Context, uid, reason string) error {
+	log.GetLogger(ctx).Infof("experiment[%s]: %s, recover it", uid, reason)
+	appendJournal(ctx, uid, storage.JournalReconcile, storage.JournalStart, reason)
+
+	code, msg := ProcessRecover(ctx, uid)
+	if code != errutil.NoErr {
+		appendJournal(ctx, uid, storage.JournalReconcile, storage.JournalError, msg)
+		return fmt.Errorf("recover error: %s", msg)
+	}
+
+	appendJournal(ctx, uid, storage.JournalReconcile, storage.JournalSuccess, reason)
+	return nil
+}
+
+func getRemainSecond(startTime, timeout string, now time.Time) (int64, error) {
+	start, err := time.ParseInLocation(utils.TimeFormat, startTime, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("start time[%s] is invalid: %s", startTime, err.Error())
+	}
+
+	timeoutSecond, err := utils.GetTimeSecond(timeout)
+	if err != nil {
+		return 0, fmt.Errorf("timeout[%s] is invalid: %s", timeout, err.Error())
+	}
+
+	return timeoutSecond - int64(now.Sub(start).Seconds()), nil
+}
@@ -0,0 +1,206 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package injector
+
+import (
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/storage"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"testing"
+	"time"
+)
+
+func TestGetRemainSecond(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name      string
+		startTime string
+		timeout   string
+		want      int64
+		wantErr   bool
+	}{
+		{name: "not elapsed", startTime: "2023-05-01 11:59:00", timeout: "5m", want: 240},
+		{name: "just elapsed", startTime: "2023-05-01 11:55:00", timeout: "5m", want: 0},
+		{name: "elapsed", startTime: "2023-05-01 10:00:00", timeout: "1h", want: -3600},
+		{name: "seconds", startTime: "2023-05-01 11:59:50", timeout: "30", want: 20},
+		{name: "bad start time", startTime: "2023/05/01 11:59:00", timeout: "5m", wantErr: true},
+		{name: "bad timeout", startTime: "2023-05-01 11:59:00", timeout: "5x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getRemainSecond(tt.startTime, tt.timeout, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getRemainSecond() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("getRemainSecond() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetReconcileAction(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.Local)
+	isPidAlive := func(pid int) bool {
+		return pid == 100
+	}
+	tests := []struct {
+		name        string
+		exp         *storage.Experiment
+		lastInject  *storage.Journal
+		lastRecover *storage.Journal
+		wantAction  string
+		wantRemain  int64
+		wantErr     bool
+	}{
+		{
+			name:       "interrupted before inject journal",
+			exp:        &storage.Experiment{Status: utils.StatusCreated, CreateTime: "2023-05-01 11:00:00"},
+			wantAction: actionRecover,
+		},
+		{
+			name:       "inject journal not written yet",
+			exp:        &storage.Experiment{Status: utils.StatusCreated, CreateTime: "2023-05-01 11:59:00"},
+			wantAction: actionNone,
+		},
+		{
+			name:       "inject process is gone",
+			exp:        &storage.Experiment{Status: utils.StatusCreated, Timeout: "10m", CreateTime: "2023-05-01 11:59:00"},
+			lastInject: &storage.Journal{Phase: storage.JournalStart, Message: "pid: 200", CreateTime: "2023-05-01 11:59:00"},
+			wantAction: actionRecover,
+		},
+		{
+			name:       "inject is running",
+			exp:        &storage.Experiment{Status: utils.StatusCreated, Timeout: "10m", CreateTime: "2023-05-01 11:59:00"},
+			lastInject: &storage.Journal{Phase: storage.JournalStart, Message: "pid: 100", CreateTime: "2023-05-01 11:59:00"},
+			wantAction: actionNone,
+		},
+		{
+			name:       "inject process is alive but stale",
+			exp:        &storage.Experiment{Status: utils.StatusCreated, CreateTime: "2023-05-01 11:00:00"},
+			lastInject: &storage.Journal{Phase: storage.JournalStart, Message: "pid: 100", CreateTime: "2023-05-01 11:50:00"},
+			wantAction: actionRecover,
+		},
+		{
+			name:       "inject without pid",
+			exp:        &storage.Experiment{Status: utils.StatusCreated, CreateTime: "2023-05-01 11:59:00"},
+			lastInject: &storage.Journal{Phase: storage.JournalStart, CreateTime: "2023-05-01 11:59:00"},
+			wantAction: actionNone,
+		},
+		{
+			name:       "inject without pid is stale",
+			exp:        &storage.Experiment{Status: utils.StatusCreated, CreateTime: "2023-05-01 11:00:00"},
+			lastInject: &storage.Journal{Phase: storage.JournalStart, CreateTime: "2023-05-01 11:00:00"},
+			wantAction: actionRecover,
+		},
+		{
+			name:    "inject with bad time",
+			exp:     &storage.Experiment{Status: utils.StatusCreated, CreateTime: "unknown"},
+			wantErr: true,
+		},
+		{
+			name:       "inject failed",
+			exp:        &storage.Experiment{Status: utils.StatusError},
+			lastInject: &storage.Journal{Phase: storage.JournalError},
+			wantAction: actionNone,
+		},
+		{
+			name:       "destroyed",
+			exp:        &storage.Experiment{Status: utils.StatusDestroyed, Timeout: "1m", CreateTime: "2023-05-01 10:00:00"},
+			wantAction: actionNone,
+		},
+		{
+			name:        "interrupted during recover",
+			exp:         &storage.Experiment{Status: utils.StatusSuccess},
+			lastInject:  &storage.Journal{Phase: storage.JournalSuccess},
+			lastRecover: &storage.Journal{Phase: storage.JournalStart},
+			wantAction:  actionRecover,
+		},
+		{
+			name:        "recover failed",
+			exp:         &storage.Experiment{Status: utils.StatusSuccess, Interval: "1m"},
+			lastRecover: &storage.Journal{Phase: storage.JournalError},
+			wantAction:  actionRecover,
+		},
+		{
+			name:       "flapping",
+			exp:        &storage.Experiment{Status: utils.StatusSuccess, Interval: "1m", Timeout: "10m", CreateTime: "2023-05-01 10:00:00"},
+			wantAction: actionFlapping,
+		},
+		{
+			name:       "no timeout",
+			exp:        &storage.Experiment{Status: utils.StatusSuccess, CreateTime: "2023-05-01 10:00:00"},
+			wantAction: actionNone,
+		},
+		{
+			name:       "timeout elapsed",
+			exp:        &storage.Experiment{Status: utils.StatusSuccess, Timeout: "1h", CreateTime: "2023-05-01 10:00:00"},
+			wantAction: actionRecover,
+		},
+		{
+			name:       "timeout counted from inject success",
+			exp:        &storage.Experiment{Status: utils.StatusSuccess, Timeout: "1h", CreateTime: "2023-05-01 10:00:00"},
+			lastInject: &storage.Journal{Phase: storage.JournalSuccess, CreateTime: "2023-05-01 11:30:00"},
+			wantAction: actionSleep,
+			wantRemain: 1800,
+		},
+		{
+			name:       "timeout counted from create time",
+			exp:        &storage.Experiment{Status: utils.StatusSuccess, Timeout: "1h", CreateTime: "2023-05-01 11:30:00"},
+			lastInject: &storage.Journal{Phase: storage.JournalStart, CreateTime: "2023-05-01 11:00:00"},
+			wantAction: actionSleep,
+			wantRemain: 1800,
+		},
+		{
+			name:    "bad create time",
+			exp:     &storage.Experiment{Status: utils.StatusSuccess, Timeout: "1h", CreateTime: "unknown"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, remain, _, err := getReconcileAction(tt.exp, tt.lastInject, tt.lastRecover, now, isPidAlive)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getReconcileAction() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if action != tt.wantAction || remain != tt.wantRemain {
+				t.Errorf("getReconcileAction() = %v, %v, want %v, %v", action, remain, tt.wantAction, tt.wantRemain)
+			}
+		})
+	}
+}
+
+func TestParseInjectPid(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want int
+	}{
+		{msg: getInjectPidMsg(1234), want: 1234},
+		{msg: "", want: utils.NoPid},
+		{msg: "pid: x", want: utils.NoPid},
+		{msg: "pid: 0", want: utils.NoPid},
+		{msg: "inject error: x", want: utils.NoPid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			if got := parseInjectPid(tt.msg); got != tt.want {
+				t.Errorf("parseInjectPid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
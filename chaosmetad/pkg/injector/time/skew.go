@@ -0,0 +1,175 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package time
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/ptrace"
+	"strings"
+	"time"
+)
+
+func init() {
+	injector.Register(TargetTime, FaultSkew, func() injector.IInjector { return &SkewInjector{} })
+}
+
+type SkewInjector struct {
+	injector.BaseInjector
+	Args    SkewArgs
+	Runtime SkewRuntime
+}
+
+type SkewArgs struct {
+	Pid      int    `json:"pid,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Offset   string `json:"offset"`
+	ClockIds string `json:"clock_ids"`
+}
+
+type SkewRuntime struct {
+	PatchList []*ptrace.ClockPatch `json:"patch_list,omitempty"`
+}
+
+func (i *SkewInjector) GetArgs() interface{} {
+	return &i.Args
+}
+
+func (i *SkewInjector) GetRuntime() interface{} {
+	return &i.Runtime
+}
+
+func (i *SkewInjector) SetDefault() {
+	i.BaseInjector.SetDefault()
+
+	if i.Args.ClockIds == "" {
+		i.Args.ClockIds = DefaultClockIds
+	}
+}
+
+func (i *SkewInjector) SetOption(cmd *cobra.Command) {
+	// i.BaseInjector.SetOption(cmd)
+
+	cmd.Flags().IntVarP(&i.Args.Pid, "pid", "p", 0, "target process's pid")
+	cmd.Flags().StringVarP(&i.Args.Key, "key", "k", "", "the key used to grep to get target process, the effect is equivalent to \"ps -ef | grep [key]\". if \"pid\" provided, \"key\" will be ignored")
+	cmd.Flags().StringVarP(&i.Args.Offset, "offset", "o", "", "time offset seen by target process, can be negative, support unit: \"ns、us、ms、s、m、h\". eg: 2h, -30m, 1h30m")
+	cmd.Flags().StringVarP(&i.Args.ClockIds, "clock-ids", "c", "", fmt.Sprintf("clocks to skew, separated by \",\". eg: CLOCK_REALTIME,CLOCK_MONOTONIC（default %s）", DefaultClockIds))
+}
+
+func (i *SkewInjector) Validator(ctx context.Context) error {
+	if err := i.BaseInjector.Validator(ctx); err != nil {
+		return err
+	}
+
+	offset, err := time.ParseDuration(i.Args.Offset)
+	if err != nil {
+		return fmt.Errorf("\"offset\"[%s] is invalid: %s", i.Args.Offset, err.Error())
+	}
+
+	if offset == 0 {
+		return fmt.Errorf("\"offset\" should not be 0")
+	}
+
+	if _, err := getClockMask(i.Args.ClockIds); err != nil {
+		return fmt.Errorf("\"clock-ids\"[%s] is invalid: %s", i.Args.ClockIds, err.Error())
+	}
+
+	if _, err := process.GetPidListByPidOrKeyInContainer(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Pid, i.Args.Key); err != nil {
+		return fmt.Errorf("get target process error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// getClockMask convert clock id list like "CLOCK_REALTIME,CLOCK_MONOTONIC" to bitmap
+func getClockMask(clockIds string) (uint64, error) {
+	var mask uint64
+	for _, unit := range strings.Split(clockIds, ",") {
+		unit = strings.ToUpper(strings.TrimSpace(unit))
+		id, ok := ptrace.ClockIdMap[unit]
+		if !ok {
+			return 0, fmt.Errorf("clock id %s is not support", unit)
+		}
+
+		mask |= 1 << id
+	}
+
+	return mask, nil
+}
+
+func (i *SkewInjector) Inject(ctx context.Context) error {
+	offset, _ := time.ParseDuration(i.Args.Offset)
+	mask, _ := getClockMask(i.Args.ClockIds)
+
+	pidList, err := process.GetPidListByPidOrKeyInContainer(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, i.Args.Pid, i.Args.Key)
+	if err != nil {
+		return fmt.Errorf("get target process error: %s", err.Error())
+	}
+
+	hostPidList, err := process.GetHostPidList(ctx, i.Info.ContainerRuntime, i.Info.ContainerId, pidList)
+	if err != nil {
+		return fmt.Errorf("get pid in host of target process error: %s", err.Error())
+	}
+
+	for _, pid := range hostPidList {
+		if dryrun.Record(ctx, dryrun.KindPtrace, dryrun.TargetHost, fmt.Sprintf("patch vdso clock functions of process[%d] with offset[%s] clock mask[%d]", pid, i.Args.Offset, mask)) {
+			i.Runtime.PatchList = append(i.Runtime.PatchList, &ptrace.ClockPatch{Pid: pid})
+			continue
+		}
+
+		patch, err := ptrace.InjectClockSkew(pid, offset.Nanoseconds(), mask)
+		if err != nil {
+			if err := i.Recover(ctx); err != nil {
+				log.GetLogger(ctx).Warnf("undo error: %s", err.Error())
+			}
+
+			return fmt.Errorf("skew clock of process[%d] error: %s", pid, err.Error())
+		}
+
+		i.Runtime.PatchList = append(i.Runtime.PatchList, patch)
+	}
+
+	return nil
+}
+
+func (i *SkewInjector) Recover(ctx context.Context) error {
+	if i.BaseInjector.Recover(ctx) == nil {
+		return nil
+	}
+
+	var errMsg string
+	for _, patch := range i.Runtime.PatchList {
+		if dryrun.Record(ctx, dryrun.KindPtrace, dryrun.TargetHost, fmt.Sprintf("restore vdso clock functions of process[%d]", patch.Pid)) {
+			continue
+		}
+
+		if err := ptrace.RecoverClockSkew(patch); err != nil {
+			errMsg = fmt.Sprintf("%s recover clock of process[%d] error: %s;", errMsg, patch.Pid, err.Error())
+		}
+	}
+
+	if errMsg != "" {
+		return fmt.Errorf(errMsg)
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/errutil"
+	"net/http"
+	"time"
+)
+
+const (
+	Namespace = "chaosmetad"
+
+	LabelTarget   = "target"
+	LabelFault    = "fault"
+	LabelResult   = "result"
+	LabelStatus   = "status"
+	LabelResource = "resource"
+
+	ResultSuccess = "success"
+	ResultFailed  = "failed"
+
+	ResourceCpuCores   = "cpu_cores"
+	ResourceMemBytes   = "mem_bytes"
+	ResourceDiskBytes  = "disk_bytes"
+	ResourceDiskInodes = "disk_inodes"
+	ResourceTcRules    = "tc_rules"
+)
+
+var (
+	// Registry only the metrics of chaosmetad, go runtime and process are exported
+	Registry = prometheus.NewRegistry()
+
+	durationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+	injectTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "inject_total",
+		Help:      "Total number of inject requests by target, fault and result.",
+	}, []string{LabelTarget, LabelFault, LabelResult})
+
+	injectDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "inject_duration_seconds",
+		Help:      "Duration of inject requests by target and fault.",
+		Buckets:   durationBuckets,
+	}, []string{LabelTarget, LabelFault})
+
+	recoverTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "recover_total",
+		Help:      "Total number of recover requests by target, fault and result.",
+	}, []string{LabelTarget, LabelFault, LabelResult})
+
+	recoverDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "recover_duration_seconds",
+		Help:      "Duration of recover requests by target and fault.",
+		Buckets:   durationBuckets,
+	}, []string{LabelTarget, LabelFault})
+
+	// ExperimentDesc the number of experiments by status, collected from db when scraped
+	ExperimentDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "experiments"),
+		"Number of experiments by status.", []string{LabelStatus}, nil)
+
+	// ResourceDesc the amount of resource occupied by the running experiments, collected when scraped
+	ResourceDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "injected_resource"),
+		"Amount of resource currently occupied by the running experiments, eg: cpu_cores, mem_bytes, disk_bytes, disk_inodes, tc_rules.",
+		[]string{LabelTarget, LabelFault, LabelResource}, nil)
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		injectTotal, injectDuration, recoverTotal, recoverDuration,
+	)
+}
+
+func getResult(code int) string {
+	if code == errutil.NoErr {
+		return ResultSuccess
+	}
+
+	return ResultFailed
+}
+
+func ObserveInject(target, fault string, code int, cost time.Duration) {
+	injectTotal.WithLabelValues(target, fault, getResult(code)).Inc()
+	injectDuration.WithLabelValues(target, fault).Observe(cost.Seconds())
+}
+
+func ObserveRecover(target, fault string, code int, cost time.Duration) {
+	recoverTotal.WithLabelValues(target, fault, getResult(code)).Inc()
+	recoverDuration.WithLabelValues(target, fault).Observe(cost.Seconds())
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -26,6 +26,9 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/errutil"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/web/handler"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/web/model"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 type OptionExpQuery struct {
@@ -109,7 +112,8 @@ func printTable(ctx context.Context, exps []*storage.Experiment, total int64, if
 			var aData []interface{}
 			if ifAll {
 				aData = []interface{}{exp.Uid, exp.Status, exp.Target, exp.Fault, exp.Args, exp.Creator, exp.Runtime,
-					exp.ContainerId, exp.ContainerRuntime, exp.Timeout, exp.Error, exp.CreateTime, exp.UpdateTime}
+					handler.ExpToExperimentDataUnit(exp).Level, handler.GetCycleInfo(exp), exp.ContainerId, exp.ContainerRuntime, exp.Timeout,
+					exp.Error, exp.CreateTime, exp.UpdateTime}
 			} else {
 				aData = []interface{}{exp.Uid, exp.Status, exp.Target, exp.Fault, exp.Args}
 			}
@@ -119,7 +123,7 @@ func printTable(ctx context.Context, exps []*storage.Experiment, total int64, if
 
 		t := gotabulate.Create(data)
 		if ifAll {
-			t.SetHeaders([]string{"UID", "STATUS", "TARGET", "FAULT", "ARGS", "CREATOR", "RUNTIME",
+			t.SetHeaders([]string{"UID", "STATUS", "TARGET", "FAULT", "ARGS", "CREATOR", "RUNTIME", "LEVEL", "CYCLE",
 				"CONTAINER_ID", "CONTAINER_RUNTIME", "TIMEOUT", "ERROR", "CREATE_TIME", "UPDATE_TIME"})
 		} else {
 			t.SetHeaders([]string{"UID", "STATUS", "TARGET", "FAULT", "ARGS"})
@@ -132,3 +136,58 @@ func printTable(ctx context.Context, exps []*storage.Experiment, total int64, if
 
 	logger.Infof("total count of experiments: %d\n%s\n", total, formatData)
 }
+
+// WatchExpByOption print the matched experiments first, then print the events of them until interrupted
+func WatchExpByOption(ctx context.Context, o *OptionExpQuery, ifAll bool, format string) {
+	events, err := storage.GetEventStore()
+	if err != nil {
+		errutil.SolveErr(ctx, errutil.DBErr, err.Error())
+	}
+
+	// get last id before query, so that no event is missed between the query and the watch
+	lastId, err := events.GetLastId()
+	if err != nil {
+		errutil.SolveErr(ctx, errutil.DBErr, fmt.Sprintf("get last event id error: %s", err.Error()))
+	}
+
+	PrintExpByOption(ctx, o, ifAll, format)
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	filter := &storage.EventFilter{Uid: o.Uid, Target: o.Target, Fault: o.Fault}
+	if err := events.Watch(watchCtx, lastId, filter, handler.WatchPollInterval, func(event *storage.Event) error {
+		printEvent(ctx, event, format)
+		return nil
+	}); err != nil {
+		errutil.SolveErr(ctx, errutil.DBErr, err.Error())
+	}
+}
+
+func printEvent(ctx context.Context, event *storage.Event, format string) {
+	var eventStr string
+	if format == JsonFormat {
+		reBytes, err := json.Marshal(handler.EventToExperimentEvent(event))
+		if err != nil {
+			errutil.SolveErr(ctx, errutil.InternalErr, fmt.Sprintf("event change to string error: %s", err.Error()))
+		}
+		eventStr = string(reBytes)
+	} else {
+		eventStr = fmt.Sprintf("%s\t%s\t%s\t%s\t%s", event.CreateTime, event.Uid, event.Type, event.Target, event.Fault)
+		if event.Error != "" {
+			eventStr = fmt.Sprintf("%s\t%s", eventStr, event.Error)
+		}
+	}
+
+	if log.Path != "" {
+		log.GetLogger(ctx).Info(eventStr)
+	} else {
+		fmt.Println(eventStr)
+	}
+}
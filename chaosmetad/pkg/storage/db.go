@@ -27,10 +27,24 @@ import (
 
 const storageFile = "chaosmetad.dat"
 
+var globalDB *dbStorage
+
 type dbStorage struct {
 	*gorm.DB
 }
 
+func getDBStorage() (*dbStorage, error) {
+	if globalDB == nil {
+		db, err := newDBStorage()
+		if err != nil {
+			return nil, err
+		}
+		globalDB = db
+	}
+
+	return globalDB, nil
+}
+
 func newDBStorage() (*dbStorage, error) {
 	// TODO: db path can be config
 	dsn := path.Join(utils.GetRunPath(), storageFile)
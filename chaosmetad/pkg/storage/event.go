@@ -0,0 +1,198 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"sync"
+	"time"
+)
+
+// event type
+const (
+	EventCreated    = "created"
+	EventInjected   = "injected"
+	EventRecovering = "recovering"
+	EventDestroyed  = "destroyed"
+	EventError      = "error"
+	// EventCycleOn and EventCycleOff the fault is injected and recovered in a cycle of flapping mode
+	EventCycleOn  = "cycle-on"
+	EventCycleOff = "cycle-off"
+)
+
+const watchBatchSize = 100
+
+var (
+	globalEventStorage *eventStore
+
+	// eventNotify is closed and replaced when an event is appended by this process, to wake up the watchers
+	eventNotify = make(chan struct{})
+	notifyMutex sync.Mutex
+)
+
+type eventStore struct {
+	db *dbStorage
+}
+
+type EventFilter struct {
+	Uid    string
+	Target string
+	Fault  string
+}
+
+func GetEventStore() (*eventStore, error) {
+	if globalEventStorage == nil {
+		db, err := getDBStorage()
+		if err != nil {
+			return nil, fmt.Errorf("getDBStorage error: %s", err.Error())
+		}
+		globalEventStorage, err = newEventStore(db)
+		if err != nil {
+			return nil, fmt.Errorf("newEventStore error: %s", err.Error())
+		}
+	}
+
+	return globalEventStorage, nil
+}
+
+func newEventStore(db *dbStorage) (*eventStore, error) {
+	if err := db.AutoMigrate(&Event{}); err != nil {
+		return nil, err
+	}
+
+	return &eventStore{db}, nil
+}
+
+// getEventType the event of experiment status
+func getEventType(status string) string {
+	switch status {
+	case utils.StatusCreated:
+		return EventCreated
+	case utils.StatusSuccess:
+		return EventInjected
+	case utils.StatusDestroyed:
+		return EventDestroyed
+	default:
+		return EventError
+	}
+}
+
+// appendEvent event is an auxiliary record, so failure of writing event will not interrupt the update of experiment
+func appendEvent(uid, target, fault, eventType, errMsg string) {
+	events, err := GetEventStore()
+	if err != nil {
+		return
+	}
+
+	_ = events.Append(uid, target, fault, eventType, errMsg)
+}
+
+func (e *eventStore) Append(uid, target, fault, eventType, errMsg string) error {
+	if err := e.db.Model(Event{}).
+		Create(&Event{
+			Uid:        uid,
+			Target:     target,
+			Fault:      fault,
+			Type:       eventType,
+			Error:      errMsg,
+			CreateTime: time.Now().Format(utils.TimeFormat),
+		}).
+		Error; err != nil {
+		return err
+	}
+
+	notifyMutex.Lock()
+	close(eventNotify)
+	eventNotify = make(chan struct{})
+	notifyMutex.Unlock()
+	return nil
+}
+
+// GetLastId return 0 if there is no event
+func (e *eventStore) GetLastId() (uint, error) {
+	var lastId uint
+	if err := e.db.Model(Event{}).
+		Select("COALESCE(MAX(id), 0)").
+		Scan(&lastId).
+		Error; err != nil {
+		return 0, err
+	}
+
+	return lastId, nil
+}
+
+func (e *eventStore) ListAfter(lastId uint, filter *EventFilter, limit int) ([]*Event, error) {
+	var events []*Event
+	db := e.db.Model(Event{}).Where("id > ?", lastId)
+
+	if filter.Uid != "" {
+		db = db.Where("uid = ?", filter.Uid)
+	}
+
+	if filter.Target != "" {
+		db = db.Where("target = ?", filter.Target)
+	}
+
+	if filter.Fault != "" {
+		db = db.Where("fault = ?", filter.Fault)
+	}
+
+	if err := db.
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Watch call f with the events after lastId in order until ctx is done or f returns error. the events appended by this
+// process are delivered at once, the ones appended by other processes(eg: cli, delay recover) are found by polling db
+func (e *eventStore) Watch(ctx context.Context, lastId uint, filter *EventFilter, interval time.Duration, f func(event *Event) error) error {
+	for {
+		notifyMutex.Lock()
+		notify := eventNotify
+		notifyMutex.Unlock()
+
+		events, err := e.ListAfter(lastId, filter, watchBatchSize)
+		if err != nil {
+			return fmt.Errorf("list events after id[%d] error: %s", lastId, err.Error())
+		}
+
+		for _, event := range events {
+			if err := f(event); err != nil {
+				return err
+			}
+			lastId = event.Id
+		}
+
+		if len(events) == watchBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-notify:
+		case <-time.After(interval):
+		}
+	}
+}
@@ -32,9 +32,9 @@ type experimentStore struct {
 
 func GetExperimentStore() (*experimentStore, error) {
 	if globalExpStorage == nil {
-		db, err := newDBStorage()
+		db, err := getDBStorage()
 		if err != nil {
-			return nil, fmt.Errorf("newDBStorage error: %s", err.Error())
+			return nil, fmt.Errorf("getDBStorage error: %s", err.Error())
 		}
 		globalExpStorage, err = newExperimentStore(db)
 		if err != nil {
@@ -62,6 +62,7 @@ func (e *experimentStore) Insert(exp *Experiment) error {
 		return err
 	}
 
+	appendEvent(exp.Uid, exp.Target, exp.Fault, EventCreated, "")
 	return nil
 }
 
@@ -74,6 +75,9 @@ func (e *experimentStore) Update(exp *Experiment) error {
 		return err
 	}
 
+	if exp.Status != "" {
+		appendEvent(exp.Uid, exp.Target, exp.Fault, getEventType(exp.Status), exp.Error)
+	}
 	return nil
 }
 
@@ -85,6 +89,7 @@ func (e *experimentStore) UpdateStatus(uid, status string) error {
 		return err
 	}
 
+	e.AppendEvent(uid, getEventType(status), "")
 	return nil
 }
 
@@ -96,9 +101,37 @@ func (e *experimentStore) UpdateStatusAndErr(uid, status, errMsg string) error {
 		return err
 	}
 
+	e.AppendEvent(uid, getEventType(status), errMsg)
 	return nil
 }
 
+// UpdateCycle record the current cycle of flapping mode, the runtime may be changed by the inject of the cycle
+func (e *experimentStore) UpdateCycle(uid string, cycle int, phase, runtime string) error {
+	if err := e.db.Model(Experiment{}).
+		Where("uid = ?", uid).
+		Updates(Experiment{Cycle: cycle, CyclePhase: phase, Runtime: runtime, UpdateTime: time.Now().Format(utils.TimeFormat)}).
+		Error; err != nil {
+		return err
+	}
+
+	if phase == utils.CyclePhaseOn {
+		e.AppendEvent(uid, EventCycleOn, "")
+	} else {
+		e.AppendEvent(uid, EventCycleOff, "")
+	}
+	return nil
+}
+
+// AppendEvent is also used to record the transition which does not change the status of experiment, eg: recovering
+func (e *experimentStore) AppendEvent(uid, eventType, errMsg string) {
+	var target, fault string
+	if exp, err := e.GetByUid(uid); err == nil {
+		target, fault = exp.Target, exp.Fault
+	}
+
+	appendEvent(uid, target, fault, eventType, errMsg)
+}
+
 func (e *experimentStore) GetByUid(uid string) (*Experiment, error) {
 	var exp = &Experiment{}
 	if err := e.db.Model(Experiment{}).
@@ -114,6 +147,40 @@ func (e *experimentStore) GetByUid(uid string) (*Experiment, error) {
 	return exp, nil
 }
 
+func (e *experimentStore) GetByStatus(status string) ([]*Experiment, error) {
+	var exps []*Experiment
+	if err := e.db.Model(Experiment{}).
+		Where("status = ?", status).
+		Order("create_time ASC").
+		Find(&exps).
+		Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	return exps, nil
+}
+
+func (e *experimentStore) CountByStatus() (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := e.db.Model(Experiment{}).
+		Select("status, count(*) as count").
+		Group("status").
+		Scan(&rows).
+		Error; err != nil {
+		return nil, err
+	}
+
+	var countMap = make(map[string]int64)
+	for _, row := range rows {
+		countMap[row.Status] = row.Count
+	}
+
+	return countMap, nil
+}
+
 func (e *experimentStore) QueryByOption(uid, status, target, fault, creator, cr, cId string, offset, limit uint) ([]*Experiment, int64, error) {
 	var exps []*Experiment
 	db := e.db.Model(Experiment{})
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"gorm.io/gorm"
+	"time"
+)
+
+// journal action
+const (
+	JournalInject    = "inject"
+	JournalRecover   = "recover"
+	JournalReconcile = "reconcile"
+	// JournalCycleInject and JournalCycleRecover the inject and recover of each cycle in flapping mode
+	JournalCycleInject  = "cycle-inject"
+	JournalCycleRecover = "cycle-recover"
+)
+
+// journal phase
+const (
+	JournalStart   = "start"
+	JournalSuccess = "success"
+	JournalError   = "error"
+)
+
+var globalJournalStorage *journalStore
+
+type journalStore struct {
+	db *dbStorage
+}
+
+func GetJournalStore() (*journalStore, error) {
+	if globalJournalStorage == nil {
+		db, err := getDBStorage()
+		if err != nil {
+			return nil, fmt.Errorf("getDBStorage error: %s", err.Error())
+		}
+		globalJournalStorage, err = newJournalStore(db)
+		if err != nil {
+			return nil, fmt.Errorf("newJournalStore error: %s", err.Error())
+		}
+	}
+
+	return globalJournalStorage, nil
+}
+
+func newJournalStore(db *dbStorage) (*journalStore, error) {
+	if err := db.AutoMigrate(&Journal{}); err != nil {
+		return nil, err
+	}
+
+	return &journalStore{db}, nil
+}
+
+// Append is the only write operation of journal, records are never updated or deleted
+func (j *journalStore) Append(uid, action, phase, msg string) error {
+	if err := j.db.Model(Journal{}).
+		Create(&Journal{
+			Uid:        uid,
+			Action:     action,
+			Phase:      phase,
+			Message:    msg,
+			CreateTime: time.Now().Format(utils.TimeFormat),
+		}).
+		Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetLastByAction return nil if no record of the action
+func (j *journalStore) GetLastByAction(uid, action string) (*Journal, error) {
+	var record = &Journal{}
+	if err := j.db.Model(Journal{}).
+		Where("uid = ? AND action = ?", uid, action).
+		Order("id DESC").
+		First(record).
+		Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
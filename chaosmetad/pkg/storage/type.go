@@ -30,4 +30,32 @@ type Experiment struct {
 	UpdateTime       string `json:"update_time"`
 	ContainerId      string `json:"container_id"`
 	ContainerRuntime string `json:"container_runtime"`
+	// flapping information, the fault is injected for "OnDuration" in each "Interval" for "Repeat" times
+	Interval   string `json:"interval,omitempty"`
+	OnDuration string `json:"on_duration,omitempty"`
+	Repeat     int    `json:"repeat,omitempty"`
+	Cycle      int    `json:"cycle,omitempty"`
+	CyclePhase string `json:"cycle_phase,omitempty"`
+}
+
+// Journal is an append-only record of the steps executed for an experiment,
+// used to find and resume the recovers that were interrupted by a crash
+type Journal struct {
+	Id         uint   `gorm:"primary_key;autoIncrement" json:"id"`
+	Uid        string `gorm:"index:journal_uid" json:"uid"`
+	Action     string `json:"action"`
+	Phase      string `json:"phase"`
+	Message    string `json:"message"`
+	CreateTime string `json:"create_time"`
+}
+
+// Event is an append-only record of the state transitions of experiments, used to stream the changes to watchers
+type Event struct {
+	Id         uint   `gorm:"primary_key;autoIncrement" json:"id"`
+	Uid        string `gorm:"index:event_uid" json:"uid"`
+	Target     string `json:"target"`
+	Fault      string `json:"fault"`
+	Type       string `json:"type"`
+	Error      string `json:"error,omitempty"`
+	CreateTime string `json:"create_time"`
 }
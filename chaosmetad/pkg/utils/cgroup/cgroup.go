@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"path/filepath"
+	"strings"
 )
 
 func GetBlkioConfig(ctx context.Context, devList []string, rBytes, wBytes string, rIO, wIO int64, cgroupPath string) string {
@@ -47,6 +49,85 @@ func GetBlkioConfig(ctx context.Context, devList []string, rBytes, wBytes string
 	return re[:len(re)-len(utils.CmdSplit)]
 }
 
+// GetIOMaxLimitList the limit lines of cgroup v2, all limits of a device are written to io.max in one line, and the
+// keys not provided keep their current value
+func GetIOMaxLimitList(devList []string, rBytes, wBytes string, rIO, wIO int64) []string {
+	var limit string
+	if rBytes != "" {
+		b, _ := utils.GetBytes(rBytes)
+		limit += fmt.Sprintf(" rbps=%d", b)
+	}
+
+	if wBytes != "" {
+		b, _ := utils.GetBytes(wBytes)
+		limit += fmt.Sprintf(" wbps=%d", b)
+	}
+
+	if rIO != 0 {
+		limit += fmt.Sprintf(" riops=%d", rIO)
+	}
+
+	if wIO != 0 {
+		limit += fmt.Sprintf(" wiops=%d", wIO)
+	}
+
+	var re []string
+	for _, unitDev := range devList {
+		re = append(re, unitDev+limit)
+	}
+
+	return re
+}
+
+// GetIOMaxRecoverList the lines to restore io.max of devList to oldIOMax, which only contains the devices with limits,
+// so the device not in oldIOMax is reset to unlimited
+func GetIOMaxRecoverList(devList []string, oldIOMax string) []string {
+	var oldMap = make(map[string]string)
+	for _, line := range strings.Split(oldIOMax, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		oldMap[fields[0]] = strings.Join(fields, " ")
+	}
+
+	var re []string
+	for _, unitDev := range devList {
+		line, ok := oldMap[unitDev]
+		if !ok {
+			line = fmt.Sprintf("%s rbps=%s wbps=%s riops=%s wiops=%s", unitDev, UnLimitValue, UnLimitValue, UnLimitValue, UnLimitValue)
+		}
+		re = append(re, line)
+	}
+
+	return re
+}
+
+// GetCpuQuotaConfig the config of cgroup v1, the processes in cgroup can use at most "quota" us of cpu time in each
+// "period" us. the period is written first, otherwise the quota may be rejected by the old period
+func GetCpuQuotaConfig(quota, period int64, cgroupPath string) string {
+	return fmt.Sprintf("echo %d > %s/%s%secho %d > %s/%s", period, cgroupPath, CpuCfsPeriodFile, utils.CmdSplit,
+		quota, cgroupPath, CpuCfsQuotaFile)
+}
+
+// GetCpusetConfig the config of cgroup v1, a process can not be moved into a cpuset cgroup whose "cpuset.mems" is
+// empty, so it is inherited from the parent cgroup
+func GetCpusetConfig(cpus, cgroupPath string) string {
+	return fmt.Sprintf("echo %s > %s/%s%scat %s/%s > %s/%s", cpus, cgroupPath, CpusetCoreFile, utils.CmdSplit,
+		filepath.Dir(cgroupPath), CpusetMemsFile, cgroupPath, CpusetMemsFile)
+}
+
+// GetCpuMaxValue the value of cpu.max in cgroup v2, the processes in cgroup can use at most "quota" us of cpu time in
+// each "period" us
+func GetCpuMaxValue(quota, period int64) string {
+	return fmt.Sprintf("%d %d", quota, period)
+}
+
+// GetNetClsConfig the flow of the processes in cgroup will be tagged with classId, which can be matched by tc cgroup filter
+func GetNetClsConfig(classId uint32, cgroupPath string) string {
+	return fmt.Sprintf("echo 0x%08x > %s/%s", classId, cgroupPath, NetClsClassIdFile)
+}
+
 func getThrottleDeviceCmdStr(devList []string, value int64, filename string) string {
 	var re string
 	for _, unitDec := range devList {
@@ -23,6 +23,8 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/containercgroup"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
 	"os"
 	"strconv"
 	"strings"
@@ -36,14 +38,44 @@ func NewCgroup(ctx context.Context, cgroupPath string, configCmdStr string) erro
 	return nil
 }
 
+// unifiedFileMap the files of cgroup v1 and their equivalents in cgroup v2
+var unifiedFileMap = map[string]string{
+	MemoryLimitInBytesFile: MemoryMaxFile,
+	MemoryUsageInBytesFile: MemoryCurrentFile,
+	CpusetCoreFile:         containercgroup.CpusetEffectiveFile,
+}
+
+// ReadCgroupFileStr fileName is the file of cgroup v1, it is converted to the equivalent file in cgroup v2 hosts,
+// and the unlimited value "max" of cgroup v2 is converted to the unlimited value of cgroup v1
 func ReadCgroupFileStr(ctx context.Context, path, subSys, fileName string) (string, error) {
 	cgroupFile := fmt.Sprintf("%s/%s%s/%s", containercgroup.RootCgroupPath, subSys, path, fileName)
+	if containercgroup.IsCgroupV2() {
+		if unifiedFile, ok := unifiedFileMap[fileName]; ok {
+			fileName = unifiedFile
+		}
+		cgroupFile = fmt.Sprintf("%s%s/%s", containercgroup.RootCgroupPath, path, fileName)
+	}
+
 	reByte, err := os.ReadFile(cgroupFile)
 	if err != nil {
 		return "", fmt.Errorf("read from %s error: %s", cgroupFile, err.Error())
 	}
 
-	return strings.TrimSpace(string(reByte)), nil
+	re := strings.TrimSpace(string(reByte))
+	if containercgroup.IsCgroupV2() && re == UnLimitValue {
+		return strconv.FormatInt(MemUnLimit, 10), nil
+	}
+
+	return re, nil
+}
+
+// GetCgroupPath get the absolute path of a cgroup, all subsystems share the same path in cgroup v2
+func GetCgroupPath(subSys, path string) string {
+	if containercgroup.IsCgroupV2() {
+		return fmt.Sprintf("%s%s", containercgroup.RootCgroupPath, path)
+	}
+
+	return fmt.Sprintf("%s/%s%s", containercgroup.RootCgroupPath, subSys, path)
 }
 
 func GetContainerCgroupPath(ctx context.Context, cr, containerID, subSys string) (string, error) {
@@ -65,18 +97,45 @@ func GetContainerCgroupPath(ctx context.Context, cr, containerID, subSys string)
 	return cPath, nil
 }
 
+// GetBlkioCPath only used in cgroup v1, the limit is written to the cgroup of target processes in cgroup v2
 func GetBlkioCPath(uid string, prefix string) string {
 	return fmt.Sprintf("%s/%s%s/%s_%s", containercgroup.RootCgroupPath, BLKIO, prefix, BlkioCgroupName, uid)
 }
 
+// GetCpuCPath only used in cgroup v1, same as GetBlkioCPath
+func GetCpuCPath(uid string, prefix string) string {
+	return fmt.Sprintf("%s/%s%s/%s_%s", containercgroup.RootCgroupPath, CPU, prefix, CpuCgroupName, uid)
+}
+
+// GetCpusetCPath only used in cgroup v1
+func GetCpusetCPath(uid string, prefix string) string {
+	return fmt.Sprintf("%s/%s%s/%s_%s", containercgroup.RootCgroupPath, CPUSET, prefix, CpuCgroupName, uid)
+}
+
+func GetNetClsCPath(uid string, prefix string) string {
+	return fmt.Sprintf("%s/%s%s/%s_%s", containercgroup.RootCgroupPath, NETCLS, prefix, NetClsCgroupName, uid)
+}
+
 func CheckPidListBlkioCgroup(ctx context.Context, pidList []int) error {
+	return checkPidListCgroup(ctx, pidList, BLKIO, BlkioCgroupName)
+}
+
+func CheckPidListCpuCgroup(ctx context.Context, pidList []int) error {
+	return checkPidListCgroup(ctx, pidList, CPU, CpuCgroupName)
+}
+
+func CheckPidListNetClsCgroup(ctx context.Context, pidList []int) error {
+	return checkPidListCgroup(ctx, pidList, NETCLS, NetClsCgroupName)
+}
+
+func checkPidListCgroup(ctx context.Context, pidList []int, subSys, cgroupName string) error {
 	for _, unitP := range pidList {
-		oldPath, err := GetpidCurCgroup(ctx, unitP, BLKIO)
+		oldPath, err := GetpidCurCgroup(ctx, unitP, subSys)
 		if err != nil {
 			return fmt.Errorf("get old cgroup path of process[%d] error: %s", unitP, err.Error())
 		}
 
-		if strings.Index(oldPath, BlkioCgroupName) >= 0 {
+		if strings.Index(oldPath, cgroupName) >= 0 {
 			return fmt.Errorf("%d is in experiment[%s]", unitP, oldPath)
 		}
 	}
@@ -97,6 +156,80 @@ func GetPidListCurCgroup(ctx context.Context, pidList []int, subSys string) (map
 	return re, nil
 }
 
+// GetPidListUnifiedCgroup get the distinct cgroups of processes in cgroup v2. processes in the root cgroup are
+// rejected, because the root cgroup has no limit files
+func GetPidListUnifiedCgroup(ctx context.Context, pidList []int) ([]string, error) {
+	var (
+		re      []string
+		pathMap = make(map[string]bool)
+	)
+
+	for _, unitP := range pidList {
+		path, err := GetpidCurCgroup(ctx, unitP, "")
+		if err != nil {
+			return nil, fmt.Errorf("get cgroup path of process[%d] error: %s", unitP, err.Error())
+		}
+
+		if path == "/" {
+			return nil, fmt.Errorf("process[%d] is in the root cgroup, which can not be limited", unitP)
+		}
+
+		if !pathMap[path] {
+			pathMap[path] = true
+			re = append(re, path)
+		}
+	}
+
+	return re, nil
+}
+
+// CheckUnifiedController the files of a controller only exist in the cgroup whose parent enables the controller, the
+// parent is not modified because it would affect all the siblings of the cgroup
+func CheckUnifiedController(cgroupPath, controller string) error {
+	controllers, err := ReadUnifiedFile(cgroupPath, ControllersFile)
+	if err != nil {
+		return err
+	}
+
+	for _, unit := range strings.Fields(controllers) {
+		if unit == controller {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("controller[%s] is not enabled in cgroup[%s]", controller, cgroupPath)
+}
+
+// ReadUnifiedFile read the raw content of a file in cgroup v2, cgroupPath is relative to the root cgroup
+func ReadUnifiedFile(cgroupPath, fileName string) (string, error) {
+	cgroupFile := fmt.Sprintf("%s%s/%s", containercgroup.RootCgroupPath, cgroupPath, fileName)
+	reByte, err := os.ReadFile(cgroupFile)
+	if err != nil {
+		return "", fmt.Errorf("read from %s error: %s", cgroupFile, err.Error())
+	}
+
+	return strings.TrimSpace(string(reByte)), nil
+}
+
+// WriteUnifiedFile write each value to a file in cgroup v2, files like io.max only accept one line in one write
+func WriteUnifiedFile(ctx context.Context, cgroupPath, fileName string, valueList []string) error {
+	cgroupFile := fmt.Sprintf("%s%s/%s", containercgroup.RootCgroupPath, cgroupPath, fileName)
+	var cmdList []string
+	for _, unit := range valueList {
+		cmdList = append(cmdList, fmt.Sprintf("echo '%s' > %s", unit, cgroupFile))
+	}
+
+	if len(cmdList) == 0 {
+		return nil
+	}
+
+	if err := cmdexec.RunBashCmdWithoutOutput(ctx, strings.Join(cmdList, utils.CmdSplit)); err != nil {
+		return fmt.Errorf("write %s error: %s", cgroupFile, err.Error())
+	}
+
+	return nil
+}
+
 func GetContainerCgroup(ctx context.Context, cr, cId string) (string, error) {
 	client, err := crclient.GetClient(ctx, cr)
 	if err != nil {
@@ -112,6 +245,10 @@ func GetContainerCgroup(ctx context.Context, cr, cId string) (string, error) {
 }
 
 func GetpidCurCgroup(ctx context.Context, pid int, subSys string) (string, error) {
+	if containercgroup.IsCgroupV2() {
+		return containercgroup.GetUnifiedCgroupPath(pid)
+	}
+
 	re, err := cmdexec.RunBashCmdWithOutput(ctx, fmt.Sprintf("cat /proc/%d/cgroup | grep -w %s", pid, subSys))
 	if err != nil {
 		return "", fmt.Errorf("run cmd error: %s", err.Error())
@@ -136,24 +273,72 @@ func MovePidListToCgroup(ctx context.Context, pidList []int, cgroupPath string)
 	return nil
 }
 
+// getTasksFile cgroup v2 does not have "tasks", and threads of a process must be in the same cgroup
+func getTasksFile() string {
+	if containercgroup.IsCgroupV2() {
+		return containercgroup.ProcsFile
+	}
+
+	return TasksFile
+}
+
 func MoveTaskToCgroup(ctx context.Context, pid int, cgroupPath string) error {
-	if err := cmdexec.RunBashCmdWithoutOutput(ctx, fmt.Sprintf("echo %d > %s/tasks", pid, cgroupPath)); err != nil {
+	return moveToCgroup(ctx, pid, cgroupPath, getTasksFile())
+}
+
+// MoveProcListToCgroup move all the threads of processes, "tasks" of cgroup v1 only moves one thread
+func MoveProcListToCgroup(ctx context.Context, pidList []int, cgroupPath string) error {
+	for _, unit := range pidList {
+		if err := moveToCgroup(ctx, unit, cgroupPath, containercgroup.ProcsFile); err != nil {
+			return fmt.Errorf("move process[%d] to cgroup[%s] error: %s", unit, cgroupPath, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func MoveProcToCgroup(ctx context.Context, pid int, cgroupPath string) error {
+	return moveToCgroup(ctx, pid, cgroupPath, containercgroup.ProcsFile)
+}
+
+func moveToCgroup(ctx context.Context, pid int, cgroupPath, file string) error {
+	if err := cmdexec.RunBashCmdWithoutOutput(ctx, fmt.Sprintf("echo %d > %s/%s", pid, cgroupPath, file)); err != nil {
 		return err
 	}
 
+	if dryrun.IsDryRun(ctx) {
+		state, _ := dryrun.GetState(ctx, cgroupPath)
+		pidList, _ := state.([]int)
+		dryrun.SetState(ctx, cgroupPath, append(pidList, pid))
+	}
+
 	return nil
 }
 
-//func MoveProcToCgroup(pid int, cgroupPath string) error {
-//	if err := cmdexec.RunBashCmdWithoutOutput(fmt.Sprintf("echo %d > %s/cgroup.procs", pid, cgroupPath)); err != nil {
-//		return err
-//	}
-//
-//	return nil
-//}
+// ExistCgroup the cgroup created in dry-run mode only exists in the plan
+func ExistCgroup(ctx context.Context, cgroupPath string) (bool, error) {
+	if _, ok := dryrun.GetState(ctx, cgroupPath); ok {
+		return true, nil
+	}
+
+	return filesys.ExistPathLocal(cgroupPath)
+}
 
 func GetPidStrListByCgroup(ctx context.Context, cgroupPath string) ([]int, error) {
-	re, err := cmdexec.RunBashCmdWithOutput(ctx, fmt.Sprintf("cat %s/tasks", cgroupPath))
+	return getPidListByCgroupFile(ctx, cgroupPath, getTasksFile())
+}
+
+// GetProcListByCgroup get the pid of processes in cgroup, not the id of threads
+func GetProcListByCgroup(ctx context.Context, cgroupPath string) ([]int, error) {
+	return getPidListByCgroupFile(ctx, cgroupPath, containercgroup.ProcsFile)
+}
+
+func getPidListByCgroupFile(ctx context.Context, cgroupPath, file string) ([]int, error) {
+	if pidList, ok := dryrun.GetState(ctx, cgroupPath); ok {
+		return pidList.([]int), nil
+	}
+
+	re, err := cmdexec.RunBashCmdWithOutput(ctx, fmt.Sprintf("cat %s/%s", cgroupPath, file))
 	if err != nil {
 		return nil, fmt.Errorf("run cmd error: %s", err.Error())
 	}
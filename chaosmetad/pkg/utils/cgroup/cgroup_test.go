@@ -18,6 +18,7 @@ package cgroup
 
 import (
 	"context"
+	"reflect"
 	"testing"
 )
 
@@ -63,3 +64,117 @@ func TestGetBlkioConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestGetIOMaxLimitList(t *testing.T) {
+	type args struct {
+		devList []string
+		rBytes  string
+		wBytes  string
+		rIO     int64
+		wIO     int64
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			args: args{
+				devList: []string{"8:0", "8:1"},
+				rBytes:  "200kb",
+				wBytes:  "500KB",
+				rIO:     5,
+				wIO:     6,
+			},
+			want: []string{"8:0 rbps=204800 wbps=512000 riops=5 wiops=6", "8:1 rbps=204800 wbps=512000 riops=5 wiops=6"},
+		},
+		{
+			args: args{
+				devList: []string{"253:0"},
+				wBytes:  "1",
+			},
+			want: []string{"253:0 wbps=1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetIOMaxLimitList(tt.args.devList, tt.args.rBytes, tt.args.wBytes, tt.args.rIO, tt.args.wIO); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetIOMaxLimitList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetIOMaxRecoverList(t *testing.T) {
+	tests := []struct {
+		name     string
+		devList  []string
+		oldIOMax string
+		want     []string
+	}{
+		{
+			name:     "no old limit",
+			devList:  []string{"8:0"},
+			oldIOMax: "",
+			want:     []string{"8:0 rbps=max wbps=max riops=max wiops=max"},
+		},
+		{
+			name:     "keep old limit",
+			devList:  []string{"8:0", "8:16"},
+			oldIOMax: "8:16 rbps=1048576 wbps=max riops=max wiops=100\n253:0 rbps=max wbps=2048 riops=max wiops=max\n",
+			want:     []string{"8:0 rbps=max wbps=max riops=max wiops=max", "8:16 rbps=1048576 wbps=max riops=max wiops=100"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetIOMaxRecoverList(tt.devList, tt.oldIOMax); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetIOMaxRecoverList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCpuQuotaConfig(t *testing.T) {
+	type args struct {
+		quota      int64
+		period     int64
+		cgroupPath string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			args: args{
+				quota:      50000,
+				period:     100000,
+				cgroupPath: "/sys/fs/cgroup/cpu/chaosmeta_cpu_1241q52",
+			},
+			want: "echo 100000 > /sys/fs/cgroup/cpu/chaosmeta_cpu_1241q52/cpu.cfs_period_us &&" +
+				" echo 50000 > /sys/fs/cgroup/cpu/chaosmeta_cpu_1241q52/cpu.cfs_quota_us",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetCpuQuotaConfig(tt.args.quota, tt.args.period, tt.args.cgroupPath); got != tt.want {
+				t.Errorf("GetCpuQuotaConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCpusetConfig(t *testing.T) {
+	got := GetCpusetConfig("0-1,3", "/sys/fs/cgroup/cpuset/docker/abc/chaosmeta_cpu_1241q52")
+	want := "echo 0-1,3 > /sys/fs/cgroup/cpuset/docker/abc/chaosmeta_cpu_1241q52/cpuset.cpus &&" +
+		" cat /sys/fs/cgroup/cpuset/docker/abc/cpuset.mems > /sys/fs/cgroup/cpuset/docker/abc/chaosmeta_cpu_1241q52/cpuset.mems"
+	if got != want {
+		t.Errorf("GetCpusetConfig() = %v, want %v", got, want)
+	}
+}
+
+func TestGetCpuMaxValue(t *testing.T) {
+	if got, want := GetCpuMaxValue(150000, 100000), "150000 100000"; got != want {
+		t.Errorf("GetCpuMaxValue() = %v, want %v", got, want)
+	}
+}
@@ -18,8 +18,10 @@ package cgroup
 
 const (
 	BLKIO  = "blkio"
+	CPU    = "cpu"
 	CPUSET = "cpuset"
 	MEMORY = "memory"
+	NETCLS = "net_cls"
 )
 
 const (
@@ -28,9 +30,29 @@ const (
 	MemoryStatFile         = "memory.stat"
 	MemoryUsageInBytesFile = "memory.usage_in_bytes"
 	CpusetCoreFile         = "cpuset.cpus"
+	CpusetMemsFile         = "cpuset.mems"
+	CpuCfsQuotaFile        = "cpu.cfs_quota_us"
+	CpuCfsPeriodFile       = "cpu.cfs_period_us"
+	CpuCgroupName          = "chaosmeta_cpu"
 	WriteBytesFile         = "blkio.throttle.write_bps_device"
 	ReadBytesFile          = "blkio.throttle.read_bps_device"
 	WriteIOFile            = "blkio.throttle.write_iops_device"
 	ReadIOFile             = "blkio.throttle.read_iops_device"
 	BlkioCgroupName        = "chaosmeta_blkio"
+	NetClsClassIdFile      = "net_cls.classid"
+	NetClsCgroupName       = "chaosmeta_netcls"
+	TasksFile              = "tasks"
+)
+
+// files of cgroup v2
+const (
+	IOController      = "io"
+	CpuController     = "cpu"
+	CpusetController  = "cpuset"
+	CpuMaxFile        = "cpu.max"
+	UnLimitValue      = "max"
+	IOMaxFile         = "io.max"
+	MemoryMaxFile     = "memory.max"
+	MemoryCurrentFile = "memory.current"
+	ControllersFile   = "cgroup.controllers"
 )
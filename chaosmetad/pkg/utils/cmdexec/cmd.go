@@ -25,6 +25,7 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/containercgroup"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
 	"os/exec"
 	"strings"
@@ -115,6 +116,10 @@ func (e *CmdExecutor) ExecTool(ctx context.Context) error {
 
 func CpContainerFile(ctx context.Context, cr, containerID, src, dst string) error {
 	log.GetLogger(ctx).Debugf("cp from %s to %s in %s", src, dst, containerID)
+	if dryrun.Record(ctx, dryrun.KindFile, dryrun.GetContainerTarget(containerID), fmt.Sprintf("cp %s %s", src, dst)) {
+		return nil
+	}
+
 	client, err := crclient.GetClient(ctx, cr)
 	if err != nil {
 		return fmt.Errorf("get %s client error: %s", cr, err.Error())
@@ -127,6 +132,31 @@ func StartSleepRecover(ctx context.Context, sleepTime int64, uid string) error {
 	return StartBashCmd(ctx, utils.GetSleepRecoverCmd(sleepTime, uid))
 }
 
+// ExistSleepRecover check if the sleep process created by StartSleepRecover is still alive
+func ExistSleepRecover(ctx context.Context, uid string) (bool, error) {
+	re, err := RunBashCmdWithOutput(ctx, fmt.Sprintf("ps -ef | grep '%s' | grep sleep | grep -v grep | wc -l", utils.GetSleepRecoverKey(uid)))
+	if err != nil {
+		return false, fmt.Errorf("cmd exec error: %s", err.Error())
+	}
+
+	return strings.TrimSpace(re) != "0", nil
+}
+
+// StartFlapping start the background process which cycles the inject and recover of the experiment
+func StartFlapping(ctx context.Context, uid string) error {
+	return StartBashCmd(ctx, utils.GetFlappingCmd(uid))
+}
+
+// ExistFlapping check if the process created by StartFlapping is still alive
+func ExistFlapping(ctx context.Context, uid string) (bool, error) {
+	re, err := RunBashCmdWithOutput(ctx, fmt.Sprintf("ps -ef | grep '%s' | grep -v grep | wc -l", utils.GetFlappingKey(uid)))
+	if err != nil {
+		return false, fmt.Errorf("cmd exec error: %s", err.Error())
+	}
+
+	return strings.TrimSpace(re) != "0", nil
+}
+
 func waitProExec(ctx context.Context, stdout, stderr *bytes.Buffer, timeoutSec int) (err error) {
 	var msg, timer = "", time.NewTimer(InjectCheckInterval)
 	var startTime = time.Now()
@@ -173,6 +203,10 @@ func SupportCmd(cmd string) bool {
 
 func RunBashCmdWithOutput(ctx context.Context, cmd string) (string, error) {
 	log.GetLogger(ctx).Debugf("run cmd with output: %s", cmd)
+	if dryrun.RecordCmd(ctx, dryrun.TargetHost, cmd) {
+		return "", nil
+	}
+
 	c := exec.Command("/bin/bash", "-c", cmd)
 
 	reByte, err := c.CombinedOutput()
@@ -196,16 +230,27 @@ func RunBashCmdWithOutput(ctx context.Context, cmd string) (string, error) {
 
 func RunBashCmdWithoutOutput(ctx context.Context, cmd string) error {
 	log.GetLogger(ctx).Debugf("run cmd: %s", cmd)
+	if dryrun.RecordCmd(ctx, dryrun.TargetHost, cmd) {
+		return nil
+	}
+
 	return exec.Command("/bin/bash", "-c", cmd).Run()
 }
 
 func StartBashCmd(ctx context.Context, cmd string) error {
 	log.GetLogger(ctx).Debugf("start cmd: %s", cmd)
+	if dryrun.Record(ctx, dryrun.KindCmd, dryrun.TargetHost, cmd) {
+		return nil
+	}
+
 	return exec.Command("/bin/bash", "-c", cmd).Start()
 }
 
 func StartBashCmdAndWaitPid(ctx context.Context, cmd string, timeoutSec int) (int, error) {
 	log.GetLogger(ctx).Debugf("start cmd: %s", cmd)
+	if dryrun.Record(ctx, dryrun.KindCmd, dryrun.TargetHost, cmd) {
+		return utils.NoPid, nil
+	}
 
 	c := exec.Command("/bin/bash", "-c", cmd)
 	var stdout, stderr bytes.Buffer
@@ -224,6 +269,9 @@ func StartBashCmdAndWaitPid(ctx context.Context, cmd string, timeoutSec int) (in
 
 func StartBashCmdAndWaitByUser(ctx context.Context, cmd, user string) error {
 	log.GetLogger(ctx).Debugf("user: %s, start cmd: %s", user, cmd)
+	if dryrun.Record(ctx, dryrun.KindCmd, fmt.Sprintf("%s(user: %s)", dryrun.TargetHost, user), cmd) {
+		return nil
+	}
 
 	c := exec.Command("runuser", "-l", user, "-c", cmd)
 	var stdout, stderr bytes.Buffer
@@ -255,6 +303,12 @@ func ExecContainer(ctx context.Context, cr, containerID string, namespaces []str
 		return "", fmt.Errorf("get pid of container[%s]'s init process error: %s", containerID, err.Error())
 	}
 
+	// the read-only cmd still runs in dry-run mode to resolve targets, except the ones which are executed in background
+	if dryrun.IsDryRun(ctx) && (method != ExecRun || !dryrun.IsReadOnlyCmd(cmd)) {
+		dryrun.Record(ctx, dryrun.KindCmd, fmt.Sprintf("%s(ns: %s)", dryrun.GetContainerTarget(containerID), strings.Join(namespaces, ",")), cmd)
+		return "", nil
+	}
+
 	// exec ns
 	c := exec.Command("/bin/bash", "-c", fmt.Sprintf("%s -t %d %s -c \"%s\"",
 		utils.GetToolPath(namespace.ExecnsKey), targetPid, namespace.GetNsOption(namespaces), base64.StdEncoding.EncodeToString([]byte(cmd))))
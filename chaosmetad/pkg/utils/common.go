@@ -65,6 +65,12 @@ const (
 	StatusDestroyed = "destroyed"
 )
 
+// cycle phase of flapping mode
+const (
+	CyclePhaseOn  = "on"
+	CyclePhaseOff = "off"
+)
+
 func NewUid() string {
 	t := time.Now()
 	timeStr := t.Format("20060102150405")
@@ -117,7 +123,20 @@ func GetContainerPath(tool string) string {
 }
 
 func GetSleepRecoverCmd(sleepTime int64, uid string) string {
-	return fmt.Sprintf("sleep %ds; %s/%s recover %s >> %s 2>&1", sleepTime, GetRunPath(), RootName, uid, RecoverLog)
+	return fmt.Sprintf("sleep %ds; %s >> %s 2>&1", sleepTime, GetSleepRecoverKey(uid), RecoverLog)
+}
+
+func GetSleepRecoverKey(uid string) string {
+	return fmt.Sprintf("%s/%s recover %s", GetRunPath(), RootName, uid)
+}
+
+// GetFlappingCmd the process cycles the inject and recover of the experiment in background until finished
+func GetFlappingCmd(uid string) string {
+	return fmt.Sprintf("%s >> %s 2>&1", GetFlappingKey(uid), RecoverLog)
+}
+
+func GetFlappingKey(uid string) string {
+	return fmt.Sprintf("%s/%s flapping %s", GetRunPath(), RootName, uid)
 }
 
 func GetTraceId(ctx context.Context) string {
@@ -19,15 +19,62 @@ package containercgroup
 import (
 	"fmt"
 	"github.com/containerd/cgroups"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const (
 	RootCgroupPath = "/sys/fs/cgroup"
+
+	ProcsFile           = "cgroup.procs"
+	CpuStatFile         = "cpu.stat"
+	CpusetEffectiveFile = "cpuset.cpus.effective"
+	OnlineCpuFile       = "/sys/devices/system/cpu/online"
+
+	FreezerSubsystem = "freezer"
+	FreezerStateFile = "freezer.state"
+	FreezerFrozen    = "FROZEN"
+	FreezerThawed    = "THAWED"
+	FreezeFile       = "cgroup.freeze"
 )
 
+// IsCgroupV2 the host only mounts the unified hierarchy, hybrid hosts still use the v1 controllers
+func IsCgroupV2() bool {
+	return cgroups.Mode() == cgroups.Unified
+}
+
+// GetUnifiedCgroupPath get the path of process in the unified hierarchy, eg: /system.slice/docker-xxx.scope
+func GetUnifiedCgroupPath(pid int) (string, error) {
+	_, path, err := cgroups.ParseCgroupFileUnified(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return "", fmt.Errorf("parse cgroup file of process[%d] error: %s", pid, err.Error())
+	}
+
+	if path == "" {
+		return "", fmt.Errorf("process[%d] is not in unified hierarchy", pid)
+	}
+
+	return path, nil
+}
+
 func AddToProCgroup(mPid, cPid int) error {
+	if IsCgroupV2() {
+		path, err := GetUnifiedCgroupPath(cPid)
+		if err != nil {
+			return err
+		}
+
+		procsFile := fmt.Sprintf("%s%s/%s", RootCgroupPath, path, ProcsFile)
+		if err := os.WriteFile(procsFile, []byte(strconv.Itoa(mPid)), 0644); err != nil {
+			return fmt.Errorf("add process[%d] to %s error: %s", mPid, procsFile, err.Error())
+		}
+
+		return nil
+	}
+
 	cgroup, err := LoadCgroup(cPid)
 	if err != nil {
 		return fmt.Errorf("load cgroup of process[%d] error: %s", cPid, err.Error())
@@ -40,7 +87,87 @@ func AddToProCgroup(mPid, cPid int) error {
 	return nil
 }
 
+// getFreezerCgroupPath get the absolute path of the freezer cgroup of process, it is the unified cgroup in cgroup v2
+func getFreezerCgroupPath(pid int) (string, error) {
+	if IsCgroupV2() {
+		path, err := GetUnifiedCgroupPath(pid)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%s%s", RootCgroupPath, path), nil
+	}
+
+	paths, err := cgroups.ParseCgroupFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return "", fmt.Errorf("parse cgroup file of process[%d] error: %s", pid, err.Error())
+	}
+
+	path, ok := paths[FreezerSubsystem]
+	if !ok {
+		return "", fmt.Errorf("process[%d] is not in %s cgroup", pid, FreezerSubsystem)
+	}
+
+	return fmt.Sprintf("%s/%s%s", RootCgroupPath, FreezerSubsystem, path), nil
+}
+
+// FreezeCgroup freeze or thaw all the processes in the cgroup of process
+func FreezeCgroup(pid int, isFreeze bool) error {
+	path, err := getFreezerCgroupPath(pid)
+	if err != nil {
+		return err
+	}
+
+	file, value := fmt.Sprintf("%s/%s", path, FreezerStateFile), FreezerThawed
+	if isFreeze {
+		value = FreezerFrozen
+	}
+
+	if IsCgroupV2() {
+		file, value = fmt.Sprintf("%s/%s", path, FreezeFile), "0"
+		if isFreeze {
+			value = "1"
+		}
+	}
+
+	if err := os.WriteFile(file, []byte(value), 0644); err != nil {
+		return fmt.Errorf("write %s to %s error: %s", value, file, err.Error())
+	}
+
+	return nil
+}
+
+// GetCgroupPidList get all the processes in the same cgroup as the process
+func GetCgroupPidList(pid int) ([]int, error) {
+	path, err := getFreezerCgroupPath(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	procsFile := fmt.Sprintf("%s/%s", path, ProcsFile)
+	reByte, err := os.ReadFile(procsFile)
+	if err != nil {
+		return nil, fmt.Errorf("read from %s error: %s", procsFile, err.Error())
+	}
+
+	var pidList []int
+	for _, unit := range strings.Fields(string(reByte)) {
+		unitPid, err := strconv.Atoi(unit)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid pid: %s", unit, err.Error())
+		}
+
+		pidList = append(pidList, unitPid)
+	}
+
+	return pidList, nil
+}
+
 func CalculateNowPercent(targetPid int) ([]float64, error) {
+	if IsCgroupV2() {
+		return calculateNowPercentV2(targetPid)
+	}
+
 	cgroup, err := LoadCgroup(targetPid)
 	if err != nil {
 		return nil, fmt.Errorf("load cgroup of [%d] error: %s", targetPid, err.Error())
@@ -64,6 +191,84 @@ func CalculateNowPercent(targetPid int) ([]float64, error) {
 	return perUsage, nil
 }
 
+// calculateNowPercentV2 cgroup v2 does not provide usage per cpu, so the total usage is shared equally by the effective cpus
+func calculateNowPercentV2(targetPid int) ([]float64, error) {
+	var path = "/"
+	if targetPid != -1 {
+		var err error
+		if path, err = GetUnifiedCgroupPath(targetPid); err != nil {
+			return nil, err
+		}
+	}
+
+	cpuList, err := getEffectiveCpuList(path)
+	if err != nil {
+		return nil, fmt.Errorf("get effective cpu list error: %s", err.Error())
+	}
+
+	usage, err := getCpuUsageUs(path)
+	if err != nil {
+		return nil, fmt.Errorf("initial stat cgroup error: %s", err.Error())
+	}
+	time.Sleep(time.Second * 2)
+	afterUsage, err := getCpuUsageUs(path)
+	if err != nil {
+		return nil, fmt.Errorf("later stat cgroup error: %s", err.Error())
+	}
+
+	var maxCore int
+	for _, core := range cpuList {
+		if core > maxCore {
+			maxCore = core
+		}
+	}
+
+	perUsage := make([]float64, maxCore+1)
+	for _, core := range cpuList {
+		perUsage[core] = float64(afterUsage-usage) / float64(time.Second/time.Microsecond) / 2 / float64(len(cpuList)) * 100
+	}
+
+	return perUsage, nil
+}
+
+func getCpuUsageUs(path string) (uint64, error) {
+	statFile := fmt.Sprintf("%s%s/%s", RootCgroupPath, path, CpuStatFile)
+	reByte, err := os.ReadFile(statFile)
+	if err != nil {
+		return 0, fmt.Errorf("read from %s error: %s", statFile, err.Error())
+	}
+
+	for _, line := range strings.Split(string(reByte), "\n") {
+		kv := strings.Fields(line)
+		if len(kv) == 2 && kv[0] == "usage_usec" {
+			return strconv.ParseUint(kv[1], 10, 64)
+		}
+	}
+
+	return 0, fmt.Errorf("usage_usec not found in %s", statFile)
+}
+
+// getEffectiveCpuList the cpuset controller may be disabled, then all online cpus are effective
+func getEffectiveCpuList(path string) ([]int, error) {
+	reByte, err := os.ReadFile(fmt.Sprintf("%s%s/%s", RootCgroupPath, path, CpusetEffectiveFile))
+	if err != nil {
+		if reByte, err = os.ReadFile(OnlineCpuFile); err != nil {
+			return nil, err
+		}
+	}
+
+	cpuList, err := utils.GetNumArrByList(strings.TrimSpace(string(reByte)))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cpuList) == 0 {
+		return nil, fmt.Errorf("cpu list is empty")
+	}
+
+	return cpuList, nil
+}
+
 func LoadCgroup(cPid int) (cgroups.Cgroup, error) {
 	if cPid == -1 {
 		return cgroups.Load(hierarchy(RootCgroupPath), cgroups.StaticPath("/"))
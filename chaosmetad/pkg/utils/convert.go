@@ -69,6 +69,56 @@ func GetTimeSecond(timeStr string) (int64, error) {
 	return -1, fmt.Errorf("unit %s is not support", unit)
 }
 
+func GetTimeUs(timeStr string) (int64, error) {
+	value, unit, err := getValueAndUnit(timeStr)
+	if err != nil {
+		return -1, err
+	}
+
+	if unit == "us" || unit == "" {
+		return value, nil
+	}
+
+	if unit == "ms" {
+		return value * 1000, nil
+	}
+
+	if unit == "s" {
+		return value * 1000 * 1000, nil
+	}
+
+	return -1, fmt.Errorf("unit %s is not support", unit)
+}
+
+func GetSpeedBit(sp string) (int64, error) {
+	value, unit, err := getValueAndUnit(sp)
+	if err != nil {
+		return -1, err
+	}
+
+	if unit == "bit" || unit == "" {
+		return value, nil
+	}
+
+	if unit == "kbit" {
+		return value * 1000, nil
+	}
+
+	if unit == "mbit" {
+		return value * 1000 * 1000, nil
+	}
+
+	if unit == "gbit" {
+		return value * 1000 * 1000 * 1000, nil
+	}
+
+	if unit == "tbit" {
+		return value * 1000 * 1000 * 1000 * 1000, nil
+	}
+
+	return -1, fmt.Errorf("unit %s is not support", unit)
+}
+
 func GetKBytes(byteStr string) (int64, error) {
 	value, unit, err := getValueAndUnit(byteStr)
 	if err != nil {
@@ -22,10 +22,22 @@ import (
 	"github.com/shirou/gopsutil/disk"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
+	"os"
+	"strconv"
 	"strings"
 )
 
+const (
+	// InodeDirName the prefix of the dir holding the empty files of inode fill
+	InodeDirName = "chaosmeta_inode"
+	// InodeFilesPerDir the empty files are spread in sub dirs, so that no dir holds too many entries, which makes
+	// the removal slow
+	InodeFilesPerDir = 10000
+	ReservedInodes   = 10
+)
+
 func GetDevList(ctx context.Context, cr, cId string, devStr string) ([]string, error) {
 	if devStr == "" {
 		return nil, fmt.Errorf("args dev-list is empty")
@@ -43,6 +55,7 @@ func GetDevList(ctx context.Context, cr, cId string, devStr string) ([]string, e
 		}
 	}
 
+	dryrun.Resolve(ctx, dryrun.ResolvedDevList, devStrList)
 	return devStrList, nil
 }
 
@@ -126,3 +139,74 @@ func GetFillKBytes(dir string, percent int, bytes string) (int64, error) {
 
 	return fillKBytes, nil
 }
+
+// GetFillInodes the count of inodes to fill, percent is the target percent of inode usage
+func GetFillInodes(dir string, percent int, count int64) (int64, error) {
+	var fillInodes int64
+	usage, err := disk.Usage(dir)
+	if err != nil {
+		return -1, fmt.Errorf("get disk info error: %s", err.Error())
+	}
+
+	if usage.InodesTotal == 0 {
+		return -1, fmt.Errorf("file system[%s] of target path has no inode limit", usage.Fstype)
+	}
+
+	if percent != 0 {
+		if float64(percent) < usage.InodesUsedPercent {
+			return -1, fmt.Errorf("target path current inode usage is %.2f%%, no need to fill", usage.InodesUsedPercent)
+		}
+
+		fillInodes = int64((float64(percent) - usage.InodesUsedPercent) / 100 * float64(usage.InodesTotal))
+	} else {
+		fillInodes = count
+	}
+
+	freeInodes := int64(usage.InodesFree)
+	if fillInodes > freeInodes {
+		return -1, fmt.Errorf("inode not enough, fill: %d, free: %d", fillInodes, freeInodes)
+	}
+
+	// same as GetFillKBytes, keep some inodes for the database file
+	if fillInodes == freeInodes {
+		fillInodes -= ReservedInodes
+	}
+
+	if fillInodes <= 0 {
+		return -1, fmt.Errorf("fill inodes[%d] must larger than 0", fillInodes)
+	}
+
+	return fillInodes, nil
+}
+
+func GetInodeFillDir(dir, uid string) string {
+	return fmt.Sprintf("%s/%s%s", dir, InodeDirName, uid)
+}
+
+// CountFillInodes the inodes used by the fill dir, include the dirs. all sub dirs are full except the last one, a full
+// sub dir uses InodeFilesPerDir inodes: itself and InodeFilesPerDir-1 files
+func CountFillInodes(fillDir string) (int64, error) {
+	subDirList, err := os.ReadDir(fillDir)
+	if err != nil {
+		return -1, fmt.Errorf("read dir[%s] error: %s", fillDir, err.Error())
+	}
+
+	var last = -1
+	for _, unit := range subDirList {
+		index, err := strconv.Atoi(unit.Name())
+		if err == nil && index > last {
+			last = index
+		}
+	}
+
+	if last < 0 {
+		return 1, nil
+	}
+
+	lastFiles, err := os.ReadDir(fmt.Sprintf("%s/%d", fillDir, last))
+	if err != nil {
+		return -1, fmt.Errorf("read dir[%s/%d] error: %s", fillDir, last, err.Error())
+	}
+
+	return 2 + int64(last)*InodeFilesPerDir + int64(len(lastFiles)), nil
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package disk
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// fillInodes use inodes in fillDir in the same way as the inode fill of chaosmeta_diskfill
+func fillInodes(fillDir string, fillInodes int64) error {
+	if err := os.Mkdir(fillDir, 0755); err != nil {
+		return err
+	}
+
+	var (
+		created int64 = 1
+		subDir  string
+		err     error
+	)
+	for index := 0; created < fillInodes; index++ {
+		if index%InodeFilesPerDir == 0 {
+			subDir = fmt.Sprintf("%s/%d", fillDir, index/InodeFilesPerDir)
+			err = os.Mkdir(subDir, 0755)
+		} else {
+			err = syscall.Mknod(fmt.Sprintf("%s/%d", subDir, index), syscall.S_IFREG|0644, 0)
+		}
+
+		if err != nil {
+			return err
+		}
+		created++
+	}
+
+	return nil
+}
+
+func TestCountFillInodes(t *testing.T) {
+	tests := []int64{1, 2, 3, InodeFilesPerDir, InodeFilesPerDir + 1, InodeFilesPerDir + 2, 2*InodeFilesPerDir + 1}
+	for _, count := range tests {
+		t.Run(fmt.Sprint(count), func(t *testing.T) {
+			fillDir := GetInodeFillDir(t.TempDir(), "test")
+			if err := fillInodes(fillDir, count); err != nil {
+				t.Fatalf("fill inodes error: %v", err)
+			}
+
+			var walked int64
+			if err := filepath.WalkDir(fillDir, func(path string, d fs.DirEntry, err error) error {
+				walked++
+				return err
+			}); err != nil {
+				t.Fatalf("walk fill dir error: %v", err)
+			}
+			if walked != count {
+				t.Fatalf("filled %d inodes, want %d", walked, count)
+			}
+
+			got, err := CountFillInodes(fillDir)
+			if err != nil {
+				t.Fatalf("CountFillInodes() error = %v", err)
+			}
+			if got != count {
+				t.Errorf("CountFillInodes() = %v, want %v", got, count)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dryrun
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	CtxPlan = "DryRunPlan"
+
+	PhaseInject  = "inject"
+	PhaseRecover = "recover"
+
+	KindCmd       = "cmd"
+	KindFile      = "file"
+	KindNetlink   = "netlink"
+	KindProcess   = "process"
+	KindPtrace    = "ptrace"
+	KindContainer = "container"
+
+	TargetHost = "host"
+
+	ResolvedContainerPid = "container_pid"
+	ResolvedPidList      = "pid_list"
+	ResolvedHostPidList  = "host_pid_list"
+	ResolvedDevList      = "dev_list"
+	ResolvedInterface    = "interface"
+)
+
+// Step an operation which would modify the system
+type Step struct {
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+	Action string `json:"action"`
+}
+
+// Plan the result of dry-run: the resolved targets, the ordered operations of inject and recover
+type Plan struct {
+	Uid              string                 `json:"uid"`
+	Target           string                 `json:"target"`
+	Fault            string                 `json:"fault"`
+	ContainerRuntime string                 `json:"container_runtime,omitempty"`
+	ContainerId      string                 `json:"container_id,omitempty"`
+	Args             interface{}            `json:"args"`
+	Resolved         map[string]interface{} `json:"resolved,omitempty"`
+	Inject           []*Step                `json:"inject"`
+	Recover          []*Step                `json:"recover"`
+	Error            string                 `json:"error,omitempty"`
+
+	lock     sync.Mutex
+	phase    string
+	stateMap map[string]interface{}
+}
+
+func NewPlan() *Plan {
+	return &Plan{
+		Resolved: make(map[string]interface{}),
+		Inject:   make([]*Step, 0),
+		Recover:  make([]*Step, 0),
+		phase:    PhaseInject,
+		stateMap: make(map[string]interface{}),
+	}
+}
+
+func GetCtxWithPlan(ctx context.Context, plan *Plan) context.Context {
+	return context.WithValue(ctx, CtxPlan, plan)
+}
+
+func GetPlan(ctx context.Context) *Plan {
+	plan, _ := ctx.Value(CtxPlan).(*Plan)
+	return plan
+}
+
+func IsDryRun(ctx context.Context) bool {
+	return GetPlan(ctx) != nil
+}
+
+func SetPhase(ctx context.Context, phase string) {
+	if plan := GetPlan(ctx); plan != nil {
+		plan.lock.Lock()
+		defer plan.lock.Unlock()
+		plan.phase = phase
+	}
+}
+
+// IsRecoverPhase the objects created by inject are not really created in dry-run mode, the recover should regard them as existing
+func IsRecoverPhase(ctx context.Context) bool {
+	plan := GetPlan(ctx)
+	if plan == nil {
+		return false
+	}
+
+	plan.lock.Lock()
+	defer plan.lock.Unlock()
+	return plan.phase == PhaseRecover
+}
+
+// Record add the operation to the plan if ctx is in dry-run mode, return true means the caller must skip the operation
+func Record(ctx context.Context, kind, target, action string) bool {
+	plan := GetPlan(ctx)
+	if plan == nil {
+		return false
+	}
+
+	plan.lock.Lock()
+	defer plan.lock.Unlock()
+	step := &Step{Kind: kind, Target: target, Action: action}
+	if plan.phase == PhaseRecover {
+		plan.Recover = append(plan.Recover, step)
+	} else {
+		plan.Inject = append(plan.Inject, step)
+	}
+
+	return true
+}
+
+// RecordCmd same as Record, but the read-only cmd is not recorded and still executed, so that the targets can be resolved
+func RecordCmd(ctx context.Context, target, cmd string) bool {
+	if !IsDryRun(ctx) || IsReadOnlyCmd(cmd) {
+		return false
+	}
+
+	return Record(ctx, KindCmd, target, cmd)
+}
+
+// Resolve record the target resolved during dry-run, eg: pid list, device list, container pid
+func Resolve(ctx context.Context, key string, value interface{}) {
+	if plan := GetPlan(ctx); plan != nil {
+		plan.lock.Lock()
+		defer plan.lock.Unlock()
+		plan.Resolved[key] = value
+	}
+}
+
+// SetState keep the state of the object which is not really created in dry-run mode, eg: the pid list of a new cgroup
+func SetState(ctx context.Context, key string, value interface{}) {
+	if plan := GetPlan(ctx); plan != nil {
+		plan.lock.Lock()
+		defer plan.lock.Unlock()
+		plan.stateMap[key] = value
+	}
+}
+
+func GetState(ctx context.Context, key string) (interface{}, bool) {
+	plan := GetPlan(ctx)
+	if plan == nil {
+		return nil, false
+	}
+
+	plan.lock.Lock()
+	defer plan.lock.Unlock()
+	value, ok := plan.stateMap[key]
+	return value, ok
+}
+
+func GetContainerTarget(cId string) string {
+	return fmt.Sprintf("container[%s]", cId)
+}
+
+func GetTarget(cr, cId string) string {
+	if cr == "" {
+		return TargetHost
+	}
+
+	return GetContainerTarget(cId)
+}
+
+var readOnlyCmdMap = map[string]bool{
+	"ps": true, "grep": true, "awk": true, "wc": true, "head": true, "tail": true, "cat": true, "test": true,
+	"stat": true, "ls": true, "lsblk": true, "netstat": true, "ss": true, "which": true, "ulimit": true, "df": true,
+	"du": true, "sort": true, "uniq": true, "cut": true, "tr": true, "nproc": true, "free": true, "uname": true,
+	"id": true, "echo": true, "export": true, "readlink": true, "findmnt": true, "mountpoint": true, "date": true,
+	"md5sum": true,
+}
+
+// IsReadOnlyCmd conservative judgment: every command of the pipeline is a query command and there is no redirection
+// to file. a cmd which can not be judged is regarded as a write cmd
+func IsReadOnlyCmd(cmd string) bool {
+	segList, ok := splitCmd(cmd)
+	if !ok {
+		return false
+	}
+
+	for _, seg := range segList {
+		fields := strings.Fields(seg)
+		if len(fields) == 0 {
+			continue
+		}
+
+		name := filepath.Base(fields[0])
+		switch name {
+		case "sed":
+			if hasArgPrefix(fields[1:], "-i") {
+				return false
+			}
+		case "iptables", "ip6tables":
+			if !hasArg(fields[1:], "-S", "-L", "-C", "--list", "--check") ||
+				hasArg(fields[1:], "-A", "-I", "-D", "-R", "-F", "-N", "-X", "-P", "-Z") {
+				return false
+			}
+		case "tc", "ip":
+			if !hasArg(fields[1:], "show", "list", "ls") {
+				return false
+			}
+		default:
+			if !readOnlyCmdMap[name] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// splitCmd split cmd into the commands connected by "|", "&&", "||" and ";". return false if there is redirection
+// to file, command substitution or background execution
+func splitCmd(cmd string) ([]string, bool) {
+	var (
+		segList      []string
+		cur          strings.Builder
+		quote        rune
+		runes        = []rune(cmd)
+		isRedirectOk = func(rest string) bool {
+			rest = strings.TrimSpace(rest)
+			return strings.HasPrefix(rest, "&") || strings.HasPrefix(rest, "/dev/null")
+		}
+	)
+
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			cur.WriteRune(c)
+			continue
+		}
+
+		switch c {
+		case '\'', '"':
+			quote = c
+			cur.WriteRune(c)
+		case '`':
+			return nil, false
+		case '$':
+			if i+1 < len(runes) && runes[i+1] == '(' {
+				return nil, false
+			}
+			cur.WriteRune(c)
+		case '>':
+			if !isRedirectOk(string(runes[i+1:])) {
+				return nil, false
+			}
+			cur.WriteRune(c)
+		case '&':
+			if i > 0 && runes[i-1] == '>' {
+				cur.WriteRune(c)
+				continue
+			}
+			if i+1 < len(runes) && runes[i+1] == '&' {
+				i++
+				segList = append(segList, cur.String())
+				cur.Reset()
+				continue
+			}
+			return nil, false
+		case '|', ';':
+			if c == '|' && i+1 < len(runes) && runes[i+1] == '|' {
+				i++
+			}
+			segList = append(segList, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(c)
+		}
+	}
+
+	if quote != 0 {
+		return nil, false
+	}
+
+	return append(segList, cur.String()), true
+}
+
+func hasArg(args []string, targets ...string) bool {
+	for _, arg := range args {
+		for _, t := range targets {
+			if arg == t {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func hasArgPrefix(args []string, prefix string) bool {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
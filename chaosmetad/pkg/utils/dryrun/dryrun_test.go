@@ -0,0 +1,53 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dryrun
+
+import "testing"
+
+func TestIsReadOnlyCmd(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want bool
+	}{
+		{"ps -ef | grep 'chaosmeta_iofault u1' | grep -v grep | awk '{print $2}'", true},
+		{"cat /proc/1/cgroup | grep -w blkio", true},
+		{"lsblk -a | grep disk | awk '{print $2}' | grep \"8:0\" | wc -l", true},
+		{"test -f /tmp/a 2>/dev/null", true},
+		{"iptables -w -t nat -S PREROUTING | grep -- '--dport 80 ' | grep -w REDIRECT | wc -l", true},
+		{"echo 'a|b;c' | grep a", true},
+		{"tc qdisc show dev eth0", true},
+		{"md5sum /etc/hosts | awk '{print $1}'", true},
+		{"echo 1 > /sys/fs/cgroup/blkio/tasks", false},
+		{"echo -en 'a' >> /tmp/a", false},
+		{"sed -i '/key/d' /tmp/a", false},
+		{"iptables -w -t nat -I PREROUTING -p tcp --dport 80 -j REDIRECT --to-ports 8080", false},
+		{"iptables -w -t nat -S PREROUTING | grep -w -- 'tag' | sed 's/^-A /-D /' | while read -r rule; do eval iptables -w -t nat $rule || exit 1; done", false},
+		{"tc qdisc add dev eth0 root netem delay 10ms", false},
+		{"ps -ef && kill -9 1", false},
+		{"cat $(echo /tmp/a)", false},
+		{"sleep 10 &", false},
+		{"/opt/chaosmeta/chaosmeta_diskfill inject fill", false},
+		{"mkdir -p /tmp/a", false},
+		{"truncate -s 0 /tmp/a", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsReadOnlyCmd(tt.cmd); got != tt.want {
+			t.Errorf("IsReadOnlyCmd(%q) = %v, want %v", tt.cmd, got, tt.want)
+		}
+	}
+}
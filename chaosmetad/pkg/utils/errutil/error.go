@@ -31,6 +31,7 @@ const (
 	InternalErr
 	RecoverErr
 	UnknownErr
+	AuthErr
 )
 
 const (
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
 	"os"
 	"path/filepath"
@@ -36,15 +37,15 @@ func getChmodCmd(path, perm string) string {
 }
 
 func getCheckFileCmd(file string) string {
-	return fmt.Sprintf("test -f %s", file)
+	return fmt.Sprintf("test -f %s", QuotePath(file))
 }
 
 func getCheckDirCmd(dir string) string {
-	return fmt.Sprintf("test -d %s", dir)
+	return fmt.Sprintf("test -d %s", QuotePath(dir))
 }
 
 func getPathExistCmd(path string) string {
-	return fmt.Sprintf("test -e %s", path)
+	return fmt.Sprintf("test -e %s", QuotePath(path))
 }
 
 func getAppendFileCmd(flag, path, content string, count, interval int) string {
@@ -82,6 +83,43 @@ func getPermCmd(file string) string {
 	return "stat -c '%a' " + file
 }
 
+// QuotePath quote the path as a single word of bash, so that the spaces and special characters in it are not
+// interpreted by the shell
+func QuotePath(path string) string {
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
+
+func getCopyFileCmd(src, dst string) string {
+	return fmt.Sprintf("cp -p %s %s", QuotePath(src), QuotePath(dst))
+}
+
+func getChecksumCmd(file string) string {
+	return fmt.Sprintf("md5sum %s | awk '{print $1}'", QuotePath(file))
+}
+
+func getFileSizeCmd(file string) string {
+	return "stat -c '%s' " + QuotePath(file)
+}
+
+func getTruncateFileCmd(file string, size int64) string {
+	return fmt.Sprintf("truncate -s %d %s", size, QuotePath(file))
+}
+
+// setPathState the path is not really changed in dry-run mode, keep whether it exists for the later check
+func setPathState(ctx context.Context, cr, cId string, path string, exist bool) {
+	dryrun.SetState(ctx, fmt.Sprintf("%s:%s", dryrun.GetTarget(cr, cId), path), exist)
+}
+
+func getPathState(ctx context.Context, cr, cId string, path string) (exist bool, ok bool) {
+	state, ok := dryrun.GetState(ctx, fmt.Sprintf("%s:%s", dryrun.GetTarget(cr, cId), path))
+	if !ok {
+		return false, false
+	}
+
+	exist, ok = state.(bool)
+	return
+}
+
 func GetPerm(ctx context.Context, cr, cId string, file string) (string, error) {
 	if file == "" {
 		return "", fmt.Errorf("\"file\" can not be empty")
@@ -101,6 +139,83 @@ func MoveFile(ctx context.Context, cr, cId string, src, dst string) error {
 	}
 
 	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getMoveFileCmd(src, dst), []string{namespace.MNT})
+	if err == nil {
+		setPathState(ctx, cr, cId, src, false)
+		setPathState(ctx, cr, cId, dst, true)
+	}
+
+	return err
+}
+
+// CopyFile in container's namespace, the mode, owner and timestamps are preserved, and the content of dst is
+// overwritten in place if dst exists
+func CopyFile(ctx context.Context, cr, cId string, src, dst string) error {
+	if src == "" {
+		return fmt.Errorf("\"src\" can not be empty")
+	}
+
+	if dst == "" {
+		return fmt.Errorf("\"dst\" can not be empty")
+	}
+
+	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getCopyFileCmd(src, dst), []string{namespace.MNT})
+	if err == nil {
+		setPathState(ctx, cr, cId, dst, true)
+	}
+
+	return err
+}
+
+// GetChecksum get the md5 of file in container's namespace
+func GetChecksum(ctx context.Context, cr, cId string, file string) (string, error) {
+	if file == "" {
+		return "", fmt.Errorf("\"file\" can not be empty")
+	}
+
+	re, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getChecksumCmd(file), []string{namespace.MNT})
+	if err != nil {
+		return "", err
+	}
+
+	checksum := strings.TrimSpace(re)
+	if checksum == "" {
+		return "", fmt.Errorf("checksum of file[%s] is empty", file)
+	}
+
+	return checksum, nil
+}
+
+// GetFileSize get the bytes of file in container's namespace
+func GetFileSize(ctx context.Context, cr, cId string, file string) (int64, error) {
+	if file == "" {
+		return -1, fmt.Errorf("\"file\" can not be empty")
+	}
+
+	re, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getFileSizeCmd(file), []string{namespace.MNT})
+	if err != nil {
+		return -1, err
+	}
+
+	reStr := strings.TrimSpace(re)
+	size, err := strconv.ParseInt(reStr, 10, 64)
+	if err != nil {
+		return -1, fmt.Errorf("%s is not a num: %s", reStr, err.Error())
+	}
+
+	return size, nil
+}
+
+// TruncateFile in container's namespace
+func TruncateFile(ctx context.Context, cr, cId string, file string, size int64) error {
+	if file == "" {
+		return fmt.Errorf("\"file\" can not be empty")
+	}
+
+	if size < 0 {
+		return fmt.Errorf("\"size\" can not less than 0")
+	}
+
+	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getTruncateFileCmd(file, size), []string{namespace.MNT})
 	return err
 }
 
@@ -110,6 +225,10 @@ func RemoveFile(ctx context.Context, cr, cId string, file string) error {
 	}
 
 	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getRemoveFileCmd(file), []string{namespace.MNT})
+	if err == nil {
+		setPathState(ctx, cr, cId, file, false)
+	}
+
 	return err
 }
 
@@ -119,6 +238,10 @@ func RemoveRF(ctx context.Context, cr, cId string, path string) error {
 	}
 
 	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getRemoveRFCmd(path), []string{namespace.MNT})
+	if err == nil {
+		setPathState(ctx, cr, cId, path, false)
+	}
+
 	return err
 }
 
@@ -128,6 +251,10 @@ func OverWriteFile(ctx context.Context, cr, cId string, path, content string) er
 	}
 
 	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getOverWriteFileCmd(path, content), []string{namespace.MNT})
+	if err == nil {
+		setPathState(ctx, cr, cId, path, true)
+	}
+
 	return err
 }
 
@@ -137,6 +264,10 @@ func MkdirForce(ctx context.Context, cr, cId string, dir string) error {
 	}
 
 	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getMkdirForceCmd(dir), []string{namespace.MNT})
+	if err == nil {
+		setPathState(ctx, cr, cId, dir, true)
+	}
+
 	return err
 }
 
@@ -145,6 +276,10 @@ func CheckDir(ctx context.Context, cr, cId string, dir string) (bool, error) {
 		return false, fmt.Errorf("\"dir\" can not be empty")
 	}
 
+	if exist, ok := getPathState(ctx, cr, cId, dir); ok {
+		return exist, nil
+	}
+
 	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getCheckDirCmd(dir), []string{namespace.MNT})
 	if err != nil {
 		if strings.Index(err.Error(), FileNotFoundKey) >= 0 {
@@ -180,6 +315,10 @@ func CheckFile(ctx context.Context, cr, cId string, file string) (bool, error) {
 		return false, fmt.Errorf("\"file\" can not be empty")
 	}
 
+	if exist, ok := getPathState(ctx, cr, cId, file); ok {
+		return exist, nil
+	}
+
 	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getCheckFileCmd(file), []string{namespace.MNT})
 	if err != nil {
 		if strings.Index(err.Error(), FileNotFoundKey) >= 0 {
@@ -198,6 +337,10 @@ func ExistPath(ctx context.Context, cr, cId string, path string) (bool, error) {
 		return false, fmt.Errorf("\"path\" can not be empty")
 	}
 
+	if exist, ok := getPathState(ctx, cr, cId, path); ok {
+		return exist, nil
+	}
+
 	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getPathExistCmd(path), []string{namespace.MNT})
 	if err != nil {
 		if strings.Index(err.Error(), FileNotFoundKey) >= 0 {
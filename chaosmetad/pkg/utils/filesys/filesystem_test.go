@@ -0,0 +1,43 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filesys
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestQuotePath(t *testing.T) {
+	tests := []string{
+		"/tmp/a.txt",
+		"/tmp/file with space",
+		"/tmp/it's; rm -rf /tmp/x",
+		"/tmp/$(id)`id`$HOME",
+		"/tmp/\"quoted\" \\n*",
+	}
+	for _, path := range tests {
+		t.Run(path, func(t *testing.T) {
+			out, err := exec.Command("bash", "-c", "printf '%s' "+QuotePath(path)).Output()
+			if err != nil {
+				t.Fatalf("run bash error: %v", err)
+			}
+			if string(out) != path {
+				t.Errorf("bash got %q, want %q", out, path)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filesys
+
+import (
+	"context"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MountInfoFile the mounts of the init process, which is in the same mount namespace as target. "/proc/self" can
+	// not be used, the proc in container is mounted for its pid namespace, where the process entered from host is
+	// invisible
+	MountInfoFile = "/proc/1/mountinfo"
+)
+
+func getMountInfoCmd() string {
+	return fmt.Sprintf("cat %s", MountInfoFile)
+}
+
+func getRealPathCmd(path string) string {
+	return fmt.Sprintf("readlink -f %s", QuotePath(path))
+}
+
+func getBindMountReadOnlyCmd(dir string) string {
+	dir = QuotePath(dir)
+	return fmt.Sprintf("mount --bind %s %s && mount -o remount,bind,ro %s", dir, dir, dir)
+}
+
+func getMountTmpfsCmd(source, dir, perm string) string {
+	return fmt.Sprintf("mount -t tmpfs -o mode=%s %s %s", perm, source, QuotePath(dir))
+}
+
+func getUmountCmd(dir string) string {
+	return fmt.Sprintf("umount -l %s", QuotePath(dir))
+}
+
+// unescapeMountPath the space, tab, newline and backslash in the paths of mountinfo are escaped in octal, eg: "\040"
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, "\\") {
+		return path
+	}
+
+	var re strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+4 <= len(path) {
+			if c, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				re.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+
+		re.WriteByte(path[i])
+	}
+
+	return re.String()
+}
+
+// parseMountIdList get the id of mounts whose mount point is dir from the content of mountinfo, in the order of
+// mountinfo, so the mount on top is the last
+func parseMountIdList(mountInfo, dir string) ([]int, error) {
+	var idList []int
+	for _, line := range strings.Split(mountInfo, "\n") {
+		// [mount id] [parent id] [major:minor] [root] [mount point] ...
+		fields := strings.Fields(line)
+		if len(fields) < 5 || unescapeMountPath(fields[4]) != dir {
+			continue
+		}
+
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid mount id: %s", fields[0], err.Error())
+		}
+
+		idList = append(idList, id)
+	}
+
+	return idList, nil
+}
+
+// GetMountIdList get the id of mounts whose mount point is dir in container's namespace, the mount on top is the last
+func GetMountIdList(ctx context.Context, cr, cId string, dir string) ([]int, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("\"dir\" can not be empty")
+	}
+
+	re, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getMountInfoCmd(), []string{namespace.MNT})
+	if err != nil {
+		return nil, fmt.Errorf("read %s error: %s", MountInfoFile, err.Error())
+	}
+
+	return parseMountIdList(re, dir)
+}
+
+// GetTopMountId return 0 if dir is not a mount point
+func GetTopMountId(ctx context.Context, cr, cId string, dir string) (int, error) {
+	idList, err := GetMountIdList(ctx, cr, cId, dir)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(idList) == 0 {
+		return 0, nil
+	}
+
+	return idList[len(idList)-1], nil
+}
+
+// GetRealPath the path with all symlinks resolved in container's namespace, which is the mount point shown in mountinfo
+func GetRealPath(ctx context.Context, cr, cId string, path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("\"path\" can not be empty")
+	}
+
+	re, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getRealPathCmd(path), []string{namespace.MNT})
+	return strings.TrimSpace(re), err
+}
+
+// BindMountReadOnly bind mount dir on itself and remount the new mount read-only, so the original mount is not changed
+func BindMountReadOnly(ctx context.Context, cr, cId string, dir string) error {
+	if dir == "" {
+		return fmt.Errorf("\"dir\" can not be empty")
+	}
+
+	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getBindMountReadOnlyCmd(dir), []string{namespace.MNT})
+	return err
+}
+
+// MountTmpfs mount an empty tmpfs on dir, the content of dir is hidden until umount
+func MountTmpfs(ctx context.Context, cr, cId string, source, dir, perm string) error {
+	if dir == "" {
+		return fmt.Errorf("\"dir\" can not be empty")
+	}
+
+	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getMountTmpfsCmd(source, dir, perm), []string{namespace.MNT})
+	return err
+}
+
+// Umount only the mount on top is removed. the mount is detached lazily, so it will not fail even if the files in it
+// are still in use
+func Umount(ctx context.Context, cr, cId string, dir string) error {
+	if dir == "" {
+		return fmt.Errorf("\"dir\" can not be empty")
+	}
+
+	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getUmountCmd(dir), []string{namespace.MNT})
+	return err
+}
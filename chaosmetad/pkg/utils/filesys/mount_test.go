@@ -0,0 +1,84 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filesys
+
+import (
+	"reflect"
+	"testing"
+)
+
+const mountInfoFixture = `1386 1230 0:118 / / rw,relatime master:412 - overlay overlay rw,lowerdir=/var/lib/docker/overlay2/l/A:/var/lib/docker/overlay2/l/B,upperdir=/var/lib/docker/overlay2/x/diff
+1387 1386 0:121 / /proc rw,nosuid,nodev,noexec,relatime - proc proc rw
+1388 1386 0:122 / /dev rw,nosuid - tmpfs tmpfs rw,size=65536k,mode=755
+1401 1386 253:1 /var/lib/docker/volumes/data/_data /data rw,relatime - ext4 /dev/vda1 rw
+1402 1386 253:1 /home/admin/my\040logs /home/admin/my\040logs rw,relatime - ext4 /dev/vda1 rw
+1403 1401 253:1 /var/lib/docker/volumes/data/_data /data ro,relatime - ext4 /dev/vda1 rw
+1404 1386 253:1 /tab /a\011b\134c rw,relatime - ext4 /dev/vda1 rw
+1405 1403 0:130 / /data rw,relatime - tmpfs chaosmeta_tmpfs rw,mode=755
+`
+
+func TestParseMountIdList(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    string
+		dir     string
+		want    []int
+		wantErr bool
+	}{
+		{name: "root", info: mountInfoFixture, dir: "/", want: []int{1386}},
+		{name: "stacked mounts", info: mountInfoFixture, dir: "/data", want: []int{1401, 1403, 1405}},
+		{name: "escaped space", info: mountInfoFixture, dir: "/home/admin/my logs", want: []int{1402}},
+		{name: "escaped tab and backslash", info: mountInfoFixture, dir: "/a\tb\\c", want: []int{1404}},
+		{name: "escaped form is not matched", info: mountInfoFixture, dir: "/home/admin/my\\040logs"},
+		{name: "root of mount is not matched", info: mountInfoFixture, dir: "/var/lib/docker/volumes/data/_data"},
+		{name: "not a mount point", info: mountInfoFixture, dir: "/data/sub"},
+		{name: "empty", info: "", dir: "/"},
+		{name: "bad mount id", info: "x 1 0:1 / /data rw - tmpfs tmpfs rw", dir: "/data", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMountIdList(tt.info, tt.dir)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseMountIdList() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMountIdList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnescapeMountPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/data", want: "/data"},
+		{path: "/my\\040logs", want: "/my logs"},
+		{path: "/a\\011b\\012c\\134d", want: "/a\tb\nc\\d"},
+		{path: "/end\\04", want: "/end\\04"},
+		{path: "/not\\9octal", want: "/not\\9octal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := unescapeMountPath(tt.path); got != tt.want {
+				t.Errorf("unescapeMountPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
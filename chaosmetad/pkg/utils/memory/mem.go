@@ -22,6 +22,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
@@ -29,10 +30,14 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cgroup"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/disk"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/filesys"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
 )
 
+// TmpfsMagic the filesystem type of tmpfs returned by statfs
+const TmpfsMagic = 0x01021994
+
 // CalculateFillKBytes The calculation of memory usage is consistent with the calculation method of the top command: Available/Total.
 // Because whether oom is calculated according to this
 func CalculateFillKBytes(ctx context.Context, cr, cId string, percent int, fillBytes string) (int64, error) {
@@ -65,6 +70,24 @@ func CalculateFillKBytes(ctx context.Context, cr, cId string, percent int, fillB
 	return fillKBytes, nil
 }
 
+// GetAvailableKBytes the available memory in KB, the available memory of host is used if the container has no limit
+func GetAvailableKBytes(ctx context.Context, cr, cId string) (int64, error) {
+	if cr != "" {
+		avail, err := getContainerMemAvailable(ctx, cr, cId)
+		if err == nil {
+			return int64(avail / 1024), nil
+		}
+		log.GetLogger(ctx).Debugf("get available mem of container[%s] error: %s, use host instead", cId, err.Error())
+	}
+
+	avail, err := getHostMemAvailable(ctx, "", "")
+	if err != nil {
+		return -1, err
+	}
+
+	return int64(avail), nil
+}
+
 func FillCache(ctx context.Context, cr, cId string, percent int, bytes string, dir string, filename string) error {
 	fillKBytes, err := CalculateFillKBytes(ctx, cr, cId, percent, bytes)
 	if err != nil {
@@ -99,6 +122,10 @@ func UndoTmpfs(ctx context.Context, dir string) error {
 
 	time.Sleep(500 * time.Millisecond)
 
+	if dryrun.Record(ctx, dryrun.KindFile, dryrun.TargetHost, fmt.Sprintf("rm -rf %s", dir)) {
+		return nil
+	}
+
 	if err := os.RemoveAll(dir); err != nil {
 		logger.Warnf("rm %s error: %s", dir, err.Error())
 		return fmt.Errorf("rm %s error: %s", dir, err.Error())
@@ -107,6 +134,20 @@ func UndoTmpfs(ctx context.Context, dir string) error {
 	return nil
 }
 
+// GetTmpfsUsedBytes the bytes used by the tmpfs mounted on dir, 0 if dir is not a tmpfs mount point
+func GetTmpfsUsedBytes(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, fmt.Errorf("statfs[%s] error: %s", dir, err.Error())
+	}
+
+	if stat.Type != TmpfsMagic {
+		return 0, nil
+	}
+
+	return (stat.Blocks - stat.Bfree) * uint64(stat.Bsize), nil
+}
+
 func getContainerMemTotal(ctx context.Context, cr, cId string) (memTotal float64, err error) {
 	path, err := cgroup.GetContainerCgroupPath(ctx, cr, cId, cgroup.MEMORY)
 	if err != nil {
@@ -0,0 +1,264 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package net
+
+import (
+	"context"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
+	"strings"
+)
+
+const (
+	FirewallIptables = "iptables"
+	FirewallNft      = "nft"
+
+	ChainInput  = "INPUT"
+	ChainOutput = "OUTPUT"
+
+	ProtocolICMP = "icmp"
+
+	DNSPort = 53
+
+	firewallTagPrefix = "chaosmeta_"
+)
+
+// FirewallRule match the flow by all the provided conditions, empty condition means no limit
+type FirewallRule struct {
+	Chain     string
+	Interface string
+	Protocol  string
+	SrcIp     []string
+	DstIp     []string
+	SrcPort   string // port range, eg: "8080:8090"
+	DstPort   string
+	Reject    bool // reject the flow(tcp reset for tcp flow, icmp port unreachable for others) instead of dropping it
+}
+
+// GetFirewallTag the tag of the firewall rules added by an experiment, used as the iptables comment and the nftables table name
+func GetFirewallTag(uid string) string {
+	return firewallTagPrefix + strings.ReplaceAll(uid, "-", "_")
+}
+
+func getIptablesRuleArgs(tag string, rule *FirewallRule) string {
+	var args []string
+	if rule.Interface != "" {
+		if rule.Chain == ChainInput {
+			args = append(args, "-i", rule.Interface)
+		} else {
+			args = append(args, "-o", rule.Interface)
+		}
+	}
+
+	if rule.Protocol != "" {
+		args = append(args, "-p", rule.Protocol)
+	}
+
+	if len(rule.SrcIp) > 0 {
+		args = append(args, "-s", strings.Join(rule.SrcIp, ","))
+	}
+
+	if len(rule.DstIp) > 0 {
+		args = append(args, "-d", strings.Join(rule.DstIp, ","))
+	}
+
+	if rule.SrcPort != "" {
+		args = append(args, "--sport", rule.SrcPort)
+	}
+
+	if rule.DstPort != "" {
+		args = append(args, "--dport", rule.DstPort)
+	}
+
+	args = append(args, "-m", "comment", "--comment", tag)
+	if !rule.Reject {
+		args = append(args, "-j", "DROP")
+	} else if rule.Protocol == ProtocolTCP {
+		args = append(args, "-j", "REJECT", "--reject-with", "tcp-reset")
+	} else {
+		args = append(args, "-j", "REJECT")
+	}
+
+	return strings.Join(args, " ")
+}
+
+func getNftRuleArgs(rule *FirewallRule) string {
+	var args []string
+	if rule.Interface != "" {
+		if rule.Chain == ChainInput {
+			args = append(args, "iifname", rule.Interface)
+		} else {
+			args = append(args, "oifname", rule.Interface)
+		}
+	}
+
+	if rule.Protocol != "" {
+		args = append(args, "ip protocol", rule.Protocol)
+	}
+
+	if len(rule.SrcIp) > 0 {
+		args = append(args, fmt.Sprintf("ip saddr { %s }", strings.Join(rule.SrcIp, ", ")))
+	}
+
+	if len(rule.DstIp) > 0 {
+		args = append(args, fmt.Sprintf("ip daddr { %s }", strings.Join(rule.DstIp, ", ")))
+	}
+
+	if rule.SrcPort != "" {
+		args = append(args, rule.Protocol, "sport", strings.ReplaceAll(rule.SrcPort, ":", "-"))
+	}
+
+	if rule.DstPort != "" {
+		args = append(args, rule.Protocol, "dport", strings.ReplaceAll(rule.DstPort, ":", "-"))
+	}
+
+	args = append(args, "counter")
+	if !rule.Reject {
+		args = append(args, "drop")
+	} else if rule.Protocol == ProtocolTCP {
+		args = append(args, "reject with tcp reset")
+	} else {
+		args = append(args, "reject")
+	}
+
+	return strings.Join(args, " ")
+}
+
+// getAddFirewallRulesCmd the rules are matched in the order of the list, and take precedence over the existing rules
+func getAddFirewallRulesCmd(tool, tag string, rules []*FirewallRule) string {
+	var cmdList []string
+	if tool == FirewallNft {
+		cmdList = append(cmdList, fmt.Sprintf("nft add table ip %s", tag),
+			fmt.Sprintf("nft 'add chain ip %s input { type filter hook input priority -10 ; policy accept ; }'", tag),
+			fmt.Sprintf("nft 'add chain ip %s output { type filter hook output priority -10 ; policy accept ; }'", tag))
+		for _, rule := range rules {
+			cmdList = append(cmdList, fmt.Sprintf("nft 'add rule ip %s %s %s'", tag, strings.ToLower(rule.Chain), getNftRuleArgs(rule)))
+		}
+	} else {
+		// "-I" insert the rule to the head of chain, so insert the rules in reverse order
+		for i := len(rules) - 1; i >= 0; i-- {
+			cmdList = append(cmdList, fmt.Sprintf("iptables -w -I %s %s", rules[i].Chain, getIptablesRuleArgs(tag, rules[i])))
+		}
+	}
+
+	return strings.Join(cmdList, " && ")
+}
+
+func getClearFirewallRulesCmd(tool, tag string) string {
+	if tool == FirewallNft {
+		return fmt.Sprintf("if nft list table ip %s > /dev/null 2>&1; then nft delete table ip %s; fi", tag, tag)
+	}
+
+	var cmdList []string
+	for _, chain := range []string{ChainInput, ChainOutput} {
+		cmdList = append(cmdList, fmt.Sprintf("iptables -w -S %s | grep -w -- '%s' | sed 's/^-A /-D /' | while read -r rule; do eval iptables -w $rule || exit 1; done", chain, tag))
+	}
+
+	return strings.Join(cmdList, " && ")
+}
+
+func AddFirewallRules(ctx context.Context, cr, cId, tool, tag string, rules []*FirewallRule) error {
+	if _, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getAddFirewallRulesCmd(tool, tag, rules), []string{namespace.NET}); err != nil {
+		if undoErr := ClearFirewallRules(ctx, cr, cId, tool, tag); undoErr != nil {
+			return fmt.Errorf("add %s rules error: %s, undo error: %s", tool, err.Error(), undoErr.Error())
+		}
+
+		return fmt.Errorf("add %s rules error: %s", tool, err.Error())
+	}
+
+	return nil
+}
+
+// ClearFirewallRules remove all the rules with the tag
+func ClearFirewallRules(ctx context.Context, cr, cId, tool, tag string) error {
+	if _, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getClearFirewallRulesCmd(tool, tag), []string{namespace.NET}); err != nil {
+		return fmt.Errorf("clear %s rules of %s error: %s", tool, tag, err.Error())
+	}
+
+	return nil
+}
+
+func getRedirectRuleArgs(tag string, port, toPort int) string {
+	return fmt.Sprintf("PREROUTING -p %s --dport %d -m comment --comment %s -j REDIRECT --to-ports %d", ProtocolTCP, port, tag, toPort)
+}
+
+// AddRedirectRule redirect the tcp flow sent to the local port to another local port by the nat table of iptables
+func AddRedirectRule(ctx context.Context, cr, cId, tag string, port, toPort int) error {
+	if _, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, fmt.Sprintf("iptables -w -t nat -I %s", getRedirectRuleArgs(tag, port, toPort)), []string{namespace.NET}); err != nil {
+		return fmt.Errorf("add redirect rule from port[%d] to port[%d] error: %s", port, toPort, err.Error())
+	}
+
+	return nil
+}
+
+// ClearRedirectRule remove all the redirect rules with the tag
+func ClearRedirectRule(ctx context.Context, cr, cId, tag string) error {
+	cmd := fmt.Sprintf("iptables -w -t nat -S PREROUTING | grep -w -- '%s' | sed 's/^-A /-D /' | while read -r rule; do eval iptables -w -t nat $rule || exit 1; done", tag)
+	if _, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, cmd, []string{namespace.NET}); err != nil {
+		return fmt.Errorf("clear redirect rules of %s error: %s", tag, err.Error())
+	}
+
+	return nil
+}
+
+// ExistRedirectRule check if the tcp flow sent to the local port is already redirected by another experiment
+func ExistRedirectRule(ctx context.Context, cr, cId string, port int) (bool, error) {
+	cmd := fmt.Sprintf("iptables -w -t nat -S PREROUTING | grep -- '--dport %d ' | grep -- '%s' | grep -w REDIRECT | wc -l", port, firewallTagPrefix)
+	re, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, cmd, []string{namespace.NET})
+	if err != nil {
+		return false, fmt.Errorf("check redirect rule of port[%d] error: %s", port, err.Error())
+	}
+
+	return strings.TrimSpace(re) != "0", nil
+}
+
+func getDNSRedirectRuleArgs(tag, protocol string, toPort, mark int) string {
+	return fmt.Sprintf("OUTPUT -p %s --dport %d -m mark ! --mark %d -m comment --comment %s -j REDIRECT --to-ports %d", protocol, DNSPort, mark, tag, toPort)
+}
+
+// AddDNSRedirectRule redirect the udp and tcp dns queries sent by local processes to the local port, except the ones with the mark
+func AddDNSRedirectRule(ctx context.Context, cr, cId, tag string, toPort, mark int) error {
+	for _, protocol := range []string{ProtocolUDP, ProtocolTCP} {
+		if _, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, fmt.Sprintf("iptables -w -t nat -I %s", getDNSRedirectRuleArgs(tag, protocol, toPort, mark)), []string{namespace.NET}); err != nil {
+			return fmt.Errorf("add %s dns redirect rule to port[%d] error: %s", protocol, toPort, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// ClearDNSRedirectRule remove all the dns redirect rules with the tag
+func ClearDNSRedirectRule(ctx context.Context, cr, cId, tag string) error {
+	cmd := fmt.Sprintf("iptables -w -t nat -S OUTPUT | grep -w -- '%s' | sed 's/^-A /-D /' | while read -r rule; do eval iptables -w -t nat $rule || exit 1; done", tag)
+	if _, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, cmd, []string{namespace.NET}); err != nil {
+		return fmt.Errorf("clear dns redirect rules of %s error: %s", tag, err.Error())
+	}
+
+	return nil
+}
+
+// ExistDNSRedirectRule check if the dns queries are already redirected by another experiment
+func ExistDNSRedirectRule(ctx context.Context, cr, cId string) (bool, error) {
+	cmd := fmt.Sprintf("iptables -w -t nat -S OUTPUT | grep -- '--dport %d ' | grep -- '%s' | grep -w REDIRECT | wc -l", DNSPort, firewallTagPrefix)
+	re, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, cmd, []string{namespace.NET})
+	if err != nil {
+		return false, fmt.Errorf("check dns redirect rule error: %s", err.Error())
+	}
+
+	return strings.TrimSpace(re) != "0", nil
+}
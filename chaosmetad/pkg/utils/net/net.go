@@ -23,6 +23,7 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
+	"hash/fnv"
 	"net"
 	"strconv"
 	"strings"
@@ -38,15 +39,9 @@ const (
 	ProtocolTCP6 = "tcp6"
 	ProtocolUDP  = "udp"
 	ProtocolUDP6 = "udp6"
-)
-
-func getExistTCRootQdiscCmd(netInterface string) string {
-	return fmt.Sprintf("tc qdisc ls dev %s | grep -w '1: root' | grep -v grep | wc -l", netInterface)
-}
 
-func GetClearTcRuleCmd(netInterface string) string {
-	return fmt.Sprintf("tc qdisc del dev %s root", netInterface)
-}
+	IfbPrefix = "cmifb"
+)
 
 func getAddNetemQdiscCmd(netInterface, parent, fault string, args string) string {
 	if parent == "" {
@@ -55,85 +50,21 @@ func getAddNetemQdiscCmd(netInterface, parent, fault string, args string) string
 		parent = fmt.Sprintf("parent %s", parent)
 	}
 
-	return fmt.Sprintf("tc qdisc add dev %s %s netem %s %s", netInterface, parent, fault, args)
-}
-
-func getAddPrioQdiscCmd(netInterface, parent, name string) string {
-	if parent == "" {
-		parent = "root"
-	} else {
-		parent = fmt.Sprintf("parent %s", parent)
-	}
-
-	return fmt.Sprintf("tc qdisc add dev %s %s handle %s prio bands 4", netInterface, parent, name)
-}
-
-func getAddHTBQdiscCmd(netInterface string) string {
-	return fmt.Sprintf("tc qdisc add dev %s root handle 1: htb default 1", netInterface)
-}
-
-func getAddLimitClassCmd(netInterface, rate, mode string) string {
-	subNum := 1
-	if mode == ModeNormal {
-		subNum = 2
+	// empty fault means args is a composite of several faults, eg: "delay 100ms loss 5%"
+	if fault != "" {
+		args = fmt.Sprintf("%s %s", fault, args)
 	}
 
-	return fmt.Sprintf("tc class add dev %s parent 1: classid 1:%d htb rate %s", netInterface, subNum, rate)
+	return fmt.Sprintf("tc qdisc add dev %s %s netem %s", netInterface, parent, args)
 }
 
-func AddLimitClass(ctx context.Context, cr, cId, netInterface, rate, mode string) error {
-	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getAddLimitClassCmd(netInterface, rate, mode), []string{namespace.NET})
-	return err
-}
-
-// AddHTBQdisc default 1:1
-func AddHTBQdisc(ctx context.Context, cr, cId, netInterface string) error {
-	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getAddHTBQdiscCmd(netInterface), []string{namespace.NET})
-	return err
-}
-
-func AddFilter(ctx context.Context, cr, cId, netInterface, target, srcIpListStr, dstIpListStr, srcPortListStr, dstPortListStr string) error {
-	cmd, err := getAddFilterCmd(ctx, netInterface, target, srcIpListStr, dstIpListStr, srcPortListStr, dstPortListStr)
-	if err != nil {
-		return fmt.Errorf("get filter cmd error: %s", err.Error())
-	}
-
-	_, err = cmdexec.ExecCommonWithNS(ctx, cr, cId, cmd, []string{namespace.NET})
-	return err
-}
-
-func AddPrioQdisc(ctx context.Context, cr, cId, netInterface, parent, name string) error {
-	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getAddPrioQdiscCmd(netInterface, parent, name), []string{namespace.NET})
-	return err
-}
-
-func AddNetemQdisc(ctx context.Context, cr, cId, netInterface, parent, fault string, args string) error {
-	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getAddNetemQdiscCmd(netInterface, parent, fault, args), []string{namespace.NET})
-	return err
-}
-
-func ClearTcRule(ctx context.Context, cr, cId, netInterface string) error {
-	_, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, GetClearTcRuleCmd(netInterface), []string{namespace.NET})
-	return err
-}
-
-func ExistTCRootQdisc(ctx context.Context, cr, cId string, netInterface string) (bool, error) {
-	if netInterface == "" {
-		return false, fmt.Errorf("interface is empty")
-	}
-
-	reStr, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getExistTCRootQdiscCmd(netInterface), []string{namespace.NET})
-	if err != nil {
-		return false, fmt.Errorf("exec cmd error: %s", err.Error())
-	}
-
-	reStr = strings.TrimSpace(reStr)
-	count, err := strconv.Atoi(reStr)
-	if err != nil {
-		return false, fmt.Errorf("tc rule count is not a num: %s, output: %s", err.Error(), reStr)
-	}
-
-	return count != 0, nil
+// GetIfbName the ifb device used to redirect the ingress flow of netInterface. the name is derived from the hash of
+// netInterface instead of netInterface itself, because the length of device name is limited, and the truncated names
+// of two interfaces with a common prefix are the same
+func GetIfbName(netInterface string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(netInterface))
+	return fmt.Sprintf("%s%08x", IfbPrefix, h.Sum32())
 }
 
 // GetValidIPList only support ipv4
@@ -196,6 +127,29 @@ func GetValidPortList(portStr string) ([]string, error) {
 	return re, nil
 }
 
+// GetValidPortRangeList convert port list like "8080,12000/8" to port range list like "8080:8080,11776:12031"
+func GetValidPortRangeList(portStr string) ([]string, error) {
+	portList, err := GetValidPortList(portStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var re = make([]string, len(portList))
+	for i, unit := range portList {
+		portArr := strings.Split(unit, utils.PortSplit)
+		port, _ := strconv.ParseInt(portArr[0], 10, 64)
+		mask, err := strconv.ParseInt(portArr[1], 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s port mask is not a num", portArr[1])
+		}
+
+		start := port & mask
+		re[i] = fmt.Sprintf("%d:%d", start, start|(^mask&0xffff))
+	}
+
+	return re, nil
+}
+
 func getPortMask(mask int) string {
 	needZero := PortBit - mask
 	var maskValue int
@@ -210,73 +164,6 @@ func getPortMask(mask int) string {
 	return fmt.Sprintf("0x%x", maskValue)
 }
 
-func getAddFilterCmd(ctx context.Context, netInterface, target, srcIpListStr, dstIpListStr, srcPortListStr, dstPortListStr string) (tcFilterStr string, err error) {
-	srcIpList, dstIpList, srcPortList, dstPortList, err := getStrList(srcIpListStr, dstIpListStr, srcPortListStr, dstPortListStr)
-	if err != nil {
-		return
-	}
-
-	var si, di, sp, dp int
-	var ruleArr []string
-	var siLen, diLen, spLen, dpLen = len(srcIpList), len(dstIpList), len(srcPortList), len(dstPortList)
-	for {
-		if si >= siLen && di >= diLen && sp >= spLen && dp >= dpLen {
-			break
-		}
-
-		var ifCtn = true
-		if (siLen > 0 && si == siLen) || (diLen > 0 && di == diLen) || (spLen > 0 && sp == spLen) || (dpLen > 0 && dp == dpLen) {
-			ifCtn = false
-		}
-
-		if ifCtn {
-			var args string
-			if siLen > 0 {
-				args += fmt.Sprintf("match ip src %s ", srcIpList[si])
-			}
-
-			if diLen > 0 {
-				args += fmt.Sprintf("match ip dst %s ", dstIpList[di])
-			}
-
-			if spLen > 0 {
-				portArr := strings.Split(srcPortList[sp], utils.PortSplit)
-				args += fmt.Sprintf("match ip sport %s %s ", portArr[0], portArr[1])
-			}
-
-			if dpLen > 0 {
-				portArr := strings.Split(dstPortList[dp], utils.PortSplit)
-				args += fmt.Sprintf("match ip dport %s %s ", portArr[0], portArr[1])
-			}
-
-			if args != "" {
-				ruleArr = append(ruleArr, fmt.Sprintf("tc filter add dev %s parent 1: prio 1 protocol ip u32 %sflowid %s", netInterface, args, target))
-				if len(ruleArr) > MaxRuleCount {
-					err = fmt.Errorf("filter rule count is larget than %d", MaxRuleCount)
-					return
-				}
-			}
-		}
-
-		if si < len(srcIpList) {
-			si++
-		} else if di < len(dstIpList) {
-			di++
-			si = 0
-		} else if sp < len(srcPortList) {
-			sp++
-			si, di = 0, 0
-		} else if dp < len(dstPortList) {
-			dp++
-			si, di, sp = 0, 0, 0
-		}
-	}
-
-	log.GetLogger(ctx).Debugf("filter rule count: %d", len(ruleArr))
-	tcFilterStr = strings.Join(ruleArr, utils.CmdSplit)
-	return
-}
-
 func getStrList(srcIpListStr, dstIpListStr, srcPortListStr, dstPortListStr string) (srcIpList, dstIpList, srcPortList, dstPortList []string, err error) {
 	if srcIpListStr != "" {
 		srcIpList, err = GetValidIPList(srcIpListStr, true)
@@ -0,0 +1,36 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package net
+
+import "testing"
+
+func TestGetIfbName(t *testing.T) {
+	names := map[string]string{}
+	for _, netInterface := range []string{"eth0", "eth1", "enp0s31f6", "veth1234567890a", "veth1234567890b", "br-0123456789ab", "br-0123456789ac"} {
+		ifb := GetIfbName(netInterface)
+		if len(ifb) > 15 {
+			t.Errorf("GetIfbName(%s) = %s, longer than 15", netInterface, ifb)
+		}
+		if ifb != GetIfbName(netInterface) {
+			t.Errorf("GetIfbName(%s) is not stable", netInterface)
+		}
+		if other, ok := names[ifb]; ok {
+			t.Errorf("GetIfbName(%s) = GetIfbName(%s) = %s", netInterface, other, ifb)
+		}
+		names[ifb] = netInterface
+	}
+}
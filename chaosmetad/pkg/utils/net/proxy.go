@@ -0,0 +1,139 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package net
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/process"
+)
+
+// CheckProxyPort check if the proxy port is available and the target port is not intercepted by other experiment
+func CheckProxyPort(ctx context.Context, cr, cId string, port, proxyPort int) error {
+	if port <= 0 || port > 65535 {
+		return fmt.Errorf("\"port\" should in (0, 65536)")
+	}
+
+	if proxyPort <= 0 || proxyPort > 65535 {
+		return fmt.Errorf("\"proxy-port\" should in (0, 65536)")
+	}
+
+	if proxyPort == port {
+		return fmt.Errorf("\"proxy-port\" should not be the same as \"port\"")
+	}
+
+	if !cmdexec.SupportCmd(FirewallIptables) {
+		return fmt.Errorf("not support command \"%s\"", FirewallIptables)
+	}
+
+	pid, err := GetPidByPort(ctx, cr, cId, proxyPort, ProtocolTCP)
+	if err != nil {
+		return fmt.Errorf("check \"proxy-port\"[%d] error: %s", proxyPort, err.Error())
+	}
+
+	if pid != utils.NoPid {
+		return fmt.Errorf("\"proxy-port\"[%d] is occupied by process[%d]", proxyPort, pid)
+	}
+
+	isRedirect, err := ExistRedirectRule(ctx, cr, cId, port)
+	if err != nil {
+		return err
+	}
+
+	if isRedirect {
+		return fmt.Errorf("port[%d] has been intercepted by other experiment", port)
+	}
+
+	return nil
+}
+
+func GetProxyKey(tool, uid string) string {
+	return fmt.Sprintf("%s %s", tool, uid)
+}
+
+// StartProxy start the proxy tool with the rule in the target network namespace, then redirect the flow sent to target port to it.
+// the tool is executed as "[tool] [uid] [proxy port] [rule(base64 of json)]", return the pid of proxy in host's pid ns
+func StartProxy(ctx context.Context, cr, cId, uid, tool string, port, proxyPort int, rule interface{}) (int, error) {
+	pid, err := startProxyTool(ctx, cr, cId, uid, tool, proxyPort, rule)
+	if err != nil {
+		return pid, err
+	}
+
+	if err := AddRedirectRule(ctx, cr, cId, GetFirewallTag(uid), port, proxyPort); err != nil {
+		return pid, err
+	}
+
+	return pid, nil
+}
+
+// StartDNSProxy same as StartProxy, but the dns queries sent by the processes in the target network namespace are redirected.
+// the queries sent to upstream by the proxy should be marked with the mark to avoid being redirected to itself
+func StartDNSProxy(ctx context.Context, cr, cId, uid, tool string, proxyPort, mark int, rule interface{}) (int, error) {
+	pid, err := startProxyTool(ctx, cr, cId, uid, tool, proxyPort, rule)
+	if err != nil {
+		return pid, err
+	}
+
+	if err := AddDNSRedirectRule(ctx, cr, cId, GetFirewallTag(uid), proxyPort, mark); err != nil {
+		return pid, err
+	}
+
+	return pid, nil
+}
+
+func startProxyTool(ctx context.Context, cr, cId, uid, tool string, proxyPort int, rule interface{}) (int, error) {
+	ruleBytes, err := json.Marshal(rule)
+	if err != nil {
+		return utils.NoPid, fmt.Errorf("marshal proxy rule error: %s", err.Error())
+	}
+
+	cmd := fmt.Sprintf("%s %s %d %s", utils.GetToolPath(tool), uid, proxyPort, base64.StdEncoding.EncodeToString(ruleBytes))
+	if err := cmdexec.WaitCommonWithNS(ctx, cr, cId, cmd, []string{namespace.NET}); err != nil {
+		return utils.NoPid, fmt.Errorf("start proxy error: %s", err.Error())
+	}
+
+	pid, err := process.GetPidByKeyWithoutExec(ctx, GetProxyKey(tool, uid))
+	if err != nil {
+		log.GetLogger(ctx).Warnf("get pid of proxy error: %s", err.Error())
+	}
+
+	return pid, nil
+}
+
+// StopProxy remove the redirect rule first to make sure that no flow is sent to a stopped proxy
+func StopProxy(ctx context.Context, cr, cId, uid, tool string) error {
+	if err := ClearRedirectRule(ctx, cr, cId, GetFirewallTag(uid)); err != nil {
+		return err
+	}
+
+	return process.CheckExistAndKillByKey(ctx, GetProxyKey(tool, uid))
+}
+
+// StopDNSProxy remove the redirect rule first to make sure that no dns query is sent to a stopped proxy
+func StopDNSProxy(ctx context.Context, cr, cId, uid, tool string) error {
+	if err := ClearDNSRedirectRule(ctx, cr, cId, GetFirewallTag(uid)); err != nil {
+		return err
+	}
+
+	return process.CheckExistAndKillByKey(ctx, GetProxyKey(tool, uid))
+}
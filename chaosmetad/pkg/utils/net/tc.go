@@ -0,0 +1,1010 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package net
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/crclient"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	RuleKindQdisc  = "qdisc"
+	RuleKindClass  = "class"
+	RuleKindFilter = "filter"
+
+	tcRootHandle    = "1:"
+	tcIngressHandle = "ffff:"
+	tcFilterPrio    = 1
+	prioBandsCount  = 4
+)
+
+// TcError the structured error of a tc operation
+type TcError struct {
+	Op     string
+	Device string
+	Parent string
+	Err    error
+}
+
+func (e *TcError) Error() string {
+	if e.Parent == "" {
+		return fmt.Sprintf("%s for %s error: %s", e.Op, e.Device, e.Err.Error())
+	}
+
+	return fmt.Sprintf("%s for %s(parent %s) error: %s", e.Op, e.Device, e.Parent, e.Err.Error())
+}
+
+func (e *TcError) Unwrap() error {
+	return e.Err
+}
+
+// TcRule a qdisc, class or filter of a network interface
+type TcRule struct {
+	Kind   string `json:"kind"`
+	Type   string `json:"type"`
+	Handle string `json:"handle"`
+	Parent string `json:"parent"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// execWithHandle run f with a netlink handle in the network namespace of the target container, or the host if cr is empty
+func execWithHandle(ctx context.Context, cr, cId string, f func(h *netlink.Handle) error) error {
+	var h *netlink.Handle
+	if cr == "" {
+		var err error
+		if h, err = netlink.NewHandle(); err != nil {
+			return fmt.Errorf("create netlink handle error: %s", err.Error())
+		}
+	} else {
+		client, err := crclient.GetClient(ctx, cr)
+		if err != nil {
+			return fmt.Errorf("get %s client error: %s", cr, err.Error())
+		}
+
+		pid, err := client.GetPidById(ctx, cId)
+		if err != nil {
+			return fmt.Errorf("get pid of container[%s] error: %s", cId, err.Error())
+		}
+
+		ns, err := netns.GetFromPid(pid)
+		if err != nil {
+			return fmt.Errorf("get net namespace of process[%d] error: %s", pid, err.Error())
+		}
+		defer ns.Close()
+
+		if h, err = netlink.NewHandleAt(ns); err != nil {
+			return fmt.Errorf("create netlink handle in net namespace of process[%d] error: %s", pid, err.Error())
+		}
+	}
+	defer h.Delete()
+
+	return f(h)
+}
+
+// ParseHandle parse the handle string of tc, eg: "1:4", "ffff:"
+func ParseHandle(handleStr string) (uint32, error) {
+	arr := strings.Split(handleStr, ":")
+	if len(arr) != 2 {
+		return 0, fmt.Errorf("handle[%s] is invalid", handleStr)
+	}
+
+	var major, minor uint64
+	var err error
+	if arr[0] != "" {
+		if major, err = strconv.ParseUint(arr[0], 16, 16); err != nil {
+			return 0, fmt.Errorf("major of handle[%s] is invalid: %s", handleStr, err.Error())
+		}
+	}
+
+	if arr[1] != "" {
+		if minor, err = strconv.ParseUint(arr[1], 16, 16); err != nil {
+			return 0, fmt.Errorf("minor of handle[%s] is invalid: %s", handleStr, err.Error())
+		}
+	}
+
+	return netlink.MakeHandle(uint16(major), uint16(minor)), nil
+}
+
+// getParentHandle empty parent means root
+func getParentHandle(parent string) (uint32, error) {
+	if parent == "" {
+		return netlink.HANDLE_ROOT, nil
+	}
+
+	return ParseHandle(parent)
+}
+
+func getLink(ctx context.Context, h *netlink.Handle, netInterface string) (netlink.Link, error) {
+	if netInterface == "" {
+		return nil, fmt.Errorf("interface is empty")
+	}
+
+	link, err := h.LinkByName(netInterface)
+	if err != nil {
+		// the ifb device is not really created in dry-run mode
+		if isLinkNotFound(err) && dryrun.IsDryRun(ctx) && strings.HasPrefix(netInterface, IfbPrefix) {
+			return &netlink.Ifb{LinkAttrs: netlink.LinkAttrs{Name: netInterface}}, nil
+		}
+
+		return nil, fmt.Errorf("get link of %s error: %s", netInterface, err.Error())
+	}
+
+	return link, nil
+}
+
+func isLinkNotFound(err error) bool {
+	var notFoundErr netlink.LinkNotFoundError
+	return errors.As(err, &notFoundErr)
+}
+
+func addQdisc(ctx context.Context, cr, cId, netInterface, parent, op string, newQdisc func(attrs netlink.QdiscAttrs) (netlink.Qdisc, error)) error {
+	err := execWithHandle(ctx, cr, cId, func(h *netlink.Handle) error {
+		link, err := getLink(ctx, h, netInterface)
+		if err != nil {
+			return err
+		}
+
+		parentHandle, err := getParentHandle(parent)
+		if err != nil {
+			return err
+		}
+
+		qdisc, err := newQdisc(netlink.QdiscAttrs{LinkIndex: link.Attrs().Index, Parent: parentHandle})
+		if err != nil {
+			return err
+		}
+
+		log.GetLogger(ctx).Debugf("%s for %s: %s", op, netInterface, qdisc)
+		if dryrun.Record(ctx, dryrun.KindNetlink, dryrun.GetTarget(cr, cId), fmt.Sprintf("%s for %s: parent: %s, handle: %s, %s", op, netInterface, netlink.HandleStr(qdisc.Attrs().Parent), netlink.HandleStr(qdisc.Attrs().Handle), qdisc)) {
+			return nil
+		}
+
+		return h.QdiscAdd(qdisc)
+	})
+
+	if err != nil {
+		return &TcError{Op: op, Device: netInterface, Parent: parent, Err: err}
+	}
+
+	return nil
+}
+
+// AddNetemQdisc the handle of netem qdisc is "1:" if parent is root. the faults which are not supported by
+// netlink(eg: "distribution", "rate") will be executed by "tc" command
+func AddNetemQdisc(ctx context.Context, cr, cId, netInterface, parent, fault string, args string) error {
+	if fault != "" {
+		args = fmt.Sprintf("%s %s", fault, args)
+	}
+
+	attrs, needTc, err := parseNetemArgs(args)
+	if err != nil {
+		return &TcError{Op: "parse netem args", Device: netInterface, Parent: parent, Err: err}
+	}
+
+	if needTc {
+		if !cmdexec.SupportCmd("tc") {
+			return &TcError{Op: "add netem qdisc", Device: netInterface, Parent: parent, Err: fmt.Errorf("not support command \"tc\", which is necessary for netem args: %s", args)}
+		}
+
+		if _, err := cmdexec.ExecCommonWithNS(ctx, cr, cId, getAddNetemQdiscCmd(netInterface, parent, "", args), []string{namespace.NET}); err != nil {
+			return &TcError{Op: "add netem qdisc", Device: netInterface, Parent: parent, Err: err}
+		}
+
+		return nil
+	}
+
+	return addQdisc(ctx, cr, cId, netInterface, parent, "add netem qdisc", func(qAttrs netlink.QdiscAttrs) (netlink.Qdisc, error) {
+		if qAttrs.Parent == netlink.HANDLE_ROOT {
+			qAttrs.Handle, _ = ParseHandle(tcRootHandle)
+		}
+
+		return netlink.NewNetem(qAttrs, *attrs), nil
+	})
+}
+
+// AddPrioQdisc add a prio qdisc with 4 bands, the 4th band has no flow by default and is used by filters
+func AddPrioQdisc(ctx context.Context, cr, cId, netInterface, parent, name string) error {
+	return addQdisc(ctx, cr, cId, netInterface, parent, "add prio qdisc", func(qAttrs netlink.QdiscAttrs) (netlink.Qdisc, error) {
+		handle, err := ParseHandle(name)
+		if err != nil {
+			return nil, err
+		}
+
+		qAttrs.Handle = handle
+		prio := netlink.NewPrio(qAttrs)
+		prio.Bands = prioBandsCount
+		return prio, nil
+	})
+}
+
+// AddHTBQdisc default 1:1
+func AddHTBQdisc(ctx context.Context, cr, cId, netInterface string) error {
+	return addQdisc(ctx, cr, cId, netInterface, "", "add htb qdisc", func(qAttrs netlink.QdiscAttrs) (netlink.Qdisc, error) {
+		qAttrs.Handle, _ = ParseHandle(tcRootHandle)
+		htb := netlink.NewHtb(qAttrs)
+		htb.Defcls = 1
+		return htb, nil
+	})
+}
+
+func AddLimitClass(ctx context.Context, cr, cId, netInterface, rate, mode string) error {
+	subNum := 1
+	if mode == ModeNormal {
+		subNum = 2
+	}
+
+	err := execWithHandle(ctx, cr, cId, func(h *netlink.Handle) error {
+		link, err := getLink(ctx, h, netInterface)
+		if err != nil {
+			return err
+		}
+
+		rateBit, err := utils.GetSpeedBit(rate)
+		if err != nil {
+			return fmt.Errorf("rate[%s] is invalid: %s", rate, err.Error())
+		}
+
+		class := netlink.NewHtbClass(netlink.ClassAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    netlink.MakeHandle(1, 0),
+			Handle:    netlink.MakeHandle(1, uint16(subNum)),
+		}, netlink.HtbClassAttrs{Rate: uint64(rateBit)})
+
+		if dryrun.Record(ctx, dryrun.KindNetlink, dryrun.GetTarget(cr, cId), fmt.Sprintf("add htb class for %s: %s", netInterface, class)) {
+			return nil
+		}
+
+		return h.ClassAdd(class)
+	})
+
+	if err != nil {
+		return &TcError{Op: "add htb class", Device: netInterface, Parent: tcRootHandle, Err: err}
+	}
+
+	return nil
+}
+
+// AddFilter add an u32 filter for each combination of the ip and port conditions, the matched flow will be sent to target class
+func AddFilter(ctx context.Context, cr, cId, netInterface, target, srcIpListStr, dstIpListStr, srcPortListStr, dstPortListStr string) error {
+	selList, err := getU32SelList(srcIpListStr, dstIpListStr, srcPortListStr, dstPortListStr)
+	if err != nil {
+		return &TcError{Op: "get filter", Device: netInterface, Parent: tcRootHandle, Err: err}
+	}
+
+	log.GetLogger(ctx).Debugf("filter rule count: %d", len(selList))
+	err = execWithHandle(ctx, cr, cId, func(h *netlink.Handle) error {
+		link, err := getLink(ctx, h, netInterface)
+		if err != nil {
+			return err
+		}
+
+		classId, err := ParseHandle(target)
+		if err != nil {
+			return err
+		}
+
+		for _, sel := range selList {
+			filter := &netlink.U32{
+				FilterAttrs: netlink.FilterAttrs{
+					LinkIndex: link.Attrs().Index,
+					Parent:    netlink.MakeHandle(1, 0),
+					Priority:  tcFilterPrio,
+					Protocol:  unix.ETH_P_IP,
+				},
+				ClassId: classId,
+				Sel:     sel,
+			}
+
+			if dryrun.Record(ctx, dryrun.KindNetlink, dryrun.GetTarget(cr, cId), fmt.Sprintf("add u32 filter for %s: %s, classid: %s, sel: %+v", netInterface, filter.Attrs(), netlink.HandleStr(classId), *sel)) {
+				continue
+			}
+
+			if err := h.FilterAdd(filter); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return &TcError{Op: "add filter", Device: netInterface, Parent: tcRootHandle, Err: err}
+	}
+
+	return nil
+}
+
+// AddCgroupFilter the flow will be sent to the class which is the classid of the net_cls cgroup of its process
+func AddCgroupFilter(ctx context.Context, cr, cId, netInterface string) error {
+	err := execWithHandle(ctx, cr, cId, func(h *netlink.Handle) error {
+		link, err := getLink(ctx, h, netInterface)
+		if err != nil {
+			return err
+		}
+
+		filter := &netlink.GenericFilter{
+			FilterAttrs: netlink.FilterAttrs{
+				LinkIndex: link.Attrs().Index,
+				Handle:    netlink.MakeHandle(1, 0),
+				Parent:    netlink.MakeHandle(1, 0),
+				Priority:  tcFilterPrio,
+				Protocol:  unix.ETH_P_ALL,
+			},
+			FilterType: "cgroup",
+		}
+
+		if dryrun.Record(ctx, dryrun.KindNetlink, dryrun.GetTarget(cr, cId), fmt.Sprintf("add cgroup filter for %s: %s", netInterface, filter.Attrs())) {
+			return nil
+		}
+
+		return h.FilterAdd(filter)
+	})
+
+	if err != nil {
+		return &TcError{Op: "add cgroup filter", Device: netInterface, Parent: tcRootHandle, Err: err}
+	}
+
+	return nil
+}
+
+// ClearTcRule delete the root qdisc added by chaosmeta(with handle "1:"), the classes and filters under it will be
+// removed together. the root qdisc with other handle is not created by the experiment, so it is kept
+func ClearTcRule(ctx context.Context, cr, cId, netInterface string) error {
+	return clearRootQdisc(ctx, cr, cId, netInterface, false)
+}
+
+// ResetTcRule delete the root qdisc whatever its handle is, used to force to inject when there are other tc rules
+func ResetTcRule(ctx context.Context, cr, cId, netInterface string) error {
+	return clearRootQdisc(ctx, cr, cId, netInterface, true)
+}
+
+func clearRootQdisc(ctx context.Context, cr, cId, netInterface string, all bool) error {
+	if dryrun.Record(ctx, dryrun.KindNetlink, dryrun.GetTarget(cr, cId), fmt.Sprintf("delete root qdisc of %s", netInterface)) {
+		return nil
+	}
+
+	rootHandle, _ := ParseHandle(tcRootHandle)
+	err := execWithHandle(ctx, cr, cId, func(h *netlink.Handle) error {
+		link, err := getLink(ctx, h, netInterface)
+		if err != nil {
+			return err
+		}
+
+		qdiscList, err := h.QdiscList(link)
+		if err != nil {
+			return fmt.Errorf("list qdisc error: %s", err.Error())
+		}
+
+		for _, qdisc := range qdiscList {
+			if qdisc.Attrs().Parent != netlink.HANDLE_ROOT {
+				continue
+			}
+
+			if !all && qdisc.Attrs().Handle != rootHandle {
+				log.GetLogger(ctx).Warnf("root qdisc[%s] of %s is not added by chaosmeta, skip", netlink.HandleStr(qdisc.Attrs().Handle), netInterface)
+				return nil
+			}
+
+			return h.QdiscDel(qdisc)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return &TcError{Op: "clear root qdisc", Device: netInterface, Err: err}
+	}
+
+	return nil
+}
+
+// ListTcRule list all the qdiscs, classes and filters of netInterface
+func ListTcRule(ctx context.Context, cr, cId, netInterface string) ([]*TcRule, error) {
+	var ruleList []*TcRule
+	err := execWithHandle(ctx, cr, cId, func(h *netlink.Handle) error {
+		link, err := getLink(ctx, h, netInterface)
+		if err != nil {
+			return err
+		}
+
+		qdiscList, err := h.QdiscList(link)
+		if err != nil {
+			return fmt.Errorf("list qdisc error: %s", err.Error())
+		}
+
+		for _, qdisc := range qdiscList {
+			attrs := qdisc.Attrs()
+			ruleList = append(ruleList, &TcRule{Kind: RuleKindQdisc, Type: qdisc.Type(), Handle: netlink.HandleStr(attrs.Handle),
+				Parent: netlink.HandleStr(attrs.Parent), Detail: getQdiscDetail(qdisc)})
+		}
+
+		classList, err := h.ClassList(link, 0)
+		if err != nil {
+			return fmt.Errorf("list class error: %s", err.Error())
+		}
+
+		for _, class := range classList {
+			attrs := class.Attrs()
+			ruleList = append(ruleList, &TcRule{Kind: RuleKindClass, Type: class.Type(), Handle: netlink.HandleStr(attrs.Handle),
+				Parent: netlink.HandleStr(attrs.Parent), Detail: getClassDetail(class)})
+		}
+
+		for _, qdisc := range qdiscList {
+			if qdisc.Attrs().Handle == 0 {
+				continue
+			}
+
+			filterList, err := h.FilterList(link, qdisc.Attrs().Handle)
+			if err != nil {
+				return fmt.Errorf("list filter of qdisc[%s] error: %s", netlink.HandleStr(qdisc.Attrs().Handle), err.Error())
+			}
+
+			for _, filter := range filterList {
+				attrs := filter.Attrs()
+				ruleList = append(ruleList, &TcRule{Kind: RuleKindFilter, Type: filter.Type(), Handle: netlink.HandleStr(attrs.Handle),
+					Parent: netlink.HandleStr(attrs.Parent), Detail: getFilterDetail(filter)})
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, &TcError{Op: "list tc rule", Device: netInterface, Err: err}
+	}
+
+	return ruleList, nil
+}
+
+// CountTcRule the number of the tc rules added by chaosmeta on netInterface: the rules under the root handle "1:" and
+// the ingress qdisc
+func CountTcRule(ctx context.Context, cr, cId, netInterface string) (int, error) {
+	ruleList, err := ListTcRule(ctx, cr, cId, netInterface)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, rule := range ruleList {
+		for _, handle := range []string{rule.Handle, rule.Parent} {
+			if strings.HasPrefix(handle, tcRootHandle) || strings.HasPrefix(handle, tcIngressHandle) {
+				count++
+				break
+			}
+		}
+	}
+
+	return count, nil
+}
+
+func getQdiscDetail(qdisc netlink.Qdisc) string {
+	switch q := qdisc.(type) {
+	case *netlink.Netem:
+		return fmt.Sprintf("latency: %dus, jitter: %dus, loss: %.2f%%, duplicate: %.2f%%, corrupt: %.2f%%, reorder: %.2f%%, gap: %d",
+			q.Latency, q.Jitter, u32ToPercent(q.Loss), u32ToPercent(q.Duplicate), u32ToPercent(q.CorruptProb), u32ToPercent(q.ReorderProb), q.Gap)
+	case *netlink.Prio:
+		return fmt.Sprintf("bands: %d", q.Bands)
+	case *netlink.Htb:
+		return fmt.Sprintf("default: %x", q.Defcls)
+	default:
+		return ""
+	}
+}
+
+func getClassDetail(class netlink.Class) string {
+	if c, ok := class.(*netlink.HtbClass); ok {
+		return fmt.Sprintf("rate: %dbit, ceil: %dbit", c.Rate*8, c.Ceil*8)
+	}
+
+	return ""
+}
+
+func getFilterDetail(filter netlink.Filter) string {
+	u32, ok := filter.(*netlink.U32)
+	if !ok {
+		return ""
+	}
+
+	var keyList []string
+	if u32.Sel != nil {
+		for _, key := range u32.Sel.Keys {
+			keyList = append(keyList, fmt.Sprintf("%08x/%08x at %d", key.Val, key.Mask, key.Off))
+		}
+	}
+
+	return fmt.Sprintf("flowid: %s, match: [%s]", netlink.HandleStr(u32.ClassId), strings.Join(keyList, ", "))
+}
+
+func u32ToPercent(value uint32) float64 {
+	return float64(value) * 100 / float64(^uint32(0))
+}
+
+func existQdisc(ctx context.Context, cr, cId, netInterface string, match func(rule *TcRule) bool) (bool, error) {
+	ruleList, err := ListTcRule(ctx, cr, cId, netInterface)
+	if err != nil {
+		return false, err
+	}
+
+	for _, rule := range ruleList {
+		if rule.Kind == RuleKindQdisc && match(rule) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ExistTCRootQdisc only the root qdisc with handle "1:" is regarded as a tc rule, the default root qdisc of system is not
+func ExistTCRootQdisc(ctx context.Context, cr, cId string, netInterface string) (bool, error) {
+	root := netlink.HandleStr(netlink.HANDLE_ROOT)
+	handle := netlink.HandleStr(netlink.MakeHandle(1, 0))
+	return existQdisc(ctx, cr, cId, netInterface, func(rule *TcRule) bool {
+		return rule.Parent == root && rule.Handle == handle
+	})
+}
+
+func ExistTCIngressQdisc(ctx context.Context, cr, cId string, netInterface string) (bool, error) {
+	return existQdisc(ctx, cr, cId, netInterface, func(rule *TcRule) bool {
+		return rule.Type == "ingress"
+	})
+}
+
+func ExistLink(ctx context.Context, cr, cId string, name string) (bool, error) {
+	var exist bool
+	err := execWithHandle(ctx, cr, cId, func(h *netlink.Handle) error {
+		_, err := h.LinkByName(name)
+		if err == nil {
+			exist = true
+			return nil
+		}
+
+		if isLinkNotFound(err) {
+			return nil
+		}
+
+		return err
+	})
+
+	return exist, err
+}
+
+// AddIngressRedirect create an ifb device and redirect the ingress flow of netInterface to it, then the ingress flow
+// can be controlled by the egress rule of the ifb device. return the name of ifb device
+func AddIngressRedirect(ctx context.Context, cr, cId, netInterface string) (string, error) {
+	logger := log.GetLogger(ctx)
+	// kernel module is global, so load it in host. "numifbs=0" avoid creating the default ifb0 and ifb1
+	if err := cmdexec.RunBashCmdWithoutOutput(ctx, "modprobe ifb numifbs=0"); err != nil {
+		logger.Warnf("load kernel module \"ifb\" error: %s", err.Error())
+	}
+
+	ifb := GetIfbName(netInterface)
+	err := execWithHandle(ctx, cr, cId, func(h *netlink.Handle) error {
+		link, err := getLink(ctx, h, netInterface)
+		if err != nil {
+			return err
+		}
+
+		if dryrun.IsDryRun(ctx) {
+			target := dryrun.GetTarget(cr, cId)
+			dryrun.Record(ctx, dryrun.KindNetlink, target, fmt.Sprintf("add ifb device %s and set it up", ifb))
+			dryrun.Record(ctx, dryrun.KindNetlink, target, fmt.Sprintf("add ingress qdisc ffff: for %s", netInterface))
+			dryrun.Record(ctx, dryrun.KindNetlink, target, fmt.Sprintf("add u32 filter for %s: redirect all ingress flow to %s", netInterface, ifb))
+			return nil
+		}
+
+		if err := h.LinkAdd(&netlink.Ifb{LinkAttrs: netlink.LinkAttrs{Name: ifb}}); err != nil {
+			return &TcError{Op: "add ifb device", Device: ifb, Err: err}
+		}
+
+		ifbLink, err := getLink(ctx, h, ifb)
+		if err != nil {
+			return err
+		}
+
+		if err := h.LinkSetUp(ifbLink); err != nil {
+			return &TcError{Op: "set up ifb device", Device: ifb, Err: err}
+		}
+
+		ingress := &netlink.Ingress{QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_INGRESS,
+		}}
+		if err := h.QdiscAdd(ingress); err != nil {
+			return &TcError{Op: "add ingress qdisc", Device: netInterface, Err: err}
+		}
+
+		filter := &netlink.U32{
+			FilterAttrs: netlink.FilterAttrs{
+				LinkIndex: link.Attrs().Index,
+				Parent:    netlink.MakeHandle(0xffff, 0),
+				Priority:  tcFilterPrio,
+				Protocol:  unix.ETH_P_IP,
+			},
+			Actions: []netlink.Action{netlink.NewMirredAction(ifbLink.Attrs().Index)},
+		}
+		if err := h.FilterAdd(filter); err != nil {
+			// the qdisc without the redirect filter can not be recognized by ClearIngressRedirect, so remove it here
+			if err := h.QdiscDel(ingress); err != nil {
+				log.GetLogger(ctx).Warnf("delete ingress qdisc of %s error: %s", netInterface, err.Error())
+			}
+
+			return &TcError{Op: "add ingress redirect filter", Device: netInterface, Parent: "ffff:", Err: err}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		if err := ClearIngressRedirect(ctx, cr, cId, netInterface); err != nil {
+			logger.Warnf("undo ingress redirect error: %s", err.Error())
+		}
+
+		return "", fmt.Errorf("redirect ingress flow of %s to %s error: %s", netInterface, ifb, err.Error())
+	}
+
+	return ifb, nil
+}
+
+// ClearIngressRedirect remove the ingress qdisc "ffff:" of netInterface only if it redirects the flow to the ifb device
+// of netInterface, and remove the ifb device. the rules of ifb will be removed together
+func ClearIngressRedirect(ctx context.Context, cr, cId, netInterface string) error {
+	return clearIngressRedirect(ctx, cr, cId, netInterface, false)
+}
+
+// ResetIngressRedirect remove the ingress qdisc of netInterface whoever adds it, used to force to inject when there are
+// other ingress rules
+func ResetIngressRedirect(ctx context.Context, cr, cId, netInterface string) error {
+	return clearIngressRedirect(ctx, cr, cId, netInterface, true)
+}
+
+func clearIngressRedirect(ctx context.Context, cr, cId, netInterface string, all bool) error {
+	ifb := GetIfbName(netInterface)
+	if dryrun.IsDryRun(ctx) {
+		target := dryrun.GetTarget(cr, cId)
+		if all {
+			dryrun.Record(ctx, dryrun.KindNetlink, target, fmt.Sprintf("delete ingress qdisc of %s", netInterface))
+		} else {
+			dryrun.Record(ctx, dryrun.KindNetlink, target, fmt.Sprintf("delete ingress qdisc ffff: of %s if it redirects to %s", netInterface, ifb))
+		}
+		dryrun.Record(ctx, dryrun.KindNetlink, target, fmt.Sprintf("delete ifb device %s", ifb))
+		return nil
+	}
+
+	return execWithHandle(ctx, cr, cId, func(h *netlink.Handle) error {
+		link, err := getLink(ctx, h, netInterface)
+		if err != nil {
+			return err
+		}
+
+		ifbLink, err := h.LinkByName(ifb)
+		if err != nil && !isLinkNotFound(err) {
+			return fmt.Errorf("get link of %s error: %s", ifb, err.Error())
+		}
+
+		qdiscList, err := h.QdiscList(link)
+		if err != nil {
+			return &TcError{Op: "list qdisc", Device: netInterface, Err: err}
+		}
+
+		for _, qdisc := range qdiscList {
+			if qdisc.Type() != "ingress" {
+				continue
+			}
+
+			if !all {
+				isRedirect, err := isIngressRedirect(h, link, qdisc, ifbLink)
+				if err != nil {
+					return &TcError{Op: "list ingress filter", Device: netInterface, Parent: "ffff:", Err: err}
+				}
+
+				if !isRedirect {
+					log.GetLogger(ctx).Warnf("ingress qdisc[%s] of %s does not redirect to %s, it is not added by chaosmeta, skip",
+						netlink.HandleStr(qdisc.Attrs().Handle), netInterface, ifb)
+					continue
+				}
+			}
+
+			if err := h.QdiscDel(qdisc); err != nil {
+				return &TcError{Op: "clear ingress qdisc", Device: netInterface, Err: err}
+			}
+		}
+
+		if ifbLink == nil {
+			return nil
+		}
+
+		if err := h.LinkDel(ifbLink); err != nil {
+			return &TcError{Op: "delete ifb device", Device: ifb, Err: err}
+		}
+
+		return nil
+	})
+}
+
+// isIngressRedirect the ingress qdisc is added by AddIngressRedirect: its handle is "ffff:" and it has a filter which
+// redirects the flow to the ifb device
+func isIngressRedirect(h *netlink.Handle, link netlink.Link, qdisc netlink.Qdisc, ifbLink netlink.Link) (bool, error) {
+	handle := netlink.MakeHandle(0xffff, 0)
+	if ifbLink == nil || qdisc.Attrs().Handle != handle {
+		return false, nil
+	}
+
+	filterList, err := h.FilterList(link, handle)
+	if err != nil {
+		return false, err
+	}
+
+	for _, filter := range filterList {
+		u32, ok := filter.(*netlink.U32)
+		if !ok {
+			continue
+		}
+
+		for _, action := range u32.Actions {
+			if mirred, ok := action.(*netlink.MirredAction); ok && mirred.Ifindex == ifbLink.Attrs().Index {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// getU32SelList the selector of each combination of ip and port conditions, same as "match ip src/dst/sport/dport" of tc
+func getU32SelList(srcIpListStr, dstIpListStr, srcPortListStr, dstPortListStr string) ([]*netlink.TcU32Sel, error) {
+	srcIpList, dstIpList, srcPortList, dstPortList, err := getStrList(srcIpListStr, dstIpListStr, srcPortListStr, dstPortListStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// empty condition means no limit
+	for _, list := range []*[]string{&srcIpList, &dstIpList, &srcPortList, &dstPortList} {
+		if len(*list) == 0 {
+			*list = []string{""}
+		}
+	}
+
+	var selList []*netlink.TcU32Sel
+	for _, srcIp := range srcIpList {
+		for _, dstIp := range dstIpList {
+			for _, srcPort := range srcPortList {
+				for _, dstPort := range dstPortList {
+					sel := &netlink.TcU32Sel{Flags: netlink.TC_U32_TERMINAL}
+					if err := addIpKey(sel, srcIp, 12); err != nil {
+						return nil, fmt.Errorf("src ip[%s] is invalid: %s", srcIp, err.Error())
+					}
+
+					if err := addIpKey(sel, dstIp, 16); err != nil {
+						return nil, fmt.Errorf("dst ip[%s] is invalid: %s", dstIp, err.Error())
+					}
+
+					if err := addPortKey(sel, srcPort, true); err != nil {
+						return nil, fmt.Errorf("src port[%s] is invalid: %s", srcPort, err.Error())
+					}
+
+					if err := addPortKey(sel, dstPort, false); err != nil {
+						return nil, fmt.Errorf("dst port[%s] is invalid: %s", dstPort, err.Error())
+					}
+
+					if len(sel.Keys) == 0 {
+						continue
+					}
+
+					selList = append(selList, sel)
+					if len(selList) > MaxRuleCount {
+						return nil, fmt.Errorf("filter rule count is larget than %d", MaxRuleCount)
+					}
+				}
+			}
+		}
+	}
+
+	return selList, nil
+}
+
+func addIpKey(sel *netlink.TcU32Sel, ipStr string, off int32) error {
+	if ipStr == "" {
+		return nil
+	}
+
+	if !strings.Contains(ipStr, "/") {
+		ipStr += "/32"
+	}
+
+	_, ipNet, err := net.ParseCIDR(ipStr)
+	if err != nil {
+		return err
+	}
+
+	ip := ipNet.IP.To4()
+	if ip == nil {
+		return fmt.Errorf("only support ipv4")
+	}
+
+	sel.Keys = append(sel.Keys, netlink.TcU32Key{
+		Mask: uint32(ipNet.Mask[0])<<24 | uint32(ipNet.Mask[1])<<16 | uint32(ipNet.Mask[2])<<8 | uint32(ipNet.Mask[3]),
+		Val:  uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3]),
+		Off:  off,
+	})
+
+	return nil
+}
+
+// addPortKey source port and destination port are in the same 32 bits at offset 20(assume no ip options), share one key
+func addPortKey(sel *netlink.TcU32Sel, portStr string, isSrc bool) error {
+	if portStr == "" {
+		return nil
+	}
+
+	portArr := strings.Split(portStr, utils.PortSplit)
+	port, err := strconv.ParseUint(portArr[0], 10, 16)
+	if err != nil {
+		return err
+	}
+
+	mask, err := strconv.ParseUint(portArr[1], 0, 16)
+	if err != nil {
+		return err
+	}
+
+	val, maskVal := uint32(port&mask), uint32(mask)
+	if isSrc {
+		val, maskVal = val<<16, maskVal<<16
+	}
+
+	for i := range sel.Keys {
+		if sel.Keys[i].Off == 20 {
+			sel.Keys[i].Val |= val
+			sel.Keys[i].Mask |= maskVal
+			return nil
+		}
+	}
+
+	sel.Keys = append(sel.Keys, netlink.TcU32Key{Mask: maskVal, Val: val, Off: 20})
+	return nil
+}
+
+// parseNetemArgs parse the netem args of tc, eg: "delay 100ms 10ms 25% loss 5% reorder 50 gap 3". the second return
+// value is true if there is any fault not supported by netlink
+func parseNetemArgs(args string) (*netlink.NetemQdiscAttrs, bool, error) {
+	var (
+		attrs  = &netlink.NetemQdiscAttrs{}
+		needTc bool
+		fields = strings.Fields(args)
+		index  int
+	)
+
+	// next return the next value which belongs to current fault, or "" if not exist
+	next := func() string {
+		if index+1 < len(fields) && !isNetemKeyword(fields[index+1]) {
+			index++
+			return fields[index]
+		}
+
+		return ""
+	}
+
+	for ; index < len(fields); index++ {
+		var (
+			err     error
+			keyword = fields[index]
+		)
+
+		switch keyword {
+		case "delay":
+			if attrs.Latency, err = parseNetemTime(next()); err != nil {
+				return nil, false, fmt.Errorf("delay is invalid: %s", err.Error())
+			}
+
+			if value := next(); value != "" {
+				if attrs.Jitter, err = parseNetemTime(value); err != nil {
+					return nil, false, fmt.Errorf("jitter is invalid: %s", err.Error())
+				}
+
+				if value := next(); value != "" {
+					if attrs.DelayCorr, err = parseNetemPercent(value); err != nil {
+						return nil, false, fmt.Errorf("delay correlation is invalid: %s", err.Error())
+					}
+				}
+			}
+		case "loss":
+			attrs.Loss, attrs.LossCorr, err = parseNetemPercentWithCorr(next(), next)
+		case "duplicate":
+			attrs.Duplicate, attrs.DuplicateCorr, err = parseNetemPercentWithCorr(next(), next)
+		case "corrupt":
+			attrs.CorruptProb, attrs.CorruptCorr, err = parseNetemPercentWithCorr(next(), next)
+		case "reorder":
+			attrs.ReorderProb, attrs.ReorderCorr, err = parseNetemPercentWithCorr(next(), next)
+		case "gap":
+			var gap uint64
+			gap, err = strconv.ParseUint(next(), 10, 32)
+			attrs.Gap = uint32(gap)
+		case "distribution", "rate":
+			needTc = true
+			next()
+		default:
+			return nil, false, fmt.Errorf("unknown netem args: %s", keyword)
+		}
+
+		if err != nil {
+			return nil, false, fmt.Errorf("%s is invalid: %s", keyword, err.Error())
+		}
+	}
+
+	return attrs, needTc, nil
+}
+
+func isNetemKeyword(field string) bool {
+	switch field {
+	case "delay", "loss", "duplicate", "corrupt", "reorder", "gap", "distribution", "rate":
+		return true
+	default:
+		return false
+	}
+}
+
+func parseNetemTime(value string) (uint32, error) {
+	us, err := utils.GetTimeUs(value)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint32(us), nil
+}
+
+func parseNetemPercent(value string) (float32, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 32)
+	if err != nil {
+		return 0, err
+	}
+
+	if percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("%s is not in [0,100]", value)
+	}
+
+	return float32(percent), nil
+}
+
+func parseNetemPercentWithCorr(value string, next func() string) (float32, float32, error) {
+	percent, err := parseNetemPercent(value)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var corr float32
+	if corrStr := next(); corrStr != "" {
+		if corr, err = parseNetemPercent(corrStr); err != nil {
+			return 0, 0, fmt.Errorf("correlation is invalid: %s", err.Error())
+		}
+	}
+
+	return percent, corr, nil
+}
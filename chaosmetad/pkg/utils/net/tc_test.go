@@ -0,0 +1,172 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package net
+
+import (
+	"github.com/vishvananda/netlink"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNetemArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       string
+		want       *netlink.NetemQdiscAttrs
+		wantNeedTc bool
+		wantErr    string
+	}{
+		{
+			name: "delay",
+			args: "delay 100ms",
+			want: &netlink.NetemQdiscAttrs{Latency: 100000},
+		},
+		{
+			name: "delay with jitter and correlation",
+			args: "delay 100ms 10ms 25%",
+			want: &netlink.NetemQdiscAttrs{Latency: 100000, Jitter: 10000, DelayCorr: 25},
+		},
+		{
+			name: "composite",
+			args: "delay 1s loss 5% 10 duplicate 1 corrupt 2% reorder 50 gap 3",
+			want: &netlink.NetemQdiscAttrs{Latency: 1000000, Loss: 5, LossCorr: 10, Duplicate: 1, CorruptProb: 2, ReorderProb: 50, Gap: 3},
+		},
+		{
+			name:       "distribution",
+			args:       "delay 100ms 10ms distribution normal",
+			want:       &netlink.NetemQdiscAttrs{Latency: 100000, Jitter: 10000},
+			wantNeedTc: true,
+		},
+		{
+			name:       "rate",
+			args:       "rate 1mbit loss 1",
+			want:       &netlink.NetemQdiscAttrs{Loss: 1},
+			wantNeedTc: true,
+		},
+		{
+			name:    "invalid delay",
+			args:    "delay 100xs",
+			wantErr: "delay is invalid",
+		},
+		{
+			name:    "invalid loss",
+			args:    "loss 101%",
+			wantErr: "loss is invalid",
+		},
+		{
+			name:    "invalid correlation",
+			args:    "duplicate 1 abc",
+			wantErr: "duplicate is invalid",
+		},
+		{
+			name:    "trailing keyword",
+			args:    "delay 100ms gap",
+			wantErr: "gap is invalid",
+		},
+		{
+			name:    "missing value",
+			args:    "reorder gap 3",
+			wantErr: "reorder is invalid",
+		},
+		{
+			name:    "unknown",
+			args:    "slot 10 delay 100ms",
+			wantErr: "unknown netem args: slot",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, needTc, err := parseNetemArgs(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("parseNetemArgs() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("parseNetemArgs() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) || needTc != tt.wantNeedTc {
+				t.Errorf("parseNetemArgs() = %+v, %v, want %+v, %v", got, needTc, tt.want, tt.wantNeedTc)
+			}
+		})
+	}
+}
+
+func TestGetU32SelList(t *testing.T) {
+	type args struct {
+		srcIpListStr   string
+		dstIpListStr   string
+		srcPortListStr string
+		dstPortListStr string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []*netlink.TcU32Sel
+		wantErr bool
+	}{
+		{
+			name: "no condition",
+			args: args{},
+		},
+		{
+			name: "dst ip",
+			args: args{dstIpListStr: "10.0.0.0/8"},
+			want: []*netlink.TcU32Sel{
+				{Flags: netlink.TC_U32_TERMINAL, Keys: []netlink.TcU32Key{{Mask: 0xff000000, Val: 0x0a000000, Off: 16}}},
+			},
+		},
+		{
+			name: "src ip and ports share one key",
+			args: args{srcIpListStr: "192.168.1.1", srcPortListStr: "8080", dstPortListStr: "80"},
+			want: []*netlink.TcU32Sel{
+				{Flags: netlink.TC_U32_TERMINAL, Keys: []netlink.TcU32Key{
+					{Mask: 0xffffffff, Val: 0xc0a80101, Off: 12},
+					{Mask: 0xffffffff, Val: 8080<<16 | 80, Off: 20},
+				}},
+			},
+		},
+		{
+			name: "combination",
+			args: args{dstIpListStr: "1.1.1.1,2.2.2.2", dstPortListStr: "53"},
+			want: []*netlink.TcU32Sel{
+				{Flags: netlink.TC_U32_TERMINAL, Keys: []netlink.TcU32Key{{Mask: 0xffffffff, Val: 0x01010101, Off: 16}, {Mask: 0xffff, Val: 53, Off: 20}}},
+				{Flags: netlink.TC_U32_TERMINAL, Keys: []netlink.TcU32Key{{Mask: 0xffffffff, Val: 0x02020202, Off: 16}, {Mask: 0xffff, Val: 53, Off: 20}}},
+			},
+		},
+		{
+			name:    "ipv6",
+			args:    args{dstIpListStr: "::1"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getU32SelList(tt.args.srcIpListStr, tt.args.dstIpListStr, tt.args.srcPortListStr, tt.args.dstPortListStr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getU32SelList() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getU32SelList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
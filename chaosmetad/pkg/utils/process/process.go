@@ -24,6 +24,7 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/cmdexec"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/namespace"
 	"os"
 	"strconv"
@@ -171,13 +172,37 @@ func ExistPid(ctx context.Context, pid int) (bool, error) {
 	return process.PidExists(int32(pid))
 }
 
+// GetRssByKey the total resident memory bytes of the processes found by key in host
+func GetRssByKey(ctx context.Context, key string) (uint64, error) {
+	pidList, err := GetProcessByKey(ctx, "", "", key)
+	if err != nil {
+		return 0, fmt.Errorf("get process by key[%s] error: %s", key, err.Error())
+	}
+
+	var total uint64
+	for _, pid := range pidList {
+		p, err := process.NewProcess(int32(pid))
+		if err != nil {
+			return 0, fmt.Errorf("get process[%d] error: %s", pid, err.Error())
+		}
+
+		info, err := p.MemoryInfo()
+		if err != nil {
+			return 0, fmt.Errorf("get memory info of process[%d] error: %s", pid, err.Error())
+		}
+		total += info.RSS
+	}
+
+	return total, nil
+}
+
 func CheckExistAndSignalByKey(ctx context.Context, processKey string, signal int) error {
 	isProExist, err := ExistProcessByKey(ctx, processKey)
 	if err != nil {
 		return fmt.Errorf("check process exist by key[%s] error: %s", processKey, err.Error())
 	}
 
-	if isProExist {
+	if isProExist || dryrun.IsRecoverPhase(ctx) {
 		if err := KillProcessByKey(ctx, processKey, signal); err != nil {
 			return fmt.Errorf("kill process by key[%s] error: %s", processKey, err.Error())
 		}
@@ -192,7 +217,7 @@ func CheckExistAndKillByKey(ctx context.Context, processKey string) error {
 		return fmt.Errorf("check process exist by key[%s] error: %s", processKey, err.Error())
 	}
 
-	if isProExist {
+	if isProExist || dryrun.IsRecoverPhase(ctx) {
 		if err := KillProcessByKey(ctx, processKey, SIGKILL); err != nil {
 			return fmt.Errorf("kill process by key[%s] error: %s", processKey, err.Error())
 		}
@@ -215,6 +240,10 @@ func ExistProcessByKey(ctx context.Context, key string) (bool, error) {
 }
 
 func KillPidWithSignal(ctx context.Context, pid int, signal int) error {
+	if dryrun.Record(ctx, dryrun.KindProcess, dryrun.TargetHost, fmt.Sprintf("send signal[%d] to process[%d]", signal, pid)) {
+		return nil
+	}
+
 	p, err := process.NewProcess(int32(pid))
 	if err != nil {
 		return fmt.Errorf("find process [%d] error: %s", pid, err.Error())
@@ -256,7 +285,12 @@ func GetPidListByPidOrKeyInContainer(ctx context.Context, cr, cId string, pid in
 
 		pidList = append(pidList, pid)
 	} else if key != "" {
-		return GetProcessByKey(ctx, cr, cId, key)
+		pidList, err := GetProcessByKey(ctx, cr, cId, key)
+		if err == nil {
+			dryrun.Resolve(ctx, dryrun.ResolvedPidList, pidList)
+		}
+
+		return pidList, err
 	} else {
 		return nil, fmt.Errorf("must provide \"pid\" or \"key\"")
 	}
@@ -265,6 +299,7 @@ func GetPidListByPidOrKeyInContainer(ctx context.Context, cr, cId string, pid in
 		return nil, fmt.Errorf("target pid is empty")
 	}
 
+	dryrun.Resolve(ctx, dryrun.ResolvedPidList, pidList)
 	return pidList, nil
 }
 
@@ -309,6 +344,67 @@ func GetPidListByKey(ctx context.Context, cr, cId string, key string) ([]int, er
 	return pidList, nil
 }
 
+// GetHostPidList convert the pid list in container's pid ns to the pid list in host's pid ns
+func GetHostPidList(ctx context.Context, cr, cId string, pidList []int) ([]int, error) {
+	if cr == "" {
+		return pidList, nil
+	}
+
+	client, err := crclient.GetClient(ctx, cr)
+	if err != nil {
+		return nil, fmt.Errorf("get %s client error: %s", cr, err.Error())
+	}
+
+	existPro, err := client.GetAllPidList(ctx, cId)
+	if err != nil {
+		return nil, fmt.Errorf("get pid of %s error: %s", cId, err.Error())
+	}
+
+	var nsPidMap = make(map[int]int)
+	for _, unit := range existPro {
+		nsPid, err := getNsPid(unit.Pid)
+		if err != nil {
+			log.GetLogger(ctx).Warnf("get pid in container of process[%d] error: %s", unit.Pid, err.Error())
+			continue
+		}
+
+		nsPidMap[nsPid] = unit.Pid
+	}
+
+	var hostPidList []int
+	for _, pid := range pidList {
+		hostPid, ok := nsPidMap[pid]
+		if !ok {
+			return nil, fmt.Errorf("process[%d] is not found in container[%s]", pid, cId)
+		}
+
+		hostPidList = append(hostPidList, hostPid)
+	}
+
+	dryrun.Resolve(ctx, dryrun.ResolvedHostPidList, hostPidList)
+	return hostPidList, nil
+}
+
+// getNsPid the pid in the innermost pid ns, from the "NSpid" line of /proc/[pid]/status
+func getNsPid(pid int) (int, error) {
+	statusFile := fmt.Sprintf("/proc/%d/status", pid)
+	reByte, err := os.ReadFile(statusFile)
+	if err != nil {
+		return -1, fmt.Errorf("read from %s error: %s", statusFile, err.Error())
+	}
+
+	for _, line := range strings.Split(string(reByte), "\n") {
+		if !strings.HasPrefix(line, "NSpid:") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		return strconv.Atoi(fields[len(fields)-1])
+	}
+
+	return -1, fmt.Errorf("\"NSpid\" is not found in %s", statusFile)
+}
+
 func GetPidListByStr(ctx context.Context, pidStr string) ([]int, error) {
 	var pidList []int
 	pidStrList := strings.Split(pidStr, ",")
@@ -362,6 +458,7 @@ func GetPidListByListStrAndKey(ctx context.Context, cr, cId string, pidListStr,
 	}
 
 	log.GetLogger(ctx).Debugf("pid list: %v", pidList)
+	dryrun.Resolve(ctx, dryrun.ResolvedPidList, pidList)
 	return
 }
 
@@ -380,6 +477,22 @@ func GetPidByKeyWithoutRunUser(ctx context.Context, key string) (int, error) {
 	return pid, nil
 }
 
+// GetPidByKeyWithoutExec return the pid in host's pid ns of the process started by tool, excluding the wrapper process of chaosmeta_execns
+func GetPidByKeyWithoutExec(ctx context.Context, key string) (int, error) {
+	re, err := cmdexec.RunBashCmdWithOutput(ctx, fmt.Sprintf("ps -ef | grep '%s' | grep -v grep | grep -v '%s inject' | grep -v '%s recover' | grep -v 'chaosmeta_execns ' | awk '{print $2}' | head -n 1", key, utils.RootName, utils.RootName))
+	if err != nil {
+		return utils.NoPid, fmt.Errorf("grep process error: %s", err.Error())
+	}
+
+	pidStr := strings.TrimSpace(re)
+	pid, err := strconv.Atoi(pidStr)
+	if err != nil {
+		return utils.NoPid, fmt.Errorf("\"%s\" change to int error: %s", pidStr, err.Error())
+	}
+
+	return pid, nil
+}
+
 func WaitDefunctProcess(ctx context.Context) {
 	logger := log.GetLogger(ctx)
 
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TypeLinear   = "linear"
+	TypeStep     = "step"
+	TypeSine     = "sine"
+	TypeSawtooth = "sawtooth"
+
+	// UnitPercent the value is a percent without "%"
+	UnitPercent = "%"
+	// UnitCount the value is a num
+	UnitCount = "count"
+	// UnitKB the value is bytes, support unit: KB/MB/GB/TB（default KB）, it is converted into KB
+	UnitKB = "KB"
+
+	DefaultSteps   = 4
+	AdjustInterval = time.Second
+)
+
+// Profile a level which varies with time from "Start" to "End":
+// linear: rise evenly in "Duration", then keep "End";
+// step: rise by "Steps" equal steps in "Duration", then keep "End";
+// sine: oscillate between "Start" and "End" with period "Duration";
+// sawtooth: rise evenly in "Duration", then drop to "Start" and repeat
+type Profile struct {
+	Type     string
+	Start    float64
+	End      float64
+	Duration time.Duration
+	Steps    int
+}
+
+// Parse spec format: "[type]:[start]-[end]:[duration][:steps]", eg: "linear:10-90:10m", "step:100MB-1GB:1h:5",
+// "sine:20-80:5m". the unit of start and end is decided by the caller, duration support unit: "s、m、h"(default s)
+func Parse(spec, unit string) (*Profile, error) {
+	fields := strings.Split(spec, ":")
+	if len(fields) != 3 && len(fields) != 4 {
+		return nil, fmt.Errorf("format must be \"[type]:[start]-[end]:[duration][:steps]\"")
+	}
+
+	p := &Profile{Type: fields[0], Steps: DefaultSteps}
+	if p.Type != TypeLinear && p.Type != TypeStep && p.Type != TypeSine && p.Type != TypeSawtooth {
+		return nil, fmt.Errorf("type[%s] is not support, only support: %s、%s、%s、%s", p.Type, TypeLinear, TypeStep, TypeSine, TypeSawtooth)
+	}
+
+	rangeArr := strings.Split(fields[1], "-")
+	if len(rangeArr) != 2 {
+		return nil, fmt.Errorf("range[%s] must be \"[start]-[end]\"", fields[1])
+	}
+
+	var err error
+	if p.Start, err = parseValue(rangeArr[0], unit); err != nil {
+		return nil, fmt.Errorf("start[%s] is invalid: %s", rangeArr[0], err.Error())
+	}
+
+	if p.End, err = parseValue(rangeArr[1], unit); err != nil {
+		return nil, fmt.Errorf("end[%s] is invalid: %s", rangeArr[1], err.Error())
+	}
+
+	durationSec, err := utils.GetTimeSecond(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("duration[%s] is invalid: %s", fields[2], err.Error())
+	}
+
+	if durationSec <= 0 {
+		return nil, fmt.Errorf("duration[%s] must larger than 0", fields[2])
+	}
+	p.Duration = time.Duration(durationSec) * time.Second
+
+	if len(fields) == 4 {
+		if p.Type != TypeStep {
+			return nil, fmt.Errorf("steps is only supported by type \"%s\"", TypeStep)
+		}
+
+		if p.Steps, err = strconv.Atoi(fields[3]); err != nil || p.Steps < 2 {
+			return nil, fmt.Errorf("steps[%s] must be an integer not less than 2", fields[3])
+		}
+	}
+
+	return p, nil
+}
+
+func parseValue(valueStr, unit string) (float64, error) {
+	if unit == UnitKB {
+		value, err := utils.GetKBytes(valueStr)
+		return float64(value), err
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		return -1, fmt.Errorf("is not an integer")
+	}
+
+	if value < 0 {
+		return -1, fmt.Errorf("can not less than 0")
+	}
+
+	if unit == UnitPercent && value > 100 {
+		return -1, fmt.Errorf("must be in [0,100]")
+	}
+
+	return float64(value), nil
+}
+
+// Value the level at the elapsed time since the profile starts
+func (p *Profile) Value(elapsed time.Duration) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	var ratio = float64(elapsed) / float64(p.Duration)
+	switch p.Type {
+	case TypeSine:
+		ratio = (1 - math.Cos(2*math.Pi*ratio)) / 2
+	case TypeSawtooth:
+		ratio -= math.Floor(ratio)
+	case TypeStep:
+		// the i-th step happens at i*Duration/Steps, so the last step reaches "End" exactly at "Duration"
+		ratio = math.Min(math.Floor(ratio*float64(p.Steps))/float64(p.Steps), 1)
+	default:
+		ratio = math.Min(ratio, 1)
+	}
+
+	return p.Start + (p.End-p.Start)*ratio
+}
+
+// Max the max level of the profile, used to check the resource in advance
+func (p *Profile) Max() float64 {
+	return math.Max(p.Start, p.End)
+}
+
+// State recorded in the runtime of experiment, so that the current level can be calculated when querying
+type State struct {
+	Profile   string `json:"profile,omitempty"`
+	Unit      string `json:"profile_unit,omitempty"`
+	StartTime string `json:"profile_start_time,omitempty"`
+}
+
+func NewState(spec, unit string) State {
+	return State{
+		Profile:   spec,
+		Unit:      unit,
+		StartTime: time.Now().Format(utils.TimeFormat),
+	}
+}
+
+// GetValue the level of the profile now
+func (s *State) GetValue() (float64, error) {
+	p, err := Parse(s.Profile, s.Unit)
+	if err != nil {
+		return -1, fmt.Errorf("parse profile[%s] error: %s", s.Profile, err.Error())
+	}
+
+	startTime, err := time.ParseInLocation(utils.TimeFormat, s.StartTime, time.Local)
+	if err != nil {
+		return -1, fmt.Errorf("parse start time[%s] error: %s", s.StartTime, err.Error())
+	}
+
+	return p.Value(time.Since(startTime)), nil
+}
+
+// GetLevel the readable level of the experiment now, return "" if the runtime of experiment has no profile
+func GetLevel(runtime string) (string, error) {
+	var s State
+	if err := json.Unmarshal([]byte(runtime), &s); err != nil {
+		return "", fmt.Errorf("load runtime error: %s", err.Error())
+	}
+
+	if s.Profile == "" {
+		return "", nil
+	}
+
+	value, err := s.GetValue()
+	if err != nil {
+		return "", err
+	}
+
+	switch s.Unit {
+	case UnitPercent:
+		return fmt.Sprintf("%d%%", int(math.Round(value))), nil
+	case UnitKB:
+		return fmt.Sprintf("%dKB", int64(value)), nil
+	default:
+		return strconv.Itoa(int(math.Round(value))), nil
+	}
+}
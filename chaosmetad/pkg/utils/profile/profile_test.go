@@ -0,0 +1,89 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package profile
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		unit    string
+		want    *Profile
+		wantErr bool
+	}{
+		{"linear:10-90:10m", UnitPercent, &Profile{Type: TypeLinear, Start: 10, End: 90, Duration: 10 * time.Minute, Steps: DefaultSteps}, false},
+		{"step:100MB-1GB:1h:5", UnitKB, &Profile{Type: TypeStep, Start: 100 * 1024, End: 1024 * 1024, Duration: time.Hour, Steps: 5}, false},
+		{"sine:80-20:60", UnitCount, &Profile{Type: TypeSine, Start: 80, End: 20, Duration: time.Minute, Steps: DefaultSteps}, false},
+		{"square:10-90:10m", UnitPercent, nil, true},
+		{"linear:10-190:10m", UnitPercent, nil, true},
+		{"linear:10:10m", UnitPercent, nil, true},
+		{"linear:10-90:0s", UnitPercent, nil, true},
+		{"linear:10-90:10m:4", UnitPercent, nil, true},
+		{"step:10-90:10m:1", UnitPercent, nil, true},
+		{"linear:10-90", UnitPercent, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.spec, tt.unit)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+
+		if !tt.wantErr && *got != *tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestProfile_Value(t *testing.T) {
+	tests := []struct {
+		p       Profile
+		elapsed time.Duration
+		want    float64
+	}{
+		{Profile{Type: TypeLinear, Start: 10, End: 90, Duration: 100 * time.Second}, 0, 10},
+		{Profile{Type: TypeLinear, Start: 10, End: 90, Duration: 100 * time.Second}, 50 * time.Second, 50},
+		{Profile{Type: TypeLinear, Start: 10, End: 90, Duration: 100 * time.Second}, 200 * time.Second, 90},
+		{Profile{Type: TypeLinear, Start: 90, End: 10, Duration: 100 * time.Second}, 25 * time.Second, 70},
+		// step: Steps equal steps of (End-Start)/Steps at every Duration/Steps, End is reached exactly at Duration
+		{Profile{Type: TypeStep, Start: 10, End: 50, Duration: 100 * time.Second, Steps: 4}, 0, 10},
+		{Profile{Type: TypeStep, Start: 10, End: 50, Duration: 100 * time.Second, Steps: 4}, 25*time.Second - time.Millisecond, 10},
+		{Profile{Type: TypeStep, Start: 10, End: 50, Duration: 100 * time.Second, Steps: 4}, 25 * time.Second, 20},
+		{Profile{Type: TypeStep, Start: 10, End: 50, Duration: 100 * time.Second, Steps: 4}, 75 * time.Second, 40},
+		{Profile{Type: TypeStep, Start: 10, End: 50, Duration: 100 * time.Second, Steps: 4}, 100*time.Second - time.Millisecond, 40},
+		{Profile{Type: TypeStep, Start: 10, End: 50, Duration: 100 * time.Second, Steps: 4}, 100 * time.Second, 50},
+		{Profile{Type: TypeStep, Start: 10, End: 50, Duration: 100 * time.Second, Steps: 4}, 300 * time.Second, 50},
+		{Profile{Type: TypeStep, Start: 100, End: 0, Duration: 10 * time.Second, Steps: 2}, 5 * time.Second, 50},
+		{Profile{Type: TypeStep, Start: 100, End: 0, Duration: 10 * time.Second, Steps: 2}, 10 * time.Second, 0},
+		{Profile{Type: TypeSine, Start: 20, End: 80, Duration: 100 * time.Second}, 0, 20},
+		{Profile{Type: TypeSine, Start: 20, End: 80, Duration: 100 * time.Second}, 25 * time.Second, 50},
+		{Profile{Type: TypeSine, Start: 20, End: 80, Duration: 100 * time.Second}, 150 * time.Second, 80},
+		{Profile{Type: TypeSawtooth, Start: 0, End: 100, Duration: 100 * time.Second}, 40 * time.Second, 40},
+		{Profile{Type: TypeSawtooth, Start: 0, End: 100, Duration: 100 * time.Second}, 140 * time.Second, 40},
+	}
+
+	for _, tt := range tests {
+		if got := tt.p.Value(tt.elapsed); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%+v Value(%s) = %v, want %v", tt.p, tt.elapsed, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ptrace
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"syscall"
+)
+
+const (
+	sysGettimeofday = 96
+	sysTime         = 201
+	sysClockGettime = 228
+
+	symClockGettime = "__vdso_clock_gettime"
+	symGettimeofday = "__vdso_gettimeofday"
+	symTime         = "__vdso_time"
+
+	// data layout of the fake page, followed by the code of fake functions
+	dataSecOffset  = 0
+	dataNsecOffset = 8
+	dataUsecOffset = 16
+	dataClockMask  = 24
+	codeOffset     = 32
+
+	// the fake page is expected near vdso, so that the functions can be replaced by a 5-byte relative jump
+	fakePageDistance = 0x10000000
+)
+
+// asm a minimal x86-64 assembler with rel8 jumps and rip-relative data access
+type asm struct {
+	page   uint64
+	base   uint64
+	code   []byte
+	labels map[string]int
+	fixes  map[int]string
+}
+
+func newAsm(page, base uint64) *asm {
+	return &asm{page: page, base: base, labels: map[string]int{}, fixes: map[int]string{}}
+}
+
+func (a *asm) emit(b ...byte) {
+	a.code = append(a.code, b...)
+}
+
+// emitRip emit an instruction which accesses the data in the fake page, its last operand is a 32-bit displacement relative to the next instruction
+func (a *asm) emitRip(dataOffset uint64, prefix ...byte) {
+	a.emit(prefix...)
+	next := a.base + uint64(len(a.code)) + 4
+	disp := make([]byte, 4)
+	binary.LittleEndian.PutUint32(disp, uint32(int32(int64(a.page+dataOffset)-int64(next))))
+	a.emit(disp...)
+}
+
+func (a *asm) jump(op byte, label string) {
+	a.emit(op, 0)
+	a.fixes[len(a.code)-1] = label
+}
+
+func (a *asm) label(name string) {
+	a.labels[name] = len(a.code)
+}
+
+func (a *asm) build() []byte {
+	for pos, label := range a.fixes {
+		a.code[pos] = byte(int8(a.labels[label] - (pos + 1)))
+	}
+
+	return a.code
+}
+
+// fakeClockGettime call the real syscall, then add the offset if the clock id is in the mask
+func fakeClockGettime(page, base uint64) []byte {
+	a := newAsm(page, base)
+	a.emit(0xb8, sysClockGettime, 0, 0, 0)           // mov eax, SYS_clock_gettime
+	a.emit(0x0f, 0x05)                               // syscall
+	a.emit(0x48, 0x85, 0xc0)                         // test rax, rax
+	a.jump(0x75, "done")                             // jnz done
+	a.emit(0x83, 0xff, clockMaxId)                   // cmp edi, 63
+	a.jump(0x77, "done")                             // ja done
+	a.emitRip(dataClockMask, 0x48, 0x8b, 0x15)       // mov rdx, [rip+mask]
+	a.emit(0x48, 0x0f, 0xa3, 0xfa)                   // bt rdx, rdi
+	a.jump(0x73, "done")                             // jnc done
+	a.emitRip(dataSecOffset, 0x48, 0x8b, 0x15)       // mov rdx, [rip+sec]
+	a.emit(0x48, 0x01, 0x16)                         // add [rsi], rdx
+	a.emitRip(dataNsecOffset, 0x48, 0x8b, 0x15)      // mov rdx, [rip+nsec]
+	a.emit(0x48, 0x03, 0x56, 0x08)                   // add rdx, [rsi+8]
+	a.emit(0x48, 0x81, 0xfa, 0x00, 0xca, 0x9a, 0x3b) // cmp rdx, 1000000000
+	a.jump(0x7c, "store")                            // jl store
+	a.emit(0x48, 0x81, 0xea, 0x00, 0xca, 0x9a, 0x3b) // sub rdx, 1000000000
+	a.emit(0x48, 0xff, 0x06)                         // inc qword [rsi]
+	a.label("store")
+	a.emit(0x48, 0x89, 0x56, 0x08) // mov [rsi+8], rdx
+	a.label("done")
+	a.emit(0xc3) // ret
+	return a.build()
+}
+
+func fakeGettimeofday(page, base uint64) []byte {
+	a := newAsm(page, base)
+	a.emit(0xb8, sysGettimeofday, 0, 0, 0)           // mov eax, SYS_gettimeofday
+	a.emit(0x0f, 0x05)                               // syscall
+	a.emit(0x48, 0x85, 0xc0)                         // test rax, rax
+	a.jump(0x75, "done")                             // jnz done
+	a.emit(0x48, 0x85, 0xff)                         // test rdi, rdi
+	a.jump(0x74, "done")                             // jz done
+	a.emitRip(dataSecOffset, 0x48, 0x8b, 0x15)       // mov rdx, [rip+sec]
+	a.emit(0x48, 0x01, 0x17)                         // add [rdi], rdx
+	a.emitRip(dataUsecOffset, 0x48, 0x8b, 0x15)      // mov rdx, [rip+usec]
+	a.emit(0x48, 0x03, 0x57, 0x08)                   // add rdx, [rdi+8]
+	a.emit(0x48, 0x81, 0xfa, 0x40, 0x42, 0x0f, 0x00) // cmp rdx, 1000000
+	a.jump(0x7c, "store")                            // jl store
+	a.emit(0x48, 0x81, 0xea, 0x40, 0x42, 0x0f, 0x00) // sub rdx, 1000000
+	a.emit(0x48, 0xff, 0x07)                         // inc qword [rdi]
+	a.label("store")
+	a.emit(0x48, 0x89, 0x57, 0x08) // mov [rdi+8], rdx
+	a.label("done")
+	a.emit(0xc3) // ret
+	return a.build()
+}
+
+func fakeTime(page, base uint64) []byte {
+	a := newAsm(page, base)
+	a.emit(0xb8, sysTime, 0, 0, 0)             // mov eax, SYS_time
+	a.emit(0x0f, 0x05)                         // syscall
+	a.emitRip(dataSecOffset, 0x48, 0x03, 0x05) // add rax, [rip+sec]
+	a.emit(0x48, 0x85, 0xff)                   // test rdi, rdi
+	a.jump(0x74, "done")                       // jz done
+	a.emit(0x48, 0x89, 0x07)                   // mov [rdi], rax
+	a.label("done")
+	a.emit(0xc3) // ret
+	return a.build()
+}
+
+// getJumpCode "jmp rel32" if the target is in the range, otherwise "mov rax, addr; jmp rax"
+func getJumpCode(from, to uint64) []byte {
+	if rel := int64(to) - int64(from+5); rel >= math.MinInt32 && rel <= math.MaxInt32 {
+		code := []byte{0xe9, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(code[1:], uint32(int32(rel)))
+		return code
+	}
+
+	code := []byte{0x48, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xe0}
+	binary.LittleEndian.PutUint64(code[2:10], to)
+	return code
+}
+
+// InjectClockSkew replace the clock functions in vdso of the process with the fake functions which add the offset to the real time,
+// offsetNs is the offset in nanosecond, clockMask is the bitmap of clock ids to affect
+func InjectClockSkew(pid int, offsetNs int64, clockMask uint64) (*ClockPatch, error) {
+	start, end, err := getVdsoRange(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := Trace(pid)
+	if err != nil {
+		return nil, fmt.Errorf("trace process[%d] error: %s", pid, err.Error())
+	}
+	defer p.Detach()
+
+	image, err := p.ReadMem(start, int(end-start))
+	if err != nil {
+		return nil, fmt.Errorf("read vdso error: %s", err.Error())
+	}
+
+	names := []string{symClockGettime}
+	if clockMask&(1<<ClockRealtime) != 0 {
+		names = append(names, symGettimeofday, symTime)
+	}
+
+	symbols, err := getVdsoSymbols(image, start, names)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := symbols[symClockGettime]; !ok {
+		return nil, fmt.Errorf("symbol %s is not found in vdso", symClockGettime)
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	page, err := p.Mmap(start-fakePageDistance, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("mmap fake page error: %s", err.Error())
+	}
+
+	patch := &ClockPatch{Pid: pid, Page: page, PageSize: pageSize}
+	if err := writeFakePage(p, patch, symbols, offsetNs, clockMask); err != nil {
+		if undoErr := restore(p, patch, true); undoErr != nil {
+			return nil, fmt.Errorf("%s, undo error: %s", err.Error(), undoErr.Error())
+		}
+
+		return nil, err
+	}
+
+	return patch, nil
+}
+
+func writeFakePage(p *TracedProcess, patch *ClockPatch, symbols map[string][2]uint64, offsetNs int64, clockMask uint64) error {
+	// keep nsec offset in [0, 1e9), so the fake functions only need to handle the carry
+	sec, nsec := offsetNs/1e9, offsetNs%1e9
+	if nsec < 0 {
+		sec, nsec = sec-1, nsec+1e9
+	}
+
+	data := make([]byte, codeOffset)
+	binary.LittleEndian.PutUint64(data[dataSecOffset:], uint64(sec))
+	binary.LittleEndian.PutUint64(data[dataNsecOffset:], uint64(nsec))
+	binary.LittleEndian.PutUint64(data[dataUsecOffset:], uint64(nsec/1000))
+	binary.LittleEndian.PutUint64(data[dataClockMask:], clockMask)
+
+	var fakeFuncMap = map[string]func(page, base uint64) []byte{
+		symClockGettime: fakeClockGettime,
+		symGettimeofday: fakeGettimeofday,
+		symTime:         fakeTime,
+	}
+
+	var page = data
+	for name, sym := range symbols {
+		fakeAddr := patch.Page + uint64(len(page))
+		page = append(page, fakeFuncMap[name](patch.Page, fakeAddr)...)
+
+		jumpCode := getJumpCode(sym[0], fakeAddr)
+		if sym[1] < uint64(len(jumpCode)) {
+			return fmt.Errorf("size of symbol %s is too small: %d", name, sym[1])
+		}
+
+		origin, err := p.ReadMem(sym[0], len(jumpCode))
+		if err != nil {
+			return err
+		}
+
+		patch.Symbols = append(patch.Symbols, &SymbolPatch{Name: name, Addr: sym[0], Origin: origin, Patch: jumpCode})
+	}
+
+	if err := p.WriteMem(patch.Page, page); err != nil {
+		return fmt.Errorf("write fake page error: %s", err.Error())
+	}
+
+	if err := p.StepOut(getPatchRangeList(patch.Symbols)); err != nil {
+		return fmt.Errorf("move threads out of the symbols to patch error: %s", err.Error())
+	}
+
+	for _, sym := range patch.Symbols {
+		if err := p.WriteMem(sym.Addr, sym.Patch); err != nil {
+			return fmt.Errorf("patch symbol %s error: %s", sym.Name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// restore the patched symbols whose code is still the jump, then release the fake page.
+// if none of the symbols is patched, the pid may have been reused by another process, so the page is not released unless force.
+// the fake functions are leaf functions, a thread is in the middle of a fake call only if its instruction pointer is in the
+// page, in that case the page is kept to let the call return, it is no longer used after the symbols are restored
+func restore(p *TracedProcess, patch *ClockPatch, force bool) error {
+	var patchedList []*SymbolPatch
+	for _, sym := range patch.Symbols {
+		current, err := p.ReadMem(sym.Addr, len(sym.Patch))
+		if err != nil {
+			return err
+		}
+
+		if bytes.Equal(current, sym.Patch) {
+			patchedList = append(patchedList, sym)
+		}
+	}
+
+	// a thread may be stopped inside the jump code, it would resume in the middle of an original instruction
+	if err := p.StepOut(getPatchRangeList(patchedList)); err != nil {
+		return fmt.Errorf("move threads out of the patched symbols error: %s", err.Error())
+	}
+
+	isPatched := len(patchedList) > 0
+	for _, sym := range patchedList {
+		if err := p.WriteMem(sym.Addr, sym.Origin); err != nil {
+			return fmt.Errorf("restore symbol %s error: %s", sym.Name, err.Error())
+		}
+	}
+
+	if patch.Page != 0 && (isPatched || force) {
+		isExecuting, err := p.IsExecuting(patch.Page, patch.Page+patch.PageSize)
+		if err != nil {
+			return fmt.Errorf("check threads in fake page error: %s", err.Error())
+		}
+
+		if isExecuting {
+			return nil
+		}
+
+		if err := p.Munmap(patch.Page, patch.PageSize); err != nil {
+			return fmt.Errorf("munmap fake page error: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// RecoverClockSkew restore the vdso of the process, do nothing if the process has exited
+func RecoverClockSkew(patch *ClockPatch) error {
+	if err := syscall.Kill(patch.Pid, 0); err == syscall.ESRCH {
+		return nil
+	}
+
+	p, err := Trace(patch.Pid)
+	if err != nil {
+		return fmt.Errorf("trace process[%d] error: %s", patch.Pid, err.Error())
+	}
+	defer p.Detach()
+
+	return restore(p, patch, false)
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ptrace
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LeakRegion an anonymous memory mapped in the target process, the pages are populated gradually to increase the rss.
+// the region is surrounded by inaccessible guard pages, so it is never merged with an adjacent mapping by kernel and
+// can be found exactly when recovering
+type LeakRegion struct {
+	Pid int `json:"pid"`
+	// StartTime the start time of the process, the 22nd field of /proc/[pid]/stat, which tells the target process
+	// from a new process reusing the pid
+	StartTime uint64 `json:"start_time,omitempty"`
+	Addr      uint64 `json:"addr"`
+	Size      uint64 `json:"size"`
+	// GuardSize the size of the guard page on each side of the region, 0 means no guard page
+	GuardSize uint64 `json:"guard_size,omitempty"`
+}
+
+// checkRegion the region can only be operated if the pid still belongs to the target process and [Addr, Addr+Size)
+// is still exactly a private anonymous mapping. return false if the process has exited or the region is not mapped
+func checkRegion(region *LeakRegion) (bool, error) {
+	if region.StartTime != 0 {
+		startTime, err := getStartTime(region.Pid)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return false, err
+		}
+
+		if startTime != region.StartTime {
+			return false, nil
+		}
+	}
+
+	mapsFile := fmt.Sprintf("/proc/%d/maps", region.Pid)
+	reByte, err := os.ReadFile(mapsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("read %s error: %s", mapsFile, err.Error())
+	}
+
+	return existMapping(string(reByte), region.Addr, region.Addr+alignPage(region.Size)), nil
+}
+
+// existMapping check whether [start, end) is exactly a private anonymous mapping in the content of /proc/[pid]/maps
+func existMapping(maps string, start, end uint64) bool {
+	for _, line := range strings.Split(maps, "\n") {
+		fields := strings.Fields(line)
+		// anonymous mapping has no path
+		if len(fields) != 5 || fields[1] != "rw-p" {
+			continue
+		}
+
+		rangeArr := strings.Split(fields[0], "-")
+		if len(rangeArr) != 2 {
+			continue
+		}
+
+		mapStart, err := strconv.ParseUint(rangeArr[0], 16, 64)
+		if err != nil {
+			continue
+		}
+
+		mapEnd, err := strconv.ParseUint(rangeArr[1], 16, 64)
+		if err != nil {
+			continue
+		}
+
+		if mapStart == start && mapEnd == end {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getStartTime(pid int) (uint64, error) {
+	reByte, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+
+	return parseStartTime(string(reByte))
+}
+
+// parseStartTime the 2nd field "comm" may contain spaces and ")", so the fields are counted from the last ")"
+func parseStartTime(stat string) (uint64, error) {
+	index := strings.LastIndex(stat, ")")
+	if index < 0 {
+		return 0, fmt.Errorf("stat format error: %s", stat)
+	}
+
+	// the fields after "comm" start from the 3rd field "state"
+	fields := strings.Fields(stat[index+1:])
+	if len(fields) < 22-2 {
+		return 0, fmt.Errorf("stat has only %d fields", len(fields)+2)
+	}
+
+	startTime, err := strconv.ParseUint(fields[22-3], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("start time[%s] is not a num", fields[22-3])
+	}
+
+	return startTime, nil
+}
+
+func alignPage(size uint64) uint64 {
+	pageSize := uint64(os.Getpagesize())
+	return (size + pageSize - 1) &^ (pageSize - 1)
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ptrace
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+const (
+	sysMprotect = 10
+	sysMadvise  = 28
+	// madvPopulateWrite populate the pages writable, supported since linux 5.14
+	madvPopulateWrite = 23
+	mapNoReserve      = 0x4000
+
+	populateBufSize = 1024 * 1024
+)
+
+// MapLeakRegion map an anonymous memory in the process without populating, so the rss is not increased yet. the whole
+// area is mapped inaccessible first, then the middle part except the guard pages is made writable
+func MapLeakRegion(pid int, size uint64) (*LeakRegion, error) {
+	startTime, err := getStartTime(pid)
+	if err != nil {
+		return nil, fmt.Errorf("get start time of process[%d] error: %s", pid, err.Error())
+	}
+
+	p, err := Trace(pid)
+	if err != nil {
+		return nil, fmt.Errorf("trace process[%d] error: %s", pid, err.Error())
+	}
+	defer p.Detach()
+
+	size, guardSize := alignPage(size), uint64(os.Getpagesize())
+	start, err := p.Syscall(sysMmap, 0, size+2*guardSize, syscall.PROT_NONE, mapAnonymous|mapNoReserve, ^uint64(0), 0)
+	if err != nil {
+		return nil, fmt.Errorf("mmap error: %s", err.Error())
+	}
+
+	if _, err := p.Syscall(sysMprotect, start+guardSize, size, syscall.PROT_READ|syscall.PROT_WRITE); err != nil {
+		if err := p.Munmap(start, size+2*guardSize); err != nil {
+			return nil, fmt.Errorf("mprotect failed and munmap error: %s", err.Error())
+		}
+		return nil, fmt.Errorf("mprotect error: %s", err.Error())
+	}
+
+	return &LeakRegion{Pid: pid, StartTime: startTime, Addr: start + guardSize, Size: size, GuardSize: guardSize}, nil
+}
+
+// PopulateLeakRegion populate the pages in [offset, offset+size) of the region, the memory is charged to the process.
+// if madvise is not supported by the kernel, the pages are written by /proc/[pid]/mem
+func PopulateLeakRegion(region *LeakRegion, offset, size uint64) error {
+	if offset >= region.Size {
+		return nil
+	}
+
+	if offset+size > region.Size {
+		size = region.Size - offset
+	}
+
+	p, err := Trace(region.Pid)
+	if err != nil {
+		return fmt.Errorf("trace process[%d] error: %s", region.Pid, err.Error())
+	}
+	defer p.Detach()
+
+	// the pid can not be reused while it is traced, so the check after attaching is reliable
+	isExist, err := checkRegion(region)
+	if err != nil {
+		return fmt.Errorf("check region[0x%x] of process[%d] error: %s", region.Addr, region.Pid, err.Error())
+	}
+
+	if !isExist {
+		return fmt.Errorf("region[0x%x] is not mapped in process[%d]", region.Addr, region.Pid)
+	}
+
+	if _, err := p.Syscall(sysMadvise, region.Addr+offset, size, madvPopulateWrite); err == nil {
+		return nil
+	}
+
+	memFile := fmt.Sprintf("/proc/%d/mem", region.Pid)
+	f, err := os.OpenFile(memFile, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("open %s error: %s", memFile, err.Error())
+	}
+	defer f.Close()
+
+	buf := make([]byte, populateBufSize)
+	for i := range buf {
+		buf[i] = 1
+	}
+
+	for done := uint64(0); done < size; {
+		n := size - done
+		if n > populateBufSize {
+			n = populateBufSize
+		}
+
+		if _, err := f.WriteAt(buf[:n], int64(region.Addr+offset+done)); err != nil {
+			return fmt.Errorf("write %s error: %s", memFile, err.Error())
+		}
+		done += n
+	}
+
+	return nil
+}
+
+// FreeLeakRegion unmap the region and its guard pages, do nothing if the process has exited or the region is not
+// mapped exactly, in which case the memory there does not belong to the experiment
+func FreeLeakRegion(region *LeakRegion) error {
+	isExist, err := checkRegion(region)
+	if err != nil {
+		return fmt.Errorf("check region[0x%x] of process[%d] error: %s", region.Addr, region.Pid, err.Error())
+	}
+
+	if !isExist {
+		return nil
+	}
+
+	p, err := Trace(region.Pid)
+	if err != nil {
+		return fmt.Errorf("trace process[%d] error: %s", region.Pid, err.Error())
+	}
+	defer p.Detach()
+
+	// the pid may be reused between the first check and attaching
+	if isExist, err = checkRegion(region); err != nil || !isExist {
+		return err
+	}
+
+	return p.Munmap(region.Addr-region.GuardSize, region.Size+2*region.GuardSize)
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ptrace
+
+import (
+	"testing"
+)
+
+func TestParseStartTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		stat    string
+		want    uint64
+		wantErr bool
+	}{
+		{
+			name: "normal",
+			stat: "1234 (sleep) S 1 1234 1234 0 -1 4194304 95 0 0 0 0 0 0 0 20 0 1 0 5550123 8429568 144 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0",
+			want: 5550123,
+		},
+		{
+			name: "comm with space and bracket",
+			stat: "99 (a) b (c) R 1 99 99 0 -1 4194304 95 0 0 0 0 0 0 0 20 0 1 0 42 8429568 144",
+			want: 42,
+		},
+		{
+			name:    "truncated",
+			stat:    "99 (a) R 1 99",
+			wantErr: true,
+		},
+		{
+			name:    "no comm",
+			stat:    "99 a R",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStartTime(tt.stat)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStartTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseStartTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExistMapping(t *testing.T) {
+	maps := "558d6cd11000-558d6cd32000 rw-p 00000000 00:00 0                          [heap]\n" +
+		"7fdb4fd09000-7fdb4fd0a000 ---p 00000000 00:00 0 \n" +
+		"7fdb4fd0a000-7fdb5010a000 rw-p 00000000 00:00 0 \n" +
+		"7fdb5010a000-7fdb5010c000 r--p 00000000 fd:01 1234                       /usr/lib/libc.so.6\n"
+	tests := []struct {
+		name  string
+		start uint64
+		end   uint64
+		want  bool
+	}{
+		{name: "exact", start: 0x7fdb4fd0a000, end: 0x7fdb5010a000, want: true},
+		{name: "inside", start: 0x7fdb4fd0b000, end: 0x7fdb5010a000, want: false},
+		{name: "larger", start: 0x7fdb4fd09000, end: 0x7fdb5010a000, want: false},
+		{name: "not anonymous", start: 0x7fdb5010a000, end: 0x7fdb5010c000, want: false},
+		{name: "named anonymous", start: 0x558d6cd11000, end: 0x558d6cd32000, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := existMapping(maps, tt.start, tt.end); got != tt.want {
+				t.Errorf("existMapping() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ptrace
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+const (
+	sysMmap   = 9
+	sysMunmap = 11
+
+	// maxStepOut the max single steps to move a thread out of the code to be replaced
+	maxStepOut = 100
+
+	protRWX      = syscall.PROT_READ | syscall.PROT_WRITE | syscall.PROT_EXEC
+	mapAnonymous = syscall.MAP_PRIVATE | syscall.MAP_ANONYMOUS
+)
+
+// TracedProcess a process whose threads are all stopped by ptrace. ptrace requests must be sent from the same os thread,
+// so the calling goroutine is locked to its thread until Detach
+type TracedProcess struct {
+	Pid     int
+	tidList []int
+}
+
+func listTid(pid int) ([]int, error) {
+	taskDir := fmt.Sprintf("/proc/%d/task", pid)
+	entries, err := os.ReadDir(taskDir)
+	if err != nil {
+		return nil, fmt.Errorf("read %s error: %s", taskDir, err.Error())
+	}
+
+	var tidList []int
+	for _, entry := range entries {
+		tid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		tidList = append(tidList, tid)
+	}
+
+	return tidList, nil
+}
+
+// Trace attach all the threads of the process, retry until no new thread is created
+func Trace(pid int) (*TracedProcess, error) {
+	runtime.LockOSThread()
+	p := &TracedProcess{Pid: pid}
+	var attached = make(map[int]bool)
+	for {
+		tidList, err := listTid(pid)
+		if err != nil {
+			p.Detach()
+			return nil, err
+		}
+
+		var isNew bool
+		for _, tid := range tidList {
+			if attached[tid] {
+				continue
+			}
+
+			isNew = true
+			if err := syscall.PtraceAttach(tid); err != nil {
+				if err == syscall.ESRCH {
+					// the thread has exited
+					continue
+				}
+
+				p.Detach()
+				return nil, fmt.Errorf("attach thread[%d] error: %s", tid, err.Error())
+			}
+
+			var status syscall.WaitStatus
+			if _, err := syscall.Wait4(tid, &status, syscall.WALL, nil); err != nil {
+				p.tidList = append(p.tidList, tid)
+				p.Detach()
+				return nil, fmt.Errorf("wait thread[%d] error: %s", tid, err.Error())
+			}
+
+			attached[tid] = true
+			p.tidList = append(p.tidList, tid)
+		}
+
+		if !isNew {
+			return p, nil
+		}
+	}
+}
+
+func (p *TracedProcess) Detach() {
+	for _, tid := range p.tidList {
+		_ = syscall.PtraceDetach(tid)
+	}
+
+	p.tidList = nil
+	runtime.UnlockOSThread()
+}
+
+func (p *TracedProcess) ReadMem(addr uint64, size int) ([]byte, error) {
+	buf := make([]byte, size)
+	if _, err := syscall.PtracePeekData(p.Pid, uintptr(addr), buf); err != nil {
+		return nil, fmt.Errorf("read memory[0x%x] error: %s", addr, err.Error())
+	}
+
+	return buf, nil
+}
+
+func (p *TracedProcess) WriteMem(addr uint64, data []byte) error {
+	if _, err := syscall.PtracePokeData(p.Pid, uintptr(addr), data); err != nil {
+		return fmt.Errorf("write memory[0x%x] error: %s", addr, err.Error())
+	}
+
+	return nil
+}
+
+// IsExecuting check whether the instruction pointer of any thread is in [start, end)
+func (p *TracedProcess) IsExecuting(start, end uint64) (bool, error) {
+	for _, tid := range p.tidList {
+		var regs syscall.PtraceRegs
+		if err := syscall.PtraceGetRegs(tid, &regs); err != nil {
+			if err == syscall.ESRCH {
+				continue
+			}
+
+			return false, fmt.Errorf("get registers of thread[%d] error: %s", tid, err.Error())
+		}
+
+		if regs.Rip >= start && regs.Rip < end {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// StepOut single step the threads whose instruction pointer is in any of the ranges until they leave the ranges, so
+// that the code in the ranges can be replaced safely. fail if a thread is still in the ranges after maxStepOut steps
+func (p *TracedProcess) StepOut(rangeList [][2]uint64) error {
+	for _, tid := range p.tidList {
+		for step := 0; ; step++ {
+			var regs syscall.PtraceRegs
+			if err := syscall.PtraceGetRegs(tid, &regs); err != nil {
+				if err == syscall.ESRCH {
+					break
+				}
+
+				return fmt.Errorf("get registers of thread[%d] error: %s", tid, err.Error())
+			}
+
+			if !inRangeList(regs.Rip, rangeList) {
+				break
+			}
+
+			if step >= maxStepOut {
+				return fmt.Errorf("thread[%d] is still executing at 0x%x after %d steps", tid, regs.Rip, maxStepOut)
+			}
+
+			if err := syscall.PtraceSingleStep(tid); err != nil {
+				return fmt.Errorf("single step thread[%d] error: %s", tid, err.Error())
+			}
+
+			var status syscall.WaitStatus
+			if _, err := syscall.Wait4(tid, &status, syscall.WALL, nil); err != nil {
+				return fmt.Errorf("wait single step of thread[%d] error: %s", tid, err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// Syscall execute a syscall in the main thread by replacing the current instruction with "syscall" and single step,
+// the registers and the instruction are restored after that
+func (p *TracedProcess) Syscall(number uint64, args ...uint64) (uint64, error) {
+	var oldRegs syscall.PtraceRegs
+	if err := syscall.PtraceGetRegs(p.Pid, &oldRegs); err != nil {
+		return 0, fmt.Errorf("get registers error: %s", err.Error())
+	}
+
+	oldCode, err := p.ReadMem(oldRegs.Rip, 2)
+	if err != nil {
+		return 0, err
+	}
+
+	regs := oldRegs
+	// avoid the syscall restart logic of kernel if the thread is stopped in a syscall
+	regs.Orig_rax = ^uint64(0)
+	regs.Rax = number
+	argRegs := []*uint64{&regs.Rdi, &regs.Rsi, &regs.Rdx, &regs.R10, &regs.R8, &regs.R9}
+	for i, arg := range args {
+		*argRegs[i] = arg
+	}
+
+	code := make([]byte, 2)
+	binary.LittleEndian.PutUint16(code, 0x050f)
+	if err := p.WriteMem(oldRegs.Rip, code); err != nil {
+		return 0, err
+	}
+
+	re, execErr := p.step(&regs)
+
+	if err := p.WriteMem(oldRegs.Rip, oldCode); err != nil {
+		return 0, fmt.Errorf("restore code error: %s", err.Error())
+	}
+
+	if err := syscall.PtraceSetRegs(p.Pid, &oldRegs); err != nil {
+		return 0, fmt.Errorf("restore registers error: %s", err.Error())
+	}
+
+	if execErr != nil {
+		return 0, execErr
+	}
+
+	// the return value in [-4095, -1] means an errno
+	if int64(re) < 0 && int64(re) > -4096 {
+		return 0, fmt.Errorf("syscall[%d] error: %s", number, syscall.Errno(-int64(re)).Error())
+	}
+
+	return re, nil
+}
+
+func (p *TracedProcess) step(regs *syscall.PtraceRegs) (uint64, error) {
+	if err := syscall.PtraceSetRegs(p.Pid, regs); err != nil {
+		return 0, fmt.Errorf("set registers error: %s", err.Error())
+	}
+
+	if err := syscall.PtraceSingleStep(p.Pid); err != nil {
+		return 0, fmt.Errorf("single step error: %s", err.Error())
+	}
+
+	var status syscall.WaitStatus
+	if _, err := syscall.Wait4(p.Pid, &status, syscall.WALL, nil); err != nil {
+		return 0, fmt.Errorf("wait single step error: %s", err.Error())
+	}
+
+	var re syscall.PtraceRegs
+	if err := syscall.PtraceGetRegs(p.Pid, &re); err != nil {
+		return 0, fmt.Errorf("get registers error: %s", err.Error())
+	}
+
+	return re.Rax, nil
+}
+
+// Mmap map an anonymous rwx memory, the kernel will try to map it at hint address if it is available
+func (p *TracedProcess) Mmap(hint, size uint64) (uint64, error) {
+	return p.Syscall(sysMmap, hint, size, protRWX, mapAnonymous, ^uint64(0), 0)
+}
+
+func (p *TracedProcess) Munmap(addr, size uint64) error {
+	_, err := p.Syscall(sysMunmap, addr, size)
+	return err
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ptrace
+
+import (
+	"bytes"
+	"debug/elf"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	ClockRealtime = 0
+	clockMaxId    = 63
+)
+
+// ClockIdMap the clock ids supported by clock_gettime
+var ClockIdMap = map[string]int{
+	"CLOCK_REALTIME":           0,
+	"CLOCK_MONOTONIC":          1,
+	"CLOCK_PROCESS_CPUTIME_ID": 2,
+	"CLOCK_THREAD_CPUTIME_ID":  3,
+	"CLOCK_MONOTONIC_RAW":      4,
+	"CLOCK_REALTIME_COARSE":    5,
+	"CLOCK_MONOTONIC_COARSE":   6,
+	"CLOCK_BOOTTIME":           7,
+	"CLOCK_REALTIME_ALARM":     8,
+	"CLOCK_BOOTTIME_ALARM":     9,
+	"CLOCK_TAI":                11,
+}
+
+// SymbolPatch the original code of a vdso function which is replaced by a jump to the fake function
+type SymbolPatch struct {
+	Name   string `json:"name"`
+	Addr   uint64 `json:"addr"`
+	Origin []byte `json:"origin"`
+	Patch  []byte `json:"patch"`
+}
+
+// ClockPatch record the modification to a process for recovering
+type ClockPatch struct {
+	Pid      int            `json:"pid"`
+	Page     uint64         `json:"page"`
+	PageSize uint64         `json:"page_size"`
+	Symbols  []*SymbolPatch `json:"symbols"`
+}
+
+// getPatchRangeList the ranges where a thread would resume in the middle of an instruction after the code of symbols
+// is replaced. the first byte is excluded, a thread stopped there executes the new code from the start
+func getPatchRangeList(symbols []*SymbolPatch) [][2]uint64 {
+	var rangeList [][2]uint64
+	for _, sym := range symbols {
+		if len(sym.Patch) > 1 {
+			rangeList = append(rangeList, [2]uint64{sym.Addr + 1, sym.Addr + uint64(len(sym.Patch))})
+		}
+	}
+
+	return rangeList
+}
+
+// inRangeList addr is in any of the ranges [start, end)
+func inRangeList(addr uint64, rangeList [][2]uint64) bool {
+	for _, r := range rangeList {
+		if addr >= r[0] && addr < r[1] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getVdsoRange get the address range of vdso from /proc/[pid]/maps
+func getVdsoRange(pid int) (uint64, uint64, error) {
+	mapsFile := fmt.Sprintf("/proc/%d/maps", pid)
+	reByte, err := os.ReadFile(mapsFile)
+	if err != nil {
+		return 0, 0, fmt.Errorf("read %s error: %s", mapsFile, err.Error())
+	}
+
+	for _, line := range strings.Split(string(reByte), "\n") {
+		if !strings.HasSuffix(line, "[vdso]") {
+			continue
+		}
+
+		rangeArr := strings.Split(strings.Fields(line)[0], "-")
+		start, err := strconv.ParseUint(rangeArr[0], 16, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("parse vdso start address[%s] error: %s", rangeArr[0], err.Error())
+		}
+
+		end, err := strconv.ParseUint(rangeArr[1], 16, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("parse vdso end address[%s] error: %s", rangeArr[1], err.Error())
+		}
+
+		return start, end, nil
+	}
+
+	return 0, 0, fmt.Errorf("vdso is not found in %s", mapsFile)
+}
+
+// getVdsoSymbols parse the vdso image and return the address and size of the target functions
+func getVdsoSymbols(image []byte, start uint64, names []string) (map[string][2]uint64, error) {
+	f, err := elf.NewFile(bytes.NewReader(image))
+	if err != nil {
+		return nil, fmt.Errorf("parse vdso elf error: %s", err.Error())
+	}
+
+	var base uint64
+	for _, prog := range f.Progs {
+		if prog.Type == elf.PT_LOAD {
+			base = prog.Vaddr
+			break
+		}
+	}
+
+	symbols, err := f.DynamicSymbols()
+	if err != nil {
+		return nil, fmt.Errorf("get vdso symbols error: %s", err.Error())
+	}
+
+	var re = make(map[string][2]uint64)
+	for _, sym := range symbols {
+		for _, name := range names {
+			if sym.Name == name {
+				re[name] = [2]uint64{start + sym.Value - base, sym.Size}
+			}
+		}
+	}
+
+	return re, nil
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ptrace
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetPatchRangeList(t *testing.T) {
+	symbols := []*SymbolPatch{
+		{Name: symClockGettime, Addr: 0x1000, Patch: make([]byte, 5)},
+		{Name: symGettimeofday, Addr: 0x2000, Patch: make([]byte, 12)},
+		{Name: symTime, Addr: 0x3000},
+	}
+	want := [][2]uint64{{0x1001, 0x1005}, {0x2001, 0x200c}}
+	if got := getPatchRangeList(symbols); !reflect.DeepEqual(got, want) {
+		t.Errorf("getPatchRangeList() = %x, want %x", got, want)
+	}
+
+	if got := getPatchRangeList(nil); got != nil {
+		t.Errorf("getPatchRangeList(nil) = %x, want nil", got)
+	}
+}
+
+func TestInRangeList(t *testing.T) {
+	rangeList := getPatchRangeList([]*SymbolPatch{
+		{Addr: 0x1000, Patch: make([]byte, 5)},
+		{Addr: 0x2000, Patch: make([]byte, 12)},
+	})
+	tests := []struct {
+		name string
+		addr uint64
+		want bool
+	}{
+		{name: "before symbol", addr: 0xfff, want: false},
+		{name: "start of symbol", addr: 0x1000, want: false},
+		{name: "inside jump", addr: 0x1001, want: true},
+		{name: "last byte of jump", addr: 0x1004, want: true},
+		{name: "after jump", addr: 0x1005, want: false},
+		{name: "inside long jump", addr: 0x200b, want: true},
+		{name: "after long jump", addr: 0x200c, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inRangeList(tt.addr, rangeList); got != tt.want {
+				t.Errorf("inRangeList(0x%x) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
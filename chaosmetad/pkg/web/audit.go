@@ -0,0 +1,79 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"os"
+)
+
+// AuditEntry who called inject or recover, and what happened
+type AuditEntry struct {
+	Identity   string
+	AuthType   string
+	ClientCert string
+	RemoteAddr string
+	Route      string
+	Target     string
+	Fault      string
+	Uid        string
+	TraceId    string
+	DryRun     bool
+}
+
+// Auditor write audit log in json format, one line per request
+type Auditor struct {
+	logger *logrus.Logger
+}
+
+func NewAuditor(path string) (*Auditor, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log file[%s] error: %s", path, err.Error())
+	}
+
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{TimestampFormat: log.TimeFormat})
+	logger.SetLevel(logrus.InfoLevel)
+	logger.SetOutput(f)
+	return &Auditor{logger: logger}, nil
+}
+
+// Record audit is disabled if auditor is nil
+func (a *Auditor) Record(ctx context.Context, entry *AuditEntry, code int, msg string) {
+	if a == nil {
+		return
+	}
+
+	log.GetLogger(ctx).Debugf("audit: %s %s %s/%s %s, code: %d", entry.Identity, entry.Route, entry.Target, entry.Fault, entry.Uid, code)
+	a.logger.WithFields(logrus.Fields{
+		"identity":    entry.Identity,
+		"auth_type":   entry.AuthType,
+		"client_cert": entry.ClientCert,
+		"remote_addr": entry.RemoteAddr,
+		"route":       entry.Route,
+		"target":      entry.Target,
+		"fault":       entry.Fault,
+		"uid":         entry.Uid,
+		"trace_id":    entry.TraceId,
+		"dry_run":     entry.DryRun,
+		"code":        code,
+	}).Info(msg)
+}
@@ -0,0 +1,400 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/storage"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/errutil"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/web/model"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CredentialToken = "token"
+	CredentialHMAC  = "hmac"
+	CredentialCert  = "cert"
+
+	AuthHeader   = "Authorization"
+	BearerPrefix = "Bearer "
+	HMACPrefix   = "HMAC-SHA256 "
+	HMACMaxSkew  = 5 * time.Minute
+	MaxNonceLen  = 64
+
+	AllowAll      = "*"
+	MaxBodyLength = 1 << 20
+)
+
+// AuthConfig the credentials which are allowed to call the api, loaded from the file provided by "--auth-config"
+type AuthConfig struct {
+	Credentials []*Credential `json:"credentials"`
+
+	// nonceMap the hmac nonces seen in the last 2*HMACMaxSkew, a request whose timestamp is valid is rejected if its
+	// nonce has been used, so a captured request can not be replayed
+	nonceMu  sync.Mutex
+	nonceMap map[string]time.Time
+}
+
+// Credential "token" is checked by "Authorization: Bearer [secret]"; "hmac" is checked by
+// "Authorization: HMAC-SHA256 [name]:[unix timestamp]:[nonce]:[hex signature]", the signature is hmac-sha256 of
+// "[method]\n[path]\n[timestamp]\n[nonce]\n[hex sha256 of body]", the timestamp must be within HMACMaxSkew of the
+// server time, and the nonce must be unique in that window; "cert" is checked by the common name of the verified
+// client certificate. "targets" are the targets whose all faults are allowed, "faults" are the allowed faults in
+// "[target]/[fault]" format, "*" means all. query and version are allowed for all credentials
+type Credential struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Secret  string   `json:"secret,omitempty"`
+	Targets []string `json:"targets,omitempty"`
+	Faults  []string `json:"faults,omitempty"`
+}
+
+func LoadAuthConfig(path string) (*AuthConfig, error) {
+	configBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file error: %s", err.Error())
+	}
+
+	var config AuthConfig
+	if err := json.Unmarshal(configBytes, &config); err != nil {
+		return nil, fmt.Errorf("file format error: %s", err.Error())
+	}
+
+	var nameMap = make(map[string]bool)
+	for _, c := range config.Credentials {
+		if c.Name == "" {
+			return nil, fmt.Errorf("\"name\" of credential can not be empty")
+		}
+
+		key := fmt.Sprintf("%s/%s", c.Type, c.Name)
+		if nameMap[key] {
+			return nil, fmt.Errorf("credential[%s] is duplicated", key)
+		}
+		nameMap[key] = true
+
+		switch c.Type {
+		case CredentialToken, CredentialHMAC:
+			if c.Secret == "" {
+				return nil, fmt.Errorf("\"secret\" of credential[%s] can not be empty", c.Name)
+			}
+		case CredentialCert:
+		default:
+			return nil, fmt.Errorf("type[%s] of credential[%s] is not support, only support: %s, %s, %s", c.Type, c.Name, CredentialToken, CredentialHMAC, CredentialCert)
+		}
+	}
+
+	return &config, nil
+}
+
+// IsAllowed check if the credential can inject or recover the fault of target
+func (c *Credential) IsAllowed(target, fault string) bool {
+	for _, t := range c.Targets {
+		if t == AllowAll || t == target {
+			return true
+		}
+	}
+
+	for _, f := range c.Faults {
+		if f == AllowAll || f == fmt.Sprintf("%s/%s", target, fault) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *AuthConfig) authenticate(r *http.Request, body []byte) (*Credential, error) {
+	header := r.Header.Get(AuthHeader)
+	if strings.HasPrefix(header, BearerPrefix) {
+		token := []byte(strings.TrimPrefix(header, BearerPrefix))
+		for _, unit := range c.Credentials {
+			if unit.Type == CredentialToken && subtle.ConstantTimeCompare(token, []byte(unit.Secret)) == 1 {
+				return unit, nil
+			}
+		}
+
+		return nil, fmt.Errorf("token is invalid")
+	}
+
+	if strings.HasPrefix(header, HMACPrefix) {
+		return c.authenticateHMAC(r, strings.TrimPrefix(header, HMACPrefix), body)
+	}
+
+	if header != "" {
+		return nil, fmt.Errorf("authorization scheme is not support")
+	}
+
+	if cn := getClientCertName(r); cn != "" {
+		for _, unit := range c.Credentials {
+			if unit.Type == CredentialCert && unit.Name == cn {
+				return unit, nil
+			}
+		}
+
+		return nil, fmt.Errorf("client certificate[%s] is not allowed", cn)
+	}
+
+	return nil, fmt.Errorf("no credential provided")
+}
+
+func (c *AuthConfig) authenticateHMAC(r *http.Request, value string, body []byte) (*Credential, error) {
+	valueArr := strings.Split(value, ":")
+	if len(valueArr) != 4 {
+		return nil, fmt.Errorf("hmac format error, true format: [name]:[unix timestamp]:[nonce]:[hex signature]")
+	}
+
+	name, timestampStr, nonce := valueArr[0], valueArr[1], valueArr[2]
+	timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("timestamp[%s] is not a num", timestampStr)
+	}
+
+	now := time.Now()
+	skew := now.Sub(time.Unix(timestamp, 0))
+	if skew > HMACMaxSkew || skew < -HMACMaxSkew {
+		return nil, fmt.Errorf("timestamp is expired")
+	}
+
+	if nonce == "" || len(nonce) > MaxNonceLen {
+		return nil, fmt.Errorf("nonce must be provided and not longer than %d", MaxNonceLen)
+	}
+
+	signature, err := hex.DecodeString(valueArr[3])
+	if err != nil {
+		return nil, fmt.Errorf("signature is not hex format")
+	}
+
+	for _, unit := range c.Credentials {
+		if unit.Type == CredentialHMAC && unit.Name == name {
+			if !hmac.Equal(signature, GetHMACSignature(unit.Secret, r.Method, r.URL.Path, timestampStr, nonce, body)) {
+				return nil, fmt.Errorf("signature is invalid")
+			}
+
+			// the nonce is only recorded after the signature is verified, otherwise anyone could burn the nonces
+			if !c.useNonce(fmt.Sprintf("%s/%s", name, nonce), now) {
+				return nil, fmt.Errorf("nonce[%s] has been used", nonce)
+			}
+
+			return unit, nil
+		}
+	}
+
+	return nil, fmt.Errorf("hmac credential[%s] is not found", name)
+}
+
+// useNonce return false if the nonce has been used. a nonce is kept for 2*HMACMaxSkew, which covers the whole window
+// of valid timestamps, the expired nonces are cleaned up on each call
+func (c *AuthConfig) useNonce(key string, now time.Time) bool {
+	c.nonceMu.Lock()
+	defer c.nonceMu.Unlock()
+
+	if c.nonceMap == nil {
+		c.nonceMap = make(map[string]time.Time)
+	}
+
+	for k, t := range c.nonceMap {
+		if now.Sub(t) > 2*HMACMaxSkew {
+			delete(c.nonceMap, k)
+		}
+	}
+
+	if _, ok := c.nonceMap[key]; ok {
+		return false
+	}
+
+	c.nonceMap[key] = now
+	return true
+}
+
+func GetHMACSignature(secret, method, path, timestamp, nonce string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%s\n%s\n%s\n%s\n%s", method, path, timestamp, nonce, hex.EncodeToString(bodyHash[:]))))
+	return mac.Sum(nil)
+}
+
+// getClientCertName the common name of the client certificate which has been verified by the ca of "--client-ca"
+func getClientCertName(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.PeerCertificates) == 0 {
+		return ""
+	}
+
+	return r.TLS.PeerCertificates[0].Subject.CommonName
+}
+
+// fillRequestScope fill the target and fault operated by the request into entry, return false if the request does not operate experiment
+func fillRequestScope(name string, body []byte, entry *AuditEntry) (bool, error) {
+	switch name {
+	case RouteInject:
+		var req model.InjectRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return false, err
+		}
+
+		entry.Target, entry.Fault, entry.Uid, entry.DryRun = req.Target, req.Fault, req.Uid, req.DryRun
+		return true, nil
+	case RouteRecover:
+		var req model.RecoverRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return false, err
+		}
+
+		entry.Uid = req.Uid
+		db, err := storage.GetExperimentStore()
+		if err != nil {
+			return false, fmt.Errorf("connect db error: %s", err.Error())
+		}
+
+		// the handler will return the error of experiment not found
+		exp, err := db.GetByUid(req.Uid)
+		if err != nil {
+			return false, nil
+		}
+
+		entry.Target, entry.Fault = exp.Target, exp.Fault
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Auth check the credential and permission of the request, and write audit log for inject and recover. auth is
+// disabled if config is nil
+func Auth(ctx context.Context, inner http.Handler, name string, config *AuthConfig, auditor *Auditor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// one more byte is read to know if the body is too large, a truncated body must not be passed to the handler
+		body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodyLength+1))
+		if err != nil {
+			writeAuthErr(ctx, w, http.StatusBadRequest, errutil.BadArgsErr, fmt.Sprintf("read body error: %s", err.Error()))
+			return
+		}
+
+		if len(body) > MaxBodyLength {
+			writeAuthErr(ctx, w, http.StatusRequestEntityTooLarge, errutil.BadArgsErr, fmt.Sprintf("body is larger than %d bytes", MaxBodyLength))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		var entry = &AuditEntry{
+			Route:      name,
+			RemoteAddr: r.RemoteAddr,
+			ClientCert: getClientCertName(r),
+			Identity:   "anonymous",
+		}
+
+		var credential *Credential
+		if config != nil {
+			if credential, err = config.authenticate(r, body); err != nil {
+				auditor.Record(ctx, entry, errutil.AuthErr, fmt.Sprintf("authenticate error: %s", err.Error()))
+				writeAuthErr(ctx, w, http.StatusUnauthorized, errutil.AuthErr, fmt.Sprintf("authenticate error: %s", err.Error()))
+				return
+			}
+
+			entry.Identity, entry.AuthType = credential.Name, credential.Type
+		}
+
+		// the permission can not be checked without scope, the request is rejected only if auth is enabled
+		needCheck, err := fillRequestScope(name, body, entry)
+		if err != nil && credential != nil {
+			auditor.Record(ctx, entry, errutil.BadArgsErr, fmt.Sprintf("get request scope error: %s", err.Error()))
+			writeAuthErr(ctx, w, http.StatusBadRequest, errutil.BadArgsErr, fmt.Sprintf("get request scope error: %s", err.Error()))
+			return
+		}
+
+		if credential != nil && !isRouteAllowed(credential, name, entry.Target, entry.Fault, needCheck) {
+			msg := fmt.Sprintf("credential[%s] has no permission for route[%s], target[%s], fault[%s]", credential.Name, name, entry.Target, entry.Fault)
+			auditor.Record(ctx, entry, errutil.AuthErr, msg)
+			writeAuthErr(ctx, w, http.StatusForbidden, errutil.AuthErr, msg)
+			return
+		}
+
+		if !isAuditRoute(name) {
+			inner.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		inner.ServeHTTP(recorder, r)
+
+		var res model.InjectResponse
+		if err := json.Unmarshal(recorder.body.Bytes(), &res); err != nil {
+			auditor.Record(ctx, entry, errutil.UnknownErr, fmt.Sprintf("response format error: %s", err.Error()))
+			return
+		}
+
+		if entry.Uid == "" && res.Data != nil && res.Data.Experiment != nil {
+			entry.Uid = res.Data.Experiment.Uid
+		}
+		entry.TraceId = res.TraceId
+		auditor.Record(ctx, entry, res.Code, res.Message)
+	})
+}
+
+func isRouteAllowed(credential *Credential, name, target, fault string, needCheck bool) bool {
+	if strings.HasPrefix(name, pprofRoutePrefix) {
+		return credential.IsAllowed(AllowAll, AllowAll)
+	}
+
+	if needCheck {
+		return credential.IsAllowed(target, fault)
+	}
+
+	return true
+}
+
+func isAuditRoute(name string) bool {
+	return name == RouteInject || name == RouteRecover
+}
+
+func writeAuthErr(ctx context.Context, w http.ResponseWriter, status, code int, msg string) {
+	log.GetLogger(ctx).Warnf(msg)
+	resBytes, _ := json.Marshal(&model.CommonResponse{
+		Code:    code,
+		Message: msg,
+	})
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	if _, err := w.Write(resBytes); err != nil {
+		log.GetLogger(ctx).Errorf("write data error: %s", err.Error())
+	}
+}
+
+// responseRecorder keep a copy of the response body for audit
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAuthConfig() *AuthConfig {
+	return &AuthConfig{
+		Credentials: []*Credential{
+			{Name: "admin", Type: CredentialToken, Secret: "admin-token", Targets: []string{AllowAll}},
+			{Name: "cpu-only", Type: CredentialToken, Secret: "cpu-token", Targets: []string{"cpu"}},
+			{Name: "signer", Type: CredentialHMAC, Secret: "hmac-secret", Faults: []string{"mem/fill"}},
+			{Name: "client-a", Type: CredentialCert},
+		},
+	}
+}
+
+func getHMACHeader(name, secret, method, path string, timestamp int64, nonce string, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	return fmt.Sprintf("%s%s:%s:%s:%s", HMACPrefix, name, ts, nonce, hex.EncodeToString(GetHMACSignature(secret, method, path, ts, nonce, body)))
+}
+
+func withClientCert(r *http.Request, cn string) *http.Request {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}}
+	return r
+}
+
+func TestCredential_IsAllowed(t *testing.T) {
+	tests := []struct {
+		name       string
+		credential Credential
+		target     string
+		fault      string
+		want       bool
+	}{
+		{name: "all targets", credential: Credential{Targets: []string{AllowAll}}, target: "cpu", fault: "burn", want: true},
+		{name: "target matched", credential: Credential{Targets: []string{"mem", "cpu"}}, target: "cpu", fault: "burn", want: true},
+		{name: "target not matched", credential: Credential{Targets: []string{"mem"}}, target: "cpu", fault: "burn", want: false},
+		{name: "all faults", credential: Credential{Faults: []string{AllowAll}}, target: "cpu", fault: "burn", want: true},
+		{name: "fault matched", credential: Credential{Faults: []string{"cpu/burn"}}, target: "cpu", fault: "burn", want: true},
+		{name: "fault of other target", credential: Credential{Faults: []string{"mem/burn"}}, target: "cpu", fault: "burn", want: false},
+		{name: "nothing allowed", credential: Credential{}, target: "cpu", fault: "burn", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.credential.IsAllowed(tt.target, tt.fault); got != tt.want {
+				t.Errorf("IsAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthConfig_authenticate(t *testing.T) {
+	body := []byte(`{"target":"cpu"}`)
+	now := time.Now().Unix()
+	tests := []struct {
+		name     string
+		header   string
+		cert     string
+		wantName string
+		wantErr  bool
+	}{
+		{name: "token", header: BearerPrefix + "cpu-token", wantName: "cpu-only"},
+		{name: "invalid token", header: BearerPrefix + "hmac-secret", wantErr: true},
+		{name: "hmac", header: getHMACHeader("signer", "hmac-secret", http.MethodPost, "/v1/experiment/inject", now, "n1", body), wantName: "signer"},
+		{name: "cert", cert: "client-a", wantName: "client-a"},
+		{name: "cert not allowed", cert: "client-b", wantErr: true},
+		{name: "header takes precedence over cert", header: BearerPrefix + "bad", cert: "client-a", wantErr: true},
+		{name: "unknown scheme", header: "Basic YWRtaW46YWRtaW4=", wantErr: true},
+		{name: "no credential", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/experiment/inject", bytes.NewReader(body))
+			if tt.header != "" {
+				r.Header.Set(AuthHeader, tt.header)
+			}
+			if tt.cert != "" {
+				r = withClientCert(r, tt.cert)
+			}
+
+			got, err := newTestAuthConfig().authenticate(r, body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Name != tt.wantName {
+				t.Errorf("authenticate() = %v, want %v", got.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestAuthConfig_authenticateHMAC(t *testing.T) {
+	var (
+		body   = []byte(`{"target":"mem","fault":"fill"}`)
+		path   = "/v1/experiment/inject"
+		now    = time.Now().Unix()
+		config = newTestAuthConfig()
+		sign   = func(name, secret, path string, ts int64, nonce string, body []byte) string {
+			return strings.TrimPrefix(getHMACHeader(name, secret, http.MethodPost, path, ts, nonce, body), HMACPrefix)
+		}
+	)
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "valid", value: sign("signer", "hmac-secret", path, now, "nonce-1", body)},
+		{name: "replayed nonce", value: sign("signer", "hmac-secret", path, now, "nonce-1", body), wantErr: true},
+		{name: "new nonce", value: sign("signer", "hmac-secret", path, now, "nonce-2", body)},
+		{name: "old format without nonce", value: fmt.Sprintf("signer:%d:%s", now, hex.EncodeToString(GetHMACSignature("hmac-secret", http.MethodPost, path, strconv.FormatInt(now, 10), "", body))), wantErr: true},
+		{name: "empty nonce", value: sign("signer", "hmac-secret", path, now, "", body), wantErr: true},
+		{name: "too long nonce", value: sign("signer", "hmac-secret", path, now, strings.Repeat("a", MaxNonceLen+1), body), wantErr: true},
+		{name: "expired timestamp", value: sign("signer", "hmac-secret", path, now-int64(HMACMaxSkew/time.Second)-10, "nonce-3", body), wantErr: true},
+		{name: "future timestamp", value: sign("signer", "hmac-secret", path, now+int64(HMACMaxSkew/time.Second)+10, "nonce-4", body), wantErr: true},
+		{name: "timestamp not num", value: "signer:abc:nonce-5:00", wantErr: true},
+		{name: "signature not hex", value: fmt.Sprintf("signer:%d:nonce-6:xyz", now), wantErr: true},
+		{name: "wrong secret", value: sign("signer", "other-secret", path, now, "nonce-7", body), wantErr: true},
+		{name: "wrong path", value: sign("signer", "hmac-secret", "/v1/experiment/recover", now, "nonce-8", body), wantErr: true},
+		{name: "wrong body", value: sign("signer", "hmac-secret", path, now, "nonce-9", []byte("{}")), wantErr: true},
+		{name: "unknown credential", value: sign("nobody", "hmac-secret", path, now, "nonce-10", body), wantErr: true},
+		{name: "token credential is not hmac", value: sign("admin", "admin-token", path, now, "nonce-11", body), wantErr: true},
+		{name: "nonce of failed signature is not burned", value: sign("signer", "hmac-secret", path, now, "nonce-7", body)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+			_, err := config.authenticateHMAC(r, tt.value, body)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("authenticateHMAC() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuth(t *testing.T) {
+	injectBody := `{"target":"cpu","fault":"burn"}`
+	tests := []struct {
+		name       string
+		route      string
+		config     *AuthConfig
+		header     string
+		body       string
+		wantStatus int
+		wantCalled bool
+	}{
+		{name: "auth disabled", route: RouteInject, body: injectBody, wantStatus: http.StatusOK, wantCalled: true},
+		{name: "allowed", route: RouteInject, config: newTestAuthConfig(), header: BearerPrefix + "cpu-token", body: injectBody, wantStatus: http.StatusOK, wantCalled: true},
+		{name: "unauthenticated", route: RouteInject, config: newTestAuthConfig(), body: injectBody, wantStatus: http.StatusUnauthorized},
+		{name: "forbidden", route: RouteInject, config: newTestAuthConfig(), header: BearerPrefix + "cpu-token", body: `{"target":"mem","fault":"fill"}`, wantStatus: http.StatusForbidden},
+		{name: "invalid body with auth", route: RouteInject, config: newTestAuthConfig(), header: BearerPrefix + "cpu-token", body: "{", wantStatus: http.StatusBadRequest},
+		{name: "invalid body without auth is handled by handler", route: RouteInject, body: "{", wantStatus: http.StatusOK, wantCalled: true},
+		{name: "query is allowed for all", route: "ExperimentQueryPost", config: newTestAuthConfig(), header: BearerPrefix + "cpu-token", wantStatus: http.StatusOK, wantCalled: true},
+		{name: "pprof needs all", route: pprofRoutePrefix + "Index", config: newTestAuthConfig(), header: BearerPrefix + "cpu-token", wantStatus: http.StatusForbidden},
+		{name: "pprof with admin", route: pprofRoutePrefix + "Index", config: newTestAuthConfig(), header: BearerPrefix + "admin-token", wantStatus: http.StatusOK, wantCalled: true},
+		{name: "body at max length", route: "ExperimentQueryPost", body: strings.Repeat("a", MaxBodyLength), wantStatus: http.StatusOK, wantCalled: true},
+		{name: "body too large", route: "ExperimentQueryPost", body: strings.Repeat("a", MaxBodyLength+1), wantStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called bool
+			inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				got, _ := io.ReadAll(r.Body)
+				if string(got) != tt.body {
+					t.Errorf("body passed to handler has %d bytes, want %d", len(got), len(tt.body))
+				}
+				_, _ = w.Write([]byte(`{"code":0,"message":"success"}`))
+			})
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/experiment/inject", strings.NewReader(tt.body))
+			if tt.header != "" {
+				r.Header.Set(AuthHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			Auth(context.Background(), inner, tt.route, tt.config, nil).ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCalled)
+			}
+		})
+	}
+}
@@ -23,8 +23,10 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/storage"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/errutil"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/profile"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/web/model"
 	"net/http"
+	"strconv"
 )
 
 func ExperimentQueryPost(w http.ResponseWriter, r *http.Request) {
@@ -79,6 +81,11 @@ func getExperimentQueryPostResponse(ctx context.Context, code int, msg string, e
 }
 
 func ExpToExperimentDataUnit(exp *storage.Experiment) model.ExperimentDataUnit {
+	var level string
+	if exp.Status == utils.StatusSuccess {
+		level, _ = profile.GetLevel(exp.Runtime)
+	}
+
 	return model.ExperimentDataUnit{
 		Uid:              exp.Uid,
 		Target:           exp.Target,
@@ -93,5 +100,30 @@ func ExpToExperimentDataUnit(exp *storage.Experiment) model.ExperimentDataUnit {
 		UpdateTime:       exp.UpdateTime,
 		ContainerId:      exp.ContainerId,
 		ContainerRuntime: exp.ContainerRuntime,
+		Level:            level,
+		Interval:         exp.Interval,
+		OnDuration:       exp.OnDuration,
+		Repeat:           exp.Repeat,
+		Cycle:            exp.Cycle,
+		CyclePhase:       exp.CyclePhase,
+	}
+}
+
+// GetCycleInfo the current cycle of flapping experiment, eg: "3/10 on", "3 off" for unlimited repeat. the phase is
+// meaningless after the experiment is finished, so only the last cycle is returned
+func GetCycleInfo(exp *storage.Experiment) string {
+	if exp.Interval == "" || exp.Cycle == 0 {
+		return ""
 	}
+
+	re := strconv.Itoa(exp.Cycle)
+	if exp.Repeat > 0 {
+		re = fmt.Sprintf("%s/%d", re, exp.Repeat)
+	}
+
+	if exp.Status == utils.StatusSuccess {
+		re = fmt.Sprintf("%s %s", re, exp.CyclePhase)
+	}
+
+	return re
 }
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/log"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/storage"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/errutil"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/web/model"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	WatchPollInterval = time.Second
+	watchHeartbeat    = 15 * time.Second
+	lastEventIdHeader = "Last-Event-ID"
+)
+
+// ExperimentWatchGet stream the events of experiments by server-sent events. the query params "uid", "target" and
+// "fault" filter the events, "last_id" or header "Last-Event-ID" resume the stream after the event, default from now
+func ExperimentWatchGet(w http.ResponseWriter, r *http.Request) {
+	var (
+		query  = r.URL.Query()
+		ctx    = utils.GetCtxWithTraceId(context.Background(), query.Get("trace_id"))
+		logger = log.GetLogger(ctx)
+		filter = &storage.EventFilter{Uid: query.Get("uid"), Target: query.Get("target"), Fault: query.Get("fault")}
+	)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeWatchErr(ctx, w, errutil.InternalErr, "streaming is not supported by the connection")
+		return
+	}
+
+	events, err := storage.GetEventStore()
+	if err != nil {
+		writeWatchErr(ctx, w, errutil.DBErr, fmt.Sprintf("get db error: %s", err.Error()))
+		return
+	}
+
+	lastId, isProvided, err := getWatchLastId(r)
+	if err != nil {
+		writeWatchErr(ctx, w, errutil.BadArgsErr, err.Error())
+		return
+	}
+
+	if !isProvided {
+		if lastId, err = events.GetLastId(); err != nil {
+			writeWatchErr(ctx, w, errutil.DBErr, fmt.Sprintf("get last event id error: %s", err.Error()))
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var (
+		reqCtx    = r.Context()
+		eventCh   = make(chan *storage.Event)
+		errCh     = make(chan error, 1)
+		heartbeat = time.NewTicker(watchHeartbeat)
+	)
+	defer heartbeat.Stop()
+
+	go func() {
+		errCh <- events.Watch(reqCtx, lastId, filter, WatchPollInterval, func(event *storage.Event) error {
+			select {
+			case eventCh <- event:
+				return nil
+			case <-reqCtx.Done():
+				return reqCtx.Err()
+			}
+		})
+	}()
+
+	for {
+		var err error
+		select {
+		case event := <-eventCh:
+			data, _ := json.Marshal(EventToExperimentEvent(event))
+			_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case watchErr := <-errCh:
+			if watchErr != nil && reqCtx.Err() == nil {
+				logger.Errorf("watch events error: %s", watchErr.Error())
+				data, _ := json.Marshal(&model.CommonResponse{Code: errutil.DBErr, Message: watchErr.Error()})
+				_, _ = fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+				flusher.Flush()
+			}
+			return
+		case <-reqCtx.Done():
+			return
+		}
+
+		if err != nil {
+			logger.Warnf("write event error: %s", err.Error())
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// getWatchLastId isProvided is false if the client does not provide the last event id
+func getWatchLastId(r *http.Request) (lastId uint, isProvided bool, err error) {
+	lastIdStr := r.Header.Get(lastEventIdHeader)
+	if lastIdStr == "" {
+		lastIdStr = r.URL.Query().Get("last_id")
+	}
+
+	if lastIdStr == "" {
+		return 0, false, nil
+	}
+
+	id, err := strconv.ParseUint(lastIdStr, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("last event id[%s] is not a valid num", lastIdStr)
+	}
+
+	return uint(id), true, nil
+}
+
+func writeWatchErr(ctx context.Context, w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	WriteResponse(ctx, w, &model.CommonResponse{
+		Code:    code,
+		Message: msg,
+		TraceId: utils.GetTraceId(ctx),
+	})
+}
+
+func EventToExperimentEvent(event *storage.Event) model.ExperimentEvent {
+	return model.ExperimentEvent{
+		Id:         event.Id,
+		Uid:        event.Uid,
+		Target:     event.Target,
+		Fault:      event.Fault,
+		Type:       event.Type,
+		Error_:     event.Error,
+		CreateTime: event.CreateTime,
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/injector"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/storage"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/errutil"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/web/model"
 	"net/http"
@@ -61,8 +62,18 @@ func ExperimentInjectPost(w http.ResponseWriter, r *http.Request) {
 				ContainerId:      injectReq.ContainerId,
 				Creator:          creator,
 				Runtime:          "{}",
+				Interval:         injectReq.Interval,
+				OnDuration:       injectReq.OnDuration,
+				Repeat:           injectReq.Repeat,
 			}, i.GetArgs(), i.GetRuntime()); err != nil {
 				injectRes = getExperimentInjectPostResponse(ctx, errutil.BadArgsErr, fmt.Sprintf("args load error: %s", err.Error()), nil)
+			} else if injectReq.DryRun {
+				plan, code, msg := injector.ProcessDryRun(ctx, i)
+				if code == errutil.NoErr {
+					injectRes = getExperimentDryRunResponse(ctx, errutil.NoErr, "success", plan)
+				} else {
+					injectRes = getExperimentDryRunResponse(ctx, errutil.InjectErr, fmt.Sprintf("dry-run error: %s", msg), plan)
+				}
 			} else {
 				code, msg := injector.ProcessInject(ctx, i)
 				if code == errutil.NoErr {
@@ -90,8 +101,25 @@ func getExperimentInjectPostResponse(ctx context.Context, code int, msg string,
 	}
 
 	if exp != nil {
+		unit := ExpToExperimentDataUnit(exp)
+		re.Data = &model.InjectSuccessResponseData{
+			Experiment: &unit,
+		}
+	}
+
+	return re
+}
+
+func getExperimentDryRunResponse(ctx context.Context, code int, msg string, plan *dryrun.Plan) *model.InjectResponse {
+	var re = &model.InjectResponse{
+		Code:    code,
+		Message: msg,
+		TraceId: utils.GetTraceId(ctx),
+	}
+
+	if plan != nil {
 		re.Data = &model.InjectSuccessResponseData{
-			Experiment: ExpToExperimentDataUnit(exp),
+			Plan: plan,
 		}
 	}
 
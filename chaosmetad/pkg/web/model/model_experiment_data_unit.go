@@ -30,4 +30,10 @@ type ExperimentDataUnit struct {
 	UpdateTime       string `json:"update_time,omitempty"`
 	ContainerId      string `json:"container_id,omitempty"`
 	ContainerRuntime string `json:"container_runtime,omitempty"`
+	Level            string `json:"level,omitempty"`
+	Interval         string `json:"interval,omitempty"`
+	OnDuration       string `json:"on_duration,omitempty"`
+	Repeat           int    `json:"repeat,omitempty"`
+	Cycle            int    `json:"cycle,omitempty"`
+	CyclePhase       string `json:"cycle_phase,omitempty"`
 }
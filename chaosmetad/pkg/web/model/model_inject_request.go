@@ -26,4 +26,8 @@ type InjectRequest struct {
 	ContainerRuntime string `json:"container_runtime"`
 	TraceId          string `json:"trace_id"`
 	Uid              string `json:"uid"`
+	DryRun           bool   `json:"dry_run"`
+	Interval         string `json:"interval"`
+	OnDuration       string `json:"on_duration"`
+	Repeat           int    `json:"repeat"`
 }
@@ -16,6 +16,9 @@
 
 package model
 
+import "github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/dryrun"
+
 type InjectSuccessResponseData struct {
-	Experiment ExperimentDataUnit `json:"experiment,omitempty"`
+	Experiment *ExperimentDataUnit `json:"experiment,omitempty"`
+	Plan       *dryrun.Plan        `json:"plan,omitempty"`
 }
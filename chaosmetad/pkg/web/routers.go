@@ -19,6 +19,7 @@ package web
 import (
 	"context"
 	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/metrics"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/web/handler"
 	"net/http"
 	"net/http/pprof"
@@ -36,7 +37,15 @@ type Route struct {
 
 type Routes []Route
 
-func NewRouter(ctx context.Context, isPprof bool) *mux.Router {
+const (
+	RouteInject  = "ExperimentInjectPost"
+	RouteRecover = "ExperimentRecoverPost"
+
+	pprofRoutePrefix = "Debug"
+)
+
+// NewRouter auth is disabled if authConfig is nil, audit is disabled if auditor is nil
+func NewRouter(ctx context.Context, isPprof bool, authConfig *AuthConfig, auditor *Auditor) *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
 
 	if isPprof {
@@ -46,6 +55,7 @@ func NewRouter(ctx context.Context, isPprof bool) *mux.Router {
 	for _, route := range routes {
 		var handler http.Handler
 		handler = route.HandlerFunc
+		handler = Auth(ctx, handler, route.Name, authConfig, auditor)
 		handler = Logger(ctx, handler, route.Name)
 
 		router.
@@ -71,7 +81,7 @@ var routes = Routes{
 	},
 
 	Route{
-		"ExperimentInjectPost",
+		RouteInject,
 		strings.ToUpper("Post"),
 		"/v1/experiment/inject",
 		handler.ExperimentInjectPost,
@@ -85,18 +95,32 @@ var routes = Routes{
 	},
 
 	Route{
-		"ExperimentRecoverPost",
+		RouteRecover,
 		strings.ToUpper("Post"),
 		"/v1/experiment/recover",
 		handler.ExperimentRecoverPost,
 	},
 
+	Route{
+		"ExperimentWatchGet",
+		strings.ToUpper("Get"),
+		"/v1/experiment/watch",
+		handler.ExperimentWatchGet,
+	},
+
 	Route{
 		"VersionGet",
 		strings.ToUpper("Get"),
 		"/v1/version",
 		handler.VersionGet,
 	},
+
+	Route{
+		"MetricsGet",
+		strings.ToUpper("Get"),
+		"/metrics",
+		metrics.Handler().ServeHTTP,
+	},
 }
 
 var pprofRoutes = Routes{
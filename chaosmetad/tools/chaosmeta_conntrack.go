@@ -0,0 +1,106 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/tools/common"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	conntrackCountPath = "/proc/sys/net/netfilter/nf_conntrack_count"
+	// resendInterval less than the default timeout of unreplied udp conntrack entry: 30s
+	resendInterval = 10 * time.Second
+	portCount      = 65535
+)
+
+// [uid] [count] [timeout]
+func main() {
+	args := os.Args
+	if len(args) < 4 {
+		common.ExitWithErr("must provide 3 args: uid、count、timeout")
+	}
+
+	count, err := strconv.Atoi(args[2])
+	if err != nil || count <= 0 {
+		common.ExitWithErr("count is invalid")
+	}
+
+	timeout, err := strconv.Atoi(args[3])
+	if err != nil {
+		common.ExitWithErr(fmt.Sprintf("timeout value is not a valid int, error: %s", err.Error()))
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		common.ExitWithErr(fmt.Sprintf("create udp socket error: %s", err.Error()))
+	}
+
+	before, err := getConntrackCount()
+	if err != nil {
+		common.ExitWithErr(fmt.Sprintf("get conntrack count error: %s", err.Error()))
+	}
+
+	sendAll(conn, count)
+	after, err := getConntrackCount()
+	if err != nil {
+		common.ExitWithErr(fmt.Sprintf("get conntrack count error: %s", err.Error()))
+	}
+
+	if after <= before {
+		common.ExitWithErr("conntrack count is not increased, conntrack may be not enabled in this network namespace")
+	}
+
+	fmt.Println("[success]inject success")
+
+	// refresh the entries before they expire
+	go func() {
+		for {
+			time.Sleep(resendInterval)
+			sendAll(conn, count)
+		}
+	}()
+
+	common.SleepWait(timeout)
+}
+
+// sendAll each datagram is sent to a different "ip:port" of 127.0.0.0/8, so a new conntrack entry is created for it
+func sendAll(conn *net.UDPConn, count int) {
+	for i := 0; i < count; i++ {
+		ipIndex := i/portCount + 1
+		addr := &net.UDPAddr{
+			IP:   net.IPv4(127, byte(ipIndex>>16), byte(ipIndex>>8), byte(ipIndex)),
+			Port: i%portCount + 1,
+		}
+
+		_, _ = conn.WriteToUDP([]byte{0}, addr)
+	}
+}
+
+func getConntrackCount() (int, error) {
+	re, err := os.ReadFile(conntrackCountPath)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(re)))
+}
@@ -19,15 +19,21 @@ package main
 import (
 	"fmt"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/containercgroup"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/profile"
 	"github.com/traas-stack/chaosmeta/chaosmetad/tools/common"
+	"math"
 	"os"
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
-var nowTargetPercent, worktime, sleeptime int
+var worktime, sleeptime int
 
-// uid core percent pid timeout
+// nowTargetPercent and maxPercent are shared by the goroutines of burning, adjusting and following profile
+var nowTargetPercent, maxPercent atomic.Int32
+
+// uid core percent pid timeout [profile]
 func main() {
 	args := os.Args
 	if len(args) < 6 {
@@ -55,10 +61,20 @@ func main() {
 		common.ExitWithErr(fmt.Sprintf("timeout[%s] is not a num: %s", timeoutStr, err.Error()))
 	}
 
-	if percent < 100 {
-		go adjustPercent(targetPid, core, percent)
+	maxPercent.Store(int32(percent))
+	if len(args) > 6 && args[6] != "" {
+		p, err := profile.Parse(args[6], profile.UnitPercent)
+		if err != nil {
+			common.ExitWithErr(fmt.Sprintf("profile[%s] is invalid: %s", args[6], err.Error()))
+		}
+
+		maxPercent.Store(int32(math.Round(p.Value(0))))
+		go followProfile(p)
+		go adjustPercent(targetPid, core)
+	} else if percent < 100 {
+		go adjustPercent(targetPid, core)
 	} else {
-		nowTargetPercent = 100
+		nowTargetPercent.Store(100)
 	}
 
 	go burnCpu()
@@ -69,14 +85,15 @@ func main() {
 }
 
 func burnCpu() {
-	if nowTargetPercent == 100 {
+	if nowTargetPercent.Load() == 100 {
 		for {
 		}
 	}
 
 	var starttime, endtime int64
 	for {
-		worktime, sleeptime = nowTargetPercent, 100-nowTargetPercent
+		worktime = int(nowTargetPercent.Load())
+		sleeptime = 100 - worktime
 		starttime = time.Now().UnixMicro()
 		endtime = starttime
 
@@ -88,7 +105,16 @@ func burnCpu() {
 	}
 }
 
-func adjustPercent(targetPid, core, maxPercent int) {
+// followProfile update the target percent by profile
+func followProfile(p *profile.Profile) {
+	start := time.Now()
+	for {
+		time.Sleep(profile.AdjustInterval)
+		maxPercent.Store(int32(math.Round(p.Value(time.Since(start)))))
+	}
+}
+
+func adjustPercent(targetPid, core int) {
 	for {
 		p, err := containercgroup.CalculateNowPercent(targetPid)
 		//p, err := cpu.Percent(2*time.Second, true)
@@ -96,11 +122,11 @@ func adjustPercent(targetPid, core, maxPercent int) {
 			common.ExitWithErr(fmt.Sprintf("get cpu usage error: %s", err.Error()))
 		}
 
-		needAdd := maxPercent - int(p[core])
-		if needAdd+nowTargetPercent < 0 {
-			nowTargetPercent = 0
+		needAdd := maxPercent.Load() - int32(p[core])
+		if needAdd+nowTargetPercent.Load() < 0 {
+			nowTargetPercent.Store(0)
 		} else {
-			nowTargetPercent += needAdd
+			nowTargetPercent.Add(needAdd)
 		}
 	}
 }
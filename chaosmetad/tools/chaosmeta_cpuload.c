@@ -17,9 +17,100 @@
 #include<unistd.h>
 #include<stdlib.h>
 #include<stdio.h>
+#include<string.h>
+#include<math.h>
+#include<signal.h>
+#include<time.h>
+#include<sys/types.h>
+#include<sys/wait.h>
+
+/* same as the Value of package pkg/utils/profile */
+double profile_value(const char *type, double start, double end, double duration, int steps, double elapsed)
+{
+    double ratio = elapsed / duration;
+    if (strcmp(type, "sine") == 0) {
+        ratio = (1 - cos(2 * M_PI * ratio)) / 2;
+    } else if (strcmp(type, "sawtooth") == 0) {
+        ratio -= floor(ratio);
+    } else if (strcmp(type, "step") == 0) {
+        ratio = fmin(floor(ratio * steps) / (steps - 1), 1);
+    } else {
+        ratio = fmin(ratio, 1);
+    }
+
+    return start + (end - start) * ratio;
+}
+
+/* a unit of load: a process blocked by vfork, the child of vfork sleeps. they are in the same process group */
+pid_t add_unit()
+{
+    pid_t pid = fork();
+    if (pid == 0) {
+        setpgid(0, 0);
+        vfork();
+        for (;;) {
+            sleep(86400);
+        }
+    }
+
+    if (pid > 0) {
+        setpgid(pid, pid);
+    }
+
+    return pid;
+}
+
+void remove_unit(pid_t pid)
+{
+    kill(-pid, SIGKILL);
+    waitpid(pid, NULL, 0);
+}
+
+/* [uid] [count] [type] [start] [end] [duration second] [steps] */
+int run_profile(char *argv[])
+{
+    const char *type = argv[3];
+    int start = atoi(argv[4]), end = atoi(argv[5]), duration = atoi(argv[6]), steps = atoi(argv[7]);
+    int max = start > end ? start : end;
+    int now = 0, target = 0, printed = 0;
+    time_t begin = time(NULL);
+    pid_t *units = malloc(sizeof(pid_t) * (max + 1));
+    if (units == NULL || duration <= 0) {
+        printf("[error]invalid profile\n");
+        return 1;
+    }
+
+    for (;;) {
+        target = (int)(profile_value(type, start, end, duration, steps, difftime(time(NULL), begin)) + 0.5);
+        for (; now < target; now++) {
+            units[now] = add_unit();
+            if (units[now] < 0) {
+                printf("[error]fork error\n");
+                return 1;
+            }
+        }
+
+        for (; now > target; now--) {
+            remove_unit(units[now - 1]);
+        }
+
+        if (!printed) {
+            printf("[success]inject success\n");
+            fflush(stdout);
+            printed = 1;
+        }
+        sleep(1);
+    }
+
+    return 0;
+}
 
 int main( int argc, char *argv[])  
 {
+    if (argc >= 8) {
+        return run_profile(argv);
+    }
+
     int count = atoi(argv[2]);
     int i = 0;
     for(i = 0; i < count; i++) {
@@ -35,8 +126,9 @@ int main( int argc, char *argv[])
 }
 
 /* 
-    g++ chaosmeta_cpuload.cpp -o chaosmeta_cpuload
-    ./chaosmeta_cpuload 16
+    gcc chaosmeta_cpuload.c -o chaosmeta_cpuload -lm
+    ./chaosmeta_cpuload uid 16
+    ./chaosmeta_cpuload uid 0 linear 4 16 600 4
     kill -9 -- -PGID
     ps j -A | grep cpuload
  */
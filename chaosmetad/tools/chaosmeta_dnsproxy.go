@@ -0,0 +1,366 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/tools/common"
+	"golang.org/x/net/dns/dnsmessage"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	faultNXDomain = "nxdomain"
+	faultServFail = "servfail"
+	faultTimeout  = "timeout"
+	faultDelay    = "delay"
+	faultSpoof    = "spoof"
+
+	// proxyMark should be consistent with ProxyMark in pkg/injector/dns/constant.go
+	proxyMark       = 0x63686d
+	upstreamTimeout = 5 * time.Second
+	// spoofTTL small ttl to make sure that the spoofed answers are not cached by client for long after recovering
+	spoofTTL   = 1
+	maxMsgSize = 65535
+)
+
+// rule should be consistent with ProxyRule in pkg/injector/dns/constant.go
+type rule struct {
+	Fault     string   `json:"fault"`
+	Domain    []string `json:"domain,omitempty"`
+	Percent   int      `json:"percent"`
+	Upstream  string   `json:"upstream"`
+	LatencyUs int64    `json:"latency_us,omitempty"`
+	Answer    []string `json:"answer,omitempty"`
+
+	answerA    [][4]byte
+	answerAAAA [][16]byte
+}
+
+// exchangeFunc send the query to upstream and return the response
+type exchangeFunc func(query []byte) ([]byte, error)
+
+// [uid] [proxy port] [rule(base64 of json)]
+func main() {
+	args := os.Args
+	if len(args) < 4 {
+		common.ExitWithErr("must provide 3 args: uid、proxy port、rule")
+	}
+
+	port, err := strconv.Atoi(args[2])
+	if err != nil || port <= 0 {
+		common.ExitWithErr("proxy port is invalid")
+	}
+
+	r, err := parseRule(args[3])
+	if err != nil {
+		common.ExitWithErr(fmt.Sprintf("rule is invalid: %s", err.Error()))
+	}
+
+	// the queries are redirected to 127.0.0.1 by iptables, listening on other addresses would expose the proxy
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	if err != nil {
+		common.ExitWithErr(fmt.Sprintf("udp listen on %d error: %s", port, err.Error()))
+	}
+
+	tcpListener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		common.ExitWithErr(fmt.Sprintf("tcp listen on %d error: %s", port, err.Error()))
+	}
+
+	dialer := &net.Dialer{
+		Timeout: upstreamTimeout,
+		// the queries to upstream with the mark are not redirected to the proxy itself
+		Control: func(network, address string, c syscall.RawConn) error {
+			var sErr error
+			if err := c.Control(func(fd uintptr) {
+				sErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_MARK, proxyMark)
+			}); err != nil {
+				return err
+			}
+			return sErr
+		},
+	}
+
+	go serveTCP(tcpListener, r, dialer)
+
+	fmt.Println("[success]inject success")
+
+	serveUDP(udpConn, r, dialer)
+}
+
+func parseRule(ruleStr string) (*rule, error) {
+	ruleBytes, err := base64.StdEncoding.DecodeString(ruleStr)
+	if err != nil {
+		return nil, fmt.Errorf("base64 decode error: %s", err.Error())
+	}
+
+	var r rule
+	if err := json.Unmarshal(ruleBytes, &r); err != nil {
+		return nil, fmt.Errorf("json unmarshal error: %s", err.Error())
+	}
+
+	switch r.Fault {
+	case faultNXDomain, faultServFail, faultTimeout, faultDelay:
+	case faultSpoof:
+		for _, unit := range r.Answer {
+			ip := net.ParseIP(unit)
+			if ip == nil {
+				return nil, fmt.Errorf("answer[%s] is not a valid ip", unit)
+			}
+
+			if ip4 := ip.To4(); ip4 != nil {
+				var a [4]byte
+				copy(a[:], ip4)
+				r.answerA = append(r.answerA, a)
+			} else {
+				var aaaa [16]byte
+				copy(aaaa[:], ip.To16())
+				r.answerAAAA = append(r.answerAAAA, aaaa)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("fault[%s] is not support", r.Fault)
+	}
+
+	return &r, nil
+}
+
+func serveUDP(conn *net.UDPConn, r *rule, dialer *net.Dialer) {
+	exchange := func(query []byte) ([]byte, error) {
+		upstreamConn, err := dialer.Dial("udp", r.Upstream)
+		if err != nil {
+			return nil, err
+		}
+		defer upstreamConn.Close()
+
+		_ = upstreamConn.SetDeadline(time.Now().Add(upstreamTimeout))
+		if _, err := upstreamConn.Write(query); err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, maxMsgSize)
+		n, err := upstreamConn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		return buf[:n], nil
+	}
+
+	for {
+		buf := make([]byte, maxMsgSize)
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			common.ExitWithErr(fmt.Sprintf("udp read error: %s", err.Error()))
+		}
+
+		go func(query []byte, addr *net.UDPAddr) {
+			if resp := r.handle(query, exchange); resp != nil {
+				_, _ = conn.WriteToUDP(resp, addr)
+			}
+		}(buf[:n], addr)
+	}
+}
+
+func serveTCP(listener net.Listener, r *rule, dialer *net.Dialer) {
+	exchange := func(query []byte) ([]byte, error) {
+		upstreamConn, err := dialer.Dial("tcp", r.Upstream)
+		if err != nil {
+			return nil, err
+		}
+		defer upstreamConn.Close()
+
+		_ = upstreamConn.SetDeadline(time.Now().Add(upstreamTimeout))
+		if err := writeTCPMsg(upstreamConn, query); err != nil {
+			return nil, err
+		}
+
+		return readTCPMsg(upstreamConn)
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			common.ExitWithErr(fmt.Sprintf("tcp accept error: %s", err.Error()))
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+			for {
+				query, err := readTCPMsg(conn)
+				if err != nil {
+					return
+				}
+
+				// no response for the query, but keep the connection for the next queries
+				resp := r.handle(query, exchange)
+				if resp == nil {
+					continue
+				}
+
+				if err := writeTCPMsg(conn, resp); err != nil {
+					return
+				}
+			}
+		}(conn)
+	}
+}
+
+// readTCPMsg the dns message over tcp is prefixed with 2 bytes of length
+func readTCPMsg(conn net.Conn) ([]byte, error) {
+	lenBuf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, lenBuf); err != nil {
+		return nil, err
+	}
+
+	msg := make([]byte, binary.BigEndian.Uint16(lenBuf))
+	if _, err := io.ReadFull(conn, msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func writeTCPMsg(conn net.Conn, msg []byte) error {
+	buf := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	copy(buf[2:], msg)
+	_, err := conn.Write(buf)
+	return err
+}
+
+func (r *rule) match(name string) bool {
+	if len(r.Domain) > 0 {
+		isMatch := false
+		for _, pattern := range r.Domain {
+			if ok, _ := path.Match(pattern, name); ok {
+				isMatch = true
+				break
+			}
+		}
+
+		if !isMatch {
+			return false
+		}
+	}
+
+	return rand.Intn(100) < r.Percent
+}
+
+// handle return the response of the query, nil means no response
+func (r *rule) handle(query []byte, exchange exchangeFunc) []byte {
+	var p dnsmessage.Parser
+	header, err := p.Start(query)
+	if err != nil {
+		return forward(query, exchange)
+	}
+
+	q, err := p.Question()
+	if err != nil {
+		return forward(query, exchange)
+	}
+
+	if !r.match(strings.ToLower(strings.TrimSuffix(q.Name.String(), "."))) {
+		return forward(query, exchange)
+	}
+
+	switch r.Fault {
+	case faultNXDomain:
+		return buildResponse(header, q, dnsmessage.RCodeNameError, nil, nil)
+	case faultServFail:
+		return buildResponse(header, q, dnsmessage.RCodeServerFailure, nil, nil)
+	case faultTimeout:
+		return nil
+	case faultDelay:
+		time.Sleep(time.Duration(r.LatencyUs) * time.Microsecond)
+	case faultSpoof:
+		// the queries of A/AAAA are answered only with the ips of the same family, no answer if not provided
+		if q.Type == dnsmessage.TypeA {
+			return buildResponse(header, q, dnsmessage.RCodeSuccess, r.answerA, nil)
+		} else if q.Type == dnsmessage.TypeAAAA {
+			return buildResponse(header, q, dnsmessage.RCodeSuccess, nil, r.answerAAAA)
+		}
+	}
+
+	return forward(query, exchange)
+}
+
+// forward no response if upstream fails, the client will retry as the real dns server has no response
+func forward(query []byte, exchange exchangeFunc) []byte {
+	resp, err := exchange(query)
+	if err != nil {
+		return nil
+	}
+
+	return resp
+}
+
+func buildResponse(queryHeader dnsmessage.Header, q dnsmessage.Question, rCode dnsmessage.RCode, answerA [][4]byte, answerAAAA [][16]byte) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:                 queryHeader.ID,
+		Response:           true,
+		OpCode:             queryHeader.OpCode,
+		RecursionDesired:   queryHeader.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              rCode,
+	})
+	b.EnableCompression()
+
+	if err := b.StartQuestions(); err != nil {
+		return nil
+	}
+
+	if err := b.Question(q); err != nil {
+		return nil
+	}
+
+	if err := b.StartAnswers(); err != nil {
+		return nil
+	}
+
+	rh := dnsmessage.ResourceHeader{Name: q.Name, Class: q.Class, TTL: spoofTTL}
+	for _, a := range answerA {
+		if err := b.AResource(rh, dnsmessage.AResource{A: a}); err != nil {
+			return nil
+		}
+	}
+
+	for _, aaaa := range answerAAAA {
+		if err := b.AAAAResource(rh, dnsmessage.AAAAResource{AAAA: aaaa}); err != nil {
+			return nil
+		}
+	}
+
+	resp, err := b.Finish()
+	if err != nil {
+		return nil
+	}
+
+	return resp
+}
@@ -0,0 +1,232 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/tools/common"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	faultDelay    = "delay"
+	faultAbort    = "abort"
+	faultTruncate = "truncate"
+)
+
+type ctxKey int
+
+const (
+	ctxKeyDst ctxKey = iota
+	ctxKeyMatch
+)
+
+// rule should be consistent with ProxyRule in pkg/injector/grpc/constant.go
+type rule struct {
+	Fault     string `json:"fault"`
+	Service   string `json:"service,omitempty"`
+	Method    string `json:"method,omitempty"`
+	Percent   int    `json:"percent"`
+	LatencyUs int64  `json:"latency_us,omitempty"`
+	Code      int    `json:"code,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Length    int    `json:"length,omitempty"`
+}
+
+// [uid] [proxy port] [rule(base64 of json)]
+func main() {
+	args := os.Args
+	if len(args) < 4 {
+		common.ExitWithErr("must provide 3 args: uid、proxy port、rule")
+	}
+
+	port, err := strconv.Atoi(args[2])
+	if err != nil || port <= 0 {
+		common.ExitWithErr("proxy port is invalid")
+	}
+
+	r, err := parseRule(args[3])
+	if err != nil {
+		common.ExitWithErr(fmt.Sprintf("rule is invalid: %s", err.Error()))
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		common.ExitWithErr(fmt.Sprintf("listen on %d error: %s", port, err.Error()))
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = "http"
+			req.URL.Host, _ = req.Context().Value(ctxKeyDst).(string)
+		},
+		// grpc upstream only accepts http2 without tls
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		},
+		// flush immediately for streaming calls
+		FlushInterval: -1,
+		ModifyResponse: func(resp *http.Response) error {
+			if isMatch, _ := resp.Request.Context().Value(ctxKeyMatch).(bool); isMatch && r.Fault == faultTruncate {
+				resp.Body = &truncateBody{body: resp.Body, remain: r.Length}
+			}
+			return nil
+		},
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Context().Value(ctxKeyDst) == nil {
+			http.Error(w, "get original destination error", http.StatusBadGateway)
+			return
+		}
+
+		isMatch := r.match(req)
+		if isMatch {
+			switch r.Fault {
+			case faultAbort:
+				// trailers-only response: grpc status is sent in headers and no message is returned
+				w.Header().Set("Content-Type", "application/grpc")
+				w.Header().Set("Grpc-Status", strconv.Itoa(r.Code))
+				if r.Message != "" {
+					w.Header().Set("Grpc-Message", encodeGrpcMessage(r.Message))
+				}
+				w.WriteHeader(http.StatusOK)
+				return
+			case faultDelay:
+				time.Sleep(time.Duration(r.LatencyUs) * time.Microsecond)
+			}
+		}
+
+		proxy.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), ctxKeyMatch, isMatch)))
+	})
+
+	server := &http.Server{
+		Handler: h2c.NewHandler(handler, &http2.Server{}),
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			dst, err := common.GetOriginalDst(c)
+			if err != nil {
+				return ctx
+			}
+			return context.WithValue(ctx, ctxKeyDst, dst)
+		},
+	}
+
+	fmt.Println("[success]inject success")
+
+	if err := server.Serve(listener); err != nil {
+		common.ExitWithErr(fmt.Sprintf("proxy serve error: %s", err.Error()))
+	}
+}
+
+func parseRule(ruleStr string) (*rule, error) {
+	ruleBytes, err := base64.StdEncoding.DecodeString(ruleStr)
+	if err != nil {
+		return nil, fmt.Errorf("base64 decode error: %s", err.Error())
+	}
+
+	var r rule
+	if err := json.Unmarshal(ruleBytes, &r); err != nil {
+		return nil, fmt.Errorf("json unmarshal error: %s", err.Error())
+	}
+
+	switch r.Fault {
+	case faultDelay, faultAbort, faultTruncate:
+	default:
+		return nil, fmt.Errorf("fault[%s] is not support", r.Fault)
+	}
+
+	return &r, nil
+}
+
+// match the grpc call by its path: /[service]/[method]
+func (r *rule) match(req *http.Request) bool {
+	if !strings.HasPrefix(req.Header.Get("Content-Type"), "application/grpc") {
+		return false
+	}
+
+	pathArr := strings.Split(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	if len(pathArr) != 2 {
+		return false
+	}
+
+	if r.Service != "" && r.Service != pathArr[0] {
+		return false
+	}
+
+	if r.Method != "" && r.Method != pathArr[1] {
+		return false
+	}
+
+	return rand.Intn(100) < r.Percent
+}
+
+// encodeGrpcMessage percent-encode the message as grpc protocol required
+func encodeGrpcMessage(msg string) string {
+	var sb strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+
+	return sb.String()
+}
+
+// truncateBody only return the first bytes of response body, the rest is discarded so that the trailers can still be received
+type truncateBody struct {
+	body   io.ReadCloser
+	remain int
+}
+
+func (b *truncateBody) Read(p []byte) (int, error) {
+	if b.remain <= 0 {
+		_, _ = io.Copy(io.Discard, b.body)
+		return 0, io.EOF
+	}
+
+	if len(p) > b.remain {
+		p = p[:b.remain]
+	}
+
+	n, err := b.body.Read(p)
+	b.remain -= n
+	return n, err
+}
+
+func (b *truncateBody) Close() error {
+	return b.body.Close()
+}
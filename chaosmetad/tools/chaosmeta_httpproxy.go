@@ -0,0 +1,213 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/tools/common"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	faultAbort       = "abort"
+	faultDelay       = "delay"
+	faultHeader      = "header"
+	faultBodyReplace = "body-replace"
+
+	// minCode and maxCode should be consistent with pkg/injector/http/constant.go
+	minCode = 200
+	maxCode = 599
+)
+
+type ctxKey int
+
+const (
+	ctxKeyDst ctxKey = iota
+	ctxKeyMatch
+)
+
+// rule should be consistent with ProxyRule in pkg/injector/http/constant.go
+type rule struct {
+	Fault     string            `json:"fault"`
+	Method    string            `json:"method,omitempty"`
+	Path      string            `json:"path,omitempty"`
+	Header    map[string]string `json:"header,omitempty"`
+	Percent   int               `json:"percent"`
+	Code      int               `json:"code,omitempty"`
+	LatencyUs int64             `json:"latency_us,omitempty"`
+	SetHeader map[string]string `json:"set_header,omitempty"`
+	DelHeader []string          `json:"del_header,omitempty"`
+	Body      string            `json:"body,omitempty"`
+
+	pathReg *regexp.Regexp
+}
+
+// [uid] [proxy port] [rule(base64 of json)]
+func main() {
+	args := os.Args
+	if len(args) < 4 {
+		common.ExitWithErr("must provide 3 args: uid、proxy port、rule")
+	}
+
+	port, err := strconv.Atoi(args[2])
+	if err != nil || port <= 0 {
+		common.ExitWithErr("proxy port is invalid")
+	}
+
+	r, err := parseRule(args[3])
+	if err != nil {
+		common.ExitWithErr(fmt.Sprintf("rule is invalid: %s", err.Error()))
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		common.ExitWithErr(fmt.Sprintf("listen on %d error: %s", port, err.Error()))
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = "http"
+			req.URL.Host, _ = req.Context().Value(ctxKeyDst).(string)
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			if isMatch, _ := resp.Request.Context().Value(ctxKeyMatch).(bool); isMatch {
+				r.modifyResponse(resp)
+			}
+			return nil
+		},
+	}
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Context().Value(ctxKeyDst) == nil {
+				http.Error(w, "get original destination error", http.StatusBadGateway)
+				return
+			}
+
+			isMatch := r.match(req)
+			if isMatch {
+				switch r.Fault {
+				case faultAbort:
+					w.WriteHeader(r.Code)
+					return
+				case faultDelay:
+					time.Sleep(time.Duration(r.LatencyUs) * time.Microsecond)
+				}
+			}
+
+			proxy.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), ctxKeyMatch, isMatch)))
+		}),
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			dst, err := common.GetOriginalDst(c)
+			if err != nil {
+				return ctx
+			}
+			return context.WithValue(ctx, ctxKeyDst, dst)
+		},
+	}
+
+	fmt.Println("[success]inject success")
+
+	if err := server.Serve(listener); err != nil {
+		common.ExitWithErr(fmt.Sprintf("proxy serve error: %s", err.Error()))
+	}
+}
+
+func parseRule(ruleStr string) (*rule, error) {
+	ruleBytes, err := base64.StdEncoding.DecodeString(ruleStr)
+	if err != nil {
+		return nil, fmt.Errorf("base64 decode error: %s", err.Error())
+	}
+
+	var r rule
+	if err := json.Unmarshal(ruleBytes, &r); err != nil {
+		return nil, fmt.Errorf("json unmarshal error: %s", err.Error())
+	}
+
+	if r.Path != "" {
+		if r.pathReg, err = regexp.Compile(r.Path); err != nil {
+			return nil, fmt.Errorf("path[%s] is not a valid regular expression: %s", r.Path, err.Error())
+		}
+	}
+
+	switch r.Fault {
+	case faultAbort, faultDelay, faultHeader, faultBodyReplace:
+	default:
+		return nil, fmt.Errorf("fault[%s] is not support", r.Fault)
+	}
+
+	// a 1xx code is sent as an informational response followed by an implicit 200, so the request is not aborted
+	if (r.Fault == faultAbort || r.Code != 0) && (r.Code < minCode || r.Code > maxCode) {
+		return nil, fmt.Errorf("code[%d] should in [%d, %d]", r.Code, minCode, maxCode)
+	}
+
+	return &r, nil
+}
+
+func (r *rule) match(req *http.Request) bool {
+	if r.Method != "" && r.Method != req.Method {
+		return false
+	}
+
+	if r.pathReg != nil && !r.pathReg.MatchString(req.URL.Path) {
+		return false
+	}
+
+	for k, v := range r.Header {
+		if req.Header.Get(k) != v {
+			return false
+		}
+	}
+
+	return rand.Intn(100) < r.Percent
+}
+
+func (r *rule) modifyResponse(resp *http.Response) {
+	switch r.Fault {
+	case faultHeader:
+		for _, k := range r.DelHeader {
+			resp.Header.Del(k)
+		}
+
+		for k, v := range r.SetHeader {
+			resp.Header.Set(k, v)
+		}
+	case faultBodyReplace:
+		_ = resp.Body.Close()
+		resp.Body = io.NopCloser(strings.NewReader(r.Body))
+		resp.ContentLength = int64(len(r.Body))
+		resp.Header.Set("Content-Length", strconv.Itoa(len(r.Body)))
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Transfer-Encoding")
+		if r.Code != 0 {
+			resp.StatusCode = r.Code
+			resp.Status = fmt.Sprintf("%d %s", r.Code, http.StatusText(r.Code))
+		}
+	}
+}
@@ -0,0 +1,561 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/tools/common"
+	"golang.org/x/sys/unix"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	faultErrno = "errno"
+	faultDelay = "delay"
+	faultShort = "short"
+
+	// the syscall number of x86-64, rax is set to -ENOSYS by kernel at syscall-enter-stop
+	noArg      = -1
+	enosys     = ^uint64(unix.ENOSYS - 1)
+	invalidNr  = ^uint64(0)
+	maxPathLen = 4096
+	// maxLinkHops the same as the limit of symbolic links in a path resolution of kernel
+	maxLinkHops = 40
+)
+
+// rule should be consistent with the rule in pkg/injector/io/fault.go
+type rule struct {
+	PidList   []int    `json:"pid_list"`
+	Path      string   `json:"path"`
+	Methods   []string `json:"methods"`
+	Percent   int      `json:"percent"`
+	Fault     string   `json:"fault"`
+	Errno     int      `json:"errno,omitempty"`
+	LatencyUs int64    `json:"latency_us,omitempty"`
+}
+
+// sysInfo the position of the args related to file in a syscall
+type sysInfo struct {
+	method   string
+	fdArg    int
+	dirfdArg int
+	pathArg  int
+	countArg int
+}
+
+var sysTable = map[uint64]*sysInfo{
+	2:   {method: "open", fdArg: noArg, dirfdArg: noArg, pathArg: 0, countArg: noArg},     // open
+	85:  {method: "open", fdArg: noArg, dirfdArg: noArg, pathArg: 0, countArg: noArg},     // creat
+	257: {method: "open", fdArg: noArg, dirfdArg: 0, pathArg: 1, countArg: noArg},         // openat
+	437: {method: "open", fdArg: noArg, dirfdArg: 0, pathArg: 1, countArg: noArg},         // openat2
+	0:   {method: "read", fdArg: 0, dirfdArg: noArg, pathArg: noArg, countArg: 2},         // read
+	17:  {method: "read", fdArg: 0, dirfdArg: noArg, pathArg: noArg, countArg: 2},         // pread64
+	19:  {method: "readv", fdArg: 0, dirfdArg: noArg, pathArg: noArg, countArg: noArg},    // readv
+	295: {method: "readv", fdArg: 0, dirfdArg: noArg, pathArg: noArg, countArg: noArg},    // preadv
+	327: {method: "readv", fdArg: 0, dirfdArg: noArg, pathArg: noArg, countArg: noArg},    // preadv2
+	1:   {method: "write", fdArg: 0, dirfdArg: noArg, pathArg: noArg, countArg: 2},        // write
+	18:  {method: "write", fdArg: 0, dirfdArg: noArg, pathArg: noArg, countArg: 2},        // pwrite64
+	20:  {method: "writev", fdArg: 0, dirfdArg: noArg, pathArg: noArg, countArg: noArg},   // writev
+	296: {method: "writev", fdArg: 0, dirfdArg: noArg, pathArg: noArg, countArg: noArg},   // pwritev
+	328: {method: "writev", fdArg: 0, dirfdArg: noArg, pathArg: noArg, countArg: noArg},   // pwritev2
+	74:  {method: "fsync", fdArg: 0, dirfdArg: noArg, pathArg: noArg, countArg: noArg},    // fsync
+	75:  {method: "fsync", fdArg: 0, dirfdArg: noArg, pathArg: noArg, countArg: noArg},    // fdatasync
+	277: {method: "fsync", fdArg: 0, dirfdArg: noArg, pathArg: noArg, countArg: noArg},    // sync_file_range
+	76:  {method: "truncate", fdArg: noArg, dirfdArg: noArg, pathArg: 0, countArg: noArg}, // truncate
+	77:  {method: "truncate", fdArg: 0, dirfdArg: noArg, pathArg: noArg, countArg: noArg}, // ftruncate
+	285: {method: "truncate", fdArg: 0, dirfdArg: noArg, pathArg: noArg, countArg: noArg}, // fallocate
+	87:  {method: "unlink", fdArg: noArg, dirfdArg: noArg, pathArg: 0, countArg: noArg},   // unlink
+	84:  {method: "unlink", fdArg: noArg, dirfdArg: noArg, pathArg: 0, countArg: noArg},   // rmdir
+	263: {method: "unlink", fdArg: noArg, dirfdArg: 0, pathArg: 1, countArg: noArg},       // unlinkat
+	83:  {method: "mkdir", fdArg: noArg, dirfdArg: noArg, pathArg: 0, countArg: noArg},    // mkdir
+	258: {method: "mkdir", fdArg: noArg, dirfdArg: 0, pathArg: 1, countArg: noArg},        // mkdirat
+	82:  {method: "rename", fdArg: noArg, dirfdArg: noArg, pathArg: 0, countArg: noArg},   // rename
+	264: {method: "rename", fdArg: noArg, dirfdArg: 0, pathArg: 1, countArg: noArg},       // renameat
+	316: {method: "rename", fdArg: noArg, dirfdArg: 0, pathArg: 1, countArg: noArg},       // renameat2
+}
+
+type thread struct {
+	tid       int
+	errno     int // the syscall is skipped at enter, and errno should be set at exit
+	isDelayed bool
+	resumeAt  time.Time
+}
+
+type tracer struct {
+	rule       *rule
+	pathReg    *regexp.Regexp
+	sysMap     map[uint64]*sysInfo
+	threads    map[int]*thread
+	isStopping bool
+}
+
+// [uid] [rule(base64 of json)]
+func main() {
+	args := os.Args
+	if len(args) < 3 {
+		common.ExitWithErr("must provide 2 args: uid、rule")
+	}
+
+	t, err := newTracer(args[2])
+	if err != nil {
+		common.ExitWithErr(fmt.Sprintf("rule is invalid: %s", err.Error()))
+	}
+
+	// all ptrace requests must be sent from the same thread
+	runtime.LockOSThread()
+	for _, pid := range t.rule.PidList {
+		if err := t.seize(pid); err != nil {
+			t.detachAll()
+			common.ExitWithErr(fmt.Sprintf("trace process[%d] error: %s", pid, err.Error()))
+		}
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+
+	fmt.Println("[success]inject success")
+
+	t.loop(sigCh)
+}
+
+func newTracer(ruleStr string) (*tracer, error) {
+	ruleBytes, err := base64.StdEncoding.DecodeString(ruleStr)
+	if err != nil {
+		return nil, fmt.Errorf("base64 decode error: %s", err.Error())
+	}
+
+	var r rule
+	if err := json.Unmarshal(ruleBytes, &r); err != nil {
+		return nil, fmt.Errorf("json unmarshal error: %s", err.Error())
+	}
+
+	switch r.Fault {
+	case faultErrno, faultDelay, faultShort:
+	default:
+		return nil, fmt.Errorf("fault[%s] is not support", r.Fault)
+	}
+
+	t := &tracer{rule: &r, sysMap: make(map[uint64]*sysInfo), threads: make(map[int]*thread)}
+	if r.Path != "" {
+		if t.pathReg, err = regexp.Compile(globToRegexp(r.Path)); err != nil {
+			return nil, fmt.Errorf("path[%s] is invalid: %s", r.Path, err.Error())
+		}
+	}
+
+	for nr, info := range sysTable {
+		for _, method := range r.Methods {
+			if info.method == method {
+				t.sysMap[nr] = info
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// globToRegexp "**" matches any characters, "*" and "?" do not match "/"
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				sb.WriteString(".*")
+				i++
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+
+	return sb.String()
+}
+
+func ptrace(request, tid int, addr, data uintptr) error {
+	if _, _, errno := unix.Syscall6(unix.SYS_PTRACE, uintptr(request), uintptr(tid), addr, data, 0, 0); errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// seize all the threads of the process without stopping them, then interrupt them to start syscall tracing
+func (t *tracer) seize(pid int) error {
+	for {
+		entries, err := os.ReadDir(fmt.Sprintf("/proc/%d/task", pid))
+		if err != nil {
+			return err
+		}
+
+		var isNew bool
+		for _, entry := range entries {
+			tid, err := strconv.Atoi(entry.Name())
+			if err != nil || t.threads[tid] != nil {
+				continue
+			}
+
+			isNew = true
+			if err := ptrace(unix.PTRACE_SEIZE, tid, 0, unix.PTRACE_O_TRACESYSGOOD|unix.PTRACE_O_TRACECLONE); err != nil {
+				if err == unix.ESRCH {
+					continue
+				}
+				return fmt.Errorf("seize thread[%d] error: %s", tid, err.Error())
+			}
+
+			t.threads[tid] = &thread{tid: tid}
+			if err := unix.PtraceInterrupt(tid); err != nil {
+				return fmt.Errorf("interrupt thread[%d] error: %s", tid, err.Error())
+			}
+		}
+
+		if !isNew {
+			return nil
+		}
+	}
+}
+
+func (t *tracer) detachAll() {
+	for tid := range t.threads {
+		_ = unix.PtraceDetach(tid)
+	}
+}
+
+// waitEvent the result of wait4
+type waitEvent struct {
+	tid    int
+	status unix.WaitStatus
+	err    error
+}
+
+// wait block in wait4 to avoid busy polling. wait4 is not interrupted by signals because go runtime installs the signal
+// handlers with SA_RESTART, so it is called in another goroutine, the tracees are waited by any thread of the tracer
+func wait(eventCh chan<- *waitEvent) {
+	for {
+		var status unix.WaitStatus
+		tid, err := unix.Wait4(-1, &status, unix.WALL, nil)
+		if err == unix.EINTR {
+			continue
+		}
+
+		eventCh <- &waitEvent{tid: tid, status: status, err: err}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (t *tracer) loop(sigCh chan os.Signal) {
+	eventCh := make(chan *waitEvent, 1)
+	go wait(eventCh)
+	for len(t.threads) > 0 {
+		// resume the delayed threads whose time is up, and wake up at the earliest resume time of the others
+		var next time.Time
+		for _, th := range t.threads {
+			if !th.isDelayed {
+				continue
+			}
+
+			if !t.isStopping && time.Now().Before(th.resumeAt) {
+				if next.IsZero() || th.resumeAt.Before(next) {
+					next = th.resumeAt
+				}
+				continue
+			}
+
+			th.isDelayed = false
+			t.resume(th, 0)
+		}
+
+		var timeout <-chan time.Time
+		if !next.IsZero() {
+			timeout = time.After(time.Until(next))
+		}
+
+		select {
+		case <-sigCh:
+			t.stop()
+		case event := <-eventCh:
+			if event.err != nil {
+				return
+			}
+
+			t.handle(event.tid, event.status)
+		case <-timeout:
+		}
+	}
+}
+
+// stop interrupt all the threads, then they will be detached at next stop
+func (t *tracer) stop() {
+	if t.isStopping {
+		return
+	}
+
+	t.isStopping = true
+	for tid, th := range t.threads {
+		if !th.isDelayed {
+			_ = unix.PtraceInterrupt(tid)
+		}
+	}
+}
+
+func (t *tracer) handle(tid int, status unix.WaitStatus) {
+	th := t.threads[tid]
+	if th == nil {
+		// new thread created by the traced process is traced automatically
+		th = &thread{tid: tid}
+		t.threads[tid] = th
+	}
+
+	if status.Exited() || status.Signaled() {
+		delete(t.threads, tid)
+		return
+	}
+
+	if !status.Stopped() {
+		return
+	}
+
+	sig := status.StopSignal()
+	switch {
+	case sig == syscall.SIGTRAP|0x80:
+		t.handleSyscall(th)
+	case status.TrapCause() == unix.PTRACE_EVENT_STOP:
+		if sig == syscall.SIGSTOP || sig == syscall.SIGTSTP || sig == syscall.SIGTTIN || sig == syscall.SIGTTOU {
+			// group-stop, keep the thread stopped until it is continued by others
+			if t.isStopping {
+				t.detach(th, 0)
+			} else {
+				_ = ptrace(unix.PTRACE_LISTEN, tid, 0, 0)
+			}
+			return
+		}
+		t.resume(th, 0)
+	case sig == syscall.SIGTRAP && status.TrapCause() > 0:
+		t.resume(th, 0)
+	default:
+		// signal-delivery-stop, deliver the signal to the thread
+		t.resume(th, sig)
+	}
+}
+
+// resume continue the thread with syscall tracing, or detach it when stopping if there is no pending errno
+func (t *tracer) resume(th *thread, sig syscall.Signal) {
+	if t.isStopping && th.errno == 0 {
+		t.detach(th, sig)
+		return
+	}
+
+	if err := unix.PtraceSyscall(th.tid, int(sig)); err != nil {
+		delete(t.threads, th.tid)
+	}
+}
+
+func (t *tracer) detach(th *thread, sig syscall.Signal) {
+	_ = ptrace(unix.PTRACE_DETACH, th.tid, 0, uintptr(sig))
+	delete(t.threads, th.tid)
+}
+
+func (t *tracer) handleSyscall(th *thread) {
+	var regs unix.PtraceRegs
+	if err := unix.PtraceGetRegs(th.tid, &regs); err != nil {
+		t.resume(th, 0)
+		return
+	}
+
+	// syscall-exit-stop of a skipped syscall
+	if th.errno != 0 {
+		regs.Rax = uint64(-int64(th.errno))
+		_ = unix.PtraceSetRegs(th.tid, &regs)
+		th.errno = 0
+		t.resume(th, 0)
+		return
+	}
+
+	if regs.Rax != enosys || t.isStopping {
+		t.resume(th, 0)
+		return
+	}
+
+	// syscall-enter-stop
+	info := t.sysMap[regs.Orig_rax]
+	if info == nil || !t.isMatch(th.tid, info, &regs) {
+		t.resume(th, 0)
+		return
+	}
+
+	switch t.rule.Fault {
+	case faultErrno:
+		regs.Orig_rax = invalidNr
+		if err := unix.PtraceSetRegs(th.tid, &regs); err == nil {
+			th.errno = t.rule.Errno
+		}
+	case faultDelay:
+		th.isDelayed, th.resumeAt = true, time.Now().Add(time.Duration(t.rule.LatencyUs)*time.Microsecond)
+		return
+	case faultShort:
+		if info.countArg != noArg {
+			if count := *getArg(&regs, info.countArg); count > 1 {
+				*getArg(&regs, info.countArg) = count / 2
+				_ = unix.PtraceSetRegs(th.tid, &regs)
+			}
+		}
+	}
+
+	t.resume(th, 0)
+}
+
+func getArg(regs *unix.PtraceRegs, index int) *uint64 {
+	return []*uint64{&regs.Rdi, &regs.Rsi, &regs.Rdx, &regs.R10, &regs.R8, &regs.R9}[index]
+}
+
+func (t *tracer) isMatch(tid int, info *sysInfo, regs *unix.PtraceRegs) bool {
+	if t.pathReg != nil {
+		path := getPath(tid, info, regs)
+		if path == "" || !t.pathReg.MatchString(path) {
+			return false
+		}
+	}
+
+	return rand.Intn(100) < t.rule.Percent
+}
+
+// getPath get the absolute path of the file operated by the syscall as seen by the tracee, so that the path glob of
+// a process in container is matched with the path in container. the links of fd and cwd in /proc are shown relative to
+// the root of the tracee's mount namespace, the path args are resolved through /proc/[tid]/root
+func getPath(tid int, info *sysInfo, regs *unix.PtraceRegs) string {
+	if info.fdArg != noArg {
+		path, _ := os.Readlink(fmt.Sprintf("/proc/%d/fd/%d", tid, int32(*getArg(regs, info.fdArg))))
+		return path
+	}
+
+	path := readString(tid, *getArg(regs, info.pathArg))
+	if path == "" {
+		return ""
+	}
+
+	if !filepath.IsAbs(path) {
+		var dir string
+		if info.dirfdArg == noArg || int32(*getArg(regs, info.dirfdArg)) == unix.AT_FDCWD {
+			dir, _ = os.Readlink(fmt.Sprintf("/proc/%d/cwd", tid))
+		} else {
+			dir, _ = os.Readlink(fmt.Sprintf("/proc/%d/fd/%d", tid, int32(*getArg(regs, info.dirfdArg))))
+		}
+		// not cleaned here, ".." after a link is resolved from the target of link
+		path = dir + "/" + path
+	}
+
+	// the last component is not followed if it is a link, except for the syscalls which operate on the link target
+	root := fmt.Sprintf("/proc/%d/root", tid)
+	index := strings.LastIndex(path, "/")
+	if base := path[index+1:]; info.method == "open" || info.method == "truncate" || base == "" || base == "." || base == ".." {
+		return resolveInRoot(root, path)
+	}
+
+	return filepath.Join(resolveInRoot(root, path[:index+1]), path[index+1:])
+}
+
+// resolveInRoot resolve the symbolic links of path in root like chroot, the absolute links are resolved from root
+// instead of the root of host. the resolution stops at the first component which does not exist, eg: the file to create
+func resolveInRoot(root, path string) string {
+	var (
+		resolved = "/"
+		rest     = strings.Split(path, "/")
+		linkHops int
+	)
+
+	for len(rest) > 0 {
+		name := rest[0]
+		rest = rest[1:]
+		if name == "" || name == "." {
+			continue
+		}
+
+		if name == ".." {
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, name)
+		fi, err := os.Lstat(root + next)
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			if err != nil {
+				return filepath.Join(append([]string{resolved}, rest...)...)
+			}
+			continue
+		}
+
+		target, err := os.Readlink(root + next)
+		if err != nil || linkHops >= maxLinkHops {
+			return filepath.Join(append([]string{next}, rest...)...)
+		}
+
+		linkHops++
+		if filepath.IsAbs(target) {
+			resolved = "/"
+		}
+		rest = append(strings.Split(target, "/"), rest...)
+	}
+
+	return resolved
+}
+
+// readString read a null-terminated string from the memory of tracee, read by page to avoid crossing into an unmapped page
+func readString(tid int, addr uint64) string {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/mem", tid))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	pageSize := uint64(os.Getpagesize())
+	var buf []byte
+	for len(buf) < maxPathLen {
+		chunk := make([]byte, pageSize-addr%pageSize)
+		n, _ := f.ReadAt(chunk, int64(addr))
+		if n <= 0 {
+			return ""
+		}
+
+		if i := strings.IndexByte(string(chunk[:n]), 0); i >= 0 {
+			return string(append(buf, chunk[:i]...))
+		}
+
+		buf = append(buf, chunk[:n]...)
+		addr += uint64(n)
+	}
+
+	return ""
+}
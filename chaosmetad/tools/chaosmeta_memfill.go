@@ -19,6 +19,7 @@ package main
 import (
 	"fmt"
 	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/profile"
 	"github.com/traas-stack/chaosmeta/chaosmetad/tools/common"
 	"math"
 	"os"
@@ -26,6 +27,13 @@ import (
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"time"
+)
+
+// the memory of profile is occupied by chunks
+const (
+	chunkKBytes = 1024
+	pageBytes   = 4096
 )
 
 func parseByteValue(value int64) (int, string, error) {
@@ -94,8 +102,50 @@ func writeScore(scoreStr string) error {
 	return nil
 }
 
+// fillByProfile adjust the memory occupied to the level of profile every second
+func fillByProfile(spec, timeoutStr string) {
+	p, err := profile.Parse(spec, profile.UnitKB)
+	if err != nil {
+		common.ExitWithErr(fmt.Sprintf("profile[%s] is invalid: %s", spec, err.Error()))
+	}
+
+	timeout, err := strconv.Atoi(timeoutStr)
+	if err != nil {
+		common.ExitWithErr(fmt.Sprintf("timeout value is not a valid int, error: %s", err.Error()))
+	}
+
+	go func() {
+		var chunks [][]byte
+		start := time.Now()
+		for {
+			count := int(int64(p.Value(time.Since(start))) / chunkKBytes)
+			for len(chunks) < count {
+				chunk := make([]byte, chunkKBytes*1024)
+				// touch every page, so that the memory is really allocated
+				for i := 0; i < len(chunk); i += pageBytes {
+					chunk[i] = 1
+				}
+				chunks = append(chunks, chunk)
+			}
+
+			if len(chunks) > count {
+				for i := count; i < len(chunks); i++ {
+					chunks[i] = nil
+				}
+				chunks = chunks[:count]
+				debug.FreeOSMemory()
+			}
+
+			time.Sleep(profile.AdjustInterval)
+		}
+	}()
+
+	fmt.Println("[success]inject success")
+	common.SleepWait(timeout)
+}
+
 // [uid] [score] [fill bytes: KB/MB/GB/TB] [timeout second]
-// [uid] [score] [percent] [bytes] [timeout second]
+// [uid] [score] [percent] [bytes] [timeout second] [profile]
 func main() {
 	debug.SetGCPercent(-1)
 	args := os.Args
@@ -109,6 +159,11 @@ func main() {
 		common.ExitWithErr(fmt.Sprintf("set score error: %s", err.Error()))
 	}
 
+	if len(args) > 6 && args[6] != "" {
+		fillByProfile(args[6], args[5])
+		return
+	}
+
 	//percent, err := strconv.Atoi(percentStr)
 	//if err != nil {
 	//	common.ExitWithErr(fmt.Sprintf("percent is not a num: %s", err.Error()))
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"github.com/traas-stack/chaosmeta/chaosmetad/pkg/utils/ptrace"
+	"github.com/traas-stack/chaosmeta/chaosmetad/tools/common"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const leakInterval = time.Second
+
+// [uid] [rate bytes per second] [region list: pid:addr:size:start time,pid:addr:size:start time]
+func main() {
+	args := os.Args
+	if len(args) < 4 {
+		common.ExitWithErr("must provide 3 args: uid、rate、region list")
+	}
+
+	rate, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil || rate == 0 {
+		common.ExitWithErr(fmt.Sprintf("rate[%s] is not a positive num", args[2]))
+	}
+
+	regionList, err := parseRegionList(args[3])
+	if err != nil {
+		common.ExitWithErr(fmt.Sprintf("region list[%s] is invalid: %s", args[3], err.Error()))
+	}
+
+	var offset uint64
+	for {
+		var leftList []*ptrace.LeakRegion
+		for _, region := range regionList {
+			if err := ptrace.PopulateLeakRegion(region, offset, rate); err != nil {
+				if offset == 0 {
+					common.ExitWithErr(fmt.Sprintf("leak memory of process[%d] error: %s", region.Pid, err.Error()))
+				}
+				// the process may have exited. nothing is printed after inject success, the stdout may be closed
+				continue
+			}
+
+			if offset+rate < region.Size {
+				leftList = append(leftList, region)
+			}
+		}
+
+		if offset == 0 {
+			fmt.Println("[success]inject success")
+		}
+
+		// the memory is kept by the target process, so there is nothing to do after the cap is reached
+		if len(leftList) == 0 {
+			return
+		}
+
+		regionList, offset = leftList, offset+rate
+		time.Sleep(leakInterval)
+	}
+}
+
+func parseRegionList(listStr string) ([]*ptrace.LeakRegion, error) {
+	var regionList []*ptrace.LeakRegion
+	for _, unit := range strings.Split(listStr, ",") {
+		fields := strings.Split(unit, ":")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("region[%s] must be \"pid:addr:size:start time\"", unit)
+		}
+
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("pid[%s] is not a num", fields[0])
+		}
+
+		addr, err := strconv.ParseUint(fields[1], 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("addr[%s] is not a num", fields[1])
+		}
+
+		size, err := strconv.ParseUint(fields[2], 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("size[%s] is not a num", fields[2])
+		}
+
+		startTime, err := strconv.ParseUint(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("start time[%s] is not a num", fields[3])
+		}
+
+		regionList = append(regionList, &ptrace.LeakRegion{Pid: pid, StartTime: startTime, Addr: addr, Size: size})
+	}
+
+	return regionList, nil
+}
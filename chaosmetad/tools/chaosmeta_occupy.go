@@ -21,10 +21,13 @@ import (
 	"github.com/traas-stack/chaosmeta/chaosmetad/tools/common"
 	"net"
 	"os"
+	"runtime"
 	"strconv"
+	"strings"
+	"syscall"
 )
 
-// [uid] [port] [protocol] [timeout]
+// [uid] [port] [protocol] [timeout], port support range format: "[start]-[end]"
 func main() {
 	args := os.Args
 	if len(args) < 5 {
@@ -32,11 +35,72 @@ func main() {
 	}
 
 	p, proto, t := args[2], args[3], args[4]
+	if strings.Contains(p, "-") {
+		occupyRange(p, proto, t)
+		return
+	}
+
 	port, err := strconv.Atoi(p)
 	if err != nil || port <= 0 {
 		common.ExitWithErr("port is invalid")
 	}
 
+	proto = getProto(proto)
+	timeout := getTimeout(t)
+
+	if _, err := listen(proto, port); err != nil {
+		common.ExitWithErr(fmt.Sprintf("%s listen on %d error: %s", proto, port, err.Error()))
+	}
+
+	fmt.Println("[success]inject success")
+
+	common.SleepWait(timeout)
+}
+
+// occupyRange occupy all the free ports in range, the ports already in use are skipped
+func occupyRange(p, proto, t string) {
+	portRange := strings.Split(p, "-")
+	start, err := strconv.Atoi(portRange[0])
+	if err != nil || start <= 0 {
+		common.ExitWithErr("start port is invalid")
+	}
+
+	end, err := strconv.Atoi(portRange[1])
+	if err != nil || end < start || end > 65535 {
+		common.ExitWithErr("end port is invalid")
+	}
+
+	proto = getProto(proto)
+	timeout := getTimeout(t)
+
+	// one fd for each port
+	var limit = &syscall.Rlimit{Cur: uint64(end-start+1) + 1024, Max: uint64(end-start+1) + 1024}
+	if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, limit); err != nil {
+		common.ExitWithErr(fmt.Sprintf("set max fd of process error: %s", err.Error()))
+	}
+
+	var listenerList []interface{}
+	for port := start; port <= end; port++ {
+		l, err := listen(proto, port)
+		if err != nil {
+			continue
+		}
+
+		listenerList = append(listenerList, l)
+	}
+
+	if len(listenerList) == 0 {
+		common.ExitWithErr(fmt.Sprintf("no port in range[%s] is occupied", p))
+	}
+
+	fmt.Println("[success]inject success")
+
+	common.SleepWait(timeout)
+	// the listeners are closed by finalizer if collected
+	runtime.KeepAlive(listenerList)
+}
+
+func getProto(proto string) string {
 	if proto != "tcp" && proto != "udp" && proto != "tcp6" && proto != "udp6" {
 		common.ExitWithErr("proto only support: udp、tcp、udp6、tcp6")
 	}
@@ -47,25 +111,24 @@ func main() {
 		proto = "udp4"
 	}
 
-	var timeout int
-	timeout, err = strconv.Atoi(t)
+	return proto
+}
+
+func getTimeout(t string) int {
+	timeout, err := strconv.Atoi(t)
 	if err != nil {
 		common.ExitWithErr(fmt.Sprintf("timeout value is not a valid int, error: %s", err.Error()))
 	}
 
+	return timeout
+}
+
+func listen(proto string, port int) (interface{}, error) {
 	if proto == "tcp4" || proto == "tcp6" {
-		if _, err := net.Listen(proto, fmt.Sprintf(":%d", port)); err != nil {
-			common.ExitWithErr(fmt.Sprintf("%s listen on %d error: %s", proto, port, err.Error()))
-		}
-	} else {
-		if _, err := net.ListenUDP(proto, &net.UDPAddr{
-			Port: port,
-		}); err != nil {
-			common.ExitWithErr(fmt.Sprintf("%s listen on %d error: %s", proto, port, err.Error()))
-		}
+		return net.Listen(proto, fmt.Sprintf(":%d", port))
 	}
 
-	fmt.Println("[success]inject success")
-
-	common.SleepWait(timeout)
+	return net.ListenUDP(proto, &net.UDPAddr{
+		Port: port,
+	})
 }
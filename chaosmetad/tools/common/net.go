@@ -0,0 +1,58 @@
+/*
+ * Copyright 2022-2023 Chaos Meta Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+)
+
+// soOriginalDst the socket option to get the original destination of a connection redirected by iptables
+const soOriginalDst = 80
+
+// GetOriginalDst get the address which the client really wants to connect before redirected
+func GetOriginalDst(c net.Conn) (string, error) {
+	tcpConn, ok := c.(*net.TCPConn)
+	if !ok {
+		return "", fmt.Errorf("not a tcp connection")
+	}
+
+	rawConn, err := tcpConn.SyscallConn()
+	if err != nil {
+		return "", err
+	}
+
+	var addr *syscall.IPv6Mreq
+	var sockErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		addr, sockErr = syscall.GetsockoptIPv6Mreq(int(fd), syscall.IPPROTO_IP, soOriginalDst)
+	}); err != nil {
+		return "", err
+	}
+
+	if sockErr != nil {
+		return "", sockErr
+	}
+
+	// the result is a sockaddr_in: 2 bytes family, 2 bytes port, 4 bytes ip
+	ip := net.IPv4(addr.Multiaddr[4], addr.Multiaddr[5], addr.Multiaddr[6], addr.Multiaddr[7])
+	port := binary.BigEndian.Uint16(addr.Multiaddr[2:4])
+	return net.JoinHostPort(ip.String(), strconv.Itoa(int(port))), nil
+}